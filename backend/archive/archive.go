@@ -637,6 +637,16 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 	return do(ctx, remote, src, options...)
 }
 
+// ResumeChunkWriter reopens the upload with uploadID started by
+// OpenChunkWriter and returns the chunks already uploaded
+func (f *Fs) ResumeChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, uploadID string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ResumableChunkWriter, parts []fs.ChunkWriterPart, err error) {
+	do := f.f.Features().ResumeChunkWriter
+	if do == nil {
+		return info, nil, nil, fs.ErrorNotImplemented
+	}
+	return do(ctx, remote, src, uploadID, options...)
+}
+
 // UserInfo returns info about the connected user
 func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
 	do := f.f.Features().UserInfo
@@ -657,23 +667,24 @@ func (f *Fs) Disconnect(ctx context.Context) error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.ChangeNotifier  = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Shutdowner      = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.PutUncheckeder  = (*Fs)(nil)
-	_ fs.MergeDirser     = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.OpenWriterAter  = (*Fs)(nil)
-	_ fs.OpenChunkWriter = (*Fs)(nil)
-	_ fs.UserInfoer      = (*Fs)(nil)
-	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Fs                 = (*Fs)(nil)
+	_ fs.Purger             = (*Fs)(nil)
+	_ fs.PutStreamer        = (*Fs)(nil)
+	_ fs.Copier             = (*Fs)(nil)
+	_ fs.Mover              = (*Fs)(nil)
+	_ fs.DirMover           = (*Fs)(nil)
+	_ fs.DirCacheFlusher    = (*Fs)(nil)
+	_ fs.ChangeNotifier     = (*Fs)(nil)
+	_ fs.Abouter            = (*Fs)(nil)
+	_ fs.Shutdowner         = (*Fs)(nil)
+	_ fs.PublicLinker       = (*Fs)(nil)
+	_ fs.PutUncheckeder     = (*Fs)(nil)
+	_ fs.MergeDirser        = (*Fs)(nil)
+	_ fs.CleanUpper         = (*Fs)(nil)
+	_ fs.OpenWriterAter     = (*Fs)(nil)
+	_ fs.OpenChunkWriter    = (*Fs)(nil)
+	_ fs.ChunkWriterResumer = (*Fs)(nil)
+	_ fs.UserInfoer         = (*Fs)(nil)
+	_ fs.Disconnecter       = (*Fs)(nil)
 	// FIXME _ fs.FullObject      = (*Object)(nil)
 )
@@ -2679,6 +2679,33 @@ func (bic *blockIDCreator) newBlockID(chunkNumber uint64) string {
 	return base64.StdEncoding.EncodeToString(binaryBlockID[:])
 }
 
+// create a blockID creator from the ID returned by uploadID
+func newBlockIDCreatorFromUploadID(uploadID string) (bic *blockIDCreator, err error) {
+	random, err := hex.DecodeString(uploadID)
+	if err != nil || len(random) != len(bic.random) {
+		return nil, fmt.Errorf("invalid upload ID %q", uploadID)
+	}
+	bic = &blockIDCreator{}
+	copy(bic.random[:], random)
+	return bic, nil
+}
+
+// uploadID returns an ID for the upload made from the random part
+// of the block IDs
+func (bic *blockIDCreator) uploadID() string {
+	return hex.EncodeToString(bic.random[:])
+}
+
+// parseID returns the chunk number of a block ID made by this
+// blockIDCreator
+func (bic *blockIDCreator) parseID(id string) (chunkNumber uint64, ok bool) {
+	binaryBlockID, err := base64.StdEncoding.DecodeString(id)
+	if err != nil || len(binaryBlockID) != 16 || !bytes.Equal(binaryBlockID[8:], bic.random[:]) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(binaryBlockID[:8]), true
+}
+
 // Check the chunkNumber is correct in the id
 func (bic *blockIDCreator) checkID(chunkNumber uint64, id string) error {
 	binaryBlockID, err := base64.StdEncoding.DecodeString(id)
@@ -2702,6 +2729,7 @@ func (bic *blockIDCreator) checkID(chunkNumber uint64, id string) error {
 type azBlock struct {
 	chunkNumber uint64
 	id          string
+	size        int64
 }
 
 // Implements the fs.ChunkWriter interface
@@ -2717,11 +2745,9 @@ type azChunkWriter struct {
 	checker   *checkForInvalidBlockOrBlob
 }
 
-// OpenChunkWriter returns the chunk size and a ChunkWriter
-//
-// Pass in the remote and the src object
-// You can also use options to hint at the desired chunk size
-func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+// newChunkWriter prepares a ChunkWriter for remote without choosing
+// the block IDs
+func (f *Fs) newChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, chunkWriter *azChunkWriter, err error) {
 	// Temporary Object under construction
 	o := &Object{
 		fs:     f,
@@ -2765,7 +2791,7 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 
 	fs.Debugf(o, "Multipart upload session started for %d parts of size %v", totalParts, partSize)
 
-	chunkWriter := &azChunkWriter{
+	chunkWriter = &azChunkWriter{
 		chunkSize: int64(partSize),
 		size:      size,
 		f:         f,
@@ -2778,14 +2804,105 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 		Concurrency: o.fs.opt.UploadConcurrency,
 		//LeavePartsOnError: o.fs.opt.LeavePartsOnError,
 	}
+	return info, chunkWriter, nil
+}
+
+// OpenChunkWriter returns the chunk size and a ChunkWriter
+//
+// Pass in the remote and the src object
+// You can also use options to hint at the desired chunk size
+func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	info, chunkWriter, err := f.newChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, nil, err
+	}
 	chunkWriter.bic, err = newBlockIDCreator()
 	if err != nil {
 		return info, nil, err
 	}
-	fs.Debugf(o, "open chunk writer: started multipart upload")
+	fs.Debugf(chunkWriter.o, "open chunk writer: started multipart upload")
 	return info, chunkWriter, nil
 }
 
+// ResumeChunkWriter carries on the multipart upload uploadID to
+// remote, returning the uncommitted blocks of the upload.
+//
+// The blocks aren't part of the upload until they are passed to AddChunk.
+func (f *Fs) ResumeChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, uploadID string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ResumableChunkWriter, parts []fs.ChunkWriterPart, err error) {
+	info, chunkWriter, err := f.newChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, nil, nil, err
+	}
+	chunkWriter.bic, err = newBlockIDCreatorFromUploadID(uploadID)
+	if err != nil {
+		return info, nil, nil, err
+	}
+	var blockList blockblob.GetBlockListResponse
+	err = f.pacer.Call(func() (bool, error) {
+		blockList, err = chunkWriter.ui.blb.GetBlockList(ctx, blockblob.BlockListTypeUncommitted, nil)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return info, nil, nil, fmt.Errorf("failed to read uncommitted block list: %w", err)
+	}
+	for _, block := range blockList.UncommittedBlocks {
+		if block.Name == nil || block.Size == nil {
+			continue
+		}
+		chunkNumber, ok := chunkWriter.bic.parseID(*block.Name)
+		if !ok {
+			continue
+		}
+		parts = append(parts, fs.ChunkWriterPart{
+			ChunkNumber: int(chunkNumber),
+			Size:        *block.Size,
+			ID:          *block.Name,
+		})
+	}
+	fs.Debugf(chunkWriter.o, "open chunk writer: resumed multipart upload with %d blocks", len(parts))
+	return info, chunkWriter, parts, nil
+}
+
+// UploadID returns the ID of the multipart upload
+func (w *azChunkWriter) UploadID() string {
+	return w.bic.uploadID()
+}
+
+// WrittenChunk returns the part written for chunkNumber if any
+func (w *azChunkWriter) WrittenChunk(chunkNumber int) (part fs.ChunkWriterPart, ok bool) {
+	w.blocksMu.Lock()
+	defer w.blocksMu.Unlock()
+	for _, block := range w.blocks {
+		if block.chunkNumber == uint64(chunkNumber) {
+			return fs.ChunkWriterPart{
+				ChunkNumber: chunkNumber,
+				Size:        block.size,
+				ID:          block.id,
+			}, true
+		}
+	}
+	return part, false
+}
+
+// AddChunk adds a block uploaded earlier to the multipart upload
+func (w *azChunkWriter) AddChunk(part fs.ChunkWriterPart) error {
+	if part.ChunkNumber < 0 {
+		return fmt.Errorf("invalid chunk number provided: %v", part.ChunkNumber)
+	}
+	err := w.bic.checkID(uint64(part.ChunkNumber), part.ID)
+	if err != nil {
+		return err
+	}
+	w.blocksMu.Lock()
+	w.blocks = append(w.blocks, azBlock{
+		chunkNumber: uint64(part.ChunkNumber),
+		id:          part.ID,
+		size:        part.Size,
+	})
+	w.blocksMu.Unlock()
+	return nil
+}
+
 // isInvalidBlockOrBlob looks for the InvalidBlockOrBlob error in err
 // returning true if it is found
 func isInvalidBlockOrBlob(err error) bool {
@@ -2894,6 +3011,7 @@ func (w *azChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader
 	w.blocks = append(w.blocks, azBlock{
 		chunkNumber: uint64(chunkNumber),
 		id:          blockID,
+		size:        currentChunkSize,
 	})
 	w.blocksMu.Unlock()
 
@@ -3347,15 +3465,16 @@ func parseTier(tier string) *blob.AccessTier {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.Copier          = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.Purger          = &Fs{}
-	_ fs.ListRer         = &Fs{}
-	_ fs.ListPer         = &Fs{}
-	_ fs.OpenChunkWriter = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.MimeTyper       = &Object{}
-	_ fs.GetTierer       = &Object{}
-	_ fs.SetTierer       = &Object{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.Copier             = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.Purger             = &Fs{}
+	_ fs.ListRer            = &Fs{}
+	_ fs.ListPer            = &Fs{}
+	_ fs.OpenChunkWriter    = &Fs{}
+	_ fs.ChunkWriterResumer = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.MimeTyper          = &Object{}
+	_ fs.GetTierer          = &Object{}
+	_ fs.SetTierer          = &Object{}
 )
@@ -52,6 +52,23 @@ func TestBlockIDCreator(t *testing.T) {
 	assert.ErrorContains(t, bic.checkID(chunkNumber, "AAAA"+got), "bad block ID length")
 	assert.ErrorContains(t, bic.checkID(chunkNumber+1, got), "expecting decoded")
 	assert.ErrorContains(t, bic2.checkID(chunkNumber, got), "random bytes")
+
+	// Test the upload ID recreates the blockIDCreator
+	assert.Equal(t, "0102030405060708", bic.uploadID())
+	bic3, err := newBlockIDCreatorFromUploadID(bic.uploadID())
+	require.NoError(t, err)
+	assert.Equal(t, bic.random, bic3.random)
+	_, err = newBlockIDCreatorFromUploadID("0102")
+	assert.ErrorContains(t, err, "invalid upload ID")
+
+	// Test parseID is working
+	gotChunkNumber, ok := bic.parseID(got)
+	assert.True(t, ok)
+	assert.Equal(t, chunkNumber, gotChunkNumber)
+	_, ok = bic2.parseID(got)
+	assert.False(t, ok)
+	_, ok = bic.parseID("AAAA" + got)
+	assert.False(t, ok)
 }
 
 func TestCopySASTimingConstants(t *testing.T) {
@@ -316,6 +316,19 @@ type UploadPartResponse struct {
 	SHA1       string `json:"contentSha1"`   // The SHA1 of the bytes stored in the file.
 }
 
+// ListPartsRequest is passed to b2_list_parts
+type ListPartsRequest struct {
+	ID              string `json:"fileId"`                    // The ID returned by b2_start_large_file.
+	StartPartNumber int64  `json:"startPartNumber,omitempty"` // The first part to return. If there is a part with this number, it will be returned as the first in the list. If not, the returned list will start with the first part number after this one.
+	MaxPartCount    int    `json:"maxPartCount,omitempty"`    // The maximum number of parts to return from this call. The default value is 100, and the maximum allowed is 1000.
+}
+
+// ListPartsResponse is the response to b2_list_parts
+type ListPartsResponse struct {
+	Parts          []UploadPartResponse `json:"parts"`          // An array of objects, each one describing one part.
+	NextPartNumber *int64               `json:"nextPartNumber"` // What to pass in to startPartNumber for the next search to continue where this one left off, or null if there are no more parts.
+}
+
 // FinishLargeFileRequest is passed to b2_finish_large_file
 //
 // The response is a FileInfo object (with extra AccountID and BucketID fields which we ignore).
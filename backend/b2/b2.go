@@ -2381,6 +2381,41 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 	return info, up, nil
 }
 
+// ResumeChunkWriter carries on the upload of the large file
+// uploadID to remote, returning the parts which have been uploaded
+// so far.
+//
+// The parts aren't part of the upload until they are passed to AddChunk.
+func (f *Fs) ResumeChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, uploadID string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ResumableChunkWriter, parts []fs.ChunkWriterPart, err error) {
+	if f.opt.Versions {
+		return info, nil, nil, errNotWithVersions
+	}
+	if f.opt.VersionAt.IsSet() {
+		return info, nil, nil, errNotWithVersionAt
+	}
+	// Temporary Object under construction
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	up, uploaded, err := f.resumeLargeUpload(ctx, o, src, f.opt.ChunkSize, uploadID)
+	if err != nil {
+		return info, nil, nil, err
+	}
+	for _, part := range uploaded {
+		parts = append(parts, fs.ChunkWriterPart{
+			ChunkNumber: int(part.PartNumber) - 1,
+			Size:        part.Size,
+			ID:          part.SHA1,
+		})
+	}
+	info = fs.ChunkWriterInfo{
+		ChunkSize:   up.chunkSize,
+		Concurrency: o.fs.opt.UploadConcurrency,
+	}
+	return info, up, parts, nil
+}
+
 // Remove an object
 func (o *Object) Remove(ctx context.Context) error {
 	bucket, bucketPath := o.split()
@@ -2614,17 +2649,18 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.Purger          = &Fs{}
-	_ fs.Copier          = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.CleanUpper      = &Fs{}
-	_ fs.ListRer         = &Fs{}
-	_ fs.ListPer         = &Fs{}
-	_ fs.PublicLinker    = &Fs{}
-	_ fs.OpenChunkWriter = &Fs{}
-	_ fs.Commander       = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.MimeTyper       = &Object{}
-	_ fs.IDer            = &Object{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.Purger             = &Fs{}
+	_ fs.Copier             = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.CleanUpper         = &Fs{}
+	_ fs.ListRer            = &Fs{}
+	_ fs.ListPer            = &Fs{}
+	_ fs.PublicLinker       = &Fs{}
+	_ fs.OpenChunkWriter    = &Fs{}
+	_ fs.ChunkWriterResumer = &Fs{}
+	_ fs.Commander          = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.MimeTyper          = &Object{}
+	_ fs.IDer               = &Object{}
 )
@@ -80,8 +80,9 @@ type largeUpload struct {
 	id        string                          // ID of the file being uploaded
 	size      int64                           // total size
 	parts     int                             // calculated number of parts, if known
-	sha1smu   sync.Mutex                      // mutex to protect sha1s
+	sha1smu   sync.Mutex                      // mutex to protect sha1s and sizes
 	sha1s     []string                        // slice of SHA1s for each part
+	sizes     []int64                         // slice of sizes for each part
 	uploadMu  sync.Mutex                      // lock for upload variable
 	uploads   []*api.GetUploadPartURLResponse // result of get upload URL calls
 	chunkSize int64                           // chunk size to use
@@ -94,17 +95,7 @@ type largeUpload struct {
 // If newInfo is set then metadata from that will be used instead of reading it from src
 func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs.ObjectInfo, defaultChunkSize fs.SizeSuffix, doCopy bool, newInfo *api.File, options ...fs.OpenOption) (up *largeUpload, err error) {
 	size := src.Size()
-	parts := 0
-	chunkSize := defaultChunkSize
-	if size == -1 {
-		fs.Debugf(o, "Streaming upload with --b2-chunk-size %s allows uploads of up to %s and will fail only when that limit is reached.", f.opt.ChunkSize, maxParts*f.opt.ChunkSize)
-	} else {
-		chunkSize = chunksize.Calculator(o, size, maxParts, defaultChunkSize)
-		parts = int(size / int64(chunkSize))
-		if size%int64(chunkSize) != 0 {
-			parts++
-		}
-	}
+	chunkSize, parts := f.calculateParts(o, size, defaultChunkSize)
 	bucket, bucketPath := o.split()
 	bucketID, err := f.getBucketID(ctx, bucket)
 	if err != nil {
@@ -188,6 +179,64 @@ func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs
 	return up, nil
 }
 
+// calculateParts works out the chunk size and number of parts, if
+// known, to upload size bytes
+func (f *Fs) calculateParts(o *Object, size int64, defaultChunkSize fs.SizeSuffix) (chunkSize fs.SizeSuffix, parts int) {
+	chunkSize = defaultChunkSize
+	if size == -1 {
+		fs.Debugf(o, "Streaming upload with --b2-chunk-size %s allows uploads of up to %s and will fail only when that limit is reached.", f.opt.ChunkSize, maxParts*f.opt.ChunkSize)
+	} else {
+		chunkSize = chunksize.Calculator(o, size, maxParts, defaultChunkSize)
+		parts = int(size / int64(chunkSize))
+		if size%int64(chunkSize) != 0 {
+			parts++
+		}
+	}
+	return chunkSize, parts
+}
+
+// resumeLargeUpload carries on the upload of the large file with id
+// to object o, returning the parts uploaded so far
+func (f *Fs) resumeLargeUpload(ctx context.Context, o *Object, src fs.ObjectInfo, defaultChunkSize fs.SizeSuffix, id string) (up *largeUpload, parts []api.UploadPartResponse, err error) {
+	size := src.Size()
+	chunkSize, nParts := f.calculateParts(o, size, defaultChunkSize)
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_list_parts",
+	}
+	var request = api.ListPartsRequest{
+		ID:           id,
+		MaxPartCount: 1000,
+	}
+	for {
+		var response api.ListPartsResponse
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
+			return f.shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list parts of large file: %w", err)
+		}
+		parts = append(parts, response.Parts...)
+		if response.NextPartNumber == nil {
+			break
+		}
+		request.StartPartNumber = *response.NextPartNumber
+	}
+	up = &largeUpload{
+		f:         f,
+		o:         o,
+		what:      "upload",
+		id:        id,
+		size:      size,
+		parts:     nParts,
+		sha1s:     make([]string, 0, 16),
+		chunkSize: int64(chunkSize),
+	}
+	up.in, up.wrap = accounting.UnWrap(nil)
+	return up, parts, nil
+}
+
 // getUploadURL returns the upload info with the UploadURL and the AuthorizationToken
 //
 // This should be returned with returnUploadURL when finished
@@ -227,14 +276,46 @@ func (up *largeUpload) returnUploadURL(upload *api.GetUploadPartURLResponse) {
 	up.uploadMu.Unlock()
 }
 
-// Add an sha1 to the being built up sha1s
-func (up *largeUpload) addSha1(chunkNumber int, sha1 string) {
+// Add an sha1 and size to the being built up sha1s and sizes
+func (up *largeUpload) addSha1(chunkNumber int, size int64, sha1 string) {
 	up.sha1smu.Lock()
 	defer up.sha1smu.Unlock()
 	if len(up.sha1s) < chunkNumber+1 {
 		up.sha1s = append(up.sha1s, make([]string, chunkNumber+1-len(up.sha1s))...)
 	}
+	if len(up.sizes) < chunkNumber+1 {
+		up.sizes = append(up.sizes, make([]int64, chunkNumber+1-len(up.sizes))...)
+	}
 	up.sha1s[chunkNumber] = sha1
+	up.sizes[chunkNumber] = size
+}
+
+// UploadID returns the ID of the large file being uploaded
+func (up *largeUpload) UploadID() string {
+	return up.id
+}
+
+// WrittenChunk returns the part written for chunkNumber if any
+func (up *largeUpload) WrittenChunk(chunkNumber int) (part fs.ChunkWriterPart, ok bool) {
+	up.sha1smu.Lock()
+	defer up.sha1smu.Unlock()
+	if chunkNumber < 0 || chunkNumber >= len(up.sha1s) || up.sha1s[chunkNumber] == "" {
+		return part, false
+	}
+	return fs.ChunkWriterPart{
+		ChunkNumber: chunkNumber,
+		Size:        up.sizes[chunkNumber],
+		ID:          up.sha1s[chunkNumber],
+	}, true
+}
+
+// AddChunk adds a part uploaded earlier to the large file
+func (up *largeUpload) AddChunk(part fs.ChunkWriterPart) error {
+	if part.ChunkNumber < 0 {
+		return fmt.Errorf("invalid chunk number provided: %v", part.ChunkNumber)
+	}
+	up.addSha1(part.ChunkNumber, part.Size, part.ID)
+	return nil
 }
 
 // WriteChunk will write chunk number with reader bytes, where chunk number >= 0
@@ -323,7 +404,7 @@ func (up *largeUpload) WriteChunk(ctx context.Context, chunkNumber int, reader i
 			upload = nil
 		}
 		up.returnUploadURL(upload)
-		up.addSha1(chunkNumber, in.HexSum())
+		up.addSha1(chunkNumber, size, in.HexSum())
 		return retry, err
 	})
 	if err != nil {
@@ -366,7 +447,7 @@ func (up *largeUpload) copyChunk(ctx context.Context, part int, partSize int64)
 		if err != nil {
 			fs.Debugf(up.o, "Error copying chunk %d (retry=%v): %v: %#v", part, retry, err, err)
 		}
-		up.addSha1(part, response.SHA1)
+		up.addSha1(part, partSize, response.SHA1)
 		return retry, err
 	})
 	if err != nil {
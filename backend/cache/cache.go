@@ -349,7 +349,7 @@ var warnDeprecated sync.Once
 // NewFs constructs an Fs from the path, container:path
 func NewFs(ctx context.Context, name, rootPath string, m configmap.Mapper) (fs.Fs, error) {
 	warnDeprecated.Do(func() {
-		fs.Logf(nil, "WARNING: Cache backend is deprecated and may be removed in future. Please use VFS instead.")
+		fs.Logf(nil, "WARNING: Cache backend is deprecated and may be removed in future. Please use VFS or the readcache backend instead.")
 	})
 
 	// Parse config into Options struct
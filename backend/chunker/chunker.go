@@ -267,6 +267,41 @@ If meta format is set to "none", rename transactions will always be used.
 This method is EXPERIMENTAL, don't use on production systems.`,
 				},
 			},
+		}, {
+			Name:     "multi_thread_upload",
+			Advanced: true,
+			Default:  false,
+			Help: `Upload the chunks of large files in parallel.
+
+If set, large files copied to chunker with a multi-thread copy (see
+--multi-thread-cutoff) have several chunks uploaded to the wrapped
+remote at once, as set by upload_concurrency.
+
+As the chunks may be uploaded in any order, chunker can't calculate
+the hashsum on the fly. If a hash_type needing a stored hashsum is
+set and the source can't supply it, every chunk is read back from the
+wrapped remote after the upload to calculate it, which doubles the
+traffic for those files.`,
+		}, {
+			Name:     "upload_concurrency",
+			Advanced: true,
+			Default:  4,
+			Help: `Concurrency for multi-thread uploads.
+
+When multi_thread_upload is set, this many chunks are uploaded to the
+wrapped remote at once. Each chunk is uploaded with a temporary name
+and the file only appears once all its chunks have been uploaded.`,
+		}, {
+			Name:     "download_concurrency",
+			Advanced: true,
+			Default:  4,
+			Help: `Concurrency for downloads of composite files.
+
+When a composite file is read, upcoming chunks are fetched from the
+wrapped remote in parallel in blocks of up to 16 MiB using this many
+streams. Each stream buffers one block in memory.
+
+Set this to 1 to read the chunks one after another.`,
 		}},
 	})
 }
@@ -282,6 +317,9 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	if opt.StartFrom < 0 {
 		return nil, errors.New("start_from must be non-negative")
 	}
+	if opt.UploadConcurrency < 1 || opt.DownloadConcurrency < 1 {
+		return nil, errors.New("upload_concurrency and download_concurrency must be at least 1")
+	}
 
 	remote := opt.Remote
 	if strings.HasPrefix(remote, name+":") {
@@ -359,19 +397,32 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	f.features.ListR = nil // Recursive listing may cause chunker skip files
 	f.features.ListP = nil // ListP not supported yet
 
+	// Chunks are written with the wrapped remote's Put, so multi-thread
+	// uploads don't need support from the wrapped remote, and each
+	// chunk is read only once.
+	if opt.MultiThreadUpload {
+		f.features.OpenChunkWriter = f.OpenChunkWriter
+		f.features.ChunkWriterDoesntSeek = true
+	} else {
+		f.features.OpenChunkWriter = nil
+	}
+
 	return f, err
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	Remote       string        `config:"remote"`
-	ChunkSize    fs.SizeSuffix `config:"chunk_size"`
-	NameFormat   string        `config:"name_format"`
-	StartFrom    int           `config:"start_from"`
-	MetaFormat   string        `config:"meta_format"`
-	HashType     string        `config:"hash_type"`
-	FailHard     bool          `config:"fail_hard"`
-	Transactions string        `config:"transactions"`
+	Remote              string        `config:"remote"`
+	ChunkSize           fs.SizeSuffix `config:"chunk_size"`
+	NameFormat          string        `config:"name_format"`
+	StartFrom           int           `config:"start_from"`
+	MetaFormat          string        `config:"meta_format"`
+	HashType            string        `config:"hash_type"`
+	FailHard            bool          `config:"fail_hard"`
+	Transactions        string        `config:"transactions"`
+	MultiThreadUpload   bool          `config:"multi_thread_upload"`
+	UploadConcurrency   int           `config:"upload_concurrency"`
+	DownloadConcurrency int           `config:"download_concurrency"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -2194,9 +2245,20 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 		return o.mainChunk().Open(ctx, options...) // chain to wrapped non-chunked file
 	}
 
-	var openOptions []fs.OpenOption
-	var offset, limit int64 = 0, -1
+	offset, limit, openOptions, err := o.parseOpenOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if o.f.opt.DownloadConcurrency > 1 && limit > maxReadBlockSize {
+		return o.newParallelReader(ctx, offset, limit, openOptions)
+	}
+	return o.newLinearReader(ctx, offset, limit, openOptions)
+}
 
+// parseOpenOptions works out the range to read from the Seek and Range
+// options, returning the remaining options to pass on to the chunks
+func (o *Object) parseOpenOptions(options []fs.OpenOption) (offset, limit int64, openOptions []fs.OpenOption, err error) {
+	offset, limit = 0, -1
 	for _, option := range options {
 		switch opt := option.(type) {
 		case *fs.SeekOption:
@@ -2210,13 +2272,12 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 	}
 
 	if offset < 0 {
-		return nil, errors.New("invalid offset")
+		return 0, 0, nil, errors.New("invalid offset")
 	}
 	if limit < 0 {
 		limit = o.size - offset
 	}
-
-	return o.newLinearReader(ctx, offset, limit, openOptions)
+	return offset, limit, openOptions, nil
 }
 
 // linearReader opens and reads file chunks sequentially, without read-ahead
@@ -2224,6 +2285,7 @@ type linearReader struct {
 	ctx     context.Context
 	chunks  []fs.Object
 	options []fs.OpenOption
+	size    int64
 	limit   int64
 	count   int64
 	pos     int
@@ -2236,19 +2298,55 @@ func (o *Object) newLinearReader(ctx context.Context, offset, limit int64, optio
 		ctx:     ctx,
 		chunks:  o.chunks,
 		options: options,
+		size:    o.size,
 		limit:   limit,
 	}
+	if err := r.skip(offset); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	// skip to chunk for given offset
+// skip to chunk for given offset
+func (r *linearReader) skip(offset int64) error {
 	err := io.EOF
 	for offset >= 0 && err != nil {
 		offset, err = r.nextChunk(offset)
 	}
 	if err == nil || err == io.EOF {
 		r.err = err
-		return r, nil
+		return nil
+	}
+	return err
+}
+
+// RangeSeek restarts reading at offset, which must be from the start
+// of the file, limiting the read to length bytes or to the end of the
+// file if length is negative.
+func (r *linearReader) RangeSeek(ctx context.Context, offset int64, whence int, length int64) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("can only seek from the start")
+	}
+	if offset < 0 || offset > r.size {
+		return 0, errors.New("invalid offset")
+	}
+	if err := r.Close(); err != nil {
+		return 0, err
+	}
+	if length < 0 || offset+length > r.size {
+		length = r.size - offset
+	}
+	r.pos, r.count, r.limit, r.err = 0, 0, length, nil
+	if err := r.skip(offset); err != nil {
+		r.err = err
+		return 0, err
 	}
-	return nil, err
+	return offset, nil
+}
+
+// Seek implements io.Seeker - only seeking from the start is supported
+func (r *linearReader) Seek(offset int64, whence int) (int64, error) {
+	return r.RangeSeek(r.ctx, offset, whence, -1)
 }
 
 func (r *linearReader) nextChunk(offset int64) (int64, error) {
@@ -2589,4 +2687,8 @@ var (
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
+	_ fs.OpenChunkWriter = (*Fs)(nil)
+	_ fs.ChunkWriter     = (*chunkWriter)(nil)
+	_ fs.RangeSeeker     = (*linearReader)(nil)
+	_ fs.RangeSeeker     = (*parallelReader)(nil)
 )
@@ -899,6 +899,128 @@ func testMD5AllSlow(t *testing.T, f *Fs) {
 	require.NoError(t, operations.Purge(ctx, baseFs, ""))
 }
 
+// Test chunks uploaded out of order by a multi-thread copy and read
+// back in parallel
+func testChunkWriter(t *testing.T, f *Fs) {
+	ctx := context.Background()
+	for _, transactions := range []string{"rename", "norename"} {
+		t.Run(transactions, func(t *testing.T) {
+			if transactions == "norename" && !f.useMeta {
+				t.Skip("Can't test norename transactions without metadata")
+			}
+			fsResult := deriveFs(ctx, t, f, "chunkwriter-"+transactions, settings{
+				"chunk_size":           "4B",
+				"transactions":         transactions,
+				"download_concurrency": 3,
+				"multi_thread_upload":  true,
+			})
+			chunkFs, ok := fsResult.(*Fs)
+			require.True(t, ok, "fs must be a chunker remote")
+			defer func() {
+				_ = operations.Purge(ctx, chunkFs.base, "")
+			}()
+
+			const contents = "abcdefghij"
+			src := object.NewStaticObjectInfo("file", mtime1, int64(len(contents)), true, nil, nil)
+			upload := func(chunkNos ...int) fs.ChunkWriter {
+				info, w, err := chunkFs.OpenChunkWriter(ctx, "file", src)
+				require.NoError(t, err)
+				assert.Equal(t, int64(4), info.ChunkSize)
+				for _, chunkNo := range chunkNos {
+					start := chunkNo * 4
+					end := min(start+4, len(contents))
+					n, err := w.WriteChunk(ctx, chunkNo, strings.NewReader(contents[start:end]))
+					require.NoError(t, err)
+					assert.Equal(t, int64(end-start), n)
+				}
+				return w
+			}
+			baseNames := func() (names []string) {
+				entries, err := chunkFs.base.List(ctx, "")
+				require.NoError(t, err)
+				for _, entry := range entries {
+					names = append(names, entry.Remote())
+				}
+				return names
+			}
+
+			// Nothing is visible until the writer is closed
+			w := upload(2, 0, 1)
+			_, err := chunkFs.NewObject(ctx, "file")
+			assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+			assert.Len(t, baseNames(), 3, "temporary chunks")
+			require.NoError(t, w.Close(ctx))
+
+			obj, err := chunkFs.NewObject(ctx, "file")
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(contents)), obj.Size())
+			assert.Equal(t, contents, fstests.ReadObject(ctx, t, obj, -1))
+			if chunkFs.useMD5 {
+				sum, err := obj.Hash(ctx, hash.MD5)
+				require.NoError(t, err)
+				assert.Equal(t, "a925576942e94b2ef57a066101b48876", sum)
+			}
+
+			// An interrupted upload leaves the file intact
+			w = upload(1)
+			assert.Len(t, baseNames(), len(obj.(*Object).chunks)+2, "one temporary chunk")
+			obj, err = chunkFs.NewObject(ctx, "file")
+			require.NoError(t, err)
+			assert.Equal(t, contents, fstests.ReadObject(ctx, t, obj, -1))
+			require.NoError(t, w.Abort(ctx))
+			assert.Len(t, baseNames(), len(obj.(*Object).chunks)+1, "temporary chunk removed")
+			obj, err = chunkFs.NewObject(ctx, "file")
+			require.NoError(t, err)
+			assert.Equal(t, contents, fstests.ReadObject(ctx, t, obj, -1))
+
+			// A missing chunk fails the upload
+			w = upload(0, 2)
+			assert.Error(t, w.Close(ctx))
+			require.NoError(t, w.Abort(ctx))
+			assert.Equal(t, contents, fstests.ReadObject(ctx, t, obj, -1))
+
+			// Read with the linear and parallel readers
+			o := obj.(*Object)
+			for name, open := range map[string]func(offset, limit int64) (io.ReadCloser, error){
+				"linear": func(offset, limit int64) (io.ReadCloser, error) {
+					return o.newLinearReader(ctx, offset, limit, nil)
+				},
+				"parallel": func(offset, limit int64) (io.ReadCloser, error) {
+					return o.newParallelReader(ctx, offset, limit, nil)
+				},
+			} {
+				t.Run(name, func(t *testing.T) {
+					in, err := open(3, 6)
+					require.NoError(t, err)
+					data, err := io.ReadAll(in)
+					require.NoError(t, err)
+					assert.Equal(t, contents[3:9], string(data))
+
+					seeker, ok := in.(fs.RangeSeeker)
+					require.True(t, ok, "must be a RangeSeeker")
+					for _, test := range []struct {
+						offset, length int64
+						want           string
+					}{
+						{0, -1, contents},
+						{5, 2, contents[5:7]},
+						{8, -1, contents[8:]},
+						{1, 100, contents[1:]},
+						{10, -1, ""},
+					} {
+						_, err = seeker.RangeSeek(ctx, test.offset, io.SeekStart, test.length)
+						require.NoError(t, err)
+						data, err = io.ReadAll(in)
+						require.NoError(t, err)
+						assert.Equal(t, test.want, string(data), "offset %d length %d", test.offset, test.length)
+					}
+					require.NoError(t, in.Close())
+				})
+			}
+		})
+	}
+}
+
 // InternalTest dispatches all internal tests
 func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("PutLarge", func(t *testing.T) {
@@ -934,6 +1056,9 @@ func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("MD5AllSlow", func(t *testing.T) {
 		testMD5AllSlow(t, f)
 	})
+	t.Run("ChunkWriter", func(t *testing.T) {
+		testChunkWriter(t, f)
+	})
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
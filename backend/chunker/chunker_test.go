@@ -42,6 +42,7 @@ func TestIntegration(t *testing.T) {
 			"PublicLink",
 			"OpenWriterAt",
 			"OpenChunkWriter",
+			"ResumeChunkWriter",
 			"MergeDirs",
 			"DirCacheFlush",
 			"UserInfo",
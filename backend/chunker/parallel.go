@@ -0,0 +1,416 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/asyncreader"
+	"github.com/rclone/rclone/fs/chunkedreader"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Blocks read in parallel never exceed this size so the memory used
+// by a reader is bounded by download_concurrency times this.
+const maxReadBlockSize = 16 * 1024 * 1024
+
+// chunkWriter uploads the chunks of a composite file in any order
+// for a multi-thread copy.
+//
+// Like put, chunks are uploaded with temporary names and only take
+// their final names (or are activated by the metadata for norename
+// transactions) in Close, so an upload interrupted part-way leaves
+// the target intact with only hidden temporary chunks behind.
+type chunkWriter struct {
+	f         *Fs
+	remote    string
+	src       fs.ObjectInfo
+	options   []fs.OpenOption
+	size      int64 // total size of the file
+	chunkSize int64 // size of all the chunks but the last
+	nChunks   int   // number of chunks expected
+	xactID    string
+	md5       string
+	sha1      string
+	needHash  bool // set if the hash must be calculated from the uploaded chunks
+
+	mu     sync.Mutex
+	chunks []fs.Object // uploaded chunks indexed by chunk number
+	done   bool        // set once closed or aborted
+}
+
+// OpenChunkWriter returns the chunk size and a ChunkWriter
+//
+// Pass in the remote and the src object
+// You can also use options to hint at the desired chunk size
+func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	if err := f.forbidChunk(src, remote); err != nil {
+		return info, nil, fmt.Errorf("upload refused: %w", err)
+	}
+	size := src.Size()
+	if size < 0 {
+		return info, nil, errors.New("can't upload chunks of a file of unknown size")
+	}
+	// Get target object with a quick directory scan and refuse to
+	// update a file of unsupported format
+	if target, err := f.scanObject(ctx, remote, true); err == nil {
+		if err := target.(*Object).readMetadata(ctx); err == ErrMetaUnknown {
+			return info, nil, fmt.Errorf("refusing to upload: %w", err)
+		}
+	}
+
+	w := &chunkWriter{
+		f:         f,
+		remote:    remote,
+		src:       src,
+		size:      size,
+		chunkSize: int64(f.opt.ChunkSize),
+	}
+	for _, option := range options {
+		switch opt := option.(type) {
+		case *fs.ChunkOption:
+			if opt.ChunkSize > 0 {
+				w.chunkSize = opt.ChunkSize
+			}
+		default:
+			w.options = append(w.options, option)
+		}
+	}
+	w.nChunks = max(int((size+w.chunkSize-1)/w.chunkSize), 1)
+	if w.nChunks-1 > maxSafeChunkNumber {
+		return info, nil, ErrChunkOverflow
+	}
+	w.chunks = make([]fs.Object, w.nChunks)
+	w.xactID, err = f.newXactID(ctx, remote)
+	if err != nil {
+		return info, nil, err
+	}
+
+	// The chunks may arrive in any order so the hash can't be
+	// calculated in transit. Ask the source for it, even if it is
+	// slow to calculate, as that is cheaper than reading the chunks
+	// back after the upload.
+	switch {
+	case f.useMD5:
+		if w.md5, _ = src.Hash(ctx, hash.MD5); w.md5 == "" {
+			if f.hashFallback {
+				w.sha1, _ = src.Hash(ctx, hash.SHA1)
+			} else {
+				w.needHash = true
+			}
+		}
+	case f.useSHA1:
+		if w.sha1, _ = src.Hash(ctx, hash.SHA1); w.sha1 == "" {
+			if f.hashFallback {
+				w.md5, _ = src.Hash(ctx, hash.MD5)
+			} else {
+				w.needHash = true
+			}
+		}
+	}
+
+	info = fs.ChunkWriterInfo{
+		ChunkSize:   w.chunkSize,
+		Concurrency: f.opt.UploadConcurrency,
+	}
+	return info, w, nil
+}
+
+// chunkSizeOf returns the expected size of chunk number chunkNo
+func (w *chunkWriter) chunkSizeOf(chunkNo int) int64 {
+	return min(w.chunkSize, w.size-int64(chunkNo)*w.chunkSize)
+}
+
+// WriteChunk will write chunk number with reader bytes, where chunk number >= 0
+func (w *chunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (bytesWritten int64, err error) {
+	if chunkNumber < 0 || chunkNumber >= w.nChunks {
+		return -1, fmt.Errorf("chunk number %d out of range: file has %d chunks", chunkNumber, w.nChunks)
+	}
+	size := w.chunkSizeOf(chunkNumber)
+	tempRemote := w.f.makeChunkName(w.remote, chunkNumber, "", w.xactID)
+	info := w.f.wrapInfo(w.src, tempRemote, size)
+	chunk, err := w.f.base.Put(ctx, reader, info, w.options...)
+	if err != nil {
+		return -1, err
+	}
+	if chunk.Size() != size {
+		silentlyRemove(ctx, chunk)
+		return -1, fmt.Errorf("incorrect size of chunk %d: %d != %d", chunkNumber, chunk.Size(), size)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		silentlyRemove(ctx, chunk)
+		return -1, errors.New("chunk writer already finished")
+	}
+	if old := w.chunks[chunkNumber]; old != nil && old.Remote() != chunk.Remote() {
+		silentlyRemove(ctx, old)
+	}
+	w.chunks[chunkNumber] = chunk
+	return size, nil
+}
+
+// Close completes the upload, giving the chunks their final names and
+// writing the metadata last of all.
+func (w *chunkWriter) Close(ctx context.Context) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return errors.New("chunk writer already finished")
+	}
+	f := w.f
+
+	// Validate uploaded chunks
+	var sizeTotal int64
+	for chunkNo, chunk := range w.chunks {
+		if chunk == nil {
+			return fmt.Errorf("chunk %d of %d was not uploaded", chunkNo, w.nChunks)
+		}
+		sizeTotal += chunk.Size()
+	}
+	if sizeTotal != w.size {
+		return fmt.Errorf("incorrect chunks size %d != %d", sizeTotal, w.size)
+	}
+
+	// Finalize small object as non-chunked, unless it looks like
+	// metadata or metadata is forced as in put.
+	if w.nChunks == 1 && !f.hashAll && f.useMeta {
+		needMeta, err := w.looksLikeMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		if !needMeta {
+			f.removeOldChunks(ctx, w.remote)
+			chunkMoved, errMove := f.baseMove(ctx, w.chunks[0], w.remote, delAlways)
+			if errMove != nil {
+				return errMove
+			}
+			w.chunks[0] = chunkMoved
+			w.done = true
+			return nil
+		}
+	}
+
+	if w.needHash && f.useMeta {
+		if err := w.hashChunks(ctx); err != nil {
+			return err
+		}
+	}
+
+	// If previous object was chunked, remove its chunks
+	f.removeOldChunks(ctx, w.remote)
+
+	xactID := w.xactID
+	if !f.useNoRename {
+		// The transaction suffix will be removed for backends with quick rename operations
+		for chunkNo, chunk := range w.chunks {
+			chunkRemote := f.makeChunkName(w.remote, chunkNo, "", "")
+			chunkMoved, errMove := f.baseMove(ctx, chunk, chunkRemote, delFailed)
+			if errMove != nil {
+				return errMove
+			}
+			w.chunks[chunkNo] = chunkMoved
+		}
+		xactID = ""
+	}
+
+	if !f.useMeta {
+		// Remove stale metadata, if any
+		oldMeta, errOldMeta := f.base.NewObject(ctx, w.remote)
+		if errOldMeta == nil {
+			silentlyRemove(ctx, oldMeta)
+		}
+		w.done = true
+		return nil
+	}
+
+	// Update meta object
+	var metadata []byte
+	switch f.opt.MetaFormat {
+	case "simplejson":
+		metadata, err = marshalSimpleJSON(ctx, sizeTotal, len(w.chunks), w.md5, w.sha1, xactID)
+	}
+	if err != nil {
+		return err
+	}
+	metaInfo := f.wrapInfo(w.src, w.remote, int64(len(metadata)))
+	if _, err = f.base.Put(ctx, bytes.NewReader(metadata), metaInfo); err != nil {
+		return err
+	}
+	w.done = true
+	return nil
+}
+
+// looksLikeMetadata checks whether a small single chunk could be
+// mistaken for chunker metadata so needs metadata of its own
+func (w *chunkWriter) looksLikeMetadata(ctx context.Context) (bool, error) {
+	chunk := w.chunks[0]
+	if chunk.Size() > maxMetadataSize {
+		return false, nil
+	}
+	in, err := chunk.Open(ctx)
+	if err != nil {
+		return false, err
+	}
+	data, err := io.ReadAll(in)
+	_ = in.Close()
+	if err != nil {
+		return false, err
+	}
+	_, madeByChunker, _ := unmarshalSimpleJSON(ctx, chunk, data)
+	return madeByChunker, nil
+}
+
+// hashChunks calculates the hash of the file by reading back the
+// uploaded chunks in order
+func (w *chunkWriter) hashChunks(ctx context.Context) error {
+	var hasher gohash.Hash
+	switch {
+	case w.f.useMD5:
+		hasher = md5.New()
+	case w.f.useSHA1:
+		hasher = sha1.New()
+	default:
+		return nil
+	}
+	fs.Debugf(w.src, "source has no hash, reading back %d chunks to calculate it", len(w.chunks))
+	for _, chunk := range w.chunks {
+		in, err := chunk.Open(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to hash chunks: %w", err)
+		}
+		_, err = io.Copy(hasher, in)
+		_ = in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to hash chunks: %w", err)
+		}
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if w.f.useMD5 {
+		w.md5 = sum
+	} else {
+		w.sha1 = sum
+	}
+	return nil
+}
+
+// Abort removes the chunks uploaded so far
+//
+// The metadata is only written when Close succeeds so the target is
+// left as it was, unless Close failed part way through finalizing.
+func (w *chunkWriter) Abort(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	var lastErr error
+	for chunkNo, chunk := range w.chunks {
+		if chunk == nil {
+			continue
+		}
+		if err := chunk.Remove(ctx); err != nil {
+			fs.Errorf(chunk, "Failed to remove temporary chunk: %v", err)
+			lastErr = err
+		}
+		w.chunks[chunkNo] = nil
+	}
+	return lastErr
+}
+
+// linearObject is an Object which is always read with a linearReader
+//
+// It is read in parallel by a chunkedreader.
+type linearObject struct {
+	*Object
+	options []fs.OpenOption
+}
+
+// Open the object using a linearReader
+func (o *linearObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	offset, limit, openOptions, err := o.parseOpenOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return o.newLinearReader(ctx, offset, limit, append(openOptions, o.options...))
+}
+
+// parallelReader reads a composite file with several streams so the
+// upcoming chunks are fetched while the current one is read
+type parallelReader struct {
+	ctx       context.Context
+	size      int64
+	cr        chunkedreader.ChunkedReader
+	remaining int64 // bytes left to read
+}
+
+func (o *Object) newParallelReader(ctx context.Context, offset, limit int64, options []fs.OpenOption) (io.ReadCloser, error) {
+	blockSize := min(int64(o.f.opt.ChunkSize), maxReadBlockSize)
+	r := &parallelReader{
+		ctx:  ctx,
+		size: o.size,
+		cr:   chunkedreader.New(ctx, &linearObject{Object: o, options: options}, blockSize, blockSize, o.f.opt.DownloadConcurrency),
+	}
+	if _, err := r.RangeSeek(ctx, offset, io.SeekStart, limit); err != nil {
+		_ = r.cr.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Read reads up to len(p) bytes into p
+func (r *parallelReader) Read(p []byte) (n int, err error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err = r.cr.Read(p)
+	r.remaining -= int64(n)
+	if err == nil && r.remaining <= 0 {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// RangeSeek restarts reading at offset, which must be from the start
+// of the file, limiting the read to length bytes or to the end of the
+// file if length is negative.
+func (r *parallelReader) RangeSeek(ctx context.Context, offset int64, whence int, length int64) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("can only seek from the start")
+	}
+	if offset < 0 || offset > r.size {
+		return 0, errors.New("invalid offset")
+	}
+	if length < 0 || offset+length > r.size {
+		length = r.size - offset
+	}
+	r.remaining = length
+	if length == 0 {
+		return offset, nil
+	}
+	return r.cr.Seek(offset, io.SeekStart)
+}
+
+// Seek implements io.Seeker - only seeking from the start is supported
+func (r *parallelReader) Seek(offset int64, whence int) (int64, error) {
+	return r.RangeSeek(r.ctx, offset, whence, -1)
+}
+
+// Close the reader, abandoning any blocks being fetched
+func (r *parallelReader) Close() error {
+	err := r.cr.Close()
+	if errors.Is(err, asyncreader.ErrorStreamAbandoned) {
+		err = nil
+	}
+	return err
+}
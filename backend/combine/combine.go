@@ -21,6 +21,7 @@ import (
 	"github.com/rclone/rclone/fs/config/configstruct"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/walk"
 	"golang.org/x/sync/errgroup"
@@ -32,6 +33,7 @@ func init() {
 		Name:        "combine",
 		Description: "Combine several remotes into one",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		MetadataInfo: &fs.MetadataInfo{
 			Help: `Any metadata supported by the underlying remote is read and written.`,
 		},
@@ -53,6 +55,36 @@ Embedded spaces can be added using quotes
 `,
 			Required: true,
 			Default:  fs.SpaceSepList(nil),
+		}, {
+			Name: "routes",
+			Help: `Rules for choosing the upstream new files are put on
+
+If this is set then the directory names of the upstreams aren't shown
+and instead the contents of all the upstreams are combined into one
+directory tree whose root is writable. Existing files are found on
+whichever upstream they are on and new files are put on the upstream
+chosen by the first route they match.
+
+These should be in the form
+
+    dir=rule dir2=rule2
+
+Where before the = is the directory name of an upstream and after is
+the rule. A rule is one or more of these conditions separated by ";"
+which must all match:
+
+- a filter style glob matched against the path of the file, e.g. "*.mp4"
+- size>N or size<N, e.g. "size>1G"
+- age>D or age<D using the modification time, e.g. "age>30d"
+
+For example
+
+    "cold=*.mp4" "cold=size>1G;age>30d" "hot=*"
+
+Use the rebalance backend command to move existing files if the routes
+are changed.`,
+			Default:  fs.SpaceSepList(nil),
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -61,6 +93,7 @@ Embedded spaces can be added using quotes
 // Options defines the configuration for this backend
 type Options struct {
 	Upstreams fs.SpaceSepList `config:"upstreams"`
+	Routes    fs.SpaceSepList `config:"routes"`
 }
 
 // Fs represents a combine of upstreams
@@ -72,6 +105,8 @@ type Fs struct {
 	hashSet   hash.Set             // common hashes
 	when      time.Time            // directory times
 	upstreams map[string]*upstream // map of upstreams
+	ordered   []*upstream          // upstreams in the order configured
+	routes    []*route             // routes for new objects if set
 }
 
 // adjustment stores the info to add a prefix to a path or chop characters off
@@ -86,12 +121,16 @@ type adjustment struct {
 //
 // mountpoint is the point the upstream is mounted and root is the combine root
 func newAdjustment(root, mountpoint string) (a adjustment) {
-	return adjustment{
+	a = adjustment{
 		root:            root,
 		rootSlash:       root + "/",
 		mountpoint:      mountpoint,
 		mountpointSlash: mountpoint + "/",
 	}
+	if mountpoint == "" {
+		a.mountpointSlash = ""
+	}
+	return a
 }
 
 var errNotUnderRoot = errors.New("file not under root")
@@ -144,12 +183,27 @@ func (f *Fs) newUpstream(ctx context.Context, dir, remote string) (*upstream, er
 		f:              uFs,
 		parent:         f,
 		dir:            dir,
-		pathAdjustment: newAdjustment(f.root, dir),
+		pathAdjustment: newAdjustment(f.root, f.mountpoint(dir)),
 	}
 	cache.PinUntilFinalized(u.f, u)
 	return u, nil
 }
 
+// routed returns true if the upstreams are combined into a single tree
+// with new objects placed by the routes
+func (f *Fs) routed() bool {
+	return len(f.opt.Routes) > 0
+}
+
+// mountpoint returns where the upstream with directory name dir is
+// mounted
+func (f *Fs) mountpoint(dir string) string {
+	if f.routed() {
+		return ""
+	}
+	return dir
+}
+
 // NewFs constructs an Fs from the path.
 //
 // The returned Fs is the actual Fs, referenced by remote in the config
@@ -181,12 +235,13 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 		root:      root,
 		opt:       *opt,
 		upstreams: make(map[string]*upstream, len(opt.Upstreams)),
+		ordered:   make([]*upstream, len(opt.Upstreams)),
 		when:      time.Now(),
 	}
 
 	g, gCtx := errgroup.WithContext(ctx)
 	var mu sync.Mutex
-	for _, upstream := range opt.Upstreams {
+	for i, upstream := range opt.Upstreams {
 		g.Go(func() (err error) {
 			equal := strings.IndexRune(upstream, '=')
 			if equal < 0 {
@@ -211,6 +266,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 				err = fmt.Errorf("duplicate directory name %q", dir)
 			} else {
 				f.upstreams[dir] = u
+				f.ordered[i] = u
 			}
 			mu.Unlock()
 			return err
@@ -220,6 +276,13 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 	if err != nil {
 		return nil, err
 	}
+	for _, text := range opt.Routes {
+		r, err := f.parseRoute(text)
+		if err != nil {
+			return nil, err
+		}
+		f.routes = append(f.routes, r)
+	}
 	// check features
 	var features = (&fs.Features{
 		CaseInsensitive:          true,
@@ -272,6 +335,13 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 	// Enable ListP always
 	features.ListP = f.ListP
 
+	// Listings are merged from all the upstreams when routing so
+	// ListR and MergeDirs can't be supported
+	if f.routed() {
+		features.ListR = nil
+		features.MergeDirs = nil
+	}
+
 	// Enable Purge when any upstreams support it
 	if features.Purge == nil {
 		for _, u := range f.upstreams {
@@ -358,7 +428,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 		}
 		// Adjust path adjustment to remove leaf
 		for _, u := range f.upstreams {
-			u.pathAdjustment = newAdjustment(f.root, u.dir)
+			u.pathAdjustment = newAdjustment(f.root, f.mountpoint(u.dir))
 		}
 		return f, fs.ErrorIsFile
 	}
@@ -426,6 +496,15 @@ func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	if f.root == "" && dir == "" {
 		return nil
 	}
+	if f.routed() {
+		return f.forEachDir(ctx, dir, func(u *upstream, uDir string) error {
+			// Only remove the directory where it exists
+			if err := u.checkDir(ctx, uDir); err != nil {
+				return err
+			}
+			return u.f.Rmdir(ctx, uDir)
+		})
+	}
 	u, uRemote, err := f.findUpstream(dir)
 	if err != nil {
 		return err
@@ -444,6 +523,15 @@ func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	if f.root == "" && dir == "" {
 		return nil
 	}
+	if f.routed() {
+		return f.multithread(ctx, func(ctx context.Context, u *upstream) error {
+			uDir, err := u.pathAdjustment.undo(dir)
+			if err != nil {
+				return err
+			}
+			return u.f.Mkdir(ctx, uDir)
+		})
+	}
 	u, uRemote, err := f.findUpstream(dir)
 	if err != nil {
 		return err
@@ -453,7 +541,29 @@ func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 
 // MkdirMetadata makes the root directory of the Fs object
 func (f *Fs) MkdirMetadata(ctx context.Context, dir string, metadata fs.Metadata) (fs.Directory, error) {
-	u, uRemote, err := f.findUpstream(dir)
+	if f.routed() {
+		var first fs.Directory
+		for _, u := range f.ordered {
+			newDir, err := u.mkdirMetadata(ctx, dir, metadata)
+			if err != nil {
+				return nil, err
+			}
+			if first == nil {
+				first = newDir
+			}
+		}
+		return first, nil
+	}
+	u, _, err := f.findUpstream(dir)
+	if err != nil {
+		return nil, err
+	}
+	return u.mkdirMetadata(ctx, dir, metadata)
+}
+
+// mkdirMetadata makes dir on this upstream
+func (u *upstream) mkdirMetadata(ctx context.Context, dir string, metadata fs.Metadata) (fs.Directory, error) {
+	uRemote, err := u.pathAdjustment.undo(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -477,6 +587,21 @@ func (f *Fs) MkdirMetadata(ctx context.Context, dir string, metadata fs.Metadata
 	return newDir, nil
 }
 
+// checkDir returns fs.ErrorDirNotFound if dir doesn't exist on the upstream
+//
+// Directories in bucket based upstreams only exist if they have
+// something in them.
+func (u *upstream) checkDir(ctx context.Context, dir string) error {
+	entries, err := u.f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && u.f.Features().BucketBased {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
 // purge the upstream or fallback to a slow way
 func (u *upstream) purge(ctx context.Context, dir string) (err error) {
 	if do := u.f.Features().Purge; do != nil {
@@ -499,6 +624,15 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 			return u.purge(ctx, "")
 		})
 	}
+	if f.routed() {
+		return f.forEachDir(ctx, dir, func(u *upstream, uDir string) error {
+			// Only purge the directory where it exists
+			if err := u.checkDir(ctx, uDir); err != nil {
+				return err
+			}
+			return u.purge(ctx, uDir)
+		})
+	}
 	u, uRemote, err := f.findUpstream(dir)
 	if err != nil {
 		return err
@@ -506,6 +640,14 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 	return u.purge(ctx, uRemote)
 }
 
+// findDstUpstream finds the upstream to write the object at remote to
+func (f *Fs) findDstUpstream(ctx context.Context, remote string, src fs.ObjectInfo) (u *upstream, uRemote string, err error) {
+	if f.routed() {
+		return f.placeObject(ctx, remote, src)
+	}
+	return f.findUpstream(remote)
+}
+
 // Copy src to this remote using server-side copy operations.
 //
 // This is stored with the remote path given.
@@ -522,7 +664,7 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		return nil, fs.ErrorCantCopy
 	}
 
-	dstU, dstRemote, err := f.findUpstream(remote)
+	dstU, dstRemote, err := f.findDstUpstream(ctx, remote, src)
 	if err != nil {
 		return nil, err
 	}
@@ -556,7 +698,7 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		return nil, fs.ErrorCantMove
 	}
 
-	dstU, dstRemote, err := f.findUpstream(remote)
+	dstU, dstRemote, err := f.findDstUpstream(ctx, remote, src)
 	if err != nil {
 		return nil, err
 	}
@@ -603,6 +745,10 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 		return fs.ErrorCantDirMove
 	}
 
+	if f.routed() {
+		return f.dirMoveRouted(ctx, srcFs, srcRemote, dstRemote)
+	}
+
 	dstU, dstURemote, err := f.findUpstream(dstRemote)
 	if err != nil {
 		return err
@@ -622,6 +768,59 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 	return do(ctx, srcU.f, srcURemote, dstURemote)
 }
 
+// dirMoveRouted moves the directory on each of the upstreams it is on
+func (f *Fs) dirMoveRouted(ctx context.Context, srcFs *Fs, srcRemote, dstRemote string) error {
+	if !srcFs.routed() {
+		return fs.ErrorCantDirMove
+	}
+	type move struct {
+		u                      *upstream
+		srcU                   *upstream
+		srcURemote, dstURemote string
+	}
+	var moves []move
+	for _, u := range f.ordered {
+		srcU := srcFs.upstreams[u.dir]
+		if srcU == nil || srcU.f.Name() != u.f.Name() {
+			fs.Debugf(srcFs, "Can't move directory - upstreams differ")
+			return fs.ErrorCantDirMove
+		}
+		dstURemote, err := u.pathAdjustment.undo(dstRemote)
+		if err != nil {
+			return err
+		}
+		srcURemote, err := srcU.pathAdjustment.undo(srcRemote)
+		if err != nil {
+			return err
+		}
+		if u.checkDir(ctx, dstURemote) == nil {
+			return fs.ErrorDirExists
+		}
+		err = srcU.checkDir(ctx, srcURemote)
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		moves = append(moves, move{u: u, srcU: srcU, srcURemote: srcURemote, dstURemote: dstURemote})
+	}
+	if len(moves) == 0 {
+		return fs.ErrorDirNotFound
+	}
+	for _, m := range moves {
+		do := m.u.f.Features().DirMove
+		if do == nil {
+			return fs.ErrorCantDirMove
+		}
+		err := do(ctx, m.srcU.f, m.srcURemote, m.dstURemote)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ChangeNotify calls the passed function with a path
 // that has had changes. If the implementation
 // uses polling, it should adhere to the given interval.
@@ -676,8 +875,7 @@ func (f *Fs) DirCacheFlush() {
 }
 
 func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, stream bool, options ...fs.OpenOption) (fs.Object, error) {
-	srcPath := src.Remote()
-	u, uRemote, err := f.findUpstream(srcPath)
+	u, uRemote, err := f.findDstUpstream(ctx, src.Remote(), src)
 	if err != nil {
 		return nil, err
 	}
@@ -832,6 +1030,9 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 // immediately.
 func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
 	// defer log.Trace(f, "dir=%q", dir)("entries = %v, err=%v", &entries, &err)
+	if f.routed() {
+		return f.listRouted(ctx, dir, callback)
+	}
 	if f.root == "" && dir == "" {
 		entries := make(fs.DirEntries, 0, len(f.upstreams))
 		for combineDir := range f.upstreams {
@@ -927,6 +1128,16 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 
 // NewObject creates a new remote combine file object
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.routed() {
+		if join(f.root, remote) == "" {
+			return nil, fs.ErrorIsDir
+		}
+		u, o, err := f.findObject(ctx, remote)
+		if err != nil {
+			return nil, err
+		}
+		return u.newObject(o), nil
+	}
 	u, uRemote, err := f.findUpstream(remote)
 	if err != nil {
 		return nil, err
@@ -966,6 +1177,9 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 
 // PublicLink generates a public link to the remote path (usually readable by anyone)
 func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	if f.routed() {
+		return f.publicLinkRouted(ctx, remote, expire, unlink)
+	}
 	u, uRemote, err := f.findUpstream(remote)
 	if err != nil {
 		return "", err
@@ -977,6 +1191,39 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	return do(ctx, uRemote, expire, unlink)
 }
 
+// publicLinkRouted makes a public link using the upstream the object
+// is on, or the first upstream which can make one if remote is a
+// directory
+func (f *Fs) publicLinkRouted(ctx context.Context, remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	u, _, err := f.findObject(ctx, remote)
+	if err == nil {
+		return u.publicLink(ctx, remote, expire, unlink)
+	}
+	if !errors.Is(err, fs.ErrorObjectNotFound) {
+		return "", err
+	}
+	for _, u := range f.ordered {
+		link, err = u.publicLink(ctx, remote, expire, unlink)
+		if err == nil {
+			return link, nil
+		}
+	}
+	return "", err
+}
+
+// publicLink makes a public link for remote on this upstream
+func (u *upstream) publicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	uRemote, err := u.pathAdjustment.undo(remote)
+	if err != nil {
+		return "", err
+	}
+	do := u.f.Features().PublicLink
+	if do == nil {
+		return "", fs.ErrorNotImplemented
+	}
+	return do(ctx, uRemote, expire, unlink)
+}
+
 // PutUnchecked in to the remote path with the modTime given of the given size
 //
 // May create the object even if it returns an error - if so
@@ -986,8 +1233,7 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 // May create duplicates or return errors if src already
 // exists.
 func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	srcPath := src.Remote()
-	u, uRemote, err := f.findUpstream(srcPath)
+	u, uRemote, err := f.findDstUpstream(ctx, src.Remote(), src)
 	if err != nil {
 		return nil, err
 	}
@@ -1030,7 +1276,31 @@ func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
 
 // DirSetModTime sets the directory modtime for dir
 func (f *Fs) DirSetModTime(ctx context.Context, dir string, modTime time.Time) error {
-	u, uDir, err := f.findUpstream(dir)
+	if f.routed() {
+		// The directory need not exist on all the upstreams so
+		// succeed if it could be set on any of them
+		var firstErr error
+		for _, u := range f.ordered {
+			err := u.dirSetModTime(ctx, dir, modTime)
+			if err == nil {
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+	u, _, err := f.findUpstream(dir)
+	if err != nil {
+		return err
+	}
+	return u.dirSetModTime(ctx, dir, modTime)
+}
+
+// dirSetModTime sets the directory modtime for dir on this upstream
+func (u *upstream) dirSetModTime(ctx context.Context, dir string, modTime time.Time) error {
+	uDir, err := u.pathAdjustment.undo(dir)
 	if err != nil {
 		return err
 	}
@@ -1063,7 +1333,8 @@ func (f *Fs) CleanUp(ctx context.Context) error {
 //
 // It truncates any existing object
 func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
-	u, uRemote, err := f.findUpstream(remote)
+	src := object.NewStaticObjectInfo(remote, time.Now(), size, true, nil, f)
+	u, uRemote, err := f.findDstUpstream(ctx, remote, src)
 	if err != nil {
 		return nil, err
 	}
@@ -1074,6 +1345,41 @@ func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.Wr
 	return do(ctx, uRemote, size)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "rebalance",
+	Short: "Move files onto the upstreams chosen by the routes.",
+	Long: `This checks every file under the path against the routes and moves
+any which aren't on the upstream their route chooses onto it. Use this
+after changing the routes.
+
+Usage example:
+
+` + "```console" + `
+rclone backend rebalance combine:[path]
+` + "```" + `
+
+It returns the number of files checked, moved and failed as JSON.
+Use the --dry-run flag to see what would be moved without moving it.`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "rebalance":
+		return f.rebalance(ctx, "")
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Object describes a wrapped Object
 //
 // This is a wrapped Object which knows its path prefix
@@ -1193,5 +1499,6 @@ var (
 	_ fs.MkdirMetadataer = (*Fs)(nil)
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.OpenWriterAter  = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.FullObject      = (*Object)(nil)
 )
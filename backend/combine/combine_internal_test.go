@@ -1,10 +1,20 @@
 package combine
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdjustmentDo(t *testing.T) {
@@ -92,3 +102,132 @@ func TestAdjustmentUndo(t *testing.T) {
 	}
 
 }
+
+func TestParseCondition(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	for _, test := range []struct {
+		cond    string
+		remote  string
+		size    int64
+		age     time.Duration
+		want    bool
+		wantErr bool
+	}{
+		{cond: "*.mp4", remote: "films/film.mp4", want: true},
+		{cond: "*.mp4", remote: "films/film.mkv", want: false},
+		{cond: "/films/**", remote: "films/a/film.mkv", want: true},
+		{cond: "/films/**", remote: "music/song.mp3", want: false},
+		{cond: "size>1k", size: 2048, want: true},
+		{cond: "size>1k", size: 1024, want: false},
+		{cond: "size<1k", size: 10, want: true},
+		{cond: "size<1k", size: -1, want: false},
+		{cond: "age>1d", age: 48 * time.Hour, want: true},
+		{cond: "age>1d", age: time.Hour, want: false},
+		{cond: "age<1h", age: time.Minute, want: true},
+		{cond: "size>potato", wantErr: true},
+		{cond: "age<potato", wantErr: true},
+		{cond: "", wantErr: true},
+	} {
+		what := fmt.Sprintf("%+v", test)
+		c, err := parseCondition(test.cond)
+		if test.wantErr {
+			assert.Error(t, err, what)
+			continue
+		}
+		require.NoError(t, err, what)
+		src := object.NewStaticObjectInfo(test.remote, now.Add(-test.age), test.size, true, nil, nil)
+		assert.Equal(t, test.want, c(ctx, test.remote, src), what)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := t.TempDir(), t.TempDir()
+	newFs := func(routes string) *Fs {
+		f, err := NewFs(ctx, "TestCombineRoutes", "", configmap.Simple{
+			"upstreams": "hot=" + hot + " cold=" + cold,
+			"routes":    routes,
+		})
+		require.NoError(t, err)
+		return f.(*Fs)
+	}
+	put := func(f *Fs, remote string) {
+		src := object.NewStaticObjectInfo(remote, time.Now(), 5, true, nil, nil)
+		_, err := f.Put(ctx, bytes.NewBufferString("hello"), src)
+		require.NoError(t, err)
+	}
+	exists := func(dir, remote string) bool {
+		_, err := os.Stat(filepath.Join(dir, remote))
+		return err == nil
+	}
+
+	_, err := NewFs(ctx, "TestCombineRoutes", "", configmap.Simple{
+		"upstreams": "hot=" + hot,
+		"routes":    "warm=*",
+	})
+	assert.ErrorContains(t, err, "unknown upstream")
+
+	f := newFs(`"cold=*.mp4" "hot=*"`)
+	put(f, "a/film.mp4")
+	put(f, "a/notes.txt")
+	assert.True(t, exists(cold, "a/film.mp4"))
+	assert.True(t, exists(hot, "a/notes.txt"))
+
+	entries, err := f.List(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	o, err := f.NewObject(ctx, "a/film.mp4")
+	require.NoError(t, err)
+	assert.Equal(t, "a/film.mp4", o.Remote())
+
+	// No route matches
+	f = newFs(`"cold=*.mp4"`)
+	src := object.NewStaticObjectInfo("b.txt", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewBufferString("hello"), src)
+	assert.ErrorContains(t, err, "no route matches")
+
+	// Change the routes and rebalance
+	f = newFs(`"hot=*.mp4" "cold=*"`)
+	out, err := f.Command(ctx, "rebalance", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &rebalanceResult{Checked: 2, Moved: 2}, out)
+	assert.True(t, exists(hot, "a/film.mp4"))
+	assert.False(t, exists(cold, "a/film.mp4"))
+	assert.True(t, exists(cold, "a/notes.txt"))
+	assert.False(t, exists(hot, "a/notes.txt"))
+
+	_, err = f.Command(ctx, "potato", nil, nil)
+	assert.Equal(t, fs.ErrorCommandNotFound, err)
+
+	// Existing objects are overwritten where they are
+	f = newFs(`"cold=*"`)
+	src = object.NewStaticObjectInfo("a/notes.txt", time.Now(), 5, true, nil, nil)
+	_, err = f.put(ctx, bytes.NewBufferString("hello"), src, false)
+	require.NoError(t, err)
+	assert.True(t, exists(cold, "a/notes.txt"))
+	assert.False(t, exists(hot, "a/notes.txt"))
+
+	// Partial uploads go where the final file will be
+	f = newFs(`"hot=*.mp4" "cold=*"`)
+	put(f, "b/film.mp4.0123abcd.partial")
+	assert.True(t, exists(hot, "b/film.mp4.0123abcd.partial"))
+	put(f, "b/film.mp4.notahash.partial")
+	assert.True(t, exists(cold, "b/film.mp4.notahash.partial"))
+}
+
+func TestStripPartial(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{in: "film.mp4", want: "film.mp4"},
+		{in: "dir/film.mp4.0123abcd.partial", want: "dir/film.mp4"},
+		{in: "film.mp4.0123ABCD.partial", want: "film.mp4.0123ABCD.partial"},
+		{in: "film.mp4.123abcd.partial", want: "film.mp4.123abcd.partial"},
+		{in: ".0123abcd.partial", want: ".0123abcd.partial"},
+	} {
+		assert.Equal(t, test.want, stripPartial(ctx, test.in), test.in)
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 var (
-	unimplementableFsMethods     = []string{"UnWrap", "WrapFs", "SetWrapper", "UserInfo", "Disconnect", "OpenChunkWriter"}
+	unimplementableFsMethods     = []string{"UnWrap", "WrapFs", "SetWrapper", "UserInfo", "Disconnect", "OpenChunkWriter", "ResumeChunkWriter"}
 	unimplementableObjectMethods = []string{}
 )
 
@@ -82,6 +82,26 @@ func TestMixed(t *testing.T) {
 	})
 }
 
+func TestRouted(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	dirs := MakeTestDirs(t, 2)
+	upstreams := "dir1=" + dirs[0] + " dir2=" + dirs[1] + " dir3=:memory:dir3"
+	routes := `"dir1=*.txt;size<100" "dir2=*.txt" "dir3=*"`
+	name := "TestCombineRouted"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "combine"},
+			{Name: name, Key: "upstreams", Value: upstreams},
+			{Name: name, Key: "routes", Value: routes},
+		},
+		UnimplementableFsMethods:     append(unimplementableFsMethods, "ListR", "MergeDirs"),
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+	})
+}
+
 // MakeTestDirs makes directories in /tmp for testing
 func MakeTestDirs(t *testing.T, n int) (dirs []string) {
 	for i := 1; i <= n; i++ {
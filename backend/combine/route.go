@@ -0,0 +1,324 @@
+package combine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// condition is a single test in a routing rule
+type condition func(ctx context.Context, remote string, src fs.ObjectInfo) bool
+
+// route decides which upstream new objects matching all its
+// conditions are put on
+type route struct {
+	text       string      // the route as configured
+	u          *upstream   // upstream to put matching objects on
+	conditions []condition // all must match
+}
+
+// parseRoute parses a route in the form "dir=cond;cond"
+func (f *Fs) parseRoute(text string) (*route, error) {
+	equal := strings.IndexRune(text, '=')
+	if equal < 0 {
+		return nil, fmt.Errorf("no \"=\" in route %q", text)
+	}
+	dir, rule := text[:equal], text[equal+1:]
+	u, found := f.upstreams[dir]
+	if !found {
+		return nil, fmt.Errorf("unknown upstream %q in route %q", dir, text)
+	}
+	r := &route{
+		text: text,
+		u:    u,
+	}
+	for cond := range strings.SplitSeq(rule, ";") {
+		c, err := parseCondition(strings.TrimSpace(cond))
+		if err != nil {
+			return nil, fmt.Errorf("bad route %q: %w", text, err)
+		}
+		r.conditions = append(r.conditions, c)
+	}
+	return r, nil
+}
+
+// parseCondition parses a single routing condition which is either
+// size<N, size>N, age<D, age>D or a filter style glob
+func parseCondition(cond string) (condition, error) {
+	if cond == "" {
+		return nil, errors.New("empty condition")
+	}
+	for _, prefix := range []string{"size", "age"} {
+		rest, ok := strings.CutPrefix(cond, prefix)
+		if !ok || len(rest) < 2 || (rest[0] != '<' && rest[0] != '>') {
+			continue
+		}
+		greater, value := rest[0] == '>', rest[1:]
+		if prefix == "size" {
+			var limit fs.SizeSuffix
+			if err := limit.Set(value); err != nil {
+				return nil, fmt.Errorf("bad size in %q: %w", cond, err)
+			}
+			return func(ctx context.Context, remote string, src fs.ObjectInfo) bool {
+				size := src.Size()
+				if size < 0 {
+					return false
+				}
+				if greater {
+					return size > int64(limit)
+				}
+				return size < int64(limit)
+			}, nil
+		}
+		limit, err := fs.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad age in %q: %w", cond, err)
+		}
+		return func(ctx context.Context, remote string, src fs.ObjectInfo) bool {
+			age := time.Since(src.ModTime(ctx))
+			if greater {
+				return age > limit
+			}
+			return age < limit
+		}, nil
+	}
+	re, err := filter.GlobPathToRegexp(cond, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad glob %q: %w", cond, err)
+	}
+	return globCondition(re), nil
+}
+
+// globCondition matches the path of the object against re
+func globCondition(re *regexp.Regexp) condition {
+	return func(ctx context.Context, remote string, src fs.ObjectInfo) bool {
+		return re.MatchString(remote)
+	}
+}
+
+// matches returns true if the object at remote satisfies all the
+// conditions
+func (r *route) matches(ctx context.Context, remote string, src fs.ObjectInfo) bool {
+	for _, c := range r.conditions {
+		if !c(ctx, remote, src) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripPartial removes the suffix added to the names of partial
+// uploads, so they are put on the upstream the final file will be on.
+func stripPartial(ctx context.Context, remote string) string {
+	partialSuffix := fs.GetConfig(ctx).PartialSuffix
+	base, ok := strings.CutSuffix(remote, partialSuffix)
+	if partialSuffix == "" || !ok {
+		return remote
+	}
+	// The suffix is "." followed by 8 hex digits then partialSuffix
+	const hashLen = 1 + 8
+	if len(base) <= hashLen || base[len(base)-hashLen] != '.' {
+		return remote
+	}
+	for _, c := range base[len(base)-hashLen+1:] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return remote
+		}
+	}
+	return base[:len(base)-hashLen]
+}
+
+// routeUpstream chooses the upstream a new object at remote should be
+// put on using the first route it matches
+//
+// remote is relative to the root of the combine remote.
+func (f *Fs) routeUpstream(ctx context.Context, remote string, src fs.ObjectInfo) (*upstream, error) {
+	absPath := join(f.root, stripPartial(ctx, remote))
+	for _, r := range f.routes {
+		if r.matches(ctx, absPath, src) {
+			return r.u, nil
+		}
+	}
+	return nil, fmt.Errorf("combine: no route matches %q", absPath)
+}
+
+// findObject finds which upstream the existing object at remote is on
+//
+// The upstreams are searched in the order they were configured.
+func (f *Fs) findObject(ctx context.Context, remote string) (*upstream, fs.Object, error) {
+	var firstErr error
+	for _, u := range f.ordered {
+		uRemote, err := u.pathAdjustment.undo(remote)
+		if err != nil {
+			return nil, nil, err
+		}
+		o, err := u.f.NewObject(ctx, uRemote)
+		if err == nil {
+			return u, o, nil
+		}
+		if !errors.Is(err, fs.ErrorObjectNotFound) && !errors.Is(err, fs.ErrorDirNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return nil, nil, fs.ErrorObjectNotFound
+}
+
+// placeObject finds the upstream for writing the object at remote
+//
+// This is the upstream the object is on already if it exists,
+// otherwise the one chosen by the routes.
+func (f *Fs) placeObject(ctx context.Context, remote string, src fs.ObjectInfo) (u *upstream, uRemote string, err error) {
+	u, _, err = f.findObject(ctx, remote)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		u, err = f.routeUpstream(ctx, remote, src)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	uRemote, err = u.pathAdjustment.undo(remote)
+	if err != nil {
+		return nil, "", err
+	}
+	return u, uRemote, nil
+}
+
+// forEachDir runs fn on dir in every upstream in turn, skipping the
+// upstreams the directory doesn't exist in.
+//
+// It returns fs.ErrorDirNotFound if dir doesn't exist anywhere.
+func (f *Fs) forEachDir(ctx context.Context, dir string, fn func(u *upstream, uDir string) error) error {
+	found := false
+	for _, u := range f.ordered {
+		uDir, err := u.pathAdjustment.undo(dir)
+		if err != nil {
+			return err
+		}
+		err = fn(u, uDir)
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// listRouted lists dir from every upstream, merging the results
+//
+// Where an object is on more than one upstream the first one found
+// wins.
+func (f *Fs) listRouted(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var (
+		entries fs.DirEntries
+		seen    = map[string]struct{}{}
+	)
+	err := f.forEachDir(ctx, dir, func(u *upstream, uDir string) error {
+		uEntries, err := u.f.List(ctx, uDir)
+		if err != nil {
+			return err
+		}
+		uEntries, err = u.wrapEntries(ctx, uEntries)
+		if err != nil {
+			return err
+		}
+		for _, entry := range uEntries {
+			if _, found := seen[entry.Remote()]; found {
+				continue
+			}
+			seen[entry.Remote()] = struct{}{}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// rebalanceResult is returned by the rebalance command
+type rebalanceResult struct {
+	Checked int64 `json:"checked"`
+	Moved   int64 `json:"moved"`
+	Errors  int64 `json:"errors"`
+}
+
+// rebalance moves any objects under dir which aren't on the upstream
+// their route chooses onto it
+//
+// All the upstreams are listed before anything is moved so objects
+// are only checked once.
+func (f *Fs) rebalance(ctx context.Context, dir string) (*rebalanceResult, error) {
+	if len(f.routes) == 0 {
+		return nil, errors.New("rebalance needs routes to be configured")
+	}
+	type found struct {
+		u *upstream
+		o fs.Object
+	}
+	var objs []found
+	for _, u := range f.ordered {
+		uDir, err := u.pathAdjustment.undo(dir)
+		if err != nil {
+			return nil, err
+		}
+		err = walk.ListR(ctx, u.f, uDir, true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+			entries.ForObject(func(o fs.Object) {
+				objs = append(objs, found{u: u, o: o})
+			})
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+			return nil, err
+		}
+	}
+	res := &rebalanceResult{}
+	var lastErr error
+	for _, x := range objs {
+		res.Checked++
+		remote, err := x.u.pathAdjustment.do(x.o.Remote())
+		if err != nil {
+			return nil, err
+		}
+		dstU, err := f.routeUpstream(ctx, remote, x.o)
+		if err != nil {
+			fs.Errorf(path.Join(x.u.dir, x.o.Remote()), "Not rebalancing: %v", err)
+			res.Errors++
+			lastErr = err
+			continue
+		}
+		if dstU == x.u {
+			continue
+		}
+		fs.Infof(x.o, "Moving from %q to %q", x.u.dir, dstU.dir)
+		_, err = operations.Move(ctx, dstU.f, nil, x.o.Remote(), x.o)
+		if err != nil {
+			fs.Errorf(x.o, "Failed to rebalance: %v", err)
+			res.Errors++
+			lastErr = err
+			continue
+		}
+		res.Moved++
+	}
+	if lastErr != nil {
+		return res, fmt.Errorf("failed to rebalance %d objects: last error: %w", res.Errors, lastErr)
+	}
+	return res, nil
+}
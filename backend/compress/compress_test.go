@@ -20,6 +20,7 @@ var defaultOpt = fstests.Opt{
 	UnimplementableFsMethods: []string{
 		"OpenWriterAt",
 		"OpenChunkWriter",
+		"ResumeChunkWriter",
 		"MergeDirs",
 		"DirCacheFlush",
 		"PutUnchecked",
@@ -2,16 +2,21 @@
 package ftp
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	gohash "hash"
 	"io"
 	"net"
 	"net/textproto"
 	"net/url"
 	"path"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -100,6 +105,9 @@ maximum of |--checkers| and |--transfers|.
 So for |concurrency 3| you'd use |--checkers 2 --transfers 2
 --check-first| or |--checkers 1 --transfers 1|.
 
+Multi-thread copies use up to |--multi-thread-streams| connections for
+each transfer so allow for these too if you use them.
+
 `, "|", "`"),
 			Default:  0,
 			Advanced: true,
@@ -226,6 +234,47 @@ folders which are write only.
 
 You will likely need to use the --inplace flag also if uploading to
 a write only folder.
+`,
+			Advanced: true,
+		}, {
+			Name:    "resume_uploads",
+			Default: false,
+			Help: `Resume uploads which fail part of the way through.
+
+If set rclone keeps the last part of each upload in memory (see
+--ftp-resume-buffer-size) and if the upload fails, for example because
+the connection dropped, it reconnects, reads the size of the partial
+file with SIZE and carries on from there using REST and STOR, or APPE
+if the server doesn't support REST for uploads.
+
+It tries this up to --low-level-retries times. Once a resumed upload
+has finished it checks the size of the file and its MD5 using HASH or
+XMD5 if the server supports them, or else reads back the end of the
+file and compares that.
+`,
+			Advanced: true,
+		}, {
+			Name:    "resume_buffer_size",
+			Default: 16 * fs.Mebi,
+			Help: `Amount of each upload to keep in memory for resuming it.
+
+An upload can only be resumed if the server has stored all but this
+much of the data rclone has sent, so it should be bigger than the
+amount of data in flight on the connection.
+`,
+			Advanced: true,
+		}, {
+			Name:    "multi_thread_uploads",
+			Default: false,
+			Help: `Allow multi-thread copies to upload to the server.
+
+If set rclone can upload big files over several connections at once
+(see --multi-thread-streams), writing each part at its offset using
+REST and STOR.
+
+The server must allow REST at offsets beyond the current end of the
+file and must not truncate the file when it does. Not all servers do
+so check yours does before setting this.
 `,
 			Advanced: true,
 		}, {
@@ -276,6 +325,9 @@ type Options struct {
 	SocksProxy              string               `config:"socks_proxy"`
 	HTTPProxy               string               `config:"http_proxy"`
 	NoCheckUpload           bool                 `config:"no_check_upload"`
+	ResumeUploads           bool                 `config:"resume_uploads"`
+	ResumeBufferSize        fs.SizeSuffix        `config:"resume_buffer_size"`
+	MultiThreadUploads      bool                 `config:"multi_thread_uploads"`
 }
 
 // Fs represents a remote FTP server
@@ -704,6 +756,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (ff fs.Fs
 		CanHaveEmptyDirectories: true,
 		PartialUploads:          true,
 	}).Fill(ctx, f)
+	if !opt.MultiThreadUploads {
+		f.features.OpenWriterAt = nil
+	}
 	// get proxy URL if set
 	if opt.HTTPProxy != "" {
 		proxyURL, err := url.Parse(opt.HTTPProxy)
@@ -1384,17 +1439,29 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 			fs.Debugf(o, "Removed after failed upload: %v", err)
 		}
 	}
+	var rr *resumeReader
+	if o.fs.opt.ResumeUploads && o.fs.opt.ResumeBufferSize > 0 {
+		rr = newResumeReader(in, int(o.fs.opt.ResumeBufferSize))
+		in = rr
+	}
+	encPath := o.fs.opt.Enc.FromStandardPath(path)
 	c, err := o.fs.getFtpConnection(ctx)
 	if err != nil {
 		return fmt.Errorf("Update: %w", err)
 	}
-	err = c.Stor(o.fs.opt.Enc.FromStandardPath(path), in)
-	// Ignore error 250 here - send by some servers
-	if errX := textprotoError(err); errX != nil {
-		switch errX.Code {
-		case ftp.StatusRequestedFileActionOK:
-			err = nil
+	err = storError(c.Stor(encPath, in))
+	resumed := false
+	for tries := 1; err != nil && o.canResume(ctx, rr, err) && tries <= o.fs.ci.LowLevelRetries; tries++ {
+		fs.Debugf(o, "Resuming upload (%d/%d) after error: %v", tries, o.fs.ci.LowLevelRetries, err)
+		_ = c.Quit()
+		o.fs.putFtpConnection(nil, err)
+		c, err = o.fs.getFtpConnection(ctx)
+		if err != nil {
+			remove()
+			return fmt.Errorf("update resume: %w", err)
 		}
+		err = o.resumeUpload(c, encPath, rr)
+		resumed = true
 	}
 	if err != nil {
 		_ = c.Quit() // toss this connection to avoid sync errors
@@ -1424,9 +1491,249 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if err != nil {
 		return fmt.Errorf("update getinfo: %w", err)
 	}
+	if resumed {
+		if int64(o.info.Size) != rr.end {
+			err = fmt.Errorf("resumed upload is %d bytes but %d were sent", o.info.Size, rr.end)
+		} else {
+			err = o.checkResumed(ctx, encPath, rr)
+		}
+		if err != nil {
+			remove()
+			return err
+		}
+	}
 	return nil
 }
 
+// storError returns the error from a STOR or APPE
+func storError(err error) error {
+	// Ignore error 250 here - send by some servers
+	if errX := textprotoError(err); errX != nil {
+		switch errX.Code {
+		case ftp.StatusRequestedFileActionOK:
+			return nil
+		}
+	}
+	return err
+}
+
+// errCantResume is returned if an upload can't be carried on
+var errCantResume = errors.New("can't resume upload")
+
+// canResume returns true if the upload reading rr which failed with
+// err should be resumed
+func (o *Object) canResume(ctx context.Context, rr *resumeReader, err error) bool {
+	if rr == nil || rr.err != nil || ctx.Err() != nil || errors.Is(err, errCantResume) {
+		return false
+	}
+	// Permanent errors such as quota exceeded won't go away
+	if errX := textprotoError(err); errX != nil && errX.Code >= 500 {
+		return false
+	}
+	return true
+}
+
+// resumeUpload carries on an upload from the size of the partial file
+// on the server using REST and STOR, or APPE if the server doesn't
+// support REST for uploads
+func (o *Object) resumeUpload(c *ftp.ServerConn, encPath string, rr *resumeReader) error {
+	size, err := c.FileSize(encPath)
+	if err != nil {
+		if errX := textprotoError(err); errX == nil || errX.Code != ftp.StatusFileUnavailable {
+			return fmt.Errorf("failed to read size of partial upload: %w", err)
+		}
+		// Nothing was stored so start again
+		size = 0
+	}
+	err = rr.Rewind(size)
+	if err != nil {
+		return err
+	}
+	fs.Debugf(o, "Resuming upload at offset %d", size)
+	err = c.StorFrom(encPath, rr, uint64(size))
+	if errX := textprotoError(err); errX != nil && size > 0 {
+		switch errX.Code {
+		case ftp.StatusBadCommand, ftp.StatusBadArguments, ftp.StatusNotImplemented, ftp.StatusNotImplementedParameter:
+			// REST was refused before any data was sent
+			fs.Debugf(o, "REST refused so resuming with APPE: %v", err)
+			err = c.Append(encPath, rr)
+		}
+	}
+	return storError(err)
+}
+
+// checkResumed checks the data of an upload resumed using rr
+//
+// It compares the MD5 of the file with the one the server reports
+// with HASH or XMD5 if it supports them, otherwise it reads back the
+// data rr still has and compares that.
+func (o *Object) checkResumed(ctx context.Context, encPath string, rr *resumeReader) error {
+	sum, err := o.fs.serverMD5(ctx, encPath)
+	if err != nil {
+		fs.Debugf(o, "Checking end of resumed upload as can't read its MD5: %v", err)
+		return o.checkResumedTail(ctx, encPath, rr)
+	}
+	if want := rr.MD5(); !strings.EqualFold(sum, want) {
+		return fmt.Errorf("resumed upload is corrupted: server has MD5 %s but %s was sent", sum, want)
+	}
+	fs.Debugf(o, "Resumed upload has correct MD5 %s", sum)
+	return nil
+}
+
+// checkResumedTail reads back the end of an upload resumed using rr
+// and checks it matches the data rr kept
+func (o *Object) checkResumedTail(ctx context.Context, encPath string, rr *resumeReader) error {
+	off, want := rr.Tail()
+	c, err := o.fs.getFtpConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("check resumed upload: %w", err)
+	}
+	fd, err := c.RetrFrom(encPath, uint64(off))
+	if err != nil {
+		o.fs.putFtpConnection(&c, err)
+		return fmt.Errorf("check resumed upload: %w", err)
+	}
+	rc := &ftpReadCloser{rc: fd, c: c, f: o.fs}
+	got, err := io.ReadAll(rc)
+	closeErr := rc.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("check resumed upload: %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("resumed upload is corrupted: last %d bytes don't match the data sent", len(want))
+	}
+	fs.Debugf(o, "Resumed upload has correct last %d bytes", len(want))
+	return nil
+}
+
+// errNoServerHash is returned if the server can't read the MD5 of a file
+var errNoServerHash = errors.New("server doesn't advertise HASH with MD5 or XMD5")
+
+// serverMD5 reads the MD5 of the file at encPath from the server
+// using HASH or XMD5, whichever FEAT advertises
+func (f *Fs) serverMD5(ctx context.Context, encPath string) (sum string, err error) {
+	if strings.ContainsAny(encPath, "\r\n") {
+		return "", errors.New("path contains CR or LF")
+	}
+	c, err := f.getFtpConnection(ctx)
+	if err != nil {
+		return "", fmt.Errorf("serverMD5: %w", err)
+	}
+	defer func() {
+		f.putFtpConnection(&c, err)
+	}()
+	var msg string
+	if algorithms, ok := c.Feature("HASH"); ok && slices.Contains(strings.Split(strings.ReplaceAll(algorithms, "*", ""), ";"), "MD5") {
+		if _, _, err = c.Cmd(ftp.StatusCommandOK, "OPTS HASH MD5"); err != nil {
+			return "", err
+		}
+		// The reply is "213 MD5 <start>-<end> <hash> <path>"
+		if _, msg, err = c.Cmd(ftp.StatusFile, "HASH %s", encPath); err != nil {
+			return "", err
+		}
+		fields := strings.Fields(msg)
+		if len(fields) >= 3 && strings.EqualFold(fields[0], "MD5") {
+			sum = fields[2]
+		}
+	} else if _, ok := c.Feature("XMD5"); ok {
+		// The reply is "250 <hash>" or "213 <hash>" usually
+		if _, msg, err = c.Cmd(2, "XMD5 %s", encPath); err != nil {
+			return "", err
+		}
+		fields := strings.Fields(msg)
+		if len(fields) > 0 {
+			sum = fields[len(fields)-1]
+		}
+	} else {
+		return "", errNoServerHash
+	}
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != 2*md5.Size {
+		return "", fmt.Errorf("can't parse MD5 from %q", msg)
+	}
+	return sum, nil
+}
+
+// resumeReader reads an upload keeping the last part of it so the
+// upload can be carried on from an earlier offset
+type resumeReader struct {
+	in   io.Reader
+	buf  []byte      // ring buffer with the data at offset x at x % len(buf)
+	off  int64       // offset of the next byte to return
+	end  int64       // number of bytes read from in
+	err  error       // error reading from in, if any
+	hash gohash.Hash // MD5 of the data read from in
+}
+
+// newResumeReader makes a resumeReader keeping the last size bytes of in
+func newResumeReader(in io.Reader, size int) *resumeReader {
+	return &resumeReader{
+		in:   in,
+		buf:  make([]byte, size),
+		hash: md5.New(),
+	}
+}
+
+// Read data replaying any kept data after a Rewind
+func (r *resumeReader) Read(p []byte) (n int, err error) {
+	size := int64(len(r.buf))
+	if r.off < r.end {
+		// Replay from the ring buffer
+		i := r.off % size
+		n = copy(p[:min(int64(len(p)), r.end-r.off)], r.buf[i:])
+		r.off += int64(n)
+		return n, nil
+	}
+	n, err = r.in.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	_, _ = r.hash.Write(p[:n])
+	// Keep the data read in the ring buffer
+	data, off := p[:n], r.end
+	if int64(len(data)) > size {
+		off += int64(len(data)) - size
+		data = data[int64(len(data))-size:]
+	}
+	for len(data) > 0 {
+		copied := copy(r.buf[off%size:], data)
+		data = data[copied:]
+		off += int64(copied)
+	}
+	r.end += int64(n)
+	r.off = r.end
+	return n, err
+}
+
+// Rewind sets the reader to return data from offset off again
+func (r *resumeReader) Rewind(off int64) error {
+	if off > r.end || off < r.end-int64(len(r.buf)) {
+		return fmt.Errorf("%w: server has %d bytes but %d have been sent and only the last %d kept", errCantResume, off, r.end, len(r.buf))
+	}
+	r.off = off
+	return nil
+}
+
+// MD5 returns the hex MD5 of all the data read from in
+func (r *resumeReader) MD5() string {
+	return hex.EncodeToString(r.hash.Sum(nil))
+}
+
+// Tail returns the data kept and the offset it starts at
+func (r *resumeReader) Tail() (off int64, data []byte) {
+	size := int64(len(r.buf))
+	off = max(r.end-size, 0)
+	data = make([]byte, 0, r.end-off)
+	for i := off; i < r.end; {
+		chunk := r.buf[i%size : min(size, i%size+r.end-i)]
+		data = append(data, chunk...)
+		i += int64(len(chunk))
+	}
+	return off, data
+}
+
 // Remove an object
 func (o *Object) Remove(ctx context.Context) (err error) {
 	// defer fs.Trace(o, "")("err=%v", &err)
@@ -1449,12 +1756,177 @@ func (o *Object) Remove(ctx context.Context) (err error) {
 	return err
 }
 
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+//
+// It truncates any existing object
+func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, fmt.Errorf("OpenWriterAt mkParentDir failed: %w", err)
+	}
+	w := &writerAt{
+		ctx:     ctx,
+		f:       f,
+		encPath: f.opt.Enc.FromStandardPath(path.Join(f.root, remote)),
+		size:    size,
+	}
+	// Start the STOR for the start of the file now as it truncates
+	// the file, so it must be running before any other writes
+	s, err := w.newStream(0)
+	if err != nil {
+		return nil, err
+	}
+	w.streams = append(w.streams, s)
+	return w, nil
+}
+
+// writerAt writes a file at random offsets using a STOR for each run
+// of sequential writes
+type writerAt struct {
+	ctx     context.Context
+	f       *Fs
+	encPath string
+	size    int64 // size of the file or -1 if unknown
+	mu      sync.Mutex
+	streams []*storStream
+}
+
+// storStream is a STOR in progress
+type storStream struct {
+	start int64          // offset the STOR started at
+	next  int64          // offset the next write must be at
+	busy  bool           // set while being written to
+	pw    *io.PipeWriter // write the data for the STOR here
+	done  chan error     // result of the STOR
+}
+
+// newStream starts a STOR at offset off returning once the server has
+// accepted it
+func (w *writerAt) newStream(off int64) (*storStream, error) {
+	c, err := w.f.getFtpConnection(w.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("OpenWriterAt: %w", err)
+	}
+	pr, pw := io.Pipe()
+	s := &storStream{
+		start: off,
+		next:  off,
+		pw:    pw,
+		done:  make(chan error, 1),
+	}
+	go func() {
+		err := storError(c.StorFrom(w.encPath, pr, uint64(off)))
+		if err != nil {
+			_ = c.Quit() // toss this connection to avoid sync errors
+			w.f.putFtpConnection(nil, err)
+		} else {
+			w.f.putFtpConnection(&c, nil)
+		}
+		_ = pr.CloseWithError(err)
+		s.done <- err
+	}()
+	// The STOR only reads the pipe once the data connection is open
+	_, err = pw.Write(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start upload at offset %d: %w", off, <-s.done)
+	}
+	return s, nil
+}
+
+// close finishes the STOR returning its error
+func (s *storStream) close() error {
+	_ = s.pw.Close()
+	return <-s.done
+}
+
+// getStream returns a stream to write at off, starting one if needed
+func (w *writerAt) getStream(off int64) (*storStream, error) {
+	w.mu.Lock()
+	for _, s := range w.streams {
+		if !s.busy && s.next == off {
+			s.busy = true
+			w.mu.Unlock()
+			return s, nil
+		}
+	}
+	w.mu.Unlock()
+	if off == 0 {
+		return nil, errors.New("can't write to the start of the file again")
+	}
+	s, err := w.newStream(off)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	s.busy = true
+	w.streams = append(w.streams, s)
+	w.mu.Unlock()
+	return s, nil
+}
+
+// WriteAt writes len(p) bytes from p at offset off
+func (w *writerAt) WriteAt(p []byte, off int64) (n int, err error) {
+	s, err := w.getStream(off)
+	if err != nil {
+		return 0, err
+	}
+	n, err = s.pw.Write(p)
+	w.mu.Lock()
+	s.next += int64(n)
+	s.busy = false
+	// The stream is finished if it has reached the end of the file
+	// or the start of another stream
+	finished := err != nil || (w.size >= 0 && s.next >= w.size)
+	for _, other := range w.streams {
+		if other != s && other.start == s.next {
+			finished = true
+		}
+	}
+	if finished {
+		for i, other := range w.streams {
+			if other == s {
+				w.streams = append(w.streams[:i], w.streams[i+1:]...)
+				break
+			}
+		}
+	}
+	w.mu.Unlock()
+	if finished {
+		closeErr := s.close()
+		if closeErr != nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return n, fmt.Errorf("WriteAt at offset %d failed: %w", off, err)
+	}
+	return n, nil
+}
+
+// Close finishes all the STORs in progress
+func (w *writerAt) Close() (err error) {
+	w.mu.Lock()
+	streams := w.streams
+	w.streams = nil
+	w.mu.Unlock()
+	for _, s := range streams {
+		closeErr := s.close()
+		if closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to finish upload at offset %d: %w", s.start, closeErr)
+		}
+	}
+	return err
+}
+
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Shutdowner  = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.OpenWriterAter = &Fs{}
+	_ fs.Shutdowner     = &Fs{}
+	_ fs.Object         = &Object{}
 )
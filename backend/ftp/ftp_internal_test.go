@@ -1,21 +1,39 @@
 package ftp
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	_ "github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	ftpserver "goftp.io/server/v2"
+	"goftp.io/server/v2/driver/file"
 )
 
 // A filename containing raw CR/LF must never survive encoding into an FTP
@@ -144,3 +162,393 @@ func (f *Fs) InternalTest(t *testing.T) {
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
+
+func TestResumeReader(t *testing.T) {
+	rr := newResumeReader(strings.NewReader("0123456789"), 4)
+	buf := make([]byte, 3)
+
+	read := func() string {
+		n, err := rr.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+	assert.Equal(t, "012", read())
+	assert.Equal(t, "345", read())
+	assert.Equal(t, "678", read())
+	off, data := rr.Tail()
+	assert.Equal(t, int64(5), off)
+	assert.Equal(t, "5678", string(data))
+
+	// Only the last 4 bytes are kept
+	assert.ErrorIs(t, rr.Rewind(4), errCantResume)
+	assert.ErrorIs(t, rr.Rewind(10), errCantResume)
+
+	// Replay wraps around the ring buffer then carries on reading
+	require.NoError(t, rr.Rewind(6))
+	assert.Equal(t, "67", read())
+	assert.Equal(t, "8", read())
+	assert.Equal(t, "9", read())
+	n, err := rr.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, rr.err)
+	assert.Equal(t, "781e5e245d69b566979b86e28d23f2c7", rr.MD5())
+	off, data = rr.Tail()
+	assert.Equal(t, int64(6), off)
+	assert.Equal(t, "6789", string(data))
+}
+
+// testDriver is a goftp file driver which writes at the REST offset
+// without truncating and can cut off an upload
+type testDriver struct {
+	ftpserver.Driver
+	root      string
+	failAfter atomic.Int64 // if > 0 cut off the next upload after this many bytes
+	corrupt   atomic.Bool  // if set corrupt the first byte of the next resumed upload
+}
+
+// PutFile implements ftpserver.Driver
+func (d *testDriver) PutFile(ctx *ftpserver.Context, destPath string, data io.Reader, offset int64) (int64, error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset < 0 {
+		flags |= os.O_TRUNC
+		offset = 0
+	}
+	f, err := os.OpenFile(filepath.Join(d.root, filepath.FromSlash(destPath)), flags, 0o666)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if limit := d.failAfter.Swap(0); limit > 0 {
+		n, _ := io.CopyN(io.NewOffsetWriter(f, offset), data, limit)
+		// Drop the data connection like a network failure would
+		if closer, ok := data.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return n, errors.New("connection lost")
+	}
+	n, err := io.Copy(io.NewOffsetWriter(f, offset), data)
+	if err == nil && offset > 0 && n > 0 && d.corrupt.Swap(false) {
+		_, err = f.WriteAt([]byte{^byte(offset)}, offset)
+	}
+	return n, err
+}
+
+// testCommand holds what the extra commands of the test server need
+type testCommand struct {
+	root string // root directory of the server
+}
+
+// setRoot sets the root directory of the server
+func (cmd *testCommand) setRoot(root string) {
+	cmd.root = root
+}
+
+// path returns the local path of the file param
+func (cmd *testCommand) path(sess *ftpserver.Session, param string) string {
+	return filepath.Join(cmd.root, filepath.FromSlash(sess.BuildPath(param)))
+}
+
+// testXMD5 implements the XMD5 command for the test server
+type testXMD5 struct {
+	testCommand
+	calls atomic.Int32
+}
+
+// IsExtend implements ftpserver.Command
+func (cmd *testXMD5) IsExtend() bool { return true }
+
+// RequireParam implements ftpserver.Command
+func (cmd *testXMD5) RequireParam() bool { return true }
+
+// RequireAuth implements ftpserver.Command
+func (cmd *testXMD5) RequireAuth() bool { return true }
+
+// Execute implements ftpserver.Command
+func (cmd *testXMD5) Execute(sess *ftpserver.Session, param string) {
+	cmd.calls.Add(1)
+	data, err := os.ReadFile(cmd.path(sess, param))
+	if err != nil {
+		sess.WriteMessage(550, err.Error())
+		return
+	}
+	sum := md5.Sum(data)
+	sess.WriteMessage(250, hex.EncodeToString(sum[:]))
+}
+
+// testRetr implements the REST and RETR commands for the test server
+//
+// Like most servers it replies 426 if the client closes the data
+// connection before the end of the file. It records the sessions it
+// was used by and the most downloads there were at once.
+type testRetr struct {
+	testCommand
+	mu        sync.Mutex
+	offsets   map[*ftpserver.Session]int64 // offsets set by REST
+	sessions  map[*ftpserver.Session]bool  // sessions which downloaded
+	active    int                          // downloads in progress
+	maxActive int                          // most downloads at once
+}
+
+func newTestRetr() *testRetr {
+	return &testRetr{
+		offsets:  map[*ftpserver.Session]int64{},
+		sessions: map[*ftpserver.Session]bool{},
+	}
+}
+
+// testRest is the REST command of a testRetr
+type testRest struct {
+	*testRetr
+}
+
+// IsExtend implements ftpserver.Command
+func (cmd *testRetr) IsExtend() bool { return false }
+
+// RequireParam implements ftpserver.Command
+func (cmd *testRetr) RequireParam() bool { return true }
+
+// RequireAuth implements ftpserver.Command
+func (cmd *testRetr) RequireAuth() bool { return true }
+
+// Execute implements ftpserver.Command for REST
+func (cmd testRest) Execute(sess *ftpserver.Session, param string) {
+	offset, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		sess.WriteMessage(501, err.Error())
+		return
+	}
+	cmd.mu.Lock()
+	cmd.offsets[sess] = offset
+	cmd.mu.Unlock()
+	sess.WriteMessage(350, "Restarting")
+}
+
+// Execute implements ftpserver.Command for RETR
+func (cmd *testRetr) Execute(sess *ftpserver.Session, param string) {
+	cmd.mu.Lock()
+	offset := cmd.offsets[sess]
+	delete(cmd.offsets, sess)
+	cmd.sessions[sess] = true
+	cmd.active++
+	cmd.maxActive = max(cmd.maxActive, cmd.active)
+	cmd.mu.Unlock()
+	defer func() {
+		cmd.mu.Lock()
+		cmd.active--
+		cmd.mu.Unlock()
+	}()
+	in, err := os.Open(cmd.path(sess, param))
+	if err != nil {
+		sess.WriteMessage(550, err.Error())
+		return
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	conn := sess.DataConn()
+	sess.WriteMessage(150, "Data transfer starting")
+	// Give other downloads a chance to start
+	time.Sleep(50 * time.Millisecond)
+	_, err = io.Copy(conn, io.NewSectionReader(in, offset, math.MaxInt64-offset))
+	_ = conn.Close()
+	if err != nil {
+		sess.WriteMessage(426, "Transfer aborted")
+		return
+	}
+	sess.WriteMessage(226, "Transfer complete")
+}
+
+// newTestServer starts an FTP server on a temporary directory
+// returning an Fs connected to it with the options in opts
+//
+// The server uses commands in place of or as well as its own.
+func newTestServer(t *testing.T, opts configmap.Simple, commands map[string]ftpserver.Command) (f fs.Fs, driver *testDriver) {
+	root := t.TempDir()
+	fileDriver, err := file.NewDriver(root)
+	require.NoError(t, err)
+	driver = &testDriver{Driver: fileDriver, root: root}
+	serverOpts := &ftpserver.Options{
+		Driver: driver,
+		Auth:   &ftpserver.SimpleAuth{Name: "rclone", Password: "password"},
+		Perm:   ftpserver.NewSimplePerm("rclone", "rclone"),
+		Logger: &ftpserver.DiscardLogger{},
+	}
+	if commands != nil {
+		// Add the commands to a copy of the default commands
+		server, err := ftpserver.NewServer(serverOpts)
+		require.NoError(t, err)
+		serverOpts.Commands = maps.Clone(server.Commands)
+		for name, cmd := range commands {
+			if cmd, ok := cmd.(interface{ setRoot(string) }); ok {
+				cmd.setRoot(root)
+			}
+			serverOpts.Commands[name] = cmd
+		}
+	}
+	server, err := ftpserver.NewServer(serverOpts)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	m := configmap.Simple{
+		"host": "127.0.0.1",
+		"port": fmt.Sprint(listener.Addr().(*net.TCPAddr).Port),
+		"user": "rclone",
+		"pass": obscure.MustObscure("password"),
+	}
+	for key, value := range opts {
+		m[key] = value
+	}
+	f, err = fs.NewFs(context.Background(), fmt.Sprintf(":ftp,%s:", m.String()))
+	require.NoError(t, err)
+	return f, driver
+}
+
+func TestResumeUploads(t *testing.T) {
+	for _, withXMD5 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("XMD5=%v", withXMD5), func(t *testing.T) {
+			testResumeUploads(t, withXMD5)
+		})
+	}
+}
+
+func testResumeUploads(t *testing.T, withXMD5 bool) {
+	ctx := context.Background()
+	const size = 1024 * 1024
+	var (
+		xmd5     *testXMD5
+		commands map[string]ftpserver.Command
+	)
+	if withXMD5 {
+		xmd5 = &testXMD5{}
+		commands = map[string]ftpserver.Command{"XMD5": xmd5}
+	}
+	f, driver := newTestServer(t, configmap.Simple{"resume_uploads": "true"}, commands)
+	modTime := fstest.Time("2020-03-08T09:30:00Z")
+	put := func(remote string) (fs.Object, error) {
+		info := object.NewStaticObjectInfo(remote, modTime, size, true, nil, nil)
+		return f.Put(ctx, readers.NewPatternReader(size), info)
+	}
+
+	// An upload cut off in the middle is carried on
+	driver.failAfter.Store(size / 3)
+	o, err := put("resumed.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(size), o.Size())
+	data, err := os.ReadFile(filepath.Join(driver.root, "resumed.bin"))
+	require.NoError(t, err)
+	want, err := io.ReadAll(readers.NewPatternReader(size))
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(want, data), "resumed upload is corrupted")
+	if xmd5 != nil {
+		assert.Equal(t, int32(1), xmd5.calls.Load())
+	}
+
+	// A resumed upload which the server corrupted is removed
+	driver.failAfter.Store(size / 3)
+	driver.corrupt.Store(true)
+	_, err = put("corrupted.bin")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resumed upload is corrupted")
+	assert.NoFileExists(t, filepath.Join(driver.root, "corrupted.bin"))
+
+	// Unless the data sent is no longer kept
+	f, driver = newTestServer(t, configmap.Simple{"resume_uploads": "true", "resume_buffer_size": "1k"}, commands)
+	driver.failAfter.Store(size / 3)
+	_, err = put("not-resumed.bin")
+	assert.ErrorIs(t, err, errCantResume)
+}
+
+func TestOpenWriterAt(t *testing.T) {
+	ctx := context.Background()
+	f, _ := newTestServer(t, nil, nil)
+	assert.Nil(t, f.Features().OpenWriterAt)
+
+	const (
+		size      = 1024 * 1024
+		chunkSize = size / 4
+	)
+	f, driver := newTestServer(t, configmap.Simple{"multi_thread_uploads": "true"}, nil)
+	require.NotNil(t, f.Features().OpenWriterAt)
+	want, err := io.ReadAll(readers.NewPatternReader(size))
+	require.NoError(t, err)
+	w, err := f.Features().OpenWriterAt(ctx, "dir/multi.bin", size)
+	require.NoError(t, err)
+
+	// Write the chunks concurrently in small pieces
+	var wg sync.WaitGroup
+	for start := int64(0); start < size; start += chunkSize {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for off := start; off < start+chunkSize; off += 4096 {
+				_, err := w.WriteAt(want[off:off+4096], off)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(filepath.Join(driver.root, "dir", "multi.bin"))
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(want, data), "multi-thread upload is corrupted")
+}
+
+func TestMultiThreadDownload(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	const (
+		size        = 64 * 1024 * 1024
+		chunkSize   = size / 8
+		concurrency = 3
+	)
+	ci.MultiThreadCutoff = chunkSize
+	ci.MultiThreadChunkSize = chunkSize
+	ci.MultiThreadStreams = 4
+	ci.MultiThreadSet = true
+	retr := newTestRetr()
+	f, driver := newTestServer(t, configmap.Simple{"concurrency": fmt.Sprint(concurrency)}, map[string]ftpserver.Command{
+		"REST": testRest{retr},
+		"RETR": retr,
+	})
+	want, err := io.ReadAll(readers.NewPatternReader(size))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(driver.root, "multi.bin"), want, 0o666))
+	src, err := f.NewObject(ctx, "multi.bin")
+	require.NoError(t, err)
+
+	// Ranged reads stop before the end of the file
+	rc, err := src.Open(ctx, &fs.RangeOption{Start: 1024, End: 2047})
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.True(t, bytes.Equal(want[1024:2048], got), "ranged read is corrupted")
+
+	dstDir := t.TempDir()
+	dst, err := fs.NewFs(ctx, dstDir)
+	require.NoError(t, err)
+	_, err = operations.Copy(ctx, dst, nil, "multi.bin", src)
+	require.NoError(t, err)
+	got, err = os.ReadFile(filepath.Join(dstDir, "multi.bin"))
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(want, got), "multi-thread download is corrupted")
+
+	// The chunks were read in parallel over connections reused from
+	// the pool even though the server aborted the ranged reads
+	retr.mu.Lock()
+	defer retr.mu.Unlock()
+	assert.Greater(t, retr.maxActive, 1)
+	assert.LessOrEqual(t, retr.maxActive, concurrency)
+	assert.LessOrEqual(t, len(retr.sessions), concurrency)
+}
@@ -28,6 +28,11 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 	switch name {
 	case "drop":
 		return nil, f.db.Stop(true)
+	case "flush":
+		if !f.opt.SharedDB {
+			return nil, errors.New("shared_db is not enabled")
+		}
+		return nil, f.flushShared(ctx)
 	case "dump", "fulldump":
 		return nil, f.dbDump(ctx, name == "fulldump", "")
 	case "import", "stickyimport":
@@ -51,6 +56,18 @@ Usage example:
 ` + "```console" + `
 rclone backend drop hasher:
 ` + "```",
+}, {
+	Name:  "flush",
+	Short: "Write the shared database.",
+	Long: `Write checksums changed in this run to the index files on the base
+remote. This is done automatically on exit and only works if
+shared_db is set.
+
+Usage example:
+
+` + "```console" + `
+rclone backend flush hasher:
+` + "```",
 }, {
 	Name:  "dump",
 	Short: "Dump the database.",
@@ -154,6 +171,7 @@ func (f *Fs) dbImport(ctx context.Context, hashName, sumRemote string, sticky bo
 		rootPath := f.Fs.Root()
 		for remote, hashVal := range hashes {
 			key := path.Join(rootPath, remote)
+			f.markShared(remote)
 			hashSums := operations.HashSums{hashName: hashVal}
 			if err := f.putRawHashes(ctx, key, anyFingerprint, hashSums); err != nil {
 				fs.Errorf(nil, "%s: failed to import: %v", remote, err)
@@ -19,6 +19,7 @@ import (
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/kv"
 )
 
@@ -51,16 +52,38 @@ func init() {
 			Advanced: true,
 			Default:  fs.SizeSuffix(0),
 			Help:     "Auto-update checksum for files smaller than this size (disabled by default).",
+		}, {
+			Name:     "shared_db",
+			Advanced: true,
+			Default:  false,
+			Help: `Keep a copy of the checksum cache on the base remote.
+
+If set, hasher stores the checksums of each directory in a small index
+file next to the data and merges it into the local cache when the
+directory is first used. This lets several machines share checksums
+for the same remote. Records are still bound to the object fingerprint
+so they are ignored when the size or modification time changes.
+
+Requires max_age to be non zero.`,
+		}, {
+			Name:     "shared_db_name",
+			Advanced: true,
+			Default:  ".rclone_hasher.json",
+			Help: `Name of the per directory index file used by shared_db.
+
+Files with this name are hidden from listings.`,
 		}},
 	})
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	Remote   string          `config:"remote"`
-	Hashes   fs.CommaSepList `config:"hashes"`
-	AutoSize fs.SizeSuffix   `config:"auto_size"`
-	MaxAge   fs.Duration     `config:"max_age"`
+	Remote       string          `config:"remote"`
+	Hashes       fs.CommaSepList `config:"hashes"`
+	AutoSize     fs.SizeSuffix   `config:"auto_size"`
+	MaxAge       fs.Duration     `config:"max_age"`
+	SharedDB     bool            `config:"shared_db"`
+	SharedDBName string          `config:"shared_db_name"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -81,6 +104,11 @@ type Fs struct {
 	slowHashes hash.Set // passed to the base and then cached
 	autoHashes hash.Set // calculated in-house and cached
 	keepHashes hash.Set // checksums to keep in cache (slow + auto)
+	// shared database
+	sharedMu     sync.Mutex
+	sharedLoaded map[string]struct{} // directories merged from the base remote
+	sharedDirty  map[string]struct{} // directories to write back
+	atexitHandle atexit.FnHandle
 }
 
 var warnExperimental sync.Once
@@ -164,6 +192,20 @@ func NewFs(ctx context.Context, fsname, rpath string, cmap configmap.Mapper) (fs
 		f.db = db
 	}
 
+	if f.opt.SharedDB {
+		if f.db == nil {
+			return nil, errors.New("shared_db requires max_age to be non zero")
+		}
+		if f.opt.SharedDBName == "" || strings.Contains(f.opt.SharedDBName, "/") {
+			return nil, fmt.Errorf("invalid shared_db_name %q", f.opt.SharedDBName)
+		}
+		f.sharedLoaded = map[string]struct{}{}
+		f.sharedDirty = map[string]struct{}{}
+		f.atexitHandle = atexit.Register(func() {
+			_ = f.flushShared(context.Background())
+		})
+	}
+
 	stubFeatures := &fs.Features{
 		CanHaveEmptyDirectories:  true,
 		IsLocal:                  true,
@@ -227,6 +269,9 @@ func (f *Fs) wrapEntries(baseEntries fs.DirEntries) (hashEntries fs.DirEntries,
 	for _, entry := range baseEntries {
 		switch x := entry.(type) {
 		case fs.Object:
+			if f.isShared(x.Remote()) {
+				continue
+			}
 			obj, err := f.wrapObject(x, nil)
 			if err != nil {
 				return nil, err
@@ -258,6 +303,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 // callback returns an error then the listing will stop
 // immediately.
 func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	f.loadShared(ctx, dir)
 	wrappedCallback := func(entries fs.DirEntries) error {
 		entries, err := f.wrapEntries(entries)
 		if err != nil {
@@ -293,6 +339,7 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 		if err := do(ctx, dir); err != nil {
 			return err
 		}
+		f.forgetShared(dir)
 		err := f.db.Do(true, &kvPurge{
 			dir: path.Join(f.Fs.Root(), dir),
 		})
@@ -304,6 +351,17 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 	return fs.ErrorCantPurge
 }
 
+// Rmdir removes the directory (container, bucket) if empty
+//
+// The shared index file is hidden from listings, so it is removed
+// first if it is the only thing left in the directory.
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if err := f.dropShared(ctx, dir); err != nil {
+		return err
+	}
+	return f.Fs.Rmdir(ctx, dir)
+}
+
 // PutStream uploads to the remote path with undeterminate size.
 func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	if do := f.Fs.Features().PutStream; do != nil {
@@ -326,6 +384,7 @@ func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo,
 
 // pruneHash deletes hash for a path
 func (f *Fs) pruneHash(remote string) error {
+	f.markShared(remote)
 	return f.db.Do(true, &kvPrune{
 		key: path.Join(f.Fs.Root(), remote),
 	})
@@ -439,6 +498,8 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if err != nil {
 		return nil, err
 	}
+	f.markShared(src.Remote())
+	f.markShared(remote)
 	_ = f.db.Do(true, &kvMove{
 		src: path.Join(f.Fs.Root(), src.Remote()),
 		dst: path.Join(f.Fs.Root(), remote),
@@ -460,6 +521,8 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 	}
 	err := do(ctx, srcFs.Fs, srcRemote, dstRemote)
 	if err == nil {
+		srcFs.forgetShared(srcRemote)
+		f.forgetShared(dstRemote)
 		_ = f.db.Do(true, &kvMove{
 			src: path.Join(srcFs.Fs.Root(), srcRemote),
 			dst: path.Join(f.Fs.Root(), dstRemote),
@@ -472,8 +535,14 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 
 // Shutdown the backend, closing any background tasks and any cached connections.
 func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	if f.opt.SharedDB {
+		atexit.Unregister(f.atexitHandle)
+		err = f.flushShared(ctx)
+	}
 	if f.db != nil && !f.db.IsStopped() {
-		err = f.db.Stop(false)
+		if err2 := f.db.Stop(false); err2 != nil {
+			err = err2
+		}
 	}
 	if do := f.Fs.Features().Shutdown; do != nil {
 		if err2 := do(ctx); err2 != nil {
@@ -485,6 +554,9 @@ func (f *Fs) Shutdown(ctx context.Context) (err error) {
 
 // NewObject finds the Object at remote.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.isShared(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
 	o, err := f.Fs.NewObject(ctx, remote)
 	return f.wrapObject(o, err)
 }
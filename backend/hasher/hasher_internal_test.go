@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"testing"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
@@ -122,6 +124,99 @@ func (f *Fs) testUpdateStoresHash(t *testing.T) {
 	_ = operations.Purge(ctx, f, dirName)
 }
 
+func (f *Fs) testSharedDB(t *testing.T) {
+	// make a temporary local remote
+	tempRoot, err := fstest.LocalRemote()
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tempRoot)
+	}()
+
+	// make a hasher remote keeping a shared database
+	ctx := context.Background()
+	remote := fmt.Sprintf(`:hasher,remote="%s",hashes=sha1,shared_db=true:`, tempRoot)
+	sharedFs, err := fs.NewFs(ctx, remote)
+	require.NoError(t, err)
+	hf := sharedFs.(*Fs)
+
+	const fileName = "shared_1/file_shared_1"
+	o := putFile(ctx, t, hf, fileName, "shared hashes")
+	hash1, err := o.Hash(ctx, hash.SHA1)
+	require.NoError(t, err)
+	require.NotEmpty(t, hash1)
+
+	// write the index to the base remote and check it is hidden
+	require.NoError(t, hf.flushShared(ctx))
+	baseObj, err := hf.Fs.NewObject(ctx, "shared_1/"+hf.opt.SharedDBName)
+	require.NoError(t, err)
+	assert.NotZero(t, baseObj.Size())
+	_, err = hf.NewObject(ctx, "shared_1/"+hf.opt.SharedDBName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	entries, err := hf.List(ctx, "shared_1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// forget the local record and check it is restored from the index
+	require.NoError(t, hf.db.Do(true, &kvPrune{key: path.Join(hf.Fs.Root(), fileName)}))
+	hf.forgetShared("")
+	hash2, err := hf.getRawHash(ctx, hash.SHA1, fileName, anyFingerprint, fs.ModTimeNotSupported)
+	assert.Error(t, err)
+	assert.Empty(t, hash2)
+	hf.loadShared(ctx, "shared_1")
+	hash2, err = hf.getRawHash(ctx, hash.SHA1, fileName, anyFingerprint, fs.ModTimeNotSupported)
+	require.NoError(t, err)
+	assert.Equal(t, hash1, hash2)
+
+	// removing the file drops the index
+	require.NoError(t, o.Remove(ctx))
+	require.NoError(t, hf.flushShared(ctx))
+	_, err = hf.Fs.NewObject(ctx, "shared_1/"+hf.opt.SharedDBName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func (f *Fs) testSharedDBRmdir(t *testing.T) {
+	// make a temporary local remote
+	tempRoot, err := fstest.LocalRemote()
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tempRoot)
+	}()
+
+	ctx := context.Background()
+	remote := fmt.Sprintf(`:hasher,remote="%s",hashes=sha1,shared_db=true:`, tempRoot)
+	sharedFs, err := fs.NewFs(ctx, remote)
+	require.NoError(t, err)
+	hf := sharedFs.(*Fs)
+
+	const dirName = "shared_rmdir"
+	require.NoError(t, hf.Mkdir(ctx, dirName))
+	o := putFile(ctx, t, hf, dirName+"/file", "shared rmdir")
+	_, err = o.Hash(ctx, hash.SHA1)
+	require.NoError(t, err)
+	require.NoError(t, hf.flushShared(ctx))
+	_, err = hf.Fs.NewObject(ctx, dirName+"/"+hf.opt.SharedDBName)
+	require.NoError(t, err)
+
+	// rmdir must fail while the file is there and keep the index
+	require.Error(t, hf.Rmdir(ctx, dirName))
+	_, err = hf.Fs.NewObject(ctx, dirName+"/"+hf.opt.SharedDBName)
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+
+	// the directory lists as empty so rmdir must succeed
+	entries, err := hf.List(ctx, dirName)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+	require.NoError(t, hf.Rmdir(ctx, dirName))
+	_, err = hf.List(ctx, dirName)
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	// nothing is written back at flush
+	require.NoError(t, hf.flushShared(ctx))
+	_, err = hf.Fs.List(ctx, dirName)
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
 // InternalTest dispatches all internal tests
 func (f *Fs) InternalTest(t *testing.T) {
 	if !kv.Supported() {
@@ -129,6 +224,8 @@ func (f *Fs) InternalTest(t *testing.T) {
 	}
 	t.Run("UploadFromCrypt", f.testUploadFromCrypt)
 	t.Run("UpdateStoresHash", f.testUpdateStoresHash)
+	t.Run("SharedDB", f.testSharedDB)
+	t.Run("SharedDBRmdir", f.testSharedDBRmdir)
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
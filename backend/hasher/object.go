@@ -23,6 +23,7 @@ func (o *Object) getHash(ctx context.Context, hashType hash.Type) (string, error
 	if fp == "" {
 		return "", errors.New("fingerprint failed")
 	}
+	o.f.loadShared(ctx, sharedDir(o.Remote()))
 	return o.f.getRawHash(ctx, hashType, o.Remote(), fp, maxAge)
 }
 
@@ -49,6 +50,7 @@ func (o *Object) putHashes(ctx context.Context, rawHashes hashMap) error {
 		return nil
 	}
 	key := path.Join(o.f.Fs.Root(), o.Remote())
+	o.f.markShared(o.Remote())
 	hashes := operations.HashSums{}
 	for hashType, hashVal := range rawHashes {
 		hashes[hashType.String()] = hashVal
@@ -0,0 +1,336 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// sharedVersion is the format version of the shared index files
+const sharedVersion = 1
+
+// sharedIndex is the content of a per directory index file kept on
+// the base remote when shared_db is enabled.
+type sharedIndex struct {
+	Version int                    `json:"version"`
+	Entries map[string]*sharedItem `json:"entries"`
+}
+
+// sharedItem is a single checksum record in a shared index file
+type sharedItem struct {
+	Fp      string              `json:"fp"`
+	Hashes  operations.HashSums `json:"hashes"`
+	Created time.Time           `json:"created"`
+}
+
+// isShared returns true if remote points to a shared index file
+func (f *Fs) isShared(remote string) bool {
+	return f.opt.SharedDB && path.Base(remote) == f.opt.SharedDBName
+}
+
+// sharedDir returns the directory of remote in the form used as
+// a key for shared index files
+func sharedDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// markShared notes that the shared index for the directory holding
+// remote needs to be written back
+func (f *Fs) markShared(remote string) {
+	if !f.opt.SharedDB {
+		return
+	}
+	f.sharedMu.Lock()
+	f.sharedDirty[sharedDir(remote)] = struct{}{}
+	f.sharedMu.Unlock()
+}
+
+// forgetShared drops the state of all the shared indexes under dir
+func (f *Fs) forgetShared(dir string) {
+	if !f.opt.SharedDB {
+		return
+	}
+	f.sharedMu.Lock()
+	defer f.sharedMu.Unlock()
+	for _, m := range []map[string]struct{}{f.sharedLoaded, f.sharedDirty} {
+		for key := range m {
+			if dir == "" || key == dir || strings.HasPrefix(key, dir+"/") {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+// readShared reads the shared index file for dir from the base remote
+//
+// It returns a nil index if the file does not exist.
+func (f *Fs) readShared(ctx context.Context, dir string) (*sharedIndex, fs.Object, error) {
+	obj, err := f.Fs.NewObject(ctx, path.Join(dir, f.opt.SharedDBName))
+	if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	in, err := obj.Open(ctx)
+	if err != nil {
+		return nil, obj, err
+	}
+	defer fs.CheckClose(in, &err)
+	var idx sharedIndex
+	if err = json.NewDecoder(in).Decode(&idx); err != nil {
+		return nil, obj, fmt.Errorf("invalid shared index: %w", err)
+	}
+	if idx.Version != sharedVersion {
+		return nil, obj, fmt.Errorf("unsupported shared index version %d", idx.Version)
+	}
+	return &idx, obj, nil
+}
+
+// loadShared merges the shared index for dir into the local database.
+// Each directory is loaded at most once per run.
+func (f *Fs) loadShared(ctx context.Context, dir string) {
+	if !f.opt.SharedDB {
+		return
+	}
+	f.sharedMu.Lock()
+	_, loaded := f.sharedLoaded[dir]
+	f.sharedLoaded[dir] = struct{}{}
+	f.sharedMu.Unlock()
+	if loaded {
+		return
+	}
+	if err := f.mergeShared(ctx, dir); err != nil {
+		fs.Errorf(f, "Failed to load shared hashes for %q: %v", dir, err)
+	}
+}
+
+// mergeShared reads the shared index for dir and merges it into the
+// local database.
+func (f *Fs) mergeShared(ctx context.Context, dir string) error {
+	idx, _, err := f.readShared(ctx, dir)
+	if err != nil || idx == nil {
+		return err
+	}
+	op := &kvMerge{
+		records: make(map[string]*hashRecord, len(idx.Entries)),
+	}
+	rootDir := path.Join(f.Fs.Root(), dir)
+	for leaf, item := range idx.Entries {
+		if item == nil || item.Fp == "" || len(item.Hashes) == 0 {
+			continue
+		}
+		op.records[path.Join(rootDir, leaf)] = &hashRecord{
+			Fp:      item.Fp,
+			Hashes:  item.Hashes,
+			Created: item.Created,
+		}
+	}
+	err = f.db.Do(true, op)
+	fs.Debugf(f, "Merged %d of %d shared hashes for %q", op.merged, len(op.records), dir)
+	return err
+}
+
+// saveShared writes a compacted shared index for dir to the base
+// remote. Only records matching the current object fingerprints are
+// kept so stale entries are dropped on every write.
+func (f *Fs) saveShared(ctx context.Context, dir string) error {
+	// Pick up records written by other hosts since we last looked
+	if err := f.mergeShared(ctx, dir); err != nil {
+		fs.Debugf(f, "Ignoring unreadable shared index for %q: %v", dir, err)
+	}
+	entries, err := f.Fs.List(ctx, dir)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	idx := &sharedIndex{
+		Version: sharedVersion,
+		Entries: map[string]*sharedItem{},
+	}
+	var oldIndex fs.Object
+	maxAge := time.Duration(f.opt.MaxAge)
+	for _, entry := range entries {
+		baseObj, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		if f.isShared(baseObj.Remote()) {
+			oldIndex = baseObj
+			continue
+		}
+		o := &Object{Object: baseObj, f: f}
+		fp := o.fingerprint(ctx)
+		if fp == "" {
+			continue
+		}
+		op := &kvGetRecord{key: path.Join(f.Fs.Root(), baseObj.Remote())}
+		if err := f.db.Do(false, op); err != nil || op.rec == nil {
+			continue
+		}
+		r := op.rec
+		if (r.Fp != fp && r.Fp != anyFingerprint) || time.Since(r.Created) > maxAge || len(r.Hashes) == 0 {
+			continue
+		}
+		idx.Entries[path.Base(baseObj.Remote())] = &sharedItem{
+			Fp:      r.Fp,
+			Hashes:  r.Hashes,
+			Created: r.Created,
+		}
+	}
+	if len(idx.Entries) == 0 {
+		if oldIndex != nil {
+			return oldIndex.Remove(ctx)
+		}
+		return nil
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	remote := path.Join(dir, f.opt.SharedDBName)
+	info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(data)), true, nil, f.Fs)
+	if oldIndex != nil {
+		return oldIndex.Update(ctx, bytes.NewReader(data), info)
+	}
+	_, err = f.Fs.Put(ctx, bytes.NewReader(data), info)
+	return err
+}
+
+// flushShared writes back all the shared indexes changed in this run
+func (f *Fs) flushShared(ctx context.Context) error {
+	if !f.opt.SharedDB {
+		return nil
+	}
+	f.sharedMu.Lock()
+	dirs := make([]string, 0, len(f.sharedDirty))
+	for dir := range f.sharedDirty {
+		dirs = append(dirs, dir)
+	}
+	f.sharedDirty = map[string]struct{}{}
+	f.sharedMu.Unlock()
+	sort.Strings(dirs)
+
+	var lastErr error
+	for _, dir := range dirs {
+		if err := f.saveShared(ctx, dir); err != nil {
+			fs.Errorf(f, "Failed to save shared hashes for %q: %v", dir, err)
+			lastErr = err
+		}
+	}
+	if len(dirs) > 0 {
+		fs.Debugf(f, "Saved shared hashes for %d directories", len(dirs))
+	}
+	return lastErr
+}
+
+// dropShared removes the shared index for dir if the directory holds
+// nothing else, so that a directory which lists as empty can be
+// removed. Pending changes for the directory are discarded as there
+// are no files left to describe.
+func (f *Fs) dropShared(ctx context.Context, dir string) error {
+	if !f.opt.SharedDB {
+		return nil
+	}
+	entries, err := f.Fs.List(ctx, dir)
+	if err != nil {
+		// Let Rmdir report the error
+		return nil
+	}
+	var index fs.Object
+	for _, entry := range entries {
+		obj, ok := entry.(fs.Object)
+		if !ok || !f.isShared(obj.Remote()) {
+			return nil
+		}
+		index = obj
+	}
+	f.sharedMu.Lock()
+	delete(f.sharedLoaded, dir)
+	delete(f.sharedDirty, dir)
+	f.sharedMu.Unlock()
+	if index == nil {
+		return nil
+	}
+	return index.Remove(ctx)
+}
+
+// kvMerge: merge records from a shared index into the database
+type kvMerge struct {
+	records map[string]*hashRecord
+	merged  int
+}
+
+func (op *kvMerge) Do(ctx context.Context, b kv.Bucket) error {
+	for key, in := range op.records {
+		data := b.Get([]byte(key))
+		var r hashRecord
+		if len(data) > 0 && r.decode(key, data) == nil && len(r.Hashes) > 0 {
+			switch {
+			case r.Fp == in.Fp:
+				// Same object: keep local values, add missing hash types
+				changed := false
+				for name, val := range in.Hashes {
+					if r.Hashes[name] == "" {
+						r.Hashes[name] = val
+						changed = true
+					}
+				}
+				if !changed {
+					continue
+				}
+			case in.Created.After(r.Created):
+				r = *in
+			default:
+				continue
+			}
+		} else {
+			r = *in
+		}
+		out, err := r.encode(key)
+		if err != nil {
+			return err
+		}
+		if err = b.Put([]byte(key), out); err != nil {
+			return err
+		}
+		op.merged++
+	}
+	return nil
+}
+
+// kvGetRecord: get the raw record for a key
+type kvGetRecord struct {
+	key string
+	rec *hashRecord
+}
+
+func (op *kvGetRecord) Do(ctx context.Context, b kv.Bucket) error {
+	data := b.Get([]byte(op.key))
+	if len(data) == 0 {
+		return nil
+	}
+	var r hashRecord
+	if err := r.decode(op.key, data); err != nil {
+		return err
+	}
+	op.rec = &r
+	return nil
+}
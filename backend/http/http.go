@@ -1,7 +1,8 @@
 // Package http provides a filesystem interface using golang.org/net/http
 //
 // It treats HTML pages served from the endpoint as directory
-// listings, and includes any links found as files.
+// listings, and includes any links found as files. It can also read
+// JSON and XML directory listings and S3 bucket listings.
 package http
 
 import (
@@ -98,6 +99,35 @@ sizes of any files, and some files that don't exist may be in the listing.`,
 			Name:    "no_escape",
 			Help:    "Do not escape URL metacharacters in path names.",
 			Default: false,
+		}, {
+			Name: "list_format",
+			Help: `Format of the directory listings to read.
+
+By default rclone reads HTML directory listings and finds the size
+and modification time of each file with a HEAD request. If the server
+returns a JSON or XML listing instead, for example nginx with
+"autoindex_format json" or "autoindex_format xml", then rclone reads
+the sizes and modification times from the listing and doesn't need
+the HEAD requests.
+
+Set this to "json" to ask the server for JSON listings with an
+"Accept: application/json" header. Caddy's file server returns these.
+
+Set this to "s3" if the URL is an S3 bucket which allows public
+listing. The bucket is listed with ListObjectsV2 requests which
+also allows recursive listing with --fast-list.`,
+			Default: listFormatAuto,
+			Examples: []fs.OptionExample{{
+				Value: listFormatAuto,
+				Help:  "Read HTML listings, or JSON or XML listings if the server returns them",
+			}, {
+				Value: listFormatJSON,
+				Help:  "Ask for JSON listings",
+			}, {
+				Value: listFormatS3,
+				Help:  "List an S3 bucket",
+			}},
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -139,11 +169,12 @@ var systemMetadataInfo = map[string]fs.MetadataHelp{
 
 // Options defines the configuration for this backend
 type Options struct {
-	Endpoint string          `config:"url"`
-	NoSlash  bool            `config:"no_slash"`
-	NoHead   bool            `config:"no_head"`
-	Headers  fs.CommaSepList `config:"headers"`
-	NoEscape bool            `config:"no_escape"`
+	Endpoint   string          `config:"url"`
+	NoSlash    bool            `config:"no_slash"`
+	NoHead     bool            `config:"no_head"`
+	Headers    fs.CommaSepList `config:"headers"`
+	NoEscape   bool            `config:"no_escape"`
+	ListFormat string          `config:"list_format"`
 }
 
 // Fs stores the interface to the remote HTTP files
@@ -317,6 +348,12 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, err
 	}
 
+	switch opt.ListFormat {
+	case listFormatAuto, listFormatJSON, listFormatS3:
+	default:
+		return nil, fmt.Errorf("unknown list_format %q", opt.ListFormat)
+	}
+
 	ci := fs.GetConfig(ctx)
 	f := &Fs{
 		name: name,
@@ -335,6 +372,11 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, err
 	}
 
+	// Only S3 bucket listings can be recursive
+	if f.opt.ListFormat != listFormatS3 || isFile {
+		f.features.ListR = nil
+	}
+
 	if isFile {
 		// return an error with an fs which points to the parent
 		return f, fs.ErrorIsFile
@@ -516,7 +558,10 @@ func (f *Fs) addHeaders(req *http.Request) {
 }
 
 // Read the directory passed in
-func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error) {
+func (f *Fs) readDir(ctx context.Context, dir string) (entries []listEntry, err error) {
+	if f.opt.ListFormat == listFormatS3 {
+		return f.readDirS3(ctx, dir)
+	}
 	URL := f.url(dir)
 	u, err := url.Parse(URL)
 	if err != nil {
@@ -530,6 +575,9 @@ func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error
 	if err != nil {
 		return nil, fmt.Errorf("readDir failed: %w", err)
 	}
+	if f.opt.ListFormat == listFormatJSON {
+		req.Header.Set("Accept", "application/json")
+	}
 	f.addHeaders(req)
 	res, err := f.httpClient.Do(req)
 	if err == nil {
@@ -546,14 +594,25 @@ func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error
 	contentType, _, _ := strings.Cut(res.Header.Get("Content-Type"), ";")
 	switch contentType {
 	case "text/html":
-		names, err = parse(u, res.Body)
+		names, err := parse(u, res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("readDir: %w", err)
+		}
+		entries = htmlEntries(names)
+	case "application/json":
+		entries, err = parseJSON(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("readDir: %w", err)
+		}
+	case "application/xml", "text/xml":
+		entries, err = parseXML(res.Body)
 		if err != nil {
 			return nil, fmt.Errorf("readDir: %w", err)
 		}
 	default:
 		return nil, fmt.Errorf("can't parse content type %q", contentType)
 	}
-	return names, nil
+	return entries, nil
 }
 
 // List the objects and directories in dir into entries.  The
@@ -580,7 +639,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	if !strings.HasSuffix(dir, "/") && dir != "" {
 		dir += "/"
 	}
-	names, err := f.readDir(ctx, dir)
+	listing, err := f.readDir(ctx, dir)
 	if err != nil {
 		return nil, fmt.Errorf("error listing %q: %w", dir, err)
 	}
@@ -614,13 +673,25 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			}
 		})
 	}
-	for _, name := range names {
+	for _, item := range listing {
+		name := item.name
 		isDir := name[len(name)-1] == '/'
 		name = strings.TrimRight(name, "/")
 		remote := path.Join(dir, name)
-		if isDir {
-			add(fs.NewDir(remote, time.Time{}))
-		} else {
+		switch {
+		case isDir:
+			add(fs.NewDir(remote, item.modTime))
+		case item.known:
+			// size and time read from the listing so no need to HEAD
+			file := &Object{
+				fs:      f,
+				remote:  remote,
+				size:    item.size,
+				modTime: item.modTime,
+			}
+			file.contentType = fs.MimeType(ctx, file)
+			add(file)
+		default:
 			in <- remote
 		}
 	}
@@ -863,6 +934,9 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 		if err != nil {
 			return nil, fmt.Errorf("reading config: %w", err)
 		}
+		if newOpt.ListFormat != f.opt.ListFormat {
+			return nil, errors.New("list_format can't be changed with set")
+		}
 		_, err = f.httpConnection(ctx, &newOpt)
 		if err != nil {
 			return nil, fmt.Errorf("updating session: %w", err)
@@ -911,4 +985,5 @@ var (
 	_ fs.MimeTyper   = &Object{}
 	_ fs.Commander   = &Fs{}
 	_ fs.Metadataer  = &Object{}
+	_ fs.ListRer     = &Fs{}
 )
@@ -479,6 +479,149 @@ func TestParseCaddy(t *testing.T) {
 	})
 }
 
+// Load a structured listing from the file given and parse it with
+// parseFn, checking it against the entries passed in
+func parseListing(t *testing.T, name string, parseFn func(io.Reader) ([]listEntry, error), want []listEntry) {
+	in, err := os.Open(filepath.Join(testPath, "index_files", name))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, in.Close())
+	}()
+	entries, err := parseFn(in)
+	require.NoError(t, err)
+	assert.Equal(t, want, entries)
+}
+
+func TestParseNginxJSON(t *testing.T) {
+	parseListing(t, "nginx.json", parseJSON, []listEntry{
+		{name: "deltas/", size: -1, modTime: fstest.Time("2017-05-04T21:37:00Z")},
+		{name: "objects/", size: -1, modTime: fstest.Time("2017-05-04T20:44:00Z")},
+		{name: "config", size: 118, modTime: fstest.Time("2017-05-04T20:42:00Z"), known: true},
+		{name: "summary", size: 806, modTime: fstest.Time("2017-05-04T21:36:00Z"), known: true},
+		{name: "link", size: -1, modTime: fstest.Time("2017-05-04T21:36:00Z")},
+	})
+}
+
+func TestParseNginxXML(t *testing.T) {
+	parseListing(t, "nginx.xml", parseXML, []listEntry{
+		{name: "deltas/", size: -1, modTime: fstest.Time("2017-05-04T21:37:00Z")},
+		{name: "objects/", size: -1, modTime: fstest.Time("2017-05-04T20:44:00Z")},
+		{name: "config", size: 118, modTime: fstest.Time("2017-05-04T20:42:00Z"), known: true},
+		{name: "summary", size: 806, modTime: fstest.Time("2017-05-04T21:36:00Z"), known: true},
+		{name: "link", size: -1, modTime: fstest.Time("2017-05-04T21:36:00Z")},
+	})
+}
+
+func TestParseCaddyJSON(t *testing.T) {
+	parseListing(t, "caddy.json", parseJSON, []listEntry{
+		{name: "v1.36-22-g06ea13a-ssh-agentβ/", size: -1, modTime: fstest.Time("2017-05-04T21:37:00.123456789Z")},
+		{name: "mimetype.zip", size: 1303, modTime: fstest.Time("2017-05-04T20:42:00Z"), known: true},
+	})
+}
+
+func TestListJSON(t *testing.T) {
+	var headCount int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "HEAD":
+			headCount++
+			http.Error(w, "unexpected HEAD", http.StatusMethodNotAllowed)
+		case r.URL.Path == "/" && r.Header.Get("Accept") == "application/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"name":"dir/","is_dir":true,"mod_time":"2017-05-04T21:37:00Z"},{"name":"file.txt","size":5,"mod_time":"2017-05-04T20:42:00Z"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	f, err := NewFs(context.Background(), remoteName, "", configmap.Simple{
+		"type":        "http",
+		"url":         ts.URL,
+		"list_format": "json",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().ListR)
+
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "dir", entries[0].Remote())
+	_, ok := entries[0].(fs.Directory)
+	assert.True(t, ok)
+	assert.Equal(t, "file.txt", entries[1].Remote())
+	assert.Equal(t, int64(5), entries[1].Size())
+	assert.Equal(t, fstest.Time("2017-05-04T20:42:00Z"), entries[1].ModTime(context.Background()))
+	assert.Equal(t, "text/plain; charset=utf-8", entries[1].(*Object).MimeType(context.Background()))
+	assert.Equal(t, 0, headCount)
+}
+
+// s3Page is a page of the fake S3 listing
+const s3Page = `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">%s</ListBucketResult>`
+
+func TestListS3(t *testing.T) {
+	ctx := context.Background()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/bucket" || q.Get("list-type") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		var body string
+		switch {
+		case q.Get("prefix") == "root/" && q.Get("delimiter") == "/":
+			body = `<Contents><Key>root/</Key><Size>0</Size></Contents>
+<Contents><Key>root/a.txt</Key><LastModified>2017-05-04T20:42:00.000Z</LastModified><Size>1</Size></Contents>
+<CommonPrefixes><Prefix>root/dir/</Prefix></CommonPrefixes>`
+		case q.Get("prefix") == "root/" && q.Get("continuation-token") == "":
+			body = `<IsTruncated>true</IsTruncated><NextContinuationToken>page2</NextContinuationToken>
+<Contents><Key>root/a.txt</Key><LastModified>2017-05-04T20:42:00.000Z</LastModified><Size>1</Size></Contents>`
+		case q.Get("prefix") == "root/" && q.Get("continuation-token") == "page2":
+			body = `<Contents><Key>root/dir/b.txt</Key><LastModified>2017-05-04T21:37:00.000Z</LastModified><Size>2</Size></Contents>`
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, s3Page, body)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	f, err := NewFs(ctx, remoteName, "root/", configmap.Simple{
+		"type":        "http",
+		"url":         ts.URL + "/bucket",
+		"list_format": "s3",
+	})
+	require.NoError(t, err)
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a.txt", entries[0].Remote())
+	assert.Equal(t, int64(1), entries[0].Size())
+	assert.Equal(t, fstest.Time("2017-05-04T20:42:00Z"), entries[0].ModTime(ctx))
+	assert.Equal(t, "dir", entries[1].Remote())
+	_, ok := entries[1].(fs.Directory)
+	assert.True(t, ok)
+
+	_, err = f.List(ctx, "missing")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	// ListR reads all the pages
+	require.NotNil(t, f.Features().ListR)
+	var remotes []string
+	err = f.Features().ListR(ctx, "", func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			remotes = append(remotes, entry.Remote())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "dir/b.txt"}, remotes)
+}
+
 func TestFsNoSlashRoots(t *testing.T) {
 	// Test Fs with roots that does not end with '/', the logic that
 	// decides if url is to be considered a file or directory, based
@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// Values for the list_format option
+const (
+	listFormatAuto = ""     // HTML or a structured listing depending on the Content-Type
+	listFormatJSON = "json" // ask for JSON listings with an Accept header
+	listFormatS3   = "s3"   // S3 ListObjectsV2 listings from a bucket URL
+)
+
+// listEntry is an entry read from a directory listing
+type listEntry struct {
+	name    string    // leaf name ending in / for directories
+	size    int64     // size in bytes or -1 if unknown
+	modTime time.Time // modification time or zero if unknown
+	known   bool      // set if this is a file with its size and modTime read from the listing
+}
+
+// htmlEntries makes entries from the names parsed from an HTML page
+func htmlEntries(names []string) (entries []listEntry) {
+	for _, name := range names {
+		entries = append(entries, listEntry{name: name, size: -1})
+	}
+	return entries
+}
+
+// checkLeaf returns the leaf name or an error if it isn't a single
+// path element
+func checkLeaf(name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || name == "." || name == ".." {
+		return "", errNameIsEmpty
+	}
+	if strings.Contains(name, "/") {
+		return "", errNameContainsSlash
+	}
+	return name, nil
+}
+
+// jsonEntry is an entry in a JSON directory listing
+//
+// This covers both nginx (autoindex_format json) and Caddy (browse
+// with Accept: application/json) which use different field names.
+type jsonEntry struct {
+	Name string `json:"name"`
+
+	// nginx
+	Type  string `json:"type"`  // "directory", "file" or "other"
+	MTime string `json:"mtime"` // in HTTP date format
+	Size  *int64 `json:"size"`  // not present for directories
+
+	// Caddy
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+// parseJSON reads a JSON directory listing
+func parseJSON(in io.Reader) (entries []listEntry, err error) {
+	var items []jsonEntry
+	err = json.NewDecoder(in).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON listing: %w", err)
+	}
+	for _, item := range items {
+		name, err := checkLeaf(item.Name)
+		if err != nil {
+			fs.Debugf(nil, "Skipping %q in listing: %v", item.Name, err)
+			continue
+		}
+		entry := listEntry{size: -1, modTime: item.ModTime}
+		if item.MTime != "" {
+			entry.modTime, err = http.ParseTime(item.MTime)
+			if err != nil {
+				fs.Debugf(nil, "Failed to parse mtime %q of %q: %v", item.MTime, item.Name, err)
+			}
+		}
+		switch {
+		case item.Type == "directory" || item.IsDir:
+			name += "/"
+		case item.Size != nil && item.Type != "other":
+			entry.size = *item.Size
+			entry.known = !entry.modTime.IsZero()
+		}
+		entry.name = name
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// nginxXMLEntry is an entry in an nginx XML directory listing
+// (autoindex_format xml)
+type nginxXMLEntry struct {
+	XMLName xml.Name
+	Name    string `xml:",chardata"`
+	MTime   string `xml:"mtime,attr"`
+	Size    string `xml:"size,attr"`
+}
+
+// parseXML reads an nginx XML directory listing
+func parseXML(in io.Reader) (entries []listEntry, err error) {
+	var listing struct {
+		XMLName xml.Name        `xml:"list"`
+		Items   []nginxXMLEntry `xml:",any"`
+	}
+	err = xml.NewDecoder(in).Decode(&listing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XML listing: %w", err)
+	}
+	for _, item := range listing.Items {
+		name, err := checkLeaf(item.Name)
+		if err != nil {
+			fs.Debugf(nil, "Skipping %q in listing: %v", item.Name, err)
+			continue
+		}
+		entry := listEntry{size: -1}
+		if item.MTime != "" {
+			entry.modTime, err = time.Parse(time.RFC3339, item.MTime)
+			if err != nil {
+				fs.Debugf(nil, "Failed to parse mtime %q of %q: %v", item.MTime, item.Name, err)
+			}
+		}
+		switch item.XMLName.Local {
+		case "directory":
+			name += "/"
+		case "file":
+			if size, err := strconv.ParseInt(item.Size, 10, 64); err == nil {
+				entry.size = size
+				entry.known = !entry.modTime.IsZero()
+			}
+		}
+		entry.name = name
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// s3ListResult is a page of an S3 ListObjectsV2 listing
+type s3ListResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		LastModified time.Time
+		Size         int64
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// s3List lists the keys under prefix from the bucket URL calling fn
+// with each page of results. If delimiter is set only one level is
+// listed.
+func (f *Fs) s3List(ctx context.Context, prefix string, delimiter bool, fn func(*s3ListResult) error) error {
+	base, err := url.Parse(f.opt.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to parse bucket URL: %w", err)
+	}
+	params := url.Values{}
+	params.Set("list-type", "2")
+	params.Set("prefix", prefix)
+	if delimiter {
+		params.Set("delimiter", "/")
+	}
+	for {
+		base.RawQuery = params.Encode()
+		req, err := http.NewRequestWithContext(ctx, "GET", base.String(), nil)
+		if err != nil {
+			return err
+		}
+		f.addHeaders(req)
+		res, err := f.httpClient.Do(req)
+		err = statusError(res, err)
+		if err != nil {
+			return err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		fs.CheckClose(res.Body, &err)
+		if err != nil {
+			return fmt.Errorf("failed to parse S3 listing: %w", err)
+		}
+		err = fn(&result)
+		if err != nil {
+			return err
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		params.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// s3Prefix returns the S3 key prefix for dir
+func (f *Fs) s3Prefix(dir string) string {
+	prefix := path.Join(f.root, dir)
+	if prefix == "" || prefix == "." {
+		return ""
+	}
+	return strings.TrimPrefix(prefix, "/") + "/"
+}
+
+// readDirS3 reads the directory dir from an S3 bucket listing
+func (f *Fs) readDirS3(ctx context.Context, dir string) (entries []listEntry, err error) {
+	prefix := f.s3Prefix(dir)
+	err = f.s3List(ctx, prefix, true, func(result *s3ListResult) error {
+		for _, p := range result.CommonPrefixes {
+			name, err := checkLeaf(strings.TrimPrefix(p.Prefix, prefix))
+			if err == nil {
+				entries = append(entries, listEntry{name: name + "/", size: -1})
+			}
+		}
+		for _, item := range result.Contents {
+			name := strings.TrimPrefix(item.Key, prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				// skip directory markers
+				continue
+			}
+			entries = append(entries, listEntry{
+				name:    name,
+				size:    item.Size,
+				modTime: item.LastModified,
+				known:   true,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to readDir: %w", err)
+	}
+	if len(entries) == 0 && prefix != "" && dir != "" {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// This is only enabled with list_format = s3 where a bucket can be
+// listed recursively in one go.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	if f.opt.ListFormat != listFormatS3 {
+		return fs.ErrorNotImplemented
+	}
+	prefix := f.s3Prefix(dir)
+	rootPrefix := f.s3Prefix("")
+	found := false
+	err = f.s3List(ctx, prefix, false, func(result *s3ListResult) error {
+		var entries fs.DirEntries
+		for _, item := range result.Contents {
+			if strings.HasSuffix(item.Key, "/") {
+				// skip directory markers
+				continue
+			}
+			found = true
+			entries = append(entries, &Object{
+				fs:          f,
+				remote:      strings.TrimPrefix(item.Key, rootPrefix),
+				size:        item.Size,
+				modTime:     item.LastModified,
+				contentType: fs.MimeTypeFromName(item.Key),
+			})
+		}
+		return callback(entries)
+	})
+	if err != nil {
+		return fmt.Errorf("error listing %q: %w", dir, err)
+	}
+	if !found && dir != "" {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
@@ -0,0 +1,476 @@
+//go:build linux
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sys/unix"
+)
+
+// events we ask inotify for on each directory we watch
+//
+// Files being written are only notified when they are closed, so we
+// don't see every write to a file.
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF |
+	unix.IN_ONLYDIR
+
+// errWatcherClosed is returned when adding a watch after the watcher
+// has been closed
+var errWatcherClosed = errors.New("change notify stopped")
+
+// inotifyWatcher watches a local directory tree with inotify
+type inotifyWatcher struct {
+	f          *Fs
+	notifyFunc func(string, fs.EntryType)
+	fd         int            // inotify instance
+	file       *os.File       // fd wrapped so reads use the poller
+	partial    *regexp.Regexp // matches the names of partial uploads
+	mu         sync.Mutex     // protect the below
+	closed     bool           // set when the watcher has been closed
+	dirs       map[int]string // remote directory for each watch descriptor
+	wds        map[string]int // watch descriptor for each remote directory
+	warnedFull bool           // set if we have warned about running out of watches
+	parentWd   int            // watch on a parent of the root if it doesn't exist yet, or -1
+	parentPath string         // local path of parentWd
+}
+
+// ChangeNotify calls the passed function with a path that has had
+// changes. The changes are read from inotify as they happen so the
+// poll interval is only used to turn the watching on and off.
+//
+// Watches are added for every directory under the root in the
+// background. If the root doesn't exist yet then its nearest parent
+// is watched until it is created. If the inotify queue overflows the
+// whole tree is rescanned and the root is notified as changed.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	// Start watching the root now so changes made as soon as this
+	// returns are queued up ready for when the first poll interval
+	// arrives.
+	w, err := newInotifyWatcher(ctx, f, notifyFunc)
+	if err != nil {
+		fs.Errorf(f, "Failed to start change notify: %v", err)
+	}
+	go func() {
+		running := false
+		for pollInterval := range pollIntervalChan {
+			if pollInterval == 0 {
+				if w != nil {
+					w.close()
+					w = nil
+					running = false
+				}
+				continue
+			}
+			if w == nil {
+				w, err = newInotifyWatcher(ctx, f, notifyFunc)
+				if err != nil {
+					fs.Errorf(f, "Failed to start change notify: %v", err)
+					continue
+				}
+				// We may have missed changes while we weren't watching
+				notifyFunc("", fs.EntryDirectory)
+			}
+			if !running {
+				running = true
+				go w.run()
+			}
+		}
+		if w != nil {
+			w.close()
+		}
+	}()
+}
+
+// newInotifyWatcher starts watching f.root, adding the watches for the
+// tree under it in the background.
+func newInotifyWatcher(ctx context.Context, f *Fs, notifyFunc func(string, fs.EntryType)) (*inotifyWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init: %w", err)
+	}
+	w := &inotifyWatcher{
+		f:          f,
+		notifyFunc: notifyFunc,
+		fd:         fd,
+		file:       os.NewFile(uintptr(fd), "inotify"),
+		partial:    regexp.MustCompile(`\.[0-9a-f]{8}` + regexp.QuoteMeta(fs.GetConfig(ctx).PartialSuffix) + `$`),
+		dirs:       make(map[int]string),
+		wds:        make(map[string]int),
+		parentWd:   -1,
+	}
+	w.mu.Lock()
+	err = w._watchRoot()
+	waiting := w.parentWd >= 0
+	w.mu.Unlock()
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	if waiting {
+		fs.Debugf(f, "Change notify: waiting for the root to be created in %q", w.parentPath)
+	} else {
+		go w.scan(false)
+	}
+	return w, nil
+}
+
+// _watchRoot adds a watch for the root. If the root doesn't exist
+// then it watches the nearest parent which does instead so
+// handleParentEvent can call this again when the next part of the
+// path is created.
+//
+// Call with mu held
+func (w *inotifyWatcher) _watchRoot() error {
+	root, err := w.f.localPath("")
+	if err != nil {
+		return err
+	}
+	for {
+		err = w._addWatch("")
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, unix.ENOENT) {
+			return err
+		}
+		// Find the nearest parent which exists and watch it
+		child := root
+		for {
+			parent := filepath.Dir(child)
+			if parent == child {
+				return err
+			}
+			wd, watchErr := unix.InotifyAddWatch(w.fd, parent, unix.IN_CREATE|unix.IN_MOVED_TO|unix.IN_ONLYDIR)
+			if watchErr == nil {
+				w.parentWd, w.parentPath = wd, parent
+				break
+			}
+			if !errors.Is(watchErr, unix.ENOENT) {
+				return fmt.Errorf("failed to watch %q: %w", parent, watchErr)
+			}
+			child = parent
+		}
+		// Check child wasn't created before the watch was added
+		if _, err = os.Stat(child); err != nil {
+			return nil
+		}
+		_, _ = unix.InotifyRmWatch(w.fd, uint32(w.parentWd))
+		w.parentWd = -1
+	}
+}
+
+// scan adds watches for the tree under the root.
+//
+// If notify is set then everything found is notified.
+func (w *inotifyWatcher) scan(notify bool) {
+	w.addTree("", notify)
+	w.mu.Lock()
+	n := len(w.dirs)
+	w.mu.Unlock()
+	fs.Debugf(w.f, "Change notify: watching %d directories", n)
+}
+
+// close stops watching, removing all the watches
+func (w *inotifyWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	_ = w.file.Close()
+}
+
+// addWatch adds a watch for the remote directory dir
+func (w *inotifyWatcher) addWatch(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w._addWatch(dir)
+}
+
+// _addWatch adds a watch for the remote directory dir
+//
+// Call with mu held
+func (w *inotifyWatcher) _addWatch(dir string) error {
+	if w.closed {
+		return errWatcherClosed
+	}
+	localPath, err := w.f.localPath(dir)
+	if err != nil {
+		return err
+	}
+	mask := uint32(inotifyMask)
+	if !w.f.opt.FollowSymlinks {
+		mask |= unix.IN_DONT_FOLLOW
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, localPath, mask)
+	if err != nil {
+		return fmt.Errorf("failed to watch %q: %w", localPath, err)
+	}
+	if old, ok := w.dirs[wd]; ok && old != dir {
+		// The same directory reached by a different path, eg a symlink loop
+		return fmt.Errorf("%q is already watched as %q", dir, old)
+	}
+	w.dirs[wd] = dir
+	w.wds[dir] = wd
+	return nil
+}
+
+// addTree adds watches for all the directories under the remote
+// directory dir, which should already be watched.
+//
+// If notify is set then the entries found are notified too, which is
+// used for new directories whose contents were created before they
+// were watched.
+func (w *inotifyWatcher) addTree(dir string, notify bool) {
+	localPath, err := w.f.localPath(dir)
+	if err != nil {
+		return
+	}
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		fs.Debugf(w.f, "Change notify: failed to read %q: %v", localPath, err)
+		return
+	}
+	for _, entry := range entries {
+		if w.partial.MatchString(entry.Name()) {
+			continue
+		}
+		remote := w.f.cleanRemote(dir, entry.Name())
+		if !w.isDir(filepath.Join(localPath, entry.Name())) {
+			if !notify {
+				continue
+			}
+			if entry.Type()&os.ModeSymlink != 0 {
+				if w.f.opt.TranslateSymlinks {
+					remote += fs.LinkSuffix
+				} else if !w.f.opt.FollowSymlinks {
+					continue // Symlinks aren't listed
+				}
+			}
+			w.notifyFunc(remote, fs.EntryObject)
+			continue
+		}
+		if err := w.addWatch(remote); err != nil {
+			if errors.Is(err, unix.ENOSPC) {
+				w.warnFull(err)
+				return
+			}
+			if errors.Is(err, errWatcherClosed) {
+				return
+			}
+			fs.Debugf(w.f, "Change notify: %v", err)
+			continue
+		}
+		if notify {
+			w.notifyFunc(remote, fs.EntryDirectory)
+		}
+		w.addTree(remote, notify)
+	}
+}
+
+// isDir returns true if localPath is a directory which the listing
+// would descend into, taking into account the link options and
+// --one-file-system.
+func (w *inotifyWatcher) isDir(localPath string) bool {
+	fi, err := os.Lstat(localPath)
+	if err != nil {
+		return false
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if !w.f.opt.FollowSymlinks {
+			return false
+		}
+		fi, err = os.Stat(localPath)
+		if err != nil {
+			return false
+		}
+	}
+	return fi.IsDir() && w.f.dev == readDevice(fi, w.f.opt.OneFileSystem)
+}
+
+// warnFull warns once that there are no more inotify watches
+func (w *inotifyWatcher) warnFull(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.warnedFull {
+		fs.Errorf(w.f, "Change notify: %v - increase fs.inotify.max_user_watches to watch the whole tree", err)
+		w.warnedFull = true
+	}
+}
+
+// removeTree forgets the watches for the remote directory dir and all
+// the directories under it.
+func (w *inotifyWatcher) removeTree(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	for subDir, wd := range w.wds {
+		if subDir == dir || strings.HasPrefix(subDir, dir+"/") {
+			_, _ = unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.wds, subDir)
+			delete(w.dirs, wd)
+		}
+	}
+}
+
+// removeWatch forgets the watch wd which the kernel has removed
+func (w *inotifyWatcher) removeWatch(wd int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dir, ok := w.dirs[wd]
+	if !ok {
+		return
+	}
+	delete(w.dirs, wd)
+	if w.wds[dir] == wd {
+		delete(w.wds, dir)
+	}
+}
+
+// run reads events until the watcher is closed
+func (w *inotifyWatcher) run() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				fs.Errorf(w.f, "Change notify: failed to read inotify events: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			nameEnd := min(nameStart+int(event.Len), n)
+			name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+			w.handleEvent(int(event.Wd), event.Mask, name)
+			offset = nameEnd
+		}
+	}
+}
+
+// handleEvent turns an inotify event into calls to notifyFunc
+func (w *inotifyWatcher) handleEvent(wd int, mask uint32, name string) {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		fs.Logf(w.f, "Change notify: inotify queue overflowed - rescanning")
+		go w.scan(false)
+		w.notifyFunc("", fs.EntryDirectory)
+		return
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		w.removeWatch(wd)
+		return
+	}
+	w.mu.Lock()
+	isParent := wd == w.parentWd
+	dir, ok := w.dirs[wd]
+	w.mu.Unlock()
+	if isParent {
+		w.handleParentEvent(name)
+		return
+	}
+	if !ok {
+		return
+	}
+	if name == "" {
+		// The directory itself was deleted or moved which its
+		// parent reports, unless it is the root.
+		if dir == "" && mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+			w.notifyFunc("", fs.EntryDirectory)
+		}
+		return
+	}
+	if w.partial.MatchString(name) {
+		// Partial uploads are notified when renamed into place
+		return
+	}
+	remote := w.f.cleanRemote(dir, name)
+	entryType := fs.EntryObject
+	if mask&unix.IN_ISDIR != 0 {
+		entryType = fs.EntryDirectory
+	} else if localPath, err := w.f.localPath(remote); err == nil {
+		fi, err := os.Lstat(localPath)
+		switch {
+		case err != nil:
+			// Gone so we don't know if it was a link
+			if w.f.opt.TranslateSymlinks {
+				w.notifyFunc(remote+fs.LinkSuffix, fs.EntryObject)
+			}
+		case fi.Mode()&os.ModeSymlink == 0:
+		case w.f.opt.TranslateSymlinks:
+			remote += fs.LinkSuffix
+		case w.f.opt.FollowSymlinks:
+			if w.isDir(localPath) {
+				entryType = fs.EntryDirectory
+			}
+		default:
+			// Symlinks aren't listed
+			return
+		}
+	}
+	if entryType == fs.EntryDirectory {
+		if mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0 {
+			w.removeTree(remote)
+		}
+		if mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			if localPath, err := w.f.localPath(remote); err == nil && w.isDir(localPath) {
+				if err := w.addWatch(remote); err != nil {
+					if errors.Is(err, unix.ENOSPC) {
+						w.warnFull(err)
+					} else if !errors.Is(err, errWatcherClosed) {
+						fs.Debugf(w.f, "Change notify: %v", err)
+					}
+				} else {
+					go w.addTree(remote, true)
+				}
+			}
+		}
+	}
+	w.notifyFunc(remote, entryType)
+}
+
+// handleParentEvent is called when name is created in the parent of
+// the root which is being watched until the root exists.
+func (w *inotifyWatcher) handleParentEvent(name string) {
+	root, err := w.f.localPath("")
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	child := filepath.Join(w.parentPath, name)
+	if w.closed || (child != root && !strings.HasPrefix(root, child+string(filepath.Separator))) {
+		w.mu.Unlock()
+		return
+	}
+	_, _ = unix.InotifyRmWatch(w.fd, uint32(w.parentWd))
+	w.parentWd = -1
+	err = w._watchRoot()
+	waiting := w.parentWd >= 0
+	w.mu.Unlock()
+	if err != nil {
+		fs.Errorf(w.f, "Change notify: %v", err)
+		return
+	}
+	if !waiting {
+		fs.Debugf(w.f, "Change notify: root created")
+		w.notifyFunc("", fs.EntryDirectory)
+		go w.scan(true)
+	}
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.ChangeNotifier = (*Fs)(nil)
+)
@@ -22,7 +22,7 @@ import (
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Object, err error) {
 	if runtime.GOOS != "darwin" || f.opt.NoClone {
 		return nil, fs.ErrorCantCopy
 	}
@@ -51,6 +51,18 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		return nil, err
 	}
 
+	// Make a hard link instead if src is a later link to a file we
+	// have already written
+	linked, linkDone := dstObj.linkHardlink(ctx, src)
+	if linked {
+		return f.NewObject(ctx, remote)
+	}
+	if linkDone != nil {
+		defer func() {
+			linkDone(err)
+		}()
+	}
+
 	srcPath := srcObj.path
 	if f.opt.FollowSymlinks { // in --copy-links mode, find the real file being pointed to and pass that in instead
 		srcPath, err = filepath.EvalSymlinks(srcPath)
@@ -63,6 +75,8 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if err != nil {
 		return nil, err
 	}
+	// Don't write through a hard link into the other names of the file
+	f.unlinkHardlink(dstObj.path)
 	err = Clone(srcPath, dstPath)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,181 @@
+//go:build linux
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sys/unix"
+)
+
+// Copy src to this remote using server-side copy operations.
+//
+// This tries to make a reflink of the file first (btrfs, xfs and
+// others) and if that isn't supported copies the data in the kernel
+// with copy_file_range.
+//
+// # This is stored with the remote path given
+//
+// # It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Object, err error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if srcObj.translatedLink { // in --links mode, only copy regular files in the kernel
+		return nil, fs.ErrorCantCopy
+	}
+
+	// Fetch metadata if --metadata is in use
+	meta, err := fs.GetMetadataOptions(ctx, f, src, fs.MetadataAsOpenOptions(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("copy: failed to read metadata: %w", err)
+	}
+
+	// Create destination
+	dstObj, err := f.newObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	err = dstObj.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make a hard link instead if src is a later link to a file we
+	// have already written
+	linked, linkDone := dstObj.linkHardlink(ctx, src)
+	if linked {
+		return f.NewObject(ctx, remote)
+	}
+	if linkDone != nil {
+		defer func() {
+			linkDone(err)
+		}()
+	}
+
+	srcPath := srcObj.path
+	if f.opt.FollowSymlinks { // in --copy-links mode, find the real file being pointed to and pass that in instead
+		srcPath, err = filepath.EvalSymlinks(srcPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Don't write through a hard link into the other names of the file
+	f.unlinkHardlink(dstObj.path)
+	err = copyFileData(srcPath, dstObj.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the mtime as the data copy doesn't
+	err = dstObj.SetModTime(ctx, srcObj.ModTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Set metadata if --metadata is in use
+	if meta != nil {
+		err = dstObj.writeMetadata(meta)
+		if err != nil {
+			return nil, fmt.Errorf("copy: failed to set metadata: %w", err)
+		}
+	}
+
+	return f.NewObject(ctx, remote)
+}
+
+// isCopyUnsupported returns true if err means that the kernel can't
+// copy between these files so rclone should copy them itself
+func isCopyUnsupported(err error) bool {
+	return errors.Is(err, unix.EOPNOTSUPP) ||
+		errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.ENOTTY) ||
+		errors.Is(err, unix.ENOSYS)
+}
+
+// maxCopyFileRange is the most to ask copy_file_range to copy at once
+const maxCopyFileRange = 1 << 30
+
+// copyFileData copies the contents of srcPath to dstPath, making a
+// reflink if possible, otherwise using copy_file_range.
+//
+// It returns fs.ErrorCantCopy if neither is supported.
+func copyFileData(srcPath, dstPath string) (err error) {
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fs.ErrorCantCopy
+	}
+	out, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := out.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			if removeErr := os.Remove(dstPath); removeErr != nil {
+				fs.Errorf(dstPath, "Failed to remove partially copied file: %v", removeErr)
+			}
+		}
+	}()
+	inFd, outFd := int(in.Fd()), int(out.Fd())
+
+	// Try a reflink first
+	err = unix.IoctlFileClone(outFd, inFd)
+	if err == nil {
+		fs.Debugf(dstPath, "Cloned with a reflink")
+		return nil
+	}
+	if !isCopyUnsupported(err) {
+		return fmt.Errorf("copy: reflink failed: %w", err)
+	}
+	fs.Debugf(dstPath, "Can't reflink: %v", err)
+
+	// Then copy the data in the kernel
+	var copied int64
+	for remaining := fi.Size(); remaining > 0; {
+		n, err := unix.CopyFileRange(inFd, nil, outFd, nil, int(min(remaining, maxCopyFileRange)), 0)
+		if err != nil {
+			if copied == 0 && isCopyUnsupported(err) {
+				fs.Debugf(dstPath, "Can't copy_file_range: %v", err)
+				return fs.ErrorCantCopy
+			}
+			return fmt.Errorf("copy: copy_file_range failed: %w", err)
+		}
+		if n == 0 {
+			break // file was truncated while copying
+		}
+		copied += int64(n)
+		remaining -= int64(n)
+	}
+	fs.Debugf(dstPath, "Copied %d bytes with copy_file_range", copied)
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Copier = &Fs{}
+)
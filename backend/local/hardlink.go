@@ -0,0 +1,83 @@
+package local
+
+import (
+	"context"
+	"os"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// hardlinkID returns the hard link ID of the object or "" if it
+// doesn't have one
+func (o *Object) hardlinkID() string {
+	o.fs.objectMetaMu.RLock()
+	defer o.fs.objectMetaMu.RUnlock()
+	return o.hardlink
+}
+
+// srcHardlinkID returns the hard link ID of src or "" if it doesn't
+// have one.
+//
+// This is read directly from local objects, otherwise from the
+// source's "hardlink" metadata.
+func srcHardlinkID(ctx context.Context, src fs.ObjectInfo) string {
+	if srcObj, ok := fs.UnWrapObjectInfo(src).(*Object); ok {
+		return srcObj.hardlinkID()
+	}
+	meta, err := fs.GetMetadata(ctx, src)
+	if err != nil {
+		fs.Debugf(src, "Failed to read metadata to find hard link: %v", err)
+		return ""
+	}
+	return meta["hardlink"]
+}
+
+// linkHardlink makes o a hard link to the file already written with
+// the same hard link ID as src, if there is one.
+//
+// It returns true if o is now a hard link. Otherwise, if done is not
+// nil, it must be called with the result of writing o so later links
+// can be made to it.
+func (o *Object) linkHardlink(ctx context.Context, src fs.ObjectInfo) (linked bool, done func(err error)) {
+	if o.fs.hardlinks == nil || o.translatedLink {
+		return false, nil
+	}
+	id := srcHardlinkID(ctx, src)
+	if id == "" {
+		return false, nil
+	}
+	target, release := o.fs.hardlinks.Acquire(id)
+	if target != "" && target != o.path {
+		err := linkOver(target, o.path)
+		if err == nil {
+			fs.Debugf(o, "Hard linked to %q", target)
+			release("")
+			return true, nil
+		}
+		fs.Debugf(o, "Failed to hard link to %q so writing instead: %v", target, err)
+	}
+	return false, func(err error) {
+		if err != nil {
+			release("")
+			return
+		}
+		release(o.path)
+	}
+}
+
+// unlinkHardlink removes the file at path if it has more than one
+// link and --local-hard-links is set, so writing a new file there
+// doesn't change the other names of the old one.
+func (f *Fs) unlinkHardlink(path string) {
+	if f.hardlinks == nil {
+		return
+	}
+	fi, err := os.Lstat(path)
+	if err != nil || !fi.Mode().IsRegular() || readHardlinkID(fi) == "" {
+		return
+	}
+	err = os.Remove(path)
+	if err != nil {
+		fs.Debugf(path, "Failed to remove hard link before writing: %v", err)
+	}
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/hardlink"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/readers"
 	"golang.org/x/text/unicode/norm"
 )
@@ -133,6 +135,31 @@ skipped.`,
 				NoPrefix: true,
 				Advanced: true,
 			},
+			{
+				Name: "hard_links",
+				Help: `Preserve hard links.
+
+Normally rclone treats every name of a hard linked file as a separate
+file, so a tree made with "cp -al" or rsnapshot is transferred and
+stored once for each link.
+
+When reading, this flag makes rclone notice files with more than one
+link and give all the names of the same file the same "hardlink"
+metadata.
+
+When writing, files sharing a "hardlink" value are created as hard
+links to the first of them written, so the data is only written once.
+The value is read from the source if it is a local remote using this
+flag, otherwise from the source's metadata, for example when restoring
+a backup made with --metadata and this flag to a remote which can't
+make hard links. An existing file with more than one link is
+replaced rather than written in place, so its other links are left
+alone.
+
+Files copied with multi-thread streams are always written in full.`,
+				Default:  false,
+				Advanced: true,
+			},
 			{
 				Name: "zero_size_links",
 				Help: `Assume the Stat size of links is zero (and read them instead) (deprecated).
@@ -247,8 +274,10 @@ deduplicated by default (i.e. having two identical files does not consume more
 storage than having just one.)  However, for use cases where data redundancy is
 preferable, --local-no-clone can be used to disable cloning and force "deep" copies.
 
-Currently, cloning is only supported when using APFS on macOS (support for other
-platforms may be added in the future.)`,
+Currently, cloning is supported when using APFS on macOS and on Linux
+filesystems which support reflinks (such as btrfs and xfs). Other Linux
+filesystems copy the data in the kernel with copy_file_range, which may also
+share blocks. Setting --local-no-clone disables both.`,
 				Default:  false,
 				Advanced: true,
 			},
@@ -318,6 +347,19 @@ retrying.`,
 				Default:  false,
 				Advanced: true,
 			},
+			{
+				Name: "change_notify",
+				Help: `Watch the local directory for changes using inotify.
+
+When enabled, changes made outside rclone are reported to rclone mount
+and rclone serve straight away rather than after --dir-cache-time.
+
+This is only supported on Linux. Each directory under the root uses
+one inotify watch and the directories are scanned when the watching
+starts.`,
+				Default:  false,
+				Advanced: true,
+			},
 			{
 				Name: "time_type",
 				Help: `Set what kind of time is returned.
@@ -378,6 +420,7 @@ type Options struct {
 	TranslateSymlinks      bool                 `config:"links"`
 	SkipSymlinks           bool                 `config:"skip_links"`
 	SkipSpecials           bool                 `config:"skip_specials"`
+	HardLinks              bool                 `config:"hard_links"`
 	UTFNorm                bool                 `config:"unicode_normalization"`
 	NoCheckUpdated         bool                 `config:"no_check_updated"`
 	NoUNC                  bool                 `config:"nounc"`
@@ -388,6 +431,7 @@ type Options struct {
 	NoSparse               bool                 `config:"no_sparse"`
 	NoSetModTime           bool                 `config:"no_set_modtime"`
 	FatalIfNoSpace         bool                 `config:"fatal_if_no_space"`
+	ChangeNotify           bool                 `config:"change_notify"`
 	TimeType               timeType             `config:"time_type"`
 	Hashes                 fs.CommaSepList      `config:"hashes"`
 	Enc                    encoder.MultiEncoder `config:"encoding"`
@@ -407,6 +451,7 @@ type Fs struct {
 	warnedMu       sync.Mutex          // used for locking access to 'warned'.
 	warned         map[string]struct{} // whether we have warned about this string
 	xattrSupported atomic.Int32        // whether xattrs are supported
+	hardlinks      *hardlink.Tracker   // hard links written if --local-hard-links is set
 
 	// do os.Lstat or os.Stat
 	lstat        func(name string) (os.FileInfo, error)
@@ -419,10 +464,11 @@ type Object struct {
 	remote string // The remote path (encoded path)
 	path   string // The local path (OS path)
 	// When using these items the fs.objectMetaMu must be held
-	size    int64 // file metadata - always present
-	mode    os.FileMode
-	modTime time.Time
-	hashes  map[hash.Type]string // Hashes
+	size     int64 // file metadata - always present
+	mode     os.FileMode
+	modTime  time.Time
+	hashes   map[hash.Type]string // Hashes
+	hardlink string               // hard link ID if --local-hard-links and more than one link
 	// these are read only and don't need the mutex held
 	translatedLink bool // Is this object a translated link
 }
@@ -467,6 +513,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if xattrSupported {
 		f.xattrSupported.Store(1)
 	}
+	if opt.HardLinks {
+		f.hardlinks = hardlink.New()
+	}
 	f.root = cleanRootPath(root, f.opt.NoUNC, f.opt.Enc)
 	f.features = (&fs.Features{
 		CaseInsensitive:          f.caseInsensitive(),
@@ -491,6 +540,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		// Disable server-side copy when --local-no-clone is set
 		f.features.Copy = nil
 	}
+	if !opt.ChangeNotify {
+		// Only watch for changes when --local-change-notify is set
+		f.features.ChangeNotify = nil
+	}
 
 	// Check to see if this points to a file
 	fi, err := f.lstat(f.root)
@@ -1078,6 +1131,9 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		fs.Debugf(src, "Can't move: %v: trying copy", err)
 		return nil, fs.ErrorCantMove
 	}
+	if f.hardlinks != nil {
+		f.hardlinks.Rename(srcObj.path, dstObj.path)
+	}
 
 	// Set metadata if --metadata is in use
 	err = dstObj.writeMetadata(meta)
@@ -1174,6 +1230,24 @@ var commandHelp = []fs.CommandHelp{
 			"echo":  "Echo the input arguments.",
 			"error": "Return an error based on option value.",
 		},
+	}, {
+		Name:  "hardlink",
+		Short: "Replace a file with a hard link to another file.",
+		Long: `This command replaces the file at the second path with a hard link to
+the file at the first path, so they share the same content on disk.
+Both paths are relative to the remote.
+
+Usage example:
+
+` + "```console" + `
+rclone backend hardlink /path/to/dir existing_file file_to_replace
+` + "```" + `
+
+The second file is replaced atomically, so it is never missing. If
+it doesn't exist it is created. Both files must be on the same file
+system.
+
+This is used by ` + "`rclone dupes --dupes-mode link`" + `.`,
 	},
 }
 
@@ -1203,11 +1277,66 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 			return out, nil
 		}
 		return nil, nil
+	case "hardlink":
+		if len(arg) != 2 {
+			return nil, errors.New("need exactly 2 arguments")
+		}
+		return nil, f.hardlink(arg[0], arg[1])
 	default:
 		return nil, fs.ErrorCommandNotFound
 	}
 }
 
+// hardlink replaces the file at remote dst with a hard link to the
+// file at remote src
+func (f *Fs) hardlink(src, dst string) error {
+	srcPath, err := f.localPath(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := f.localPath(dst)
+	if err != nil {
+		return err
+	}
+	srcInfo, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+	if !srcInfo.Mode().IsRegular() {
+		return fmt.Errorf("can't hard link to %q as it isn't a file", src)
+	}
+	dstInfo, err := os.Lstat(dstPath)
+	if err == nil {
+		if !dstInfo.Mode().IsRegular() {
+			return fmt.Errorf("can't replace %q with a hard link as it isn't a file", dst)
+		}
+		// Renaming one link over another to the same file does nothing
+		if os.SameFile(srcInfo, dstInfo) {
+			return nil
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return linkOver(srcPath, dstPath)
+}
+
+// linkOver replaces the file at dstPath with a hard link to srcPath
+// so dstPath is never missing
+func linkOver(srcPath, dstPath string) error {
+	// Make the link under a temporary name then rename it over dst
+	tmpPath := dstPath + ".rclone-link-" + random.String(8)
+	err := os.Link(srcPath, tmpPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, dstPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -1618,11 +1747,25 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	// Wipe hashes before update
 	o.clearHashCache()
 
+	// Make a hard link instead if src is a later link to a file we
+	// have already written
+	linked, linkDone := o.linkHardlink(ctx, src)
+	if linked {
+		return o.lstat()
+	}
+	if linkDone != nil {
+		defer func() {
+			linkDone(err)
+		}()
+	}
+
 	var symlinkData bytes.Buffer
 	// If the object is a regular file, create it.
 	// If it is a translated link, just read in the contents, and
 	// then create a symlink
 	if !o.translatedLink {
+		// Don't write through a hard link into the other names of the file
+		o.fs.unlinkHardlink(o.path)
 		f, err := o.fs.openFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
 			if runtime.GOOS == "windows" && os.IsPermission(err) {
@@ -1772,6 +1915,9 @@ func (o *Object) setMetadata(info os.FileInfo) {
 	o.size = info.Size()
 	o.modTime = readTime(o.fs.opt.TimeType, info)
 	o.mode = info.Mode()
+	if o.fs.opt.HardLinks && o.mode.IsRegular() {
+		o.hardlink = readHardlinkID(info)
+	}
 	o.fs.objectMetaMu.Unlock()
 	// Read the size of the link.
 	//
@@ -1823,6 +1969,9 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 	if err != nil {
 		return nil, err
 	}
+	if id := o.hardlinkID(); id != "" {
+		metadata.Set("hardlink", id)
+	}
 	return metadata, nil
 }
 
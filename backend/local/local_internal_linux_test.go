@@ -0,0 +1,169 @@
+//go:build linux
+
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeRecorder records the changes passed to a ChangeNotify callback
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes map[string]fs.EntryType
+}
+
+func (c *changeRecorder) notify(remote string, entryType fs.EntryType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes[remote] = entryType
+}
+
+// waitFor waits for remote to be notified as entryType
+func (c *changeRecorder) waitFor(t *testing.T, remote string, entryType fs.EntryType) {
+	assert.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		got, ok := c.changes[remote]
+		return ok && got == entryType
+	}, 5*time.Second, 10*time.Millisecond, "waiting for change to %q", remote)
+}
+
+func TestChangeNotify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "existing"), 0777))
+
+	// ChangeNotify is off by default
+	f, err := NewFs(ctx, "local", dir, configmap.Simple{})
+	require.NoError(t, err)
+	require.Nil(t, f.Features().ChangeNotify)
+
+	f, err = NewFs(ctx, "local", dir, configmap.Simple{"links": "true", "change_notify": "true"})
+	require.NoError(t, err)
+	require.NotNil(t, f.Features().ChangeNotify)
+
+	c := &changeRecorder{changes: make(map[string]fs.EntryType)}
+	pollInterval := make(chan time.Duration)
+	f.Features().ChangeNotify(ctx, c.notify, pollInterval)
+	defer close(pollInterval)
+	pollInterval <- time.Second
+
+	// Wait for the watches to be set up in the background
+	assert.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "existing", "ready"), []byte("ready"), 0666))
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, ok := c.changes["existing/ready"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A file in the root
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0666))
+	c.waitFor(t, "file.txt", fs.EntryObject)
+
+	// A file in a directory which existed when we started
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing", "file.txt"), []byte("hello"), 0666))
+	c.waitFor(t, "existing/file.txt", fs.EntryObject)
+
+	// A new directory and a file in it
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "new"), 0777))
+	c.waitFor(t, "new", fs.EntryDirectory)
+	assert.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "file.txt"), []byte("hello"), 0666))
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, ok := c.changes["new/file.txt"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A moved directory is watched in its new place
+	require.NoError(t, os.Rename(filepath.Join(dir, "new"), filepath.Join(dir, "existing", "moved")))
+	c.waitFor(t, "new", fs.EntryDirectory)
+	c.waitFor(t, "existing/moved", fs.EntryDirectory)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing", "moved", "file2.txt"), []byte("hello"), 0666))
+	c.waitFor(t, "existing/moved/file2.txt", fs.EntryObject)
+
+	// Symlinks are translated with --links
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(dir, "link")))
+	c.waitFor(t, "link"+fs.LinkSuffix, fs.EntryObject)
+
+	// Deleting a file
+	c.mu.Lock()
+	delete(c.changes, "existing/file.txt")
+	c.mu.Unlock()
+	require.NoError(t, os.Remove(filepath.Join(dir, "existing", "file.txt")))
+	c.waitFor(t, "existing/file.txt", fs.EntryObject)
+}
+
+func TestChangeNotifyMissingRoot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "a", "b")
+
+	f, err := NewFs(ctx, "local", root, configmap.Simple{"change_notify": "true"})
+	require.NoError(t, err)
+
+	c := &changeRecorder{changes: make(map[string]fs.EntryType)}
+	pollInterval := make(chan time.Duration)
+	f.Features().ChangeNotify(ctx, c.notify, pollInterval)
+	defer close(pollInterval)
+	pollInterval <- time.Second
+
+	// Creating the root, with something in it, is noticed
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "file.txt"), []byte("hello"), 0666))
+	c.waitFor(t, "dir", fs.EntryDirectory)
+	c.waitFor(t, "dir/file.txt", fs.EntryObject)
+
+	// Then the root is watched as normal
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file.txt"), []byte("hello"), 0666))
+	c.waitFor(t, "file.txt", fs.EntryObject)
+}
+
+func TestCopyLinux(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.txt")
+	contents := []byte("hello world")
+	require.NoError(t, os.WriteFile(srcPath, contents, 0666))
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	require.NoError(t, os.Chtimes(srcPath, modTime, modTime))
+
+	f, err := NewFs(ctx, "local", dir, configmap.Simple{})
+	require.NoError(t, err)
+	require.NotNil(t, f.Features().Copy)
+	src, err := f.NewObject(ctx, "src.txt")
+	require.NoError(t, err)
+
+	dst, err := f.Features().Copy(ctx, src, "sub/dst.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "sub/dst.txt", dst.Remote())
+	assert.Equal(t, int64(len(contents)), dst.Size())
+	assert.True(t, modTime.Equal(dst.ModTime(ctx)), "want %v got %v", modTime, dst.ModTime(ctx))
+	got, err := os.ReadFile(filepath.Join(dir, "sub", "dst.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, contents, got)
+
+	// Copying over an existing file replaces it
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("a much longer existing file"), 0666))
+	_, err = f.Features().Copy(ctx, src, "existing.txt")
+	require.NoError(t, err)
+	got, err = os.ReadFile(filepath.Join(dir, "existing.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, contents, got)
+
+	// Server-side copies are disabled with no_clone
+	f, err = NewFs(ctx, "local", dir, configmap.Simple{"no_clone": "true"})
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().Copy)
+}
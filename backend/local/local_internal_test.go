@@ -452,6 +452,130 @@ func TestLocalPath(t *testing.T) {
 	}
 }
 
+// Test the hardlink backend command
+func TestHardlinkCommand(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	f := r.Flocal.(*Fs)
+	f1 := r.WriteFile("one", "One", fstest.Time("2001-02-03T04:05:10.123123123Z"))
+	r.WriteFile("dir/two", "Two", fstest.Time("2002-02-03T04:05:10.123123123Z"))
+
+	sameFile := func(a, b string) bool {
+		aInfo, err := os.Stat(filepath.Join(r.LocalName, a))
+		require.NoError(t, err)
+		bInfo, err := os.Stat(filepath.Join(r.LocalName, b))
+		require.NoError(t, err)
+		return os.SameFile(aInfo, bInfo)
+	}
+
+	// Replace an existing file
+	_, err := f.Command(ctx, "hardlink", []string{"one", "dir/two"}, nil)
+	require.NoError(t, err)
+	assert.True(t, sameFile("one", "dir/two"))
+
+	// Linking again does nothing
+	_, err = f.Command(ctx, "hardlink", []string{"one", "dir/two"}, nil)
+	require.NoError(t, err)
+
+	// Make a new file
+	_, err = f.Command(ctx, "hardlink", []string{"one", "three"}, nil)
+	require.NoError(t, err)
+	assert.True(t, sameFile("one", "three"))
+
+	f2, f3 := f1, f1
+	f2.Path, f3.Path = "dir/two", "three"
+	r.CheckLocalItems(t, f1, f2, f3)
+
+	// Errors
+	_, err = f.Command(ctx, "hardlink", []string{"one"}, nil)
+	assert.Error(t, err)
+	_, err = f.Command(ctx, "hardlink", []string{"dir", "four"}, nil)
+	assert.ErrorContains(t, err, "isn't a file")
+	_, err = f.Command(ctx, "hardlink", []string{"one", "dir"}, nil)
+	assert.ErrorContains(t, err, "isn't a file")
+	_, err = f.Command(ctx, "hardlink", []string{"missing", "four"}, nil)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link detection not supported on Windows")
+	}
+	ctx := context.Background()
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	t1 := fstest.Time("2001-02-03T04:05:10.123123123Z")
+	for _, name := range []string{"one", "three"} {
+		require.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte(name), 0666))
+		require.NoError(t, os.Chtimes(filepath.Join(srcDir, name), t1, t1))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(srcDir, "dir"), 0777))
+	require.NoError(t, os.Link(filepath.Join(srcDir, "one"), filepath.Join(srcDir, "dir", "two")))
+
+	opt := configmap.Simple{"hard_links": "true"}
+	fsrc, err := NewFs(ctx, "local", srcDir, opt)
+	require.NoError(t, err)
+	fdst, err := NewFs(ctx, "local", dstDir, opt)
+	require.NoError(t, err)
+
+	// The links share an ID in the metadata
+	getID := func(remote string) string {
+		o, err := fsrc.NewObject(ctx, remote)
+		require.NoError(t, err)
+		m, err := o.(*Object).Metadata(ctx)
+		require.NoError(t, err)
+		return m["hardlink"]
+	}
+	id := getID("one")
+	assert.NotEqual(t, "", id)
+	assert.Equal(t, id, getID("dir/two"))
+	assert.Equal(t, "", getID("three"))
+
+	sameFile := func(a, b string) bool {
+		aInfo, err := os.Stat(filepath.Join(dstDir, a))
+		require.NoError(t, err)
+		bInfo, err := os.Stat(filepath.Join(dstDir, b))
+		require.NoError(t, err)
+		return os.SameFile(aInfo, bInfo)
+	}
+	copyAll := func() {
+		for _, remote := range []string{"one", "dir/two", "three"} {
+			src, err := fsrc.NewObject(ctx, remote)
+			require.NoError(t, err)
+			_, err = operations.Copy(ctx, fdst, nil, remote, src)
+			require.NoError(t, err)
+		}
+	}
+
+	// Later links are made as hard links with server-side copies
+	// and with uploads
+	for _, disableCopy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disableCopy=%v", disableCopy), func(t *testing.T) {
+			require.NoError(t, os.RemoveAll(dstDir))
+			fdst, err = NewFs(ctx, "local", dstDir, opt)
+			require.NoError(t, err)
+			if disableCopy {
+				fdst.Features().Disable("Copy")
+			}
+			copyAll()
+			assert.True(t, sameFile("one", "dir/two"))
+			assert.False(t, sameFile("one", "three"))
+			got, err := os.ReadFile(filepath.Join(dstDir, "dir", "two"))
+			require.NoError(t, err)
+			assert.Equal(t, "one", string(got))
+		})
+	}
+
+	// Writing over a hard link doesn't change its other names
+	dst, err := fdst.NewObject(ctx, "one")
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("one", t1, 3, true, nil, nil)
+	require.NoError(t, dst.Update(ctx, bytes.NewBufferString("new"), src))
+	assert.False(t, sameFile("one", "dir/two"))
+	got, err := os.ReadFile(filepath.Join(dstDir, "dir", "two"))
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(got))
+}
+
 func TestHashWithTypeNone(t *testing.T) {
 	ctx := context.Background()
 	r := fstest.NewRun(t)
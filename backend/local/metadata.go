@@ -52,6 +52,12 @@ var systemMetadataInfo = map[string]fs.MetadataHelp{
 		Type:    "RFC 3339",
 		Example: "2006-01-02T15:04:05.999999999Z07:00",
 	},
+	"hardlink": {
+		Help:     "ID shared by all hard links to a file (with --local-hard-links)",
+		Type:     "string",
+		Example:  "fd01:1a2b3c",
+		ReadOnly: true,
+	},
 }
 
 // parse a time string from metadata with key
@@ -5,6 +5,7 @@
 package local
 
 import (
+	"fmt"
 	"os"
 	"syscall"
 
@@ -24,3 +25,18 @@ func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
 	}
 	return uint64(statT.Dev) // nolint: unconvert
 }
+
+// readHardlinkID turns a valid os.FileInfo into an ID shared by all
+// the hard links to the file, returning "" if it only has one link or
+// it fails.
+func readHardlinkID(fi os.FileInfo) string {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		fs.Debugf(fi.Name(), "Type assertion fi.Sys().(*syscall.Stat_t) failed from: %#v", fi.Sys())
+		return ""
+	}
+	if uint64(statT.Nlink) <= 1 { // nolint: unconvert
+		return ""
+	}
+	return fmt.Sprintf("%x:%x", uint64(statT.Dev), uint64(statT.Ino)) // nolint: unconvert
+}
@@ -12,6 +12,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs"
@@ -20,6 +21,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/lib/bucket"
+	"github.com/rclone/rclone/lib/pacer"
 )
 
 var (
@@ -27,6 +29,9 @@ var (
 	// the object storage is persistent
 	buckets      = newBucketsInfo()
 	errWriteOnly = errors.New("can't read when using --memory-discard")
+	// the longest eventual_consistency of any remote so old versions
+	// are kept for long enough
+	consistencyWindow atomic.Int64
 )
 
 // Register with Fs
@@ -52,14 +57,101 @@ Probably most easily used by using the connection string syntax:
 
     :memory,discard:bucket
 
+`,
+		}, {
+			Name:     "snapshot",
+			Default:  "",
+			Advanced: true,
+			Help: `Path to a file to keep the memory contents in between runs.
+
+If set then the contents of all the memory remotes are loaded from
+this file when the remote is first used, if it exists, and saved to
+it when rclone exits. They can also be saved and loaded at any time
+with the save and load backend commands.
+
+The snapshot is a JSON file holding the data of all the objects so
+it is only suitable for small amounts of data.
+`,
+		}, {
+			Name:     "latency",
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+			Help: `Latency to add to each operation.
+
+This is a comma separated list of durations to wait before doing each
+operation, for example
+
+    --memory-latency 100ms,list=1s,write=500ms
+
+A duration on its own sets the latency for all the operations and one
+given as operation=duration sets it for that operation. The operations
+are ` + "`list`, `stat`, `read`, `write`, `copy`, `delete` and `dir`" + `.
+`,
+		}, {
+			Name:     "error_rate",
+			Default:  0.0,
+			Advanced: true,
+			Help: `Probability of each operation failing, from 0 to 1.
+
+Operations which fail return a simulated HTTP 429, 500 or 503 error
+before doing anything. These are retried with the pacer up to
+--low-level-retries times, as they would be on a cloud storage
+system, and then returned as retryable errors.
+
+Use --memory-seed to make the failures repeatable.
+`,
+		}, {
+			Name:     "rate_limit",
+			Default:  0.0,
+			Advanced: true,
+			Help: `Maximum number of operations per second, 0 for unlimited.
+
+Operations over the limit fail with a simulated HTTP 429 error with a
+retry after time which the pacer waits for.
+`,
+		}, {
+			Name:     "eventual_consistency",
+			Default:  fs.Duration(0),
+			Advanced: true,
+			Help: `Time it takes changes to appear in listings.
+
+If set listings show objects as they were this long ago, so new
+objects are missing and removed objects are still present for a
+while. Reading an object always returns the latest version.
+`,
+		}, {
+			Name:     "seed",
+			Default:  0,
+			Advanced: true,
+			Help: `Seed for the random failures, 0 for a random seed.
+
+Setting this makes the failures from --memory-error-rate happen in the
+same order each run.
+`,
+		}, {
+			Name:     "pacer_min_sleep",
+			Default:  defaultMinSleep,
+			Advanced: true,
+			Help: `Minimum time to sleep between operations.
+
+This is only used if --memory-latency, --memory-error-rate or
+--memory-rate-limit is set.
 `,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	Discard bool `config:"discard"`
+	Discard             bool            `config:"discard"`
+	Snapshot            string          `config:"snapshot"`
+	Latency             fs.CommaSepList `config:"latency"`
+	ErrorRate           float64         `config:"error_rate"`
+	RateLimit           float64         `config:"rate_limit"`
+	EventualConsistency fs.Duration     `config:"eventual_consistency"`
+	Seed                int64           `config:"seed"`
+	PacerMinSleep       fs.Duration     `config:"pacer_min_sleep"`
 }
 
 // Fs represents a remote memory server
@@ -70,6 +162,8 @@ type Fs struct {
 	rootBucket    string       // bucket part of root (if any)
 	rootDirectory string       // directory part of root (if any)
 	features      *fs.Features // optional features
+	sim           *simulator   // simulates a cloud storage system if set
+	pacer         *fs.Pacer    // to retry the simulated errors
 }
 
 // bucketsInfo holds info about all the buckets
@@ -133,7 +227,7 @@ func (bi *bucketsInfo) getObjectData(bucketName, bucketPath string) (od *objectD
 func (bi *bucketsInfo) updateObjectData(bucketName, bucketPath string, od *objectData) {
 	b := bi.makeBucket(bucketName)
 	b.mu.Lock()
-	b.objects[bucketPath] = od
+	b.setObjectData(bucketPath, od)
 	b.mu.Unlock()
 }
 
@@ -143,8 +237,8 @@ func (bi *bucketsInfo) removeObjectData(bucketName, bucketPath string) (removed
 	if b != nil {
 		b.mu.Lock()
 		od := b.objects[bucketPath]
-		if od != nil {
-			delete(b.objects, bucketPath)
+		if od != nil && !od.deleted {
+			b.setObjectData(bucketPath, &objectData{deleted: true})
 			removed = true
 		}
 		b.mu.Unlock()
@@ -169,15 +263,41 @@ func (bi *bucketInfo) getObjectData(name string) (od *objectData) {
 	bi.mu.RLock()
 	od = bi.objects[name]
 	bi.mu.RUnlock()
+	if od != nil && od.deleted {
+		return nil
+	}
 	return od
 }
 
 // getBucket gets a names bucket or nil
 func (bi *bucketInfo) isEmpty() (empty bool) {
 	bi.mu.RLock()
-	empty = len(bi.objects) == 0
-	bi.mu.RUnlock()
-	return empty
+	defer bi.mu.RUnlock()
+	for _, od := range bi.objects {
+		if !od.deleted {
+			return false
+		}
+	}
+	return true
+}
+
+// setObjectData makes od the latest version of name keeping the
+// earlier versions listings may still show
+//
+// Call with the lock held
+func (bi *bucketInfo) setObjectData(name string, od *objectData) {
+	now := time.Now()
+	od.written = now
+	od.prev = nil
+	if window := time.Duration(consistencyWindow.Load()); window > 0 {
+		od.prev = bi.objects[name]
+		od.trim(now.Add(-window))
+	}
+	if od.deleted && od.prev == nil {
+		delete(bi.objects, name)
+		return
+	}
+	bi.objects[name] = od
 }
 
 // the object data and metadata
@@ -187,6 +307,35 @@ type objectData struct {
 	mimeType string
 	data     []byte
 	size     int64
+	written  time.Time   // when this version was written
+	deleted  bool        // set if this version marks the object as removed
+	prev     *objectData // the previous version if listings may still show it
+}
+
+// trim drops the versions before the first one written before cutoff
+// as no listing can show them
+func (od *objectData) trim(cutoff time.Time) {
+	for v := od; v != nil; v = v.prev {
+		if v.written.Before(cutoff) {
+			v.prev = nil
+			return
+		}
+	}
+}
+
+// visible returns the version of od a listing with the given
+// eventual consistency window shows or nil if it shouldn't show one
+func (od *objectData) visible(window time.Duration) *objectData {
+	if window > 0 {
+		cutoff := time.Now().Add(-window)
+		for od != nil && od.written.After(cutoff) {
+			od = od.prev
+		}
+	}
+	if od == nil || od.deleted {
+		return nil
+	}
+	return od
 }
 
 // Object describes a memory object
@@ -241,6 +390,17 @@ func (f *Fs) setRoot(root string) {
 	f.rootBucket, f.rootDirectory = bucket.Split(f.root)
 }
 
+// keepVersionsFor makes sure old versions are kept for at least
+// window for listings to show
+func keepVersionsFor(window time.Duration) {
+	for {
+		old := consistencyWindow.Load()
+		if int64(window) <= old || consistencyWindow.CompareAndSwap(old, int64(window)) {
+			return
+		}
+	}
+}
+
 // NewFs constructs an Fs from the path, bucket:path
 func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
 	// Parse config into Options struct
@@ -249,11 +409,24 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if err != nil {
 		return nil, err
 	}
+	sim, err := newSimulator(opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Snapshot != "" {
+		err = buckets.loadOnce(opt.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keepVersionsFor(time.Duration(opt.EventualConsistency))
 	root = strings.Trim(root, "/")
 	f := &Fs{
-		name: name,
-		root: root,
-		opt:  *opt,
+		name:  name,
+		root:  root,
+		opt:   *opt,
+		sim:   sim,
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(opt.PacerMinSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
 	}
 	f.setRoot(root)
 	f.features = (&fs.Features{
@@ -284,13 +457,17 @@ func (f *Fs) newObject(remote string, od *objectData) *Object {
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (o fs.Object, err error) {
 	bucket, bucketPath := f.split(remote)
-	od := buckets.getObjectData(bucket, bucketPath)
-	if od == nil {
-		return nil, fs.ErrorObjectNotFound
-	}
-	return f.newObject(remote, od), nil
+	err = f.call(ctx, opStat, func() error {
+		od := buckets.getObjectData(bucket, bucketPath)
+		if od == nil {
+			return fs.ErrorObjectNotFound
+		}
+		o = f.newObject(remote, od)
+		return nil
+	})
+	return o, err
 }
 
 // listFn is called from list to handle an object.
@@ -312,6 +489,10 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 	defer b.mu.RUnlock()
 	dirs := make(map[string]struct{})
 	for absPath, od := range b.objects {
+		od = od.visible(time.Duration(f.opt.EventualConsistency))
+		if od == nil {
+			continue
+		}
 		if strings.HasPrefix(absPath, directory) {
 			remote := absPath[len(prefix):]
 			if !recurse {
@@ -393,6 +574,13 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 // callback returns an error then the listing will stop
 // immediately.
 func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return f.call(ctx, opList, func() error {
+		return f.listP(ctx, dir, callback)
+	})
+}
+
+// listP implements ListP
+func (f *Fs) listP(ctx context.Context, dir string, callback fs.ListRCallback) error {
 	list := list.NewHelper(callback)
 	bucket, directory := f.split(dir)
 	if bucket == "" {
@@ -435,6 +623,13 @@ func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) e
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
 func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.call(ctx, opList, func() error {
+		return f.listR(ctx, dir, callback)
+	})
+}
+
+// listR implements ListR
+func (f *Fs) listR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	bucket, directory := f.split(dir)
 	list := list.NewHelper(callback)
 	entries := fs.DirEntries{}
@@ -504,8 +699,10 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 // Mkdir creates the bucket if it doesn't exist
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	bucket, _ := f.split(dir)
-	buckets.makeBucket(bucket)
-	return nil
+	return f.call(ctx, opDir, func() error {
+		buckets.makeBucket(bucket)
+		return nil
+	})
 }
 
 // Rmdir deletes the bucket if the fs is at the root
@@ -516,7 +713,9 @@ func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	if bucket == "" || directory != "" {
 		return nil
 	}
-	return buckets.deleteBucket(bucket)
+	return f.call(ctx, opDir, func() error {
+		return buckets.deleteBucket(bucket)
+	})
 }
 
 // Precision of the remote
@@ -535,20 +734,26 @@ func (f *Fs) Precision() time.Duration {
 // If it isn't possible then return fs.ErrorCantCopy
 func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	dstBucket, dstPath := f.split(remote)
-	_ = buckets.makeBucket(dstBucket)
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
 		return nil, fs.ErrorCantCopy
 	}
 	srcBucket, srcPath := srcObj.split()
-	od := buckets.getObjectData(srcBucket, srcPath)
-	if od == nil {
-		return nil, fs.ErrorObjectNotFound
+	odCopy := new(objectData)
+	err := f.call(ctx, opCopy, func() error {
+		od := buckets.getObjectData(srcBucket, srcPath)
+		if od == nil {
+			return fs.ErrorObjectNotFound
+		}
+		*odCopy = *od
+		buckets.updateObjectData(dstBucket, dstPath, odCopy)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	odCopy := *od
-	buckets.updateObjectData(dstBucket, dstPath, &odCopy)
-	return f.NewObject(ctx, remote)
+	return f.newObject(remote, odCopy), nil
 }
 
 // Hashes returns the supported hash sets.
@@ -556,6 +761,66 @@ func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hashType)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "save",
+	Short: "Save the contents of the memory remotes to a file.",
+	Long: `This saves the contents of all the memory remotes to the file given
+so they can be loaded again later, for example
+
+` + "```console" + `
+rclone backend save :memory: /path/to/snapshot.json
+` + "```" + `
+
+If no file is given the --memory-snapshot file is used.`,
+}, {
+	Name:  "load",
+	Short: "Load the contents of the memory remotes from a file.",
+	Long: `This replaces the contents of all the memory remotes with those
+saved in the file given, for example
+
+` + "```console" + `
+rclone backend load :memory: /path/to/snapshot.json
+` + "```" + `
+
+If no file is given the --memory-snapshot file is used.`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "save", "load":
+		file := f.opt.Snapshot
+		if len(arg) > 0 {
+			file = arg[0]
+		}
+		if file == "" {
+			return nil, errors.New("need a file name or --memory-snapshot")
+		}
+		if name == "save" {
+			return nil, buckets.save(file)
+		}
+		return nil, buckets.load(file)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// Shutdown saves the snapshot if one is configured
+func (f *Fs) Shutdown(ctx context.Context) error {
+	if f.opt.Snapshot == "" {
+		return nil
+	}
+	return buckets.save(f.opt.Snapshot)
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -605,8 +870,10 @@ func (o *Object) ModTime(ctx context.Context) (result time.Time) {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
-	o.od.modTime = modTime
-	return nil
+	return o.fs.call(ctx, opWrite, func() error {
+		o.od.modTime = modTime
+		return nil
+	})
 }
 
 // Storable returns if this object is storable
@@ -619,6 +886,11 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 	if o.fs.opt.Discard {
 		return nil, errWriteOnly
 	}
+	// The data is read from memory so only the start can fail
+	err = o.fs.call(ctx, opRead, func() error { return nil })
+	if err != nil {
+		return nil, err
+	}
 	var offset, limit int64 = 0, -1
 	for _, option := range options {
 		switch x := option.(type) {
@@ -650,6 +922,11 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 // The new object may have been created if an error is returned
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
 	bucket, bucketPath := o.split()
+	// in can't be read again so only the start can fail
+	err = o.fs.call(ctx, opWrite, func() error { return nil })
+	if err != nil {
+		return err
+	}
 	var data []byte
 	var size int64
 	var hash string
@@ -678,11 +955,13 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 // Remove an object
 func (o *Object) Remove(ctx context.Context) error {
 	bucket, bucketPath := o.split()
-	removed := buckets.removeObjectData(bucket, bucketPath)
-	if !removed {
-		return fs.ErrorObjectNotFound
-	}
-	return nil
+	return o.fs.call(ctx, opDelete, func() error {
+		removed := buckets.removeObjectData(bucket, bucketPath)
+		if !removed {
+			return fs.ErrorObjectNotFound
+		}
+		return nil
+	})
 }
 
 // MimeType of an Object if known, "" otherwise
@@ -697,6 +976,8 @@ var (
 	_ fs.PutStreamer = &Fs{}
 	_ fs.ListRer     = &Fs{}
 	_ fs.ListPer     = &Fs{}
+	_ fs.Commander   = &Fs{}
+	_ fs.Shutdowner  = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
 )
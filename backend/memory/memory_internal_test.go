@@ -2,13 +2,21 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,3 +46,166 @@ func testPurgeListDeadlock(t *testing.T) {
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
+
+// newFs makes a memory Fs from the connection string remote
+// removing its bucket at the end of the test
+func newFs(ctx context.Context, t *testing.T, remote string) fs.Fs {
+	f, err := fs.NewFs(ctx, remote)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		buckets.mu.Lock()
+		delete(buckets.buckets, f.(*Fs).rootBucket)
+		buckets.mu.Unlock()
+	})
+	return f
+}
+
+// rcat writes an object with contents to f
+func rcat(ctx context.Context, t *testing.T, f fs.Fs, remote, contents string) fs.Object {
+	o, err := operations.Rcat(ctx, f, remote, io.NopCloser(strings.NewReader(contents)), t1, nil)
+	require.NoError(t, err)
+	return o
+}
+
+// listNames returns the names of the objects in the root of f
+func listNames(ctx context.Context, t *testing.T, f fs.Fs) (names []string) {
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	return names
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Restore the contents for the other tests
+	saved := filepath.Join(dir, "saved.json")
+	require.NoError(t, buckets.save(saved))
+	defer func() {
+		require.NoError(t, buckets.load(saved))
+	}()
+
+	snapshot := filepath.Join(dir, "snapshot.json")
+	f := newFs(ctx, t, fmt.Sprintf(":memory,snapshot=%q:snapshot-test", snapshot))
+	rcat(ctx, t, f, "file.txt", "hello")
+	require.NoError(t, f.Features().Shutdown(ctx))
+
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	require.NoError(t, f.Rmdir(ctx, ""))
+
+	// Loading the snapshot brings the object back
+	_, err = f.Features().Command(ctx, "load", nil, nil)
+	require.NoError(t, err)
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+	assert.True(t, t1.Equal(o.ModTime(ctx)))
+	assert.Equal(t, "hello", fstests.ReadObject(ctx, t, o, -1))
+
+	// The save command can write to another file
+	other := filepath.Join(dir, "other.json")
+	_, err = f.Features().Command(ctx, "save", []string{other}, nil)
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_, err = f.Features().Command(ctx, "load", []string{other}, nil)
+	require.NoError(t, err)
+	_, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+
+	_, err = f.Features().Command(ctx, "load", []string{filepath.Join(dir, "missing.json")}, nil)
+	assert.Error(t, err)
+}
+
+func TestParseLatency(t *testing.T) {
+	latency, err := parseLatency(fs.CommaSepList{"100ms", "list=1s", "write = 2s"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"":      100 * time.Millisecond,
+		"list":  time.Second,
+		"write": 2 * time.Second,
+	}, latency)
+
+	_, err = parseLatency(fs.CommaSepList{"upload=1s"})
+	assert.ErrorContains(t, err, "unknown operation")
+	_, err = parseLatency(fs.CommaSepList{"list=fast"})
+	assert.ErrorContains(t, err, "bad latency")
+}
+
+func TestLatency(t *testing.T) {
+	ctx := context.Background()
+	f := newFs(ctx, t, ":memory,latency=list=100ms,pacer_min_sleep=0:latency-test")
+	require.NoError(t, f.Mkdir(ctx, ""))
+	start := time.Now()
+	listNames(ctx, t, f)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestSimulatedErrors(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 2
+
+	// Every operation fails with a retryable error
+	f := newFs(ctx, t, ":memory,error_rate=1,seed=1,pacer_min_sleep=1ms:errors-test")
+	err := f.Mkdir(ctx, "")
+	require.Error(t, err)
+	var simErr *simulatedError
+	require.True(t, errors.As(err, &simErr))
+	assert.Contains(t, retryErrorCodes, simErr.code)
+	assert.True(t, fserrors.IsRetryError(err))
+
+	// Some operations fail but are retried
+	ci.LowLevelRetries = 10
+	f = newFs(ctx, t, ":memory,error_rate=0.5,seed=1,pacer_min_sleep=1ms:errors-test")
+	require.NoError(t, f.Mkdir(ctx, ""))
+	for i := range 10 {
+		rcat(ctx, t, f, fmt.Sprintf("file%d.txt", i), "hello")
+	}
+	assert.Len(t, listNames(ctx, t, f), 10)
+
+	_, err = fs.NewFs(ctx, ":memory,error_rate=2:errors-test")
+	assert.ErrorContains(t, err, "error_rate")
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	f := newFs(ctx, t, ":memory,rate_limit=2,pacer_min_sleep=1ms:rate-limit-test")
+	start := time.Now()
+	for range 4 {
+		require.NoError(t, f.Mkdir(ctx, ""))
+	}
+	// 2 operations are allowed at once then 2 per second
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestEventualConsistency(t *testing.T) {
+	ctx := context.Background()
+	const window = 200 * time.Millisecond
+	f := newFs(ctx, t, ":memory,eventual_consistency=200ms:consistency-test")
+	o := rcat(ctx, t, f, "file.txt", "hello")
+
+	// New objects can be read but aren't listed straight away
+	_, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Empty(t, listNames(ctx, t, f))
+	time.Sleep(window)
+	assert.Equal(t, []string{"file.txt"}, listNames(ctx, t, f))
+
+	// Removed objects are listed for a while
+	require.NoError(t, o.Remove(ctx))
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	assert.Equal(t, []string{"file.txt"}, listNames(ctx, t, f))
+	time.Sleep(window)
+	assert.Empty(t, listNames(ctx, t, f))
+
+	// Remotes without the option see the changes straight away
+	g := newFs(ctx, t, ":memory:consistency-test")
+	rcat(ctx, t, f, "file2.txt", "hello")
+	assert.Equal(t, []string{"file2.txt"}, listNames(ctx, t, g))
+	assert.Empty(t, listNames(ctx, t, f))
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/lib/pacer"
+	"golang.org/x/time/rate"
+)
+
+// Operations which can be given their own latency
+const (
+	opList   = "list"   // List, ListP and ListR
+	opStat   = "stat"   // NewObject
+	opRead   = "read"   // Open
+	opWrite  = "write"  // Put, Update and SetModTime
+	opCopy   = "copy"   // Copy
+	opDelete = "delete" // Remove
+	opDir    = "dir"    // Mkdir and Rmdir
+)
+
+var operationNames = []string{opList, opStat, opRead, opWrite, opCopy, opDelete, opDir}
+
+const (
+	defaultMinSleep = fs.Duration(10 * time.Millisecond)
+	maxSleep        = 2 * time.Second
+	decayConstant   = 2 // bigger for slower decay, exponential
+)
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests
+	500, // Internal Server Error
+	503, // Service Unavailable
+}
+
+// simulatedError is an error returned instead of doing an operation
+type simulatedError struct {
+	op   string // operation which failed
+	code int    // HTTP status code it failed with
+}
+
+// Error satisfies the error interface
+func (e *simulatedError) Error() string {
+	return fmt.Sprintf("simulated %s error: %d %s", e.op, e.code, http.StatusText(e.code))
+}
+
+// shouldRetry returns a boolean as to whether this err deserves to be
+// retried. It returns the err as a convenience.
+//
+// Only the simulated errors are retried as the operation hasn't been
+// done when they are returned.
+func shouldRetry(ctx context.Context, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	var simErr *simulatedError
+	if errors.As(err, &simErr) {
+		return fserrors.ShouldRetryHTTP(&http.Response{StatusCode: simErr.code}, retryErrorCodes), err
+	}
+	return false, err
+}
+
+// simulator makes the memory backend behave more like a cloud
+// storage system
+type simulator struct {
+	latency   map[string]time.Duration // latency of each operation, "" for the default
+	errorRate float64                  // probability of an operation failing
+	limiter   *rate.Limiter            // limits the operations per second if set
+	mu        sync.Mutex
+	rnd       *rand.Rand // protected by mu
+}
+
+// parseLatency parses the latency option into a map of operation to
+// latency
+func parseLatency(items fs.CommaSepList) (latency map[string]time.Duration, err error) {
+	latency = make(map[string]time.Duration, len(items))
+	for _, item := range items {
+		op, value, found := strings.Cut(item, "=")
+		if !found {
+			op, value = "", item
+		} else if op = strings.TrimSpace(op); !slices.Contains(operationNames, op) {
+			return nil, fmt.Errorf("unknown operation %q in latency - must be one of %s", op, strings.Join(operationNames, ", "))
+		}
+		d, err := fs.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("bad latency %q: %w", item, err)
+		}
+		latency[op] = d
+	}
+	return latency, nil
+}
+
+// newSimulator makes a simulator from the options or returns nil if
+// none of them are set
+func newSimulator(opt *Options) (*simulator, error) {
+	if len(opt.Latency) == 0 && opt.ErrorRate == 0 && opt.RateLimit == 0 {
+		return nil, nil
+	}
+	if opt.ErrorRate < 0 || opt.ErrorRate > 1 {
+		return nil, fmt.Errorf("error_rate must be between 0 and 1, got %g", opt.ErrorRate)
+	}
+	if opt.RateLimit < 0 {
+		return nil, fmt.Errorf("rate_limit must be positive, got %g", opt.RateLimit)
+	}
+	latency, err := parseLatency(opt.Latency)
+	if err != nil {
+		return nil, err
+	}
+	seed := uint64(opt.Seed)
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	s := &simulator{
+		latency:   latency,
+		errorRate: opt.ErrorRate,
+		rnd:       rand.New(rand.NewPCG(seed, seed)),
+	}
+	if opt.RateLimit > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(opt.RateLimit), max(1, int(opt.RateLimit)))
+	}
+	return s, nil
+}
+
+// inject waits for the latency of op then returns an error if the
+// operation should fail
+func (s *simulator) inject(ctx context.Context, op string) error {
+	latency, ok := s.latency[op]
+	if !ok {
+		latency = s.latency[""]
+	}
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if s.limiter != nil {
+		r := s.limiter.Reserve()
+		if delay := r.Delay(); delay > 0 {
+			r.Cancel()
+			return pacer.RetryAfterError(&simulatedError{op: op, code: http.StatusTooManyRequests}, delay)
+		}
+	}
+	if s.errorRate > 0 {
+		s.mu.Lock()
+		fail := s.rnd.Float64() < s.errorRate
+		code := retryErrorCodes[s.rnd.IntN(len(retryErrorCodes))]
+		s.mu.Unlock()
+		if fail {
+			return &simulatedError{op: op, code: code}
+		}
+	}
+	return nil
+}
+
+// call does the operation op by calling fn, simulating the latency,
+// errors and rate limits configured and retrying with the pacer.
+func (f *Fs) call(ctx context.Context, op string, fn func() error) error {
+	if f.sim == nil {
+		return fn()
+	}
+	return f.pacer.Call(func() (bool, error) {
+		err := f.sim.inject(ctx, op)
+		if err == nil {
+			err = fn()
+		}
+		return shouldRetry(ctx, err)
+	})
+}
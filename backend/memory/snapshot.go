@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// snapshot is the on disk format of the memory contents
+type snapshot struct {
+	Buckets map[string]map[string]snapshotObject `json:"buckets"`
+}
+
+// snapshotObject is an object in a snapshot
+type snapshotObject struct {
+	ModTime  time.Time `json:"modTime"`
+	Size     int64     `json:"size"`
+	Hash     string    `json:"hash,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	Data     []byte    `json:"data,omitempty"`
+}
+
+var (
+	loadedMu sync.Mutex
+	loaded   = map[string]bool{} // snapshot files loaded already
+)
+
+// save writes the contents of all the buckets to the file at name
+func (bi *bucketsInfo) save(name string) (err error) {
+	snap := snapshot{
+		Buckets: make(map[string]map[string]snapshotObject),
+	}
+	bi.mu.RLock()
+	for bucketName, b := range bi.buckets {
+		objects := make(map[string]snapshotObject)
+		b.mu.RLock()
+		for bucketPath, od := range b.objects {
+			if od.deleted {
+				continue
+			}
+			objects[bucketPath] = snapshotObject{
+				ModTime:  od.modTime,
+				Size:     od.size,
+				Hash:     od.hash,
+				MimeType: od.mimeType,
+				Data:     od.data,
+			}
+		}
+		b.mu.RUnlock()
+		snap.Buckets[bucketName] = objects
+	}
+	bi.mu.RUnlock()
+
+	// Write to a temporary file and rename it so the snapshot is
+	// never left half written
+	out, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(out.Name())
+		}
+	}()
+	err = json.NewEncoder(out).Encode(&snap)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// load replaces the contents of all the buckets with the file at name
+func (bi *bucketsInfo) load(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	var snap snapshot
+	err = json.NewDecoder(in).Decode(&snap)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot %q: %w", name, err)
+	}
+	newBuckets := make(map[string]*bucketInfo, len(snap.Buckets))
+	for bucketName, objects := range snap.Buckets {
+		b := newBucketInfo()
+		for bucketPath, o := range objects {
+			b.objects[bucketPath] = &objectData{
+				modTime:  o.ModTime,
+				size:     o.Size,
+				hash:     o.Hash,
+				mimeType: o.MimeType,
+				data:     o.Data,
+			}
+		}
+		newBuckets[bucketName] = b
+	}
+	bi.mu.Lock()
+	bi.buckets = newBuckets
+	bi.mu.Unlock()
+	return nil
+}
+
+// loadOnce loads the snapshot at name if it exists unless it has
+// been loaded already
+func (bi *bucketsInfo) loadOnce(name string) error {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	if loaded[name] {
+		return nil
+	}
+	err := bi.load(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	loaded[name] = true
+	return nil
+}
@@ -0,0 +1,51 @@
+package readcache
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/fs"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "stats",
+	Short: "Print stats on the cache.",
+	Long: `Print statistics about the data cache as JSON.
+
+Usage example:
+
+` + "```console" + `
+rclone backend stats readcache:
+` + "```",
+}, {
+	Name:  "flush",
+	Short: "Flush the directory listing cache.",
+	Long: `Forget all the cached directory listings for this remote so the
+next listing of each directory is read from the wrapped remote.
+
+Usage example:
+
+` + "```console" + `
+rclone backend flush readcache:path
+` + "```",
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "stats":
+		return f.cache.Stats(), nil
+	case "flush":
+		f.invalidateDir("", true)
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
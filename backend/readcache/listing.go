@@ -0,0 +1,182 @@
+package readcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// dirRecord is a directory listing as stored in the listing cache
+type dirRecord struct {
+	Created time.Time
+	Entries []entryRecord
+}
+
+// entryRecord is a single directory entry in a dirRecord
+type entryRecord struct {
+	Leaf    string
+	Dir     bool
+	Size    int64
+	ModTime time.Time
+}
+
+// object makes an Object at remote from the entry
+func (e *entryRecord) object(f *Fs, remote string) *Object {
+	return &Object{
+		f:       f,
+		remote:  remote,
+		size:    e.Size,
+		modTime: e.ModTime,
+	}
+}
+
+// entries turns the record back into directory entries
+func (r *dirRecord) entries(f *Fs, dir string) fs.DirEntries {
+	entries := make(fs.DirEntries, 0, len(r.Entries))
+	for i := range r.Entries {
+		e := &r.Entries[i]
+		remote := path.Join(dir, e.Leaf)
+		if e.Dir {
+			entries = append(entries, fs.NewDir(remote, e.ModTime).SetSize(e.Size))
+		} else {
+			entries = append(entries, e.object(f, remote))
+		}
+	}
+	return entries
+}
+
+// listingKey returns the key in the listing cache for dir
+func (f *Fs) listingKey(dir string) string {
+	return dirKey(path.Join("/", f.Fs.Root(), dir))
+}
+
+// dirKey returns the key for the absolute directory path absDir
+//
+// Keys end in "/" so the keys of all the directories below a
+// directory start with its key.
+func dirKey(absDir string) string {
+	if absDir == "/" {
+		return absDir
+	}
+	return absDir + "/"
+}
+
+// getListing returns a cached listing for dir or nil if there isn't
+// a valid one
+func (f *Fs) getListing(dir string) *dirRecord {
+	if f.db == nil {
+		return nil
+	}
+	op := &kv.OpGet{Key: f.listingKey(dir)}
+	if err := f.db.Do(false, op); err != nil || op.Value == nil {
+		return nil
+	}
+	var rec dirRecord
+	if err := gob.NewDecoder(bytes.NewReader(op.Value)).Decode(&rec); err != nil {
+		fs.Debugf(op.Key, "readcache: decoding listing failed: %v", err)
+		return nil
+	}
+	if time.Since(rec.Created) > time.Duration(f.opt.DirCacheTime) {
+		return nil
+	}
+	return &rec
+}
+
+// putListing stores the listing for dir in the cache
+func (f *Fs) putListing(dir string, entries fs.DirEntries) {
+	if f.db == nil {
+		return
+	}
+	ctx := context.Background()
+	rec := &dirRecord{
+		Created: time.Now(),
+		Entries: make([]entryRecord, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		e := entryRecord{
+			Leaf:    path.Base(entry.Remote()),
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(ctx),
+		}
+		_, e.Dir = entry.(fs.Directory)
+		rec.Entries = append(rec.Entries, e)
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(rec)
+	if err == nil {
+		err = f.db.Do(true, &kv.OpPut{Key: f.listingKey(dir), Value: buf.Bytes()})
+	}
+	if err != nil {
+		fs.Debugf(f, "Failed to cache listing of %q: %v", dir, err)
+	}
+}
+
+// invalidate removes the cached listing of the directory containing
+// remote.
+//
+// Creating an object may create its parent directories too, so the
+// listings of the ancestors which don't list the directory below are
+// removed as well.
+func (f *Fs) invalidate(remote string) {
+	if f.db == nil {
+		return
+	}
+	_ = f.db.Do(true, &kvInvalidate{
+		path: path.Join("/", f.Fs.Root(), remote),
+	})
+}
+
+// invalidateDir removes the cached listings of dir and its parents,
+// and of all the directories below dir if recursive is set
+func (f *Fs) invalidateDir(dir string, recursive bool) {
+	if f.db == nil {
+		return
+	}
+	op := &kv.OpDelete{}
+	if recursive {
+		op.Prefixes = []string{f.listingKey(dir)}
+	} else {
+		op.Keys = []string{f.listingKey(dir)}
+	}
+	_ = f.db.Do(true, op)
+	f.invalidate(dir)
+}
+
+// kvInvalidate: remove the listing containing path and the listings
+// of any ancestors which don't contain the directory below
+type kvInvalidate struct {
+	path string
+}
+
+func (op *kvInvalidate) Do(ctx context.Context, b kv.Bucket) error {
+	for p := op.path; p != "/"; p = path.Dir(p) {
+		leaf, key := path.Base(p), []byte(dirKey(path.Dir(p)))
+		data := b.Get(key)
+		if len(data) == 0 {
+			continue
+		}
+		var rec dirRecord
+		found := false
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(&rec) == nil {
+			for i := range rec.Entries {
+				if rec.Entries[i].Leaf == leaf {
+					found = true
+					break
+				}
+			}
+		}
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+		if found {
+			// p existed already so its parents are unchanged
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package readcache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscache"
+)
+
+// lockRetryDelay is how often to retry taking a lock held by another
+// process
+const lockRetryDelay = 100 * time.Millisecond
+
+// itemLocker locks cache items against other processes sharing the
+// cache directory.
+//
+// Readers take a shared lock on the item which is held while it is
+// open. A reader which needs to fetch data into the cache upgrades it
+// to an exclusive lock, as does the cache cleaner before removing an
+// item, so processes only wait for each other when the cache is
+// changed.
+//
+// Readers in this process share a single file lock per item. Lock
+// files are removed when the last user releases them.
+type itemLocker struct {
+	dir    string
+	remote string // identifies the remote the items belong to
+
+	mu      sync.Mutex
+	held    map[string]*heldLock    // locks held by readers
+	cleaner map[string]*flock.Flock // locks held by the cache cleaner
+}
+
+// heldLock is a file lock shared by readers in this process
+type heldLock struct {
+	mu        sync.Mutex   // held while taking the file lock
+	n         int          // number of readers - protected by itemLocker.mu
+	fl        *flock.Flock // the file lock once taken
+	exclusive bool         // set if fl is an exclusive lock
+}
+
+// newItemLocker makes a locker keeping its lock files in dir for the
+// items of remote
+func newItemLocker(dir, remote string) *itemLocker {
+	return &itemLocker{
+		dir:     dir,
+		remote:  remote,
+		held:    map[string]*heldLock{},
+		cleaner: map[string]*flock.Flock{},
+	}
+}
+
+// lockPath returns the path of the lock file for the item called name
+func (l *itemLocker) lockPath(name string) string {
+	sum := sha1.Sum([]byte(l.remote + "\x00" + name))
+	return filepath.Join(l.dir, hex.EncodeToString(sum[:])+".lock")
+}
+
+// isCurrent returns true if fl still refers to the lock file on disk,
+// ie it wasn't removed by another process while we waited for it.
+func isCurrent(fl *flock.Flock) bool {
+	fi, err := fl.Stat()
+	if err != nil {
+		return false
+	}
+	diskFi, err := os.Stat(fl.Path())
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fi, diskFi)
+}
+
+// removeLock removes the lock file and releases fl, which must be
+// locked exclusively
+func removeLock(fl *flock.Flock) {
+	_ = os.Remove(fl.Path())
+	_ = fl.Close()
+}
+
+// acquire takes a file lock for the item called name.
+//
+// If wait is set it waits for other processes to release the lock
+// until ctx is cancelled, otherwise it returns nil if the item is
+// locked elsewhere.
+func (l *itemLocker) acquire(ctx context.Context, name string, exclusive, wait bool) (*flock.Flock, error) {
+	if err := os.MkdirAll(l.dir, 0700); err != nil {
+		return nil, err
+	}
+	lockPath := l.lockPath(name)
+	for {
+		fl := flock.New(lockPath)
+		tryLock, tryLockContext := fl.TryRLock, fl.TryRLockContext
+		if exclusive {
+			tryLock, tryLockContext = fl.TryLock, fl.TryLockContext
+		}
+		locked, err := tryLock()
+		if err == nil && !locked && wait {
+			fs.Debugf(name, "Waiting for another process to release the cache lock")
+			locked, err = tryLockContext(ctx, lockRetryDelay)
+		}
+		if err != nil {
+			_ = fl.Close()
+			return nil, err
+		}
+		if !locked {
+			_ = fl.Close()
+			if wait {
+				return nil, ctx.Err()
+			}
+			return nil, nil
+		}
+		if isCurrent(fl) {
+			return fl, nil
+		}
+		// The lock file was removed while we waited so try again
+		_ = fl.Close()
+	}
+}
+
+// lock the item called name for reading, waiting for other processes
+// to release it if necessary
+func (l *itemLocker) lock(ctx context.Context, name string) (err error) {
+	l.mu.Lock()
+	h := l.held[name]
+	if h == nil {
+		h = &heldLock{}
+		l.held[name] = h
+	}
+	h.n++
+	l.mu.Unlock()
+
+	err = l.take(ctx, name, h, false)
+	if err != nil {
+		l.release(name, h)
+	}
+	return err
+}
+
+// upgrade the lock taken with lock to an exclusive lock so the item
+// can be filled, waiting for other processes to release it if
+// necessary
+func (l *itemLocker) upgrade(ctx context.Context, name string) error {
+	l.mu.Lock()
+	h := l.held[name]
+	l.mu.Unlock()
+	if h == nil {
+		return errors.New("cache item not locked")
+	}
+	return l.take(ctx, name, h, true)
+}
+
+// take the file lock for h if not already held in the required mode
+func (l *itemLocker) take(ctx context.Context, name string, h *heldLock, exclusive bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fl != nil && (h.exclusive || !exclusive) {
+		return nil
+	}
+	if h.fl != nil {
+		// Converting a lock isn't atomic and a failed conversion
+		// may lose the shared lock, so release it first
+		_ = h.fl.Close()
+		h.fl = nil
+	}
+	fl, err := l.acquire(ctx, name, exclusive, true)
+	if err != nil {
+		return fmt.Errorf("failed to lock cache item: %w", err)
+	}
+	h.fl = fl
+	h.exclusive = exclusive
+	return nil
+}
+
+// unlock releases a lock taken with lock
+func (l *itemLocker) unlock(name string) {
+	l.mu.Lock()
+	h := l.held[name]
+	l.mu.Unlock()
+	if h != nil {
+		l.release(name, h)
+	}
+}
+
+// release drops a reference to h, unlocking the file if it was the
+// last one
+func (l *itemLocker) release(name string, h *heldLock) {
+	l.mu.Lock()
+	h.n--
+	last := h.n == 0
+	if last {
+		delete(l.held, name)
+	}
+	l.mu.Unlock()
+	if !last {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fl == nil {
+		return
+	}
+	// Remove the lock file if nobody else is using it
+	if h.exclusive {
+		removeLock(h.fl)
+	} else if locked, err := h.fl.TryLock(); err == nil && locked {
+		removeLock(h.fl)
+	} else if err := h.fl.Close(); err != nil {
+		fs.Errorf(name, "Failed to release cache lock: %v", err)
+	}
+	h.fl = nil
+}
+
+// TryLock attempts to lock the item for removal by the cache cleaner
+func (l *itemLocker) TryLock(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, found := l.held[name]; found {
+		return false
+	}
+	fl, err := l.acquire(context.Background(), name, true, false)
+	if err != nil || fl == nil {
+		return false
+	}
+	l.cleaner[name] = fl
+	return true
+}
+
+// Unlock releases a lock taken with TryLock
+func (l *itemLocker) Unlock(name string) {
+	l.mu.Lock()
+	fl := l.cleaner[name]
+	delete(l.cleaner, name)
+	l.mu.Unlock()
+	if fl != nil {
+		removeLock(fl)
+	}
+}
+
+// removeStale removes lock files left behind by processes which
+// exited without releasing them
+func (l *itemLocker) removeStale() {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".lock") {
+			continue
+		}
+		fl := flock.New(filepath.Join(l.dir, entry.Name()))
+		locked, err := fl.TryLock()
+		if err != nil || !locked {
+			_ = fl.Close()
+			continue
+		}
+		if isCurrent(fl) {
+			removeLock(fl)
+			removed++
+		} else {
+			_ = fl.Close()
+		}
+	}
+	if removed > 0 {
+		fs.Debugf(nil, "readcache: removed %d stale lock files", removed)
+	}
+}
+
+// Check the interfaces are satisfied
+var _ vfscache.ItemLocker = (*itemLocker)(nil)
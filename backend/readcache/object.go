@@ -0,0 +1,355 @@
+package readcache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/ranges"
+	"github.com/rclone/rclone/vfs/vfscache"
+)
+
+// Object describes a cached object
+//
+// Objects made from cached listings only know their size and
+// modification time. The underlying object is looked up when it is
+// needed.
+type Object struct {
+	f       *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu   sync.Mutex
+	base fs.Object // underlying object - may be nil until needed
+}
+
+// newObject wraps the base object o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		f:       f,
+		remote:  o.Remote(),
+		size:    o.Size(),
+		modTime: o.ModTime(context.Background()),
+		base:    o,
+	}
+}
+
+// getBase returns the underlying object, looking it up if necessary
+func (o *Object) getBase(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.base != nil {
+		return o.base, nil
+	}
+	base, err := o.f.Fs.NewObject(ctx, o.remote)
+	if err != nil {
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			// The cached listing is out of date
+			o.f.invalidate(o.remote)
+		}
+		return nil, err
+	}
+	o.base = base
+	return base, nil
+}
+
+// setBase updates the object from the base object
+func (o *Object) setBase(base fs.Object) {
+	o.mu.Lock()
+	o.base = base
+	o.size = base.Size()
+	o.modTime = base.ModTime(context.Background())
+	o.mu.Unlock()
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.modTime
+}
+
+// Storable returns whether object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return "", err
+	}
+	return base.Hash(ctx, ht)
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.f.invalidate(o.remote)
+	err = base.SetModTime(ctx, modTime)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.modTime = modTime
+	o.mu.Unlock()
+	return nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.f.invalidate(o.remote)
+	err = base.Update(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	o.f.cache.Remove(o.remote)
+	o.setBase(base)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.f.invalidate(o.remote)
+	err = base.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.cache.Remove(o.remote)
+	return nil
+}
+
+// Open an object for read
+//
+// The data is read through the cache, so only the parts of the
+// object which aren't cached already are fetched from the remote.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.ReadCloser, err error) {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	size := base.Size()
+	if size < 0 {
+		// Can't cache objects of unknown size
+		return base.Open(ctx, options...)
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	end := size
+	if limit >= 0 && offset+limit < size {
+		end = offset + limit
+	}
+
+	if o.f.locker != nil {
+		if err = o.f.locker.lock(ctx, o.remote); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				o.f.locker.unlock(o.remote)
+			}
+		}()
+	}
+	item := o.f.cache.Item(o.remote)
+	if o.f.locker != nil {
+		// Pick up data cached by other processes
+		if err = item.Reload(); err != nil {
+			fs.Debugf(o, "Failed to reload cache metadata: %v", err)
+		}
+		// Only lock out other processes if the cache needs filling
+		if !isCached(item, size, offset, end) {
+			if err = o.f.locker.upgrade(ctx, o.remote); err != nil {
+				return nil, err
+			}
+			if err = item.Reload(); err != nil {
+				fs.Debugf(o, "Failed to reload cache metadata: %v", err)
+			}
+		}
+	}
+	if err = item.Open(base); err != nil {
+		return nil, err
+	}
+	return &reader{
+		o:    o,
+		item: item,
+		off:  offset,
+		end:  end,
+	}, nil
+}
+
+// isCached returns true if the data from offset to end of an object of
+// size is in the cache already
+func isCached(item *vfscache.Item, size, offset, end int64) bool {
+	cachedSize, err := item.GetSize()
+	if err != nil || cachedSize != size {
+		return false
+	}
+	return item.HasRange(ranges.Range{Pos: offset, Size: end - offset})
+}
+
+// reader reads an object from the cache
+type reader struct {
+	o      *Object
+	item   *vfscache.Item
+	mu     sync.Mutex
+	off    int64 // current offset
+	end    int64 // offset to stop reading at
+	closed bool
+}
+
+// Read bytes from the cache, fetching them if necessary
+func (r *reader) Read(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, errors.New("read on closed file")
+	}
+	if r.off >= r.end {
+		return 0, io.EOF
+	}
+	if left := r.end - r.off; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err = r.item.ReadAt(p, r.off)
+	r.off += int64(n)
+	if err == io.EOF && r.off < r.end {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Close the reader, releasing the cache item
+func (r *reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.item.Close(nil)
+	if r.o.f.locker != nil {
+		r.o.f.locker.unlock(r.o.remote)
+	}
+	return err
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	base, err := o.getBase(context.Background())
+	if err != nil {
+		return nil
+	}
+	return base
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	base, err := o.getBase(ctx)
+	if err != nil {
+		return ""
+	}
+	if do, ok := base.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	base, err := o.getBase(context.Background())
+	if err != nil {
+		return ""
+	}
+	if do, ok := base.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// GetTier returns the storage tier of the Object if known
+func (o *Object) GetTier() string {
+	base, err := o.getBase(context.Background())
+	if err != nil {
+		return ""
+	}
+	if do, ok := base.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// SetTier sets the storage tier of the Object
+func (o *Object) SetTier(tier string) error {
+	base, err := o.getBase(context.Background())
+	if err != nil {
+		return err
+	}
+	if do, ok := base.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
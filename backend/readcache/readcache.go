@@ -0,0 +1,577 @@
+// Package readcache implements a read-through caching backend
+// using the VFS cache
+package readcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/vfs/vfscache"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "readcache",
+		Description: "Cache reads from another remote on local disk",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to cache.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:    "dir_cache_time",
+			Default: fs.Duration(5 * time.Minute),
+			Help: `Time to cache directory listings for.
+
+Set to 0 to disable caching of directory listings.`,
+		}, {
+			Name:    "max_age",
+			Default: fs.Duration(24 * time.Hour),
+			Help: `Max time since last access of objects in the cache.
+
+Objects not read for longer than this are removed from the cache.`,
+		}, {
+			Name:    "max_size",
+			Default: fs.SizeSuffix(-1),
+			Help:    "Max total size of objects in the cache.",
+		}, {
+			Name:     "min_free_space",
+			Default:  fs.SizeSuffix(-1),
+			Help:     "Target minimum free space on the disk containing the cache.",
+			Advanced: true,
+		}, {
+			Name:     "poll_interval",
+			Default:  fs.Duration(time.Minute),
+			Help:     "Interval to check the cache for stale objects.",
+			Advanced: true,
+		}, {
+			Name:     "chunk_size",
+			Default:  fs.SizeSuffix(128 * fs.Mebi),
+			Help:     "Read the source objects in chunks of this size.",
+			Advanced: true,
+		}, {
+			Name:     "chunk_size_limit",
+			Default:  fs.SizeSuffix(-1),
+			Help:     "If greater than chunk_size, double the chunk size after each chunk read, until the limit is reached ('off' is unlimited).",
+			Advanced: true,
+		}, {
+			Name:     "read_ahead",
+			Default:  fs.SizeSuffix(0),
+			Help:     "Extra read ahead over chunk_size.",
+			Advanced: true,
+		}, {
+			Name:     "fast_fingerprint",
+			Default:  false,
+			Help:     "Use fast (less accurate) fingerprints for change detection.",
+			Advanced: true,
+		}, {
+			Name:     "shared",
+			Default:  false,
+			Advanced: true,
+			Help: `Share the cache directory with other rclone processes.
+
+If set, objects are locked in the cache while they are being read or
+fetched so that several rclone processes using the same remote and
+cache directory can safely share the cached data and directory
+listings.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote          string        `config:"remote"`
+	DirCacheTime    fs.Duration   `config:"dir_cache_time"`
+	MaxAge          fs.Duration   `config:"max_age"`
+	MaxSize         fs.SizeSuffix `config:"max_size"`
+	MinFreeSpace    fs.SizeSuffix `config:"min_free_space"`
+	PollInterval    fs.Duration   `config:"poll_interval"`
+	ChunkSize       fs.SizeSuffix `config:"chunk_size"`
+	ChunkSizeLimit  fs.SizeSuffix `config:"chunk_size_limit"`
+	ReadAhead       fs.SizeSuffix `config:"read_ahead"`
+	FastFingerprint bool          `config:"fast_fingerprint"`
+	Shared          bool          `config:"shared"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	wrapper  fs.Fs
+	features *fs.Features
+	opt      *Options
+	cache    *vfscache.Cache    // data cache
+	cancel   context.CancelFunc // cancel the data cache
+	db       *kv.DB             // directory listings - may be nil
+	locker   *itemLocker        // cross process locks - may be nil
+}
+
+// NewFs constructs an Fs from the remote:path string
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point readcache remote at itself - check the value of the remote setting")
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	baseFs, baseErr := cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	f := &Fs{
+		Fs:   baseFs,
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	// Correct root if definitely pointing to a file
+	if baseErr == fs.ErrorIsFile {
+		f.root = path.Dir(f.root)
+		if f.root == "." || f.root == "/" {
+			f.root = ""
+		}
+	}
+
+	if opt.DirCacheTime > 0 {
+		if kv.Supported() {
+			f.db, err = kv.Start(ctx, "readcache", f.Fs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open listing cache: %w", err)
+			}
+		} else {
+			fs.Logf(f, "Caching of directory listings is not supported on this OS")
+		}
+	}
+
+	vfsOpt := vfscommon.Opt
+	vfsOpt.CacheMode = vfscommon.CacheModeFull
+	vfsOpt.CacheMaxAge = opt.MaxAge
+	vfsOpt.CacheMaxSize = opt.MaxSize
+	vfsOpt.CacheMinFreeSpace = opt.MinFreeSpace
+	vfsOpt.CachePollInterval = opt.PollInterval
+	vfsOpt.ChunkSize = opt.ChunkSize
+	vfsOpt.ChunkSizeLimit = opt.ChunkSizeLimit
+	vfsOpt.ReadAhead = opt.ReadAhead
+	vfsOpt.FastFingerprint = opt.FastFingerprint
+	vfsOpt.WriteBack = 0
+	if opt.Shared {
+		// Items must really be closed before the lock is released
+		vfsOpt.HandleCaching = 0
+		f.locker = newItemLocker(filepath.Join(config.GetCacheDir(), "readcache", "locks"), fs.ConfigString(f))
+		f.locker.removeStale()
+	}
+
+	// The data cache lives until Shutdown and keeps its own stats
+	// so reads into the cache aren't counted twice
+	cacheCtx, cancel := context.WithCancel(accounting.WithStatsGroup(context.WithoutCancel(ctx), "readcache"))
+	f.cancel = cancel
+	f.cache, err = vfscache.New(cacheCtx, f, &vfsOpt, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	if f.locker != nil {
+		f.cache.SetItemLocker(f.locker)
+	}
+
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		PartialUploads:          true,
+	}).Fill(ctx, f).Mask(ctx, baseFs).WrapsFs(f, baseFs)
+	// Only advertise features the base can do
+	baseFeatures := baseFs.Features()
+	if baseFeatures.PutStream == nil {
+		f.features.PutStream = nil
+	}
+	if baseFeatures.PutUnchecked == nil {
+		f.features.PutUnchecked = nil
+	}
+	if baseFeatures.Purge == nil {
+		f.features.Purge = nil
+	}
+	if baseFeatures.Copy == nil {
+		f.features.Copy = nil
+	}
+	if baseFeatures.Move == nil {
+		f.features.Move = nil
+	}
+	if baseFeatures.DirMove == nil {
+		f.features.DirMove = nil
+	}
+	if baseFeatures.About == nil {
+		f.features.About = nil
+	}
+	if baseFeatures.ChangeNotify == nil {
+		f.features.ChangeNotify = nil
+	}
+
+	cache.PinUntilFinalized(f.Fs, f)
+	return f, baseErr
+}
+
+//
+// Filesystem
+//
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string { return f.name }
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string { return f.root }
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features { return f.features }
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Read cache '%s:%s'", f.name, f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs { return f.Fs }
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs { return f.wrapper }
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) { f.wrapper = wrapper }
+
+// parentDir returns the parent directory of remote in the form used
+// for listings
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// List the objects and directories in dir into entries. The entries
+// can be returned in any order but should be for a complete
+// directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	if rec := f.getListing(dir); rec != nil {
+		fs.Debugf(f, "List %q: using cached listing", dir)
+		return rec.entries(f, dir), nil
+	}
+	baseEntries, err := f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries = make(fs.DirEntries, 0, len(baseEntries))
+	for _, entry := range baseEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			entries = append(entries, f.newObject(x))
+		default:
+			entries = append(entries, entry)
+		}
+	}
+	f.putListing(dir, baseEntries)
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if rec := f.getListing(parentDir(remote)); rec != nil {
+		leaf := path.Base(remote)
+		for i := range rec.Entries {
+			e := &rec.Entries[i]
+			if e.Leaf != leaf {
+				continue
+			}
+			if e.Dir {
+				return nil, fs.ErrorIsDir
+			}
+			return e.object(f, remote), nil
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	defer f.invalidate(src.Remote())
+	o, err := f.Fs.Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("can't PutUnchecked")
+	}
+	defer f.invalidate(src.Remote())
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	defer f.invalidate(src.Remote())
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	defer f.invalidateDir(dir, false)
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	defer f.invalidateDir(dir, false)
+	return f.Fs.Rmdir(ctx, dir)
+}
+
+// Purge all files in the directory specified
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	defer f.invalidateDir(dir, true)
+	return do(ctx, dir)
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	base, err := srcObj.getBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer f.invalidate(remote)
+	o, err := do(ctx, base, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	base, err := srcObj.getBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer srcObj.f.invalidate(srcObj.remote)
+	defer f.invalidate(remote)
+	o, err := do(ctx, base, remote)
+	if err != nil {
+		return nil, err
+	}
+	srcObj.f.cache.Remove(srcObj.remote)
+	return f.newObject(o), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	defer srcFs.invalidateDir(srcRemote, true)
+	defer f.invalidateDir(dstRemote, true)
+	err := do(ctx, srcFs.Fs, srcRemote, dstRemote)
+	if err != nil {
+		return err
+	}
+	if srcFs.cache.DirExists(srcRemote) {
+		if err := srcFs.cache.DirRename(srcRemote, dstRemote); err != nil {
+			fs.Debugf(f, "Failed to rename cached directory %q: %v", srcRemote, err)
+		}
+	}
+	return nil
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// CleanUp the trash in the Fs and remove stale objects from the cache
+func (f *Fs) CleanUp(ctx context.Context) error {
+	if err := f.cache.CleanUp(); err != nil {
+		return err
+	}
+	if do := f.Fs.Features().CleanUp; do != nil {
+		return do(ctx)
+	}
+	return nil
+}
+
+// ChangeNotify calls the passed function with a path that has had
+// changes, invalidating any cached listings first.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	do := f.Fs.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	do(ctx, func(remote string, entryType fs.EntryType) {
+		if entryType == fs.EntryDirectory {
+			f.invalidateDir(remote, false)
+		} else {
+			f.invalidate(remote)
+		}
+		notifyFunc(remote, entryType)
+	}, pollIntervalChan)
+}
+
+// DirCacheFlush resets the directory cache
+func (f *Fs) DirCacheFlush() {
+	f.invalidateDir("", true)
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	f.cancel()
+	if f.db != nil && !f.db.IsStopped() {
+		err = f.db.Stop(false)
+	}
+	if do := f.Fs.Features().Shutdown; do != nil {
+		if err2 := do(ctx); err2 != nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+)
@@ -0,0 +1,135 @@
+package readcache
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check that listings are served from the cache until flushed
+func (f *Fs) testListingCache(t *testing.T) {
+	if f.db == nil {
+		t.Skip("listing cache not enabled")
+	}
+	ctx := context.Background()
+	const dir = "listing_cache"
+	item := fstest.Item{Path: dir + "/file1", ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	_ = fstests.PutTestContents(ctx, t, f, &item, "listing cache", true)
+
+	entries, err := f.List(ctx, dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Add a file behind the back of the cache
+	item2 := fstest.Item{Path: dir + "/file2", ModTime: item.ModTime}
+	_ = fstests.PutTestContents(ctx, t, f.Fs, &item2, "not seen", true)
+	entries, err = f.List(ctx, dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	_, err = f.NewObject(ctx, item2.Path)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Flushing the cache makes it visible
+	_, err = f.Command(ctx, "flush", nil, nil)
+	require.NoError(t, err)
+	entries, err = f.List(ctx, dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	require.NoError(t, operations.Purge(ctx, f, dir))
+}
+
+// Check that a partial read is served from the cache afterwards
+func (f *Fs) testReadThrough(t *testing.T) {
+	ctx := context.Background()
+	const contents = "0123456789abcdefghijklmnopqrstuvwxyz"
+	item := fstest.Item{Path: "read_through/file", ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	o := fstests.PutTestContents(ctx, t, f, &item, contents, true)
+
+	in, err := o.Open(ctx, &fs.RangeOption{Start: 10, End: 19})
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents[10:20], string(data))
+
+	item2 := f.cache.Item(o.Remote())
+	assert.True(t, item2.Exists())
+
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	data, err = io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(data))
+
+	require.NoError(t, o.Remove(ctx))
+	assert.False(t, f.cache.Exists(o.Remote()))
+}
+
+// InternalTest dispatches all internal tests
+func (f *Fs) InternalTest(t *testing.T) {
+	t.Run("ListingCache", f.testListingCache)
+	t.Run("ReadThrough", f.testReadThrough)
+}
+
+var _ fstests.InternalTester = (*Fs)(nil)
+
+func TestItemLocker(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "locks")
+	l1 := newItemLocker(dir, "remote:")
+	l2 := newItemLocker(dir, "remote:")
+	other := newItemLocker(dir, "other:")
+	ctx := context.Background()
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	// Readers share the lock
+	require.NoError(t, l1.lock(ctx, "a"))
+	require.NoError(t, l1.lock(ctx, "a"))
+	require.NoError(t, l2.lock(cancelledCtx, "a"))
+
+	// Readers block the cleaners of the same remote only
+	assert.False(t, l1.TryLock("a"))
+	assert.False(t, l2.TryLock("a"))
+	assert.True(t, other.TryLock("a"))
+	other.Unlock("a")
+
+	// A reader can't fill the item while another process reads it
+	assert.Error(t, l1.upgrade(cancelledCtx, "a"))
+	l2.unlock("a")
+	require.NoError(t, l1.upgrade(ctx, "a"))
+	assert.Error(t, l2.lock(cancelledCtx, "a"))
+	assert.False(t, l2.TryLock("a"))
+	l1.unlock("a")
+	assert.False(t, l2.TryLock("a"))
+	l1.unlock("a")
+
+	// The cleaner blocks readers
+	assert.True(t, l2.TryLock("a"))
+	assert.Error(t, l1.lock(cancelledCtx, "a"))
+	l2.Unlock("a")
+	require.NoError(t, l1.lock(ctx, "a"))
+	l1.unlock("a")
+
+	// The lock files are removed when released
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	// Stale lock files are removed
+	stale := filepath.Join(dir, "stale.lock")
+	require.NoError(t, os.WriteFile(stale, nil, 0600))
+	l1.removeStale()
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,53 @@
+package readcache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/readcache"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+
+	_ "github.com/rclone/rclone/backend/all" // for integration tests
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	opt := fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*readcache.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"OpenChunkWriter",
+			"ResumeChunkWriter",
+			"MergeDirs",
+			"DirSetModTime",
+			"MkdirMetadata",
+			"PublicLink",
+			"ListR",
+			"ListP",
+			"UserInfo",
+			"Disconnect",
+		},
+		UnimplementableObjectMethods: []string{
+			"Metadata",
+			"SetMetadata",
+		},
+	}
+	if *fstest.RemoteName == "" {
+		tempDir := filepath.Join(os.TempDir(), "rclone-readcache-test")
+		opt.ExtraConfig = []fstests.ExtraConfigItem{
+			{Name: "TestReadCache", Key: "type", Value: "readcache"},
+			{Name: "TestReadCache", Key: "remote", Value: tempDir},
+		}
+		opt.RemoteName = "TestReadCache:"
+		opt.QuickTestOK = true
+	}
+	fstests.Run(t, &opt)
+	// test again with the cache shared between processes
+	if *fstest.RemoteName == "" {
+		opt.ExtraConfig = append(opt.ExtraConfig, fstests.ExtraConfigItem{Name: "TestReadCache", Key: "shared", Value: "true"})
+		fstests.Run(t, &opt)
+	}
+}
@@ -1943,6 +1943,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if !opt.UseMultipartUploads.Value {
 		fs.Debugf(f, "Disabling multipart uploads")
 		f.features.OpenChunkWriter = nil
+		f.features.ResumeChunkWriter = nil
 	}
 
 	if f.rootBucket != "" && f.rootDirectory != "" && !opt.NoHeadObject && !strings.HasSuffix(root, "/") {
@@ -4472,6 +4473,7 @@ type s3ChunkWriter struct {
 	multiPartUploadInput *s3.CreateMultipartUploadInput
 	completedPartsMu     sync.Mutex
 	completedParts       []types.CompletedPart
+	parts                map[int]fs.ChunkWriterPart // chunks written, indexed by chunk number
 	eTag                 string
 	versionID            string
 	md5sMu               sync.Mutex
@@ -4480,11 +4482,9 @@ type s3ChunkWriter struct {
 	o                    *Object
 }
 
-// OpenChunkWriter returns the chunk size and a ChunkWriter
-//
-// Pass in the remote and the src object
-// You can also use options to hint at the desired chunk size
-func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+// newChunkWriter prepares a ChunkWriter for remote without starting
+// or resuming a multipart upload
+func (f *Fs) newChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, chunkWriter *s3ChunkWriter, err error) {
 	// Temporary Object under construction
 	o := &Object{
 		fs:     f,
@@ -4522,9 +4522,39 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 		chunkSize = chunksize.Calculator(src, size, uploadParts, chunkSize)
 	}
 
+	chunkWriter = &s3ChunkWriter{
+		chunkSize:            int64(chunkSize),
+		size:                 size,
+		f:                    f,
+		bucket:               ui.req.Bucket,
+		key:                  ui.req.Key,
+		multiPartUploadInput: &mReq,
+		completedParts:       make([]types.CompletedPart, 0),
+		parts:                make(map[int]fs.ChunkWriterPart),
+		ui:                   ui,
+		o:                    o,
+	}
+	info = fs.ChunkWriterInfo{
+		ChunkSize:         int64(chunkSize),
+		Concurrency:       o.fs.opt.UploadConcurrency,
+		LeavePartsOnError: o.fs.opt.LeavePartsOnError,
+	}
+	return info, chunkWriter, nil
+}
+
+// OpenChunkWriter returns the chunk size and a ChunkWriter
+//
+// Pass in the remote and the src object
+// You can also use options to hint at the desired chunk size
+func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	info, chunkWriter, err := f.newChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, nil, err
+	}
+
 	var mOut *s3.CreateMultipartUploadOutput
 	err = f.pacer.Call(func() (bool, error) {
-		mOut, err = f.c.CreateMultipartUpload(ctx, &mReq)
+		mOut, err = f.c.CreateMultipartUpload(ctx, chunkWriter.multiPartUploadInput)
 		if err == nil {
 			if mOut == nil {
 				err = fserrors.RetryErrorf("internal error: no info from multipart upload")
@@ -4537,36 +4567,101 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 	if err != nil {
 		return info, nil, fmt.Errorf("create multipart upload failed: %w", err)
 	}
+	chunkWriter.uploadID = mOut.UploadId
 
-	chunkWriter := &s3ChunkWriter{
-		chunkSize:            int64(chunkSize),
-		size:                 size,
-		f:                    f,
-		bucket:               ui.req.Bucket,
-		key:                  ui.req.Key,
-		uploadID:             mOut.UploadId,
-		multiPartUploadInput: &mReq,
-		completedParts:       make([]types.CompletedPart, 0),
-		ui:                   ui,
-		o:                    o,
+	fs.Debugf(chunkWriter.o, "open chunk writer: started multipart upload: %v", *mOut.UploadId)
+	return info, chunkWriter, err
+}
+
+// ResumeChunkWriter carries on the multipart upload uploadID to
+// remote, returning the parts which have been uploaded so far.
+//
+// The parts aren't part of the upload until they are passed to AddChunk.
+func (f *Fs) ResumeChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, uploadID string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ResumableChunkWriter, parts []fs.ChunkWriterPart, err error) {
+	info, chunkWriter, err := f.newChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, nil, nil, err
 	}
-	info = fs.ChunkWriterInfo{
-		ChunkSize:         int64(chunkSize),
-		Concurrency:       o.fs.opt.UploadConcurrency,
-		LeavePartsOnError: o.fs.opt.LeavePartsOnError,
+	chunkWriter.uploadID = aws.String(uploadID)
+
+	mReq := chunkWriter.multiPartUploadInput
+	req := s3.ListPartsInput{
+		Bucket:               chunkWriter.bucket,
+		Key:                  chunkWriter.key,
+		UploadId:             chunkWriter.uploadID,
+		RequestPayer:         mReq.RequestPayer,
+		SSECustomerAlgorithm: mReq.SSECustomerAlgorithm,
+		SSECustomerKey:       mReq.SSECustomerKey,
+		SSECustomerKeyMD5:    mReq.SSECustomerKeyMD5,
 	}
-	fs.Debugf(o, "open chunk writer: started multipart upload: %v", *mOut.UploadId)
-	return info, chunkWriter, err
+	for {
+		var resp *s3.ListPartsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListParts(ctx, &req)
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return info, nil, nil, fmt.Errorf("failed to list parts of multipart upload %q: %w", uploadID, err)
+		}
+		for _, part := range resp.Parts {
+			if part.PartNumber == nil || part.ETag == nil || part.Size == nil {
+				continue
+			}
+			parts = append(parts, fs.ChunkWriterPart{
+				ChunkNumber: int(*part.PartNumber) - 1,
+				Size:        *part.Size,
+				ID:          *part.ETag,
+			})
+		}
+		if !deref(resp.IsTruncated) || resp.NextPartNumberMarker == nil {
+			break
+		}
+		req.PartNumberMarker = resp.NextPartNumberMarker
+	}
+
+	fs.Debugf(chunkWriter.o, "open chunk writer: resumed multipart upload %v with %d parts", uploadID, len(parts))
+	return info, chunkWriter, parts, nil
 }
 
-// add a part number and etag to the completed parts
-func (w *s3ChunkWriter) addCompletedPart(partNum *int32, eTag *string) {
+// UploadID returns the ID of the multipart upload
+func (w *s3ChunkWriter) UploadID() string {
+	return *w.uploadID
+}
+
+// WrittenChunk returns the part written for chunkNumber if any
+func (w *s3ChunkWriter) WrittenChunk(chunkNumber int) (part fs.ChunkWriterPart, ok bool) {
+	w.completedPartsMu.Lock()
+	defer w.completedPartsMu.Unlock()
+	part, ok = w.parts[chunkNumber]
+	return part, ok
+}
+
+// AddChunk adds a part uploaded earlier to the multipart upload
+func (w *s3ChunkWriter) AddChunk(part fs.ChunkWriterPart) error {
+	if part.ChunkNumber < 0 {
+		return fmt.Errorf("invalid chunk number provided: %v", part.ChunkNumber)
+	}
+	// The ETag of a part is the MD5 of its contents
+	if md5binary, err := hex.DecodeString(strings.Trim(part.ID, `"`)); err == nil && len(md5binary) == md5.Size {
+		w.addMd5(&md5binary, int64(part.ChunkNumber))
+	}
+	w.addCompletedPart(part.ChunkNumber, part.Size, aws.String(part.ID))
+	return nil
+}
+
+// add a chunk number and etag to the completed parts
+func (w *s3ChunkWriter) addCompletedPart(chunkNumber int, size int64, eTag *string) {
 	w.completedPartsMu.Lock()
 	defer w.completedPartsMu.Unlock()
 	w.completedParts = append(w.completedParts, types.CompletedPart{
-		PartNumber: partNum,
+		PartNumber: aws.Int32(int32(chunkNumber + 1)),
 		ETag:       eTag,
 	})
+	w.parts[chunkNumber] = fs.ChunkWriterPart{
+		ChunkNumber: chunkNumber,
+		Size:        size,
+		ID:          *eTag,
+	}
 }
 
 // addMd5 adds a binary md5 to the md5 calculated so far
@@ -4659,7 +4754,7 @@ func (w *s3ChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader
 		return -1, fmt.Errorf("failed to upload chunk %d with %v bytes: %w", chunkNumber+1, currentChunkSize, err)
 	}
 
-	w.addCompletedPart(s3PartNumber, uout.ETag)
+	w.addCompletedPart(chunkNumber, currentChunkSize, uout.ETag)
 
 	fs.Debugf(w.o, "multipart upload wrote chunk %d with %v bytes and etag %v", chunkNumber+1, currentChunkSize, *uout.ETag)
 	return currentChunkSize, err
@@ -5396,18 +5491,19 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.Purger          = &Fs{}
-	_ fs.Copier          = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.ListRer         = &Fs{}
-	_ fs.ListPer         = &Fs{}
-	_ fs.Commander       = &Fs{}
-	_ fs.CleanUpper      = &Fs{}
-	_ fs.OpenChunkWriter = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.MimeTyper       = &Object{}
-	_ fs.GetTierer       = &Object{}
-	_ fs.SetTierer       = &Object{}
-	_ fs.Metadataer      = &Object{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.Purger             = &Fs{}
+	_ fs.Copier             = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.ListRer            = &Fs{}
+	_ fs.ListPer            = &Fs{}
+	_ fs.Commander          = &Fs{}
+	_ fs.CleanUpper         = &Fs{}
+	_ fs.OpenChunkWriter    = &Fs{}
+	_ fs.ChunkWriterResumer = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.MimeTyper          = &Object{}
+	_ fs.GetTierer          = &Object{}
+	_ fs.SetTierer          = &Object{}
+	_ fs.Metadataer         = &Object{}
 )
@@ -0,0 +1,334 @@
+//go:build !plan9
+
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/rclone/rclone/fs"
+)
+
+const metadataTimeFormat = time.RFC3339Nano
+
+// system metadata keys which this backend owns
+//
+// These are the same as the keys the local backend uses so metadata
+// can be round tripped between them.
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"mode": {
+		Help:    "File type and mode",
+		Type:    "octal, unix style",
+		Example: "0100664",
+	},
+	"uid": {
+		Help:    "User ID of owner",
+		Type:    "decimal number",
+		Example: "500",
+	},
+	"gid": {
+		Help:    "Group ID of owner",
+		Type:    "decimal number",
+		Example: "500",
+	},
+	"atime": {
+		Help:    "Time of last access",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05Z07:00",
+	},
+	"mtime": {
+		Help:    "Time of last modification",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05Z07:00",
+	},
+}
+
+// metadataHelp is the help for the metadata of this backend
+const metadataHelp = `The sftp backend reads and writes the ownership, mode and times of
+files and directories using the same keys as the local backend, so
+metadata can be copied between the two with ` + "`--metadata`" + `.
+
+SFTP only transfers times to the nearest second. Symlinks are followed
+so the metadata returned is that of the file they point to, unless
+` + "`--sftp-links`" + ` is in use. In that case the metadata returned is
+that of the symlink itself, and metadata isn't set on symlinks as SFTP
+would set it on the file they point to. The server may refuse to change
+the ownership of files unless the user is privileged, in which case
+setting "uid" and "gid" will fail.
+
+User metadata is not supported as the SFTP protocol has no portable
+way of reading or writing extended attributes.
+
+When restoring metadata with ` + "`--metadata`" + ` rclone applies the
+"mode", "uid" and "gid" from the source. The setuid, setgid and sticky
+bits are not restored by default - see the
+` + "`--sftp-metadata-restore-special-bits`" + ` flag.
+`
+
+// parse a time string from metadata with key
+func parseMetadataTime(m fs.Metadata, key string) (t time.Time, ok bool, err error) {
+	value, ok := m[key]
+	if !ok {
+		return t, false, nil
+	}
+	t, err = time.Parse(metadataTimeFormat, value)
+	if err != nil {
+		return t, false, fmt.Errorf("failed to parse metadata %s: %q: %w", key, value, err)
+	}
+	return t, true, nil
+}
+
+// parse a uint32 from metadata with key and base
+func parseMetadataUint32(m fs.Metadata, key string, base int) (result uint32, ok bool, err error) {
+	value, ok := m[key]
+	if !ok {
+		return 0, false, nil
+	}
+	parsed, err := strconv.ParseUint(value, base, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse metadata %s: %q: %w", key, value, err)
+	}
+	return uint32(parsed), true, nil
+}
+
+// readMetadata returns the metadata for the object from the stat
+// result read when it was last listed or stat-ed.
+func (o *Object) readMetadata() fs.Metadata {
+	m := fs.Metadata{
+		"mtime": time.Unix(int64(o.modTime), 0).Format(metadataTimeFormat),
+	}
+	if o.rawMode != 0 {
+		m["mode"] = fmt.Sprintf("%0o", o.rawMode)
+		m["uid"] = strconv.FormatUint(uint64(o.uid), 10)
+		m["gid"] = strconv.FormatUint(uint64(o.gid), 10)
+		m["atime"] = time.Unix(int64(o.atime), 0).Format(metadataTimeFormat)
+	}
+	return m
+}
+
+// writeMetadata sets the metadata m on the file or directory at the
+// native path filePath.
+//
+// The ownership is set before the mode as changing the owner may
+// clear the setuid and setgid bits.
+func (f *Fs) writeMetadata(ctx context.Context, filePath string, m fs.Metadata) (err error) {
+	uid, hasUID, err := parseMetadataUint32(m, "uid", 10)
+	if err != nil {
+		return err
+	}
+	gid, hasGID, err := parseMetadataUint32(m, "gid", 10)
+	if err != nil {
+		return err
+	}
+	mode, hasMode, err := parseMetadataUint32(m, "mode", 8)
+	if err != nil {
+		return err
+	}
+	atime, hasAtime, err := parseMetadataTime(m, "atime")
+	if err != nil {
+		return err
+	}
+	mtime, hasMtime, err := parseMetadataTime(m, "mtime")
+	if err != nil {
+		return err
+	}
+	if !f.opt.SetModTime {
+		hasAtime, hasMtime = false, false
+	}
+	if !hasUID && !hasGID && !hasMode && !hasAtime && !hasMtime {
+		return nil
+	}
+	c, err := f.getSftpConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	defer func() {
+		f.putSftpConnection(&c, err)
+	}()
+	if hasUID || hasGID {
+		if !hasUID || !hasGID {
+			// SFTP can only set both at once so fill in the missing one
+			info, err := c.sftpClient.Stat(filePath)
+			if err != nil {
+				return fmt.Errorf("failed to read ownership: %w", err)
+			}
+			stat := info.Sys().(*sftp.FileStat)
+			if !hasUID {
+				uid = stat.UID
+			}
+			if !hasGID {
+				gid = stat.GID
+			}
+		}
+		err = c.sftpClient.Chown(filePath, int(uid), int(gid))
+		if err != nil {
+			return fmt.Errorf("failed to change ownership: %w", err)
+		}
+	}
+	if hasMode {
+		// mode comes from the source, which may be untrusted, so
+		// by default apply only the permission bits and strip the
+		// setuid, setgid and sticky bits.
+		perm := mode & 0o7777
+		if !f.opt.MetadataRestoreSpecial {
+			perm &= 0o777
+		}
+		// Chmod passes the raw setuid, setgid and sticky bits through
+		err = c.sftpClient.Chmod(filePath, os.FileMode(perm))
+		if err != nil {
+			return fmt.Errorf("failed to change permissions: %w", err)
+		}
+	}
+	if hasAtime || hasMtime {
+		if !hasAtime {
+			atime = mtime
+		}
+		if !hasMtime {
+			mtime = atime
+		}
+		err = c.sftpClient.Chtimes(filePath, atime, mtime)
+		if err != nil {
+			return fmt.Errorf("failed to set times: %w", err)
+		}
+	}
+	return nil
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	return o.readMetadata(), nil
+}
+
+// SetMetadata sets metadata for an Object
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	if o.translatedLink {
+		// SFTP can only set the metadata of the target of a symlink
+		fs.Debugf(o, "Can't set metadata on a symlink")
+		return nil
+	}
+	err := o.fs.writeMetadata(ctx, o.path(), metadata)
+	if err != nil {
+		return fmt.Errorf("SetMetadata failed on Object: %w", err)
+	}
+	// Re-read info now we have finished setting stuff
+	return o.stat(ctx)
+}
+
+// Directory represents an sftp directory
+type Directory struct {
+	Object
+}
+
+// newDirectory makes a Directory from the stat result passed in
+func (f *Fs) newDirectory(dir string, info os.FileInfo) *Directory {
+	d := &Directory{
+		Object: Object{
+			fs:     f,
+			remote: dir,
+		},
+	}
+	d.setMetadata(info)
+	return d
+}
+
+// Size returns the size of the directory which is unknown
+func (d *Directory) Size() int64 {
+	return -1
+}
+
+// Items returns the count of items in this directory or this
+// directory and subdirectories if known, -1 for unknown
+func (d *Directory) Items() int64 {
+	return -1
+}
+
+// ID returns the internal ID of this directory if known, or
+// "" otherwise
+func (d *Directory) ID() string {
+	return ""
+}
+
+// SetMetadata sets metadata for a Directory
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (d *Directory) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	err := d.fs.writeMetadata(ctx, d.path(), metadata)
+	if err != nil {
+		return fmt.Errorf("SetMetadata failed on Directory: %w", err)
+	}
+	// Re-read info now we have finished setting stuff
+	return d.stat(ctx)
+}
+
+// stat updates the info in the Directory
+func (d *Directory) stat(ctx context.Context) error {
+	info, err := d.fs.stat(ctx, d.remote)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs.ErrorDirNotFound
+		}
+		return fmt.Errorf("stat failed: %w", err)
+	}
+	if !info.IsDir() {
+		return fs.ErrorIsFile
+	}
+	d.setMetadata(info)
+	return nil
+}
+
+// Hash does nothing on a directory
+//
+// This method is implemented with the incorrect type signature to
+// stop the Directory type asserting to fs.Object or fs.ObjectInfo
+func (d *Directory) Hash() {
+	// Does nothing
+}
+
+// MkdirMetadata makes the directory passed in as dir.
+//
+// It shouldn't return an error if it already exists.
+//
+// If the metadata is not nil it is set.
+//
+// It returns the directory that was created.
+func (f *Fs) MkdirMetadata(ctx context.Context, dir string, metadata fs.Metadata) (fs.Directory, error) {
+	err := f.Mkdir(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("mkdir metadata: failed make directory: %w", err)
+	}
+	if metadata != nil {
+		err = f.writeMetadata(ctx, f.remotePath(dir), metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set metadata on directory: %w", err)
+		}
+	}
+	d := &Directory{
+		Object: Object{
+			fs:     f,
+			remote: dir,
+		},
+	}
+	err = d.stat(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mkdir metadata: failed to read info: %w", err)
+	}
+	return d, nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.MkdirMetadataer = &Fs{}
+	_ fs.Metadataer      = &Object{}
+	_ fs.SetMetadataer   = &Object{}
+	_ fs.Directory       = &Directory{}
+	_ fs.Metadataer      = &Directory{}
+	_ fs.SetMetadataer   = &Directory{}
+)
@@ -34,6 +34,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/env"
+	"github.com/rclone/rclone/lib/hardlink"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/readers"
 	sshagent "github.com/xanzy/ssh-agent"
@@ -63,6 +64,10 @@ func init() {
 		Name:        "sftp",
 		Description: "SSH/SFTP",
 		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   metadataHelp,
+		},
 		Options: []fs.Option{{
 			Name:      "host",
 			Help:      "SSH host to connect to.\n\nE.g. \"example.com\".",
@@ -327,6 +332,16 @@ not prevent writes from following symlinks on the server - confining an
 SFTP account to a directory must be enforced server side (for example
 with a chroot jail or restricted permissions).`,
 			Advanced: true,
+		}, {
+			Name:    "links",
+			Default: false,
+			Help: `Translate symlinks to/from regular files with a '` + fs.LinkSuffix + `' extension.
+
+Symlinks on the server are listed as files with a '` + fs.LinkSuffix + `'
+extension whose contents are the target of the link, and uploading such
+a file makes a symlink on the server. This means symlinks can be copied
+between the sftp and local backends with --links.`,
+			Advanced: true,
 		}, {
 			Name:     "subsystem",
 			Default:  "sftp",
@@ -607,6 +622,35 @@ as the source and the destination will be the same file.
 
 This feature may be useful backups made with --copy-dest.`,
 			Advanced: true,
+		}, {
+			Name:    "hard_links",
+			Default: false,
+			Help: `Recreate hard links from the source.
+
+If this is set then files which share a "hardlink" value in the
+source's metadata, for example a local remote using
+--local-hard-links, are uploaded once and the later ones are created
+as hard links to the first.
+
+Existing files are removed before being uploaded so the upload doesn't
+change any other links to them.
+
+Not all sftp servers support hard links. If the server doesn't then
+the files are uploaded in full.`,
+			Advanced: true,
+		}, {
+			Name:    "metadata_restore_special_bits",
+			Default: false,
+			Help: `Restore the setuid, setgid and sticky bits from metadata.
+
+When restoring metadata with --metadata rclone applies the "mode" from
+the source. By default rclone applies only the permission bits and
+strips the setuid, setgid and sticky bits as the source may not be
+trusted.
+
+If you trust the source and want these bits restored - for example
+when migrating a server - set this flag.`,
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -643,6 +687,7 @@ type Options struct {
 	Xxh3sumCommand          string               `config:"xxh3sum_command"`
 	Xxh128sumCommand        string               `config:"xxh128sum_command"`
 	SkipLinks               bool                 `config:"skip_links"`
+	TranslateSymlinks       bool                 `config:"links"`
 	Subsystem               string               `config:"subsystem"`
 	ServerCommand           string               `config:"server_command"`
 	UseFstat                bool                 `config:"use_fstat"`
@@ -661,6 +706,8 @@ type Options struct {
 	SocksProxy              string               `config:"socks_proxy"`
 	HTTPProxy               string               `config:"http_proxy"`
 	CopyIsHardlink          bool                 `config:"copy_is_hardlink"`
+	HardLinks               bool                 `config:"hard_links"`
+	MetadataRestoreSpecial  bool                 `config:"metadata_restore_special_bits"`
 }
 
 // Fs stores the interface to the remote SFTP files
@@ -685,7 +732,8 @@ type Fs struct {
 	savedpswd    string
 	sessions     atomic.Int32 // count in use sessions
 	tokens       *pacer.TokenDispenser
-	proxyURL     *url.URL // address of HTTP proxy read from environment
+	proxyURL     *url.URL          // address of HTTP proxy read from environment
+	hardlinks    *hardlink.Tracker // hard links uploaded if --sftp-hard-links is set
 
 	hostKeysMu sync.RWMutex
 	hostKeys   map[string][][]byte // algo -> list of trusted marshalled key bytes
@@ -706,18 +754,23 @@ type pendingKey struct {
 
 // Object is a remote SFTP file that has been stat'd (so it exists, but is not necessarily open for reading)
 type Object struct {
-	fs        *Fs
-	remote    string
-	size      int64       // size of the object
-	modTime   uint32      // modification time of the object as unix time
-	mode      os.FileMode // mode bits from the file
-	md5sum    *string     // Cached MD5 checksum
-	sha1sum   *string     // Cached SHA-1 checksum
-	crc32sum  *string     // Cached CRC-32 checksum
-	sha256sum *string     // Cached SHA-256 checksum
-	blake3sum *string     // Cached BLAKE3 checksum
-	xxh3sum   *string     // Cached XXH3 checksum
-	xxh128sum *string     // Cached XXH128 checksum
+	fs             *Fs
+	remote         string
+	size           int64       // size of the object
+	modTime        uint32      // modification time of the object as unix time
+	mode           os.FileMode // mode bits from the file
+	rawMode        uint32      // file type and mode as returned by the server
+	atime          uint32      // access time of the object as unix time
+	uid            uint32      // user ID of the owner
+	gid            uint32      // group ID of the owner
+	translatedLink bool        // set if this is a symlink translated to a file with fs.LinkSuffix
+	md5sum         *string     // Cached MD5 checksum
+	sha1sum        *string     // Cached SHA-1 checksum
+	crc32sum       *string     // Cached CRC-32 checksum
+	sha256sum      *string     // Cached SHA-256 checksum
+	blake3sum      *string     // Cached BLAKE3 checksum
+	xxh3sum        *string     // Cached XXH3 checksum
+	xxh128sum      *string     // Cached XXH128 checksum
 }
 
 // conn encapsulates an ssh client and corresponding sftp client
@@ -1251,6 +1304,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if err != nil {
 		return nil, err
 	}
+	// Override --sftp-links with --links if set
+	if f.ci.Links {
+		opt.TranslateSymlinks = true
+	}
 	if len(opt.SSH) != 0 && ((opt.User != currentUser && opt.User != "") || opt.Host != "" || (opt.Port != "22" && opt.Port != "")) {
 		fs.Logf(name, "--sftp-ssh is in use - ignoring user/host/port from config - set in the parameters to --sftp-ssh (remove them from the config to silence this warning)")
 	}
@@ -1567,6 +1624,9 @@ func NewFsWithConnection(ctx context.Context, f *Fs, name string, root string, m
 	f.config = sshConfig
 	f.url = "sftp://" + opt.User + "@" + opt.Host + ":" + opt.Port + "/" + root
 	f.mkdirLock = newStringLock()
+	if opt.HardLinks {
+		f.hardlinks = hardlink.New()
+	}
 	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
 	f.savedpswd = ""
 
@@ -1589,6 +1649,10 @@ func NewFsWithConnection(ctx context.Context, f *Fs, name string, root string, m
 		SlowHash:                 true,
 		PartialUploads:           true,
 		DirModTimeUpdatesOnWrite: true, // indicate writing files to a directory updates its modtime
+		ReadMetadata:             true,
+		WriteMetadata:            true,
+		ReadDirMetadata:          true,
+		WriteDirMetadata:         true,
 	}).Fill(ctx, f)
 	if !opt.CopyIsHardlink {
 		// Disable server side copy unless --sftp-copy-is-hardlink is set
@@ -1721,10 +1785,7 @@ func (f *Fs) Precision() time.Duration {
 
 // NewObject creates a new remote sftp file object
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
-	o := &Object{
-		fs:     f,
-		remote: remote,
-	}
+	o := f.newObject(remote)
 	err := o.stat(ctx)
 	if err != nil {
 		return nil, err
@@ -1785,6 +1846,20 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	}
 	for _, info := range infos {
 		remote := path.Join(dir, f.opt.Enc.ToStandardName(info.Name()))
+		if f.opt.TranslateSymlinks && info.Mode()&os.ModeSymlink != 0 {
+			o := f.newObject(remote + fs.LinkSuffix)
+			o.setMetadata(info)
+			// Read the size of the link from its target as the
+			// size the server returns isn't always correct
+			target, err := o.readLink(ctx)
+			if err != nil {
+				fs.Errorf(o, "Failed to read link size: %v", err)
+			} else {
+				o.size = int64(len(target))
+			}
+			entries = append(entries, o)
+			continue
+		}
 		// If file is a symlink (not a regular file is the best cross platform test we can do), do a stat to
 		// pick up the size and type of the destination, instead of the size and type of the symlink.
 		if !info.Mode().IsRegular() && !info.IsDir() {
@@ -1802,7 +1877,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			}
 		}
 		if info.IsDir() {
-			d := fs.NewDir(remote, info.ModTime())
+			d := f.newDirectory(remote, info)
 			entries = append(entries, d)
 		} else {
 			o := &Object{
@@ -1823,10 +1898,7 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 		return nil, fmt.Errorf("Put mkParentDir failed: %w", err)
 	}
 	// Temporary object under construction
-	o := &Object{
-		fs:     f,
-		remote: src.Remote(),
-	}
+	o := f.newObject(src.Remote())
 	err = o.Update(ctx, in, src, options...)
 	if err != nil {
 		return nil, err
@@ -1929,11 +2001,16 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if err != nil {
 		return nil, fmt.Errorf("Move mkParentDir failed: %w", err)
 	}
+	// Fetch metadata if --metadata is in use
+	meta, err := fs.GetMetadataOptions(ctx, f, src, fs.MetadataAsOpenOptions(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Move failed to read metadata: %w", err)
+	}
 	c, err := f.getSftpConnection(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Move: %w", err)
 	}
-	srcPath, dstPath := srcObj.path(), f.remotePath(remote)
+	srcPath, dstPath := srcObj.path(), f.newObject(remote).path()
 	if _, ok := c.sftpClient.HasExtension("posix-rename@openssh.com"); ok {
 		err = c.sftpClient.PosixRename(srcPath, dstPath)
 	} else {
@@ -1948,10 +2025,19 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if err != nil {
 		return nil, fmt.Errorf("Move Rename failed: %w", err)
 	}
+	if f.hardlinks != nil {
+		f.hardlinks.Rename(srcPath, dstPath)
+	}
 	dstObj, err := f.NewObject(ctx, remote)
 	if err != nil {
 		return nil, fmt.Errorf("Move NewObject failed: %w", err)
 	}
+	if meta != nil {
+		err = dstObj.(*Object).SetMetadata(ctx, meta)
+		if err != nil {
+			return nil, fmt.Errorf("Move: %w", err)
+		}
+	}
 	return dstObj, nil
 }
 
@@ -1973,7 +2059,7 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if err != nil {
 		return nil, fmt.Errorf("Copy: %w", err)
 	}
-	srcPath, dstPath := srcObj.path(), f.remotePath(remote)
+	srcPath, dstPath := srcObj.path(), f.newObject(remote).path()
 	err = c.sftpClient.Link(srcPath, dstPath)
 	f.putSftpConnection(&c, err)
 	if err != nil {
@@ -2372,6 +2458,21 @@ func (o *Object) Hash(ctx context.Context, r hash.Type) (string, error) {
 	if o.fs.opt.DisableHashCheck {
 		return "", nil
 	}
+	if o.translatedLink {
+		// Hash the target of the link as that is its contents
+		if !o.fs.Hashes().Contains(r) {
+			return "", hash.ErrUnsupported
+		}
+		target, err := o.readLink(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate %v hash: %w", r, err)
+		}
+		hashes, err := hash.StreamTypes(strings.NewReader(target), hash.NewHashSet(r))
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate %v hash: %w", r, err)
+		}
+		return hashes[r], nil
+	}
 	_ = o.fs.Hashes()
 
 	var hashCmd string
@@ -2577,11 +2678,37 @@ func (o *Object) ModTime(ctx context.Context) time.Time {
 	return time.Unix(int64(o.modTime), 0)
 }
 
+// newObject makes an Object for remote which hasn't been stat-ed yet
+func (f *Fs) newObject(remote string) *Object {
+	return &Object{
+		fs:             f,
+		remote:         remote,
+		translatedLink: f.opt.TranslateSymlinks && strings.HasSuffix(remote, fs.LinkSuffix),
+	}
+}
+
 // path returns the native SFTP path of the object
+//
+// For translated links this is the path of the symlink without the
+// link suffix.
 func (o *Object) path() string {
+	if o.translatedLink {
+		return o.fs.remotePath(strings.TrimSuffix(o.remote, fs.LinkSuffix))
+	}
 	return o.fs.remotePath(o.remote)
 }
 
+// readLink returns the target of the symlink of a translated link
+func (o *Object) readLink(ctx context.Context) (string, error) {
+	c, err := o.fs.getSftpConnection(ctx)
+	if err != nil {
+		return "", fmt.Errorf("readlink: %w", err)
+	}
+	target, err := c.sftpClient.ReadLink(o.path())
+	o.fs.putSftpConnection(&c, err)
+	return target, err
+}
+
 // shellPath returns the SSH shell path of the object
 func (o *Object) shellPath() string {
 	return o.fs.remoteShellPath(o.remote)
@@ -2589,9 +2716,14 @@ func (o *Object) shellPath() string {
 
 // setMetadata updates the info in the object from the stat result passed in
 func (o *Object) setMetadata(info os.FileInfo) {
-	o.modTime = info.Sys().(*sftp.FileStat).Mtime
+	stat := info.Sys().(*sftp.FileStat)
+	o.modTime = stat.Mtime
 	o.size = info.Size()
 	o.mode = info.Mode()
+	o.rawMode = stat.Mode
+	o.atime = stat.Atime
+	o.uid = stat.UID
+	o.gid = stat.GID
 }
 
 // statRemote stats the file or directory at the remote given
@@ -2609,9 +2741,27 @@ func (f *Fs) stat(ctx context.Context, remote string) (info os.FileInfo, err err
 	return info, err
 }
 
+// lstat returns the info for the native SFTP path given without
+// following symlinks
+func (f *Fs) lstat(ctx context.Context, absPath string) (info os.FileInfo, err error) {
+	c, err := f.getSftpConnection(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lstat: %w", err)
+	}
+	info, err = c.sftpClient.Lstat(absPath)
+	f.putSftpConnection(&c, err)
+	return info, err
+}
+
 // stat updates the info in the Object
 func (o *Object) stat(ctx context.Context) error {
-	info, err := o.fs.stat(ctx, o.remote)
+	var info os.FileInfo
+	var err error
+	if o.fs.opt.TranslateSymlinks {
+		info, err = o.fs.lstat(ctx, o.path())
+	} else {
+		info, err = o.fs.stat(ctx, o.remote)
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fs.ErrorObjectNotFound
@@ -2621,7 +2771,18 @@ func (o *Object) stat(ctx context.Context) error {
 	if info.IsDir() {
 		return fs.ErrorIsDir
 	}
+	if o.fs.opt.TranslateSymlinks && (info.Mode()&os.ModeSymlink != 0) != o.translatedLink {
+		// Symlinks must be referred to with the link suffix
+		return fs.ErrorObjectNotFound
+	}
 	o.setMetadata(info)
+	if o.translatedLink {
+		target, err := o.readLink(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read link size: %w", err)
+		}
+		o.size = int64(len(target))
+	}
 	return nil
 }
 
@@ -2632,6 +2793,11 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	if !o.fs.opt.SetModTime {
 		return nil
 	}
+	if o.translatedLink {
+		// SFTP can only set the times of the target of a symlink
+		fs.Debugf(o, "Can't set the modification time of a symlink")
+		return nil
+	}
 	c, err := o.fs.getSftpConnection(ctx)
 	if err != nil {
 		return fmt.Errorf("SetModTime: %w", err)
@@ -2650,7 +2816,7 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 
 // Storable returns whether the remote sftp file is a regular file (not a directory, symbolic link, block device, character device, named pipe, etc.)
 func (o *Object) Storable() bool {
-	return o.mode.IsRegular()
+	return o.mode.IsRegular() || o.translatedLink
 }
 
 // objectReader represents a file open for reading on the SFTP server
@@ -2720,6 +2886,18 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 			}
 		}
 	}
+	if o.translatedLink {
+		// The contents of a translated link is its target
+		target, err := o.readLink(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Open failed: %w", err)
+		}
+		r := strings.NewReader(target)
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("Open Seek failed: %w", err)
+		}
+		return readers.NewLimitedReadCloser(io.NopCloser(r), limit), nil
+	}
 	c, err := o.fs.getSftpConnection(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Open: %w", err)
@@ -2753,7 +2931,7 @@ func (sr *sizeReader) Size() int64 {
 }
 
 // Update a remote sftp file using the data <in> and ModTime from <src>
-func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
 	o.fs.addSession() // Show session in use
 	defer o.fs.removeSession()
 	// Clear the hash cache since we are about to update the object
@@ -2764,10 +2942,31 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	o.blake3sum = nil
 	o.xxh3sum = nil
 	o.xxh128sum = nil
+	if o.translatedLink {
+		return o.updateLink(ctx, in)
+	}
+	// Make a hard link instead if src is a later link to a file we
+	// have already uploaded
+	linked, linkDone := o.linkHardlink(ctx, src)
+	if linked {
+		return o.stat(ctx)
+	}
+	if linkDone != nil {
+		defer func() {
+			linkDone(err)
+		}()
+	}
 	c, err := o.fs.getSftpConnection(ctx)
 	if err != nil {
 		return fmt.Errorf("Update: %w", err)
 	}
+	if o.fs.hardlinks != nil {
+		// Don't write through a hard link into the other names of the file
+		removeErr := c.sftpClient.Remove(o.path())
+		if removeErr != nil && !errors.Is(removeErr, iofs.ErrNotExist) {
+			fs.Debugf(o, "Failed to remove before upload: %v", removeErr)
+		}
+	}
 	// Hang on to the connection for the whole upload so it doesn't get reused while we are uploading
 	file, err := c.sftpClient.OpenFile(o.path(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
@@ -2810,6 +3009,18 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return fmt.Errorf("Update SetModTime failed: %w", err)
 	}
 
+	// Set the metadata if required - this stats the object
+	meta, err := fs.GetMetadataOptions(ctx, o.fs, src, options)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata from source object: %w", err)
+	}
+	if meta != nil {
+		err = o.SetMetadata(ctx, meta)
+		if err != nil {
+			return fmt.Errorf("Update: %w", err)
+		}
+	}
+
 	// Stat the file after the upload to read its stats back if o.fs.opt.SetModTime == false
 	if !o.fs.opt.SetModTime {
 		err = o.stat(ctx)
@@ -2828,6 +3039,76 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	return nil
 }
 
+// updateLink makes o a symlink to the target read from in
+//
+// The times and metadata of the symlink aren't set as SFTP would set
+// them on its target.
+func (o *Object) updateLink(ctx context.Context, in io.Reader) error {
+	target, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("Update failed to read link: %w", err)
+	}
+	c, err := o.fs.getSftpConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("Update: %w", err)
+	}
+	// Replace whatever is there already rather than writing through it
+	err = c.sftpClient.Remove(o.path())
+	if err == nil || errors.Is(err, iofs.ErrNotExist) {
+		err = c.sftpClient.Symlink(string(target), o.path())
+	}
+	o.fs.putSftpConnection(&c, err)
+	if err != nil {
+		return fmt.Errorf("Update Symlink failed: %w", err)
+	}
+	return o.stat(ctx)
+}
+
+// linkHardlink makes o a hard link to the file already uploaded with
+// the same "hardlink" metadata as src, if there is one.
+//
+// It returns true if o is now a hard link. Otherwise, if done is not
+// nil, it must be called with the result of uploading o so later
+// links can be made to it.
+func (o *Object) linkHardlink(ctx context.Context, src fs.ObjectInfo) (linked bool, done func(err error)) {
+	if o.fs.hardlinks == nil {
+		return false, nil
+	}
+	meta, err := fs.GetMetadata(ctx, src)
+	if err != nil {
+		fs.Debugf(src, "Failed to read metadata to find hard link: %v", err)
+		return false, nil
+	}
+	id := meta["hardlink"]
+	if id == "" {
+		return false, nil
+	}
+	target, release := o.fs.hardlinks.Acquire(id)
+	if target != "" && target != o.path() {
+		c, err := o.fs.getSftpConnection(ctx)
+		if err == nil {
+			err = c.sftpClient.Remove(o.path())
+			if err == nil || errors.Is(err, iofs.ErrNotExist) {
+				err = c.sftpClient.Link(target, o.path())
+			}
+			o.fs.putSftpConnection(&c, err)
+		}
+		if err == nil {
+			fs.Debugf(o, "Hard linked to %q", target)
+			release("")
+			return true, nil
+		}
+		fs.Debugf(o, "Failed to hard link to %q so uploading instead: %v", target, err)
+	}
+	return false, func(err error) {
+		if err != nil {
+			release("")
+			return
+		}
+		release(o.path())
+	}
+}
+
 // Remove a remote sftp file object
 func (o *Object) Remove(ctx context.Context) error {
 	c, err := o.fs.getSftpConnection(ctx)
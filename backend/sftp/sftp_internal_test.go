@@ -16,7 +16,9 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/sftp"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/obscure"
@@ -120,6 +122,30 @@ func TestRemotePathEncodesRemoteNames(t *testing.T) {
 	assert.Equal(t, "/srv/root", f.remotePath(""))
 }
 
+func TestTranslatedLinkPath(t *testing.T) {
+	f := &Fs{
+		absRoot: "/srv/root",
+		opt: Options{
+			Enc: encoder.Display,
+		},
+	}
+
+	// Without --sftp-links the suffix is part of the name
+	o := f.newObject("dir/link" + fs.LinkSuffix)
+	assert.False(t, o.translatedLink)
+	assert.Equal(t, "/srv/root/dir/link"+fs.LinkSuffix, o.path())
+
+	f.opt.TranslateSymlinks = true
+	o = f.newObject("dir/link" + fs.LinkSuffix)
+	assert.True(t, o.translatedLink)
+	assert.True(t, o.Storable())
+	assert.Equal(t, "/srv/root/dir/link", o.path())
+
+	o = f.newObject("dir/file")
+	assert.False(t, o.translatedLink)
+	assert.Equal(t, "/srv/root/dir/file", o.path())
+}
+
 func TestRemoteShellPathEncodesRemoteNames(t *testing.T) {
 	f := &Fs{
 		absRoot: "/srv/root",
@@ -685,6 +711,60 @@ func TestParseUsage(t *testing.T) {
 	}
 }
 
+// statInfo is an os.FileInfo returning an sftp.FileStat
+type statInfo struct {
+	os.FileInfo
+	stat *sftp.FileStat
+}
+
+func (i statInfo) Size() int64        { return int64(i.stat.Size) }
+func (i statInfo) Mode() os.FileMode  { return os.FileMode(i.stat.Mode & 0o777) }
+func (i statInfo) ModTime() time.Time { return i.stat.ModTime() }
+func (i statInfo) IsDir() bool        { return i.stat.Mode&0o170000 == 0o040000 }
+func (i statInfo) Sys() any           { return i.stat }
+
+func TestMetadata(t *testing.T) {
+	f := &Fs{}
+	o := &Object{fs: f, remote: "file.txt"}
+	o.setMetadata(statInfo{stat: &sftp.FileStat{
+		Size:  100,
+		Mode:  0o100640,
+		Mtime: 1136239445,
+		Atime: 1136239446,
+		UID:   1000,
+		GID:   100,
+	}})
+	m, err := o.Metadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, fs.Metadata{
+		"mode":  "100640",
+		"uid":   "1000",
+		"gid":   "100",
+		"mtime": time.Unix(1136239445, 0).Format(time.RFC3339Nano),
+		"atime": time.Unix(1136239446, 0).Format(time.RFC3339Nano),
+	}, m)
+
+	d := f.newDirectory("dir", statInfo{stat: &sftp.FileStat{
+		Mode:  0o40755,
+		Mtime: 1136239445,
+	}})
+	assert.Equal(t, int64(-1), d.Size())
+	m, err = d.Metadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "40755", m["mode"])
+
+	// Bad values are rejected before anything is written
+	for _, meta := range []fs.Metadata{
+		{"mode": "potato"},
+		{"uid": "-1"},
+		{"gid": "4294967296"},
+		{"mtime": "yesterday"},
+	} {
+		err = f.writeMetadata(context.Background(), "file.txt", meta)
+		assert.ErrorContains(t, err, "failed to parse metadata", meta)
+	}
+}
+
 // internalTestHostKeyPinning exercises the full PinHostKey host-key flow
 // against the live SFTP server by mirroring the running Fs's connection
 // details into a fresh configmap, then driving NewFs through
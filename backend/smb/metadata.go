@@ -0,0 +1,255 @@
+package smb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	smb2 "github.com/cloudsoda/go-smb2"
+	"github.com/cloudsoda/sddl"
+	"github.com/rclone/rclone/fs"
+)
+
+const metadataTimeFormat = time.RFC3339Nano
+
+// DOS file attributes we use
+const (
+	fileAttributeReadonly = 0x1
+	fileAttributeNormal   = 0x80
+)
+
+// the parts of the security descriptor read and written as metadata
+//
+// The SACL is left out as it needs privileges most users don't have.
+const securityInformation = smb2.OwnerSecurityInformation | smb2.GroupSecurityInformation | smb2.DACLSecurityInformation
+
+// system metadata keys which this backend owns
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"atime": {
+		Help:    "Time of last access",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"mtime": {
+		Help:    "Time of last modification",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"btime": {
+		Help:    "Time of file birth (creation)",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"attributes": {
+		Help:    "DOS file attributes - only the read only bit (1) can be set",
+		Type:    "hexadecimal",
+		Example: "21",
+	},
+	"security-descriptor": {
+		Help:    "Owner, group and access control list in SDDL form",
+		Type:    "string",
+		Example: "O:S-1-5-21-1-2-3-1000G:S-1-5-21-1-2-3-513D:(A;;FA;;;SY)",
+	},
+}
+
+// metadataHelp is the help for the metadata of this backend
+const metadataHelp = `The SMB backend reads the times, DOS attributes and NT security
+descriptor of files and can set them all, so these are preserved when
+syncing between SMB remotes with ` + "`--metadata`" + `.
+
+The security descriptor contains the owner, group and access control
+list but not the audit list (SACL). Setting it needs the WRITE_DAC
+right and setting the owner usually needs administrative rights on
+the server. The SIDs in it are only meaningful on servers in the same
+domain.
+
+Metadata is only supported on files, not directories.
+`
+
+// securityInformationFlags returns which parts of the security
+// descriptor are present in the SDDL string s, limited to those in
+// securityInformation.
+func securityInformationFlags(s string) (flags smb2.SecurityInformationRequestFlags) {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth != 0 || s[i+1] != ':' {
+			continue
+		}
+		switch s[i] {
+		case 'O':
+			flags |= smb2.OwnerSecurityInformation
+		case 'G':
+			flags |= smb2.GroupSecurityInformation
+		case 'D':
+			flags |= smb2.DACLSecurityInformation
+		}
+	}
+	return flags & securityInformation
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	metadata = make(fs.Metadata, len(systemMetadataInfo))
+	if stat, ok := o.statResult.Sys().(*smb2.FileStat); ok {
+		metadata["atime"] = stat.LastAccessTime.Format(metadataTimeFormat)
+		metadata["mtime"] = stat.LastWriteTime.Format(metadataTimeFormat)
+		metadata["btime"] = stat.CreationTime.Format(metadataTimeFormat)
+		metadata["attributes"] = strconv.FormatUint(uint64(stat.FileAttributes), 16)
+	} else {
+		metadata["mtime"] = o.ModTime(ctx).Format(metadataTimeFormat)
+	}
+
+	share, filename := o.split()
+	cn, err := o.fs.getConnection(ctx, share)
+	if err != nil {
+		return nil, err
+	}
+	sd, err := cn.smbShare.SecurityInfo(o.fs.toSambaPath(filename), securityInformation)
+	o.fs.putConnection(&cn, err)
+	if err != nil {
+		// Not being allowed to read the security descriptor
+		// shouldn't stop the rest of the metadata being read
+		fs.Debugf(o, "Failed to read security descriptor: %v", err)
+	} else {
+		metadata["security-descriptor"] = sd.String()
+	}
+	return metadata, nil
+}
+
+// writeMetadata sets the metadata m on the file at filename in share
+//
+// The times and attributes are set together after the security
+// descriptor as the read only bit may stop other changes.
+func (f *Fs) writeMetadata(ctx context.Context, share, filename string, m fs.Metadata) (err error) {
+	var (
+		sd                  *sddl.SecurityDescriptor
+		sdFlags             smb2.SecurityInformationRequestFlags
+		btime, atime, mtime time.Time
+		attributes          uint64
+		hasTimes            bool
+		hasAttributes       bool
+	)
+	if value, ok := m["security-descriptor"]; ok {
+		sd, err = sddl.FromString(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata security-descriptor: %q: %w", value, err)
+		}
+		sdFlags = securityInformationFlags(value)
+	}
+	for _, key := range []string{"btime", "atime", "mtime"} {
+		value, ok := m[key]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(metadataTimeFormat, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata %s: %q: %w", key, value, err)
+		}
+		switch key {
+		case "btime":
+			btime = t
+		case "atime":
+			atime = t
+		default:
+			mtime = t
+		}
+		hasTimes = true
+	}
+	if value, ok := m["attributes"]; ok {
+		attributes, err = strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata attributes: %q: %w", value, err)
+		}
+		hasAttributes = true
+	}
+	if sdFlags == 0 && !hasTimes && !hasAttributes {
+		return nil
+	}
+
+	cn, err := f.getConnection(ctx, share)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.putConnection(&cn, err)
+	}()
+	smbPath := f.toSambaPath(filename)
+	if sdFlags != 0 {
+		err = cn.smbShare.SetSecurityInfo(smbPath, sdFlags, sd)
+		if err != nil {
+			return fmt.Errorf("failed to set security descriptor: %w", err)
+		}
+	}
+	if !hasTimes && !hasAttributes {
+		return nil
+	}
+	// Zero times and attributes are left as they are
+	var attrs uint32
+	if hasAttributes {
+		// Only the read only attribute is changed
+		fi, err := cn.smbShare.Stat(smbPath)
+		if err != nil {
+			return fmt.Errorf("failed to read attributes: %w", err)
+		}
+		if stat, ok := fi.Sys().(*smb2.FileStat); ok {
+			attrs = stat.FileAttributes
+		}
+		attrs = attrs&^fileAttributeReadonly | uint32(attributes)&fileAttributeReadonly
+		if attrs == 0 {
+			attrs = fileAttributeNormal
+		}
+	}
+	err = cn.smbShare.SetBasicInfo(smbPath, btime, atime, mtime, attrs)
+	if err != nil {
+		return fmt.Errorf("failed to set times and attributes: %w", err)
+	}
+	return nil
+}
+
+// SetMetadata sets metadata for an Object
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	share, filename := o.split()
+	if share == "" || filename == "" {
+		return fs.ErrorIsDir
+	}
+	err := o.fs.writeMetadata(ctx, share, filename, metadata)
+	if err != nil {
+		return fmt.Errorf("SetMetadata failed on Object: %w", err)
+	}
+	// Re-read info now we have finished setting stuff
+	return o.stat(ctx)
+}
+
+// stat updates the info in the Object
+func (o *Object) stat(ctx context.Context) (err error) {
+	share, filename := o.split()
+	cn, err := o.fs.getConnection(ctx, share)
+	if err != nil {
+		return err
+	}
+	fi, err := cn.smbShare.Stat(o.fs.toSambaPath(filename))
+	o.fs.putConnection(&cn, err)
+	if err != nil {
+		return fmt.Errorf("stat: %w", translateError(err, false))
+	}
+	o.statResult = fi
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Metadataer    = &Object{}
+	_ fs.SetMetadataer = &Object{}
+)
@@ -41,6 +41,10 @@ func init() {
 		Name:        "smb",
 		Description: "SMB / CIFS",
 		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   metadataHelp,
+		},
 
 		Options: []fs.Option{{
 			Name:      "host",
@@ -208,6 +212,8 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		CanHaveEmptyDirectories: true,
 		BucketBased:             true,
 		PartialUploads:          true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
 	}).Fill(ctx, f)
 
 	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
@@ -406,6 +412,95 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (_ fs.Objec
 	return f.findObjectSeparate(ctx, dstShare, dstPath)
 }
 
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Object, err error) {
+	dstShare, dstPath := f.split(remote)
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	srcShare, srcPath := srcObj.split()
+	if dstShare != srcShare {
+		fs.Debugf(src, "Can't copy - must be on the same share")
+		return nil, fs.ErrorCantCopy
+	}
+
+	err = f.ensureDirectory(ctx, dstShare, dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make parent directories: %w", err)
+	}
+
+	// Fetch metadata if --metadata is in use
+	meta, err := fs.GetMetadataOptions(ctx, f, src, fs.MetadataAsOpenOptions(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("copy: failed to read metadata: %w", err)
+	}
+
+	cn, err := f.getConnection(ctx, dstShare)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.putConnection(&cn, err)
+	}()
+
+	srcFile, err := cn.smbShare.OpenFile(f.toSambaPath(srcPath), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, translateError(err, false)
+	}
+	defer fs.CheckClose(srcFile, &err)
+	dstSambaPath := f.toSambaPath(dstPath)
+	dstFile, err := cn.smbShare.OpenFile(dstSambaPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("copy: failed to open destination: %w", err)
+	}
+	// As both files are open on the same share this copies the
+	// data on the server with FSCTL_SRV_COPYCHUNK. If the server
+	// doesn't support that the data is copied through rclone.
+	_, err = dstFile.ReadFrom(srcFile)
+	closeErr := dstFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		removeErr := cn.smbShare.Remove(dstSambaPath)
+		if removeErr != nil {
+			fs.Debugf(src, "failed to remove after failed copy: %v", removeErr)
+		}
+		return nil, fmt.Errorf("copy failed: %w", err)
+	}
+
+	// Server-side copy doesn't copy the times
+	modTime := src.ModTime(ctx)
+	err = cn.smbShare.Chtimes(dstSambaPath, modTime, modTime)
+	if err != nil {
+		return nil, fmt.Errorf("copy: failed to set modification time: %w", err)
+	}
+
+	// Set metadata if --metadata is in use
+	if meta != nil {
+		err = f.writeMetadata(ctx, dstShare, dstPath, meta)
+		if err != nil {
+			return nil, fmt.Errorf("copy: failed to set metadata: %w", err)
+		}
+	}
+
+	fi, err := cn.smbShare.Stat(dstSambaPath)
+	if err != nil {
+		return nil, translateError(err, false)
+	}
+	return f.makeEntry(dstShare, dstPath, fi), nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server-side move operations.
 //
@@ -847,6 +942,18 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return fmt.Errorf("Update SetModTime failed: %w", err)
 	}
 
+	// Set the metadata if required - this also updates o.statResult
+	meta, err := fs.GetMetadataOptions(ctx, o.fs, src, options)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata from source object: %w", err)
+	}
+	if meta != nil {
+		err = o.SetMetadata(ctx, meta)
+		if err != nil {
+			return fmt.Errorf("Update: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -966,6 +1073,7 @@ var (
 	_ fs.Fs          = &Fs{}
 	_ fs.PutStreamer = &Fs{}
 	_ fs.Mover       = &Fs{}
+	_ fs.Copier      = &Fs{}
 	_ fs.DirMover    = &Fs{}
 	_ fs.Abouter     = &Fs{}
 	_ fs.Shutdowner  = &Fs{}
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	smb2 "github.com/cloudsoda/go-smb2"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest"
@@ -129,3 +130,83 @@ func TestIsPathDir(t *testing.T) {
 		})
 	}
 }
+
+func TestSecurityInformationFlags(t *testing.T) {
+	for _, test := range []struct {
+		sddl string
+		want smb2.SecurityInformationRequestFlags
+	}{
+		{"", 0},
+		{"O:SY", smb2.OwnerSecurityInformation},
+		{"O:SYG:BA", smb2.OwnerSecurityInformation | smb2.GroupSecurityInformation},
+		{"D:(A;;FA;;;SY)", smb2.DACLSecurityInformation},
+		{"O:S-1-5-21-1-2-3-1000G:S-1-5-21-1-2-3-513D:(A;;FA;;;SY)", securityInformation},
+		// The SACL is never set
+		{"S:(AU;SA;FA;;;WD)", 0},
+		// Markers inside ACEs are ignored
+		{"D:(A;;FA;;;S-1-5-21-1-2-3-1000)(OA;;CR;;;O:)", smb2.DACLSecurityInformation},
+	} {
+		assert.Equal(t, test.want, securityInformationFlags(test.sddl), test.sddl)
+	}
+}
+
+func TestCopyMetadata(t *testing.T) {
+	ctx := context.Background()
+	fstest.Initialise()
+	remoteName := *fstest.RemoteName
+	if remoteName == "" {
+		remoteName = "TestSMB:rclone"
+	}
+	remote, err := fs.NewFs(ctx, remoteName)
+	if errors.Is(err, fs.ErrorNotFoundInConfigFile) {
+		t.Skipf("skipping as %q is not configured", remoteName)
+	}
+	require.NoError(t, err)
+	f, ok := remote.(*Fs)
+	if !ok {
+		t.Skipf("skipping as %q is not an SMB remote", remoteName)
+	}
+	defer func() { require.NoError(t, f.Shutdown(ctx)) }()
+
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Metadata = true
+
+	const contents = "server-side copy test"
+	modTime := fstest.Time("2001-02-03T04:05:06Z")
+	atime := fstest.Time("2002-02-03T04:05:06Z")
+	btime := fstest.Time("2000-02-03T04:05:06Z")
+	srcPath := fmt.Sprintf("rclone-test-copy-src-%d.txt", time.Now().UnixNano())
+	dstPath := srcPath + ".copy"
+	src := object.NewStaticObjectInfo(srcPath, modTime, int64(len(contents)), true, nil, nil).WithMetadata(fs.Metadata{
+		"atime": atime.Format(time.RFC3339Nano),
+		"btime": btime.Format(time.RFC3339Nano),
+	})
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, o.Remove(ctx)) }()
+
+	srcMeta, err := o.(*Object).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, atime.Format(time.RFC3339Nano), srcMeta["atime"])
+	assert.Equal(t, modTime.Format(time.RFC3339Nano), srcMeta["mtime"])
+	assert.Equal(t, btime.Format(time.RFC3339Nano), srcMeta["btime"])
+	assert.NotEmpty(t, srcMeta["attributes"])
+
+	dst, err := f.Copy(ctx, o, dstPath)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, dst.Remove(ctx)) }()
+	assert.Equal(t, int64(len(contents)), dst.Size())
+	fstest.AssertTimeEqualWithPrecision(t, dstPath, modTime, dst.ModTime(ctx), f.Precision())
+	in, err := dst.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(got))
+
+	dstMeta, err := dst.(*Object).Metadata(ctx)
+	require.NoError(t, err)
+	for _, key := range []string{"atime", "mtime", "btime", "security-descriptor"} {
+		assert.Equal(t, srcMeta[key], dstMeta[key], key)
+	}
+}
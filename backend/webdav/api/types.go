@@ -22,11 +22,72 @@ const (
 	noZerosRFC1123 = "Mon, _2 Jan 2006 15:04:05 MST"
 )
 
+// MetadataNamespace is the XML namespace of the dead properties
+// rclone stores user metadata in
+const MetadataNamespace = "http://rclone.org/ns/metadata"
+
 // Multistatus contains responses returned from an HTTP 207 return code
 type Multistatus struct {
 	Responses []Response `xml:"response"`
 }
 
+// AllPropMultistatus contains responses to a PROPFIND allprop request
+// keeping all the properties returned
+type AllPropMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				Values []PropValue `xml:",any"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// SyncMultistatus contains the responses to a sync-collection REPORT
+// as defined in RFC 6578
+//
+//	<d:multistatus xmlns:d="DAV:">
+//	 <d:response>
+//	  <d:href>/dav/dir/file.txt</d:href>
+//	  <d:propstat>
+//	   <d:prop><d:resourcetype/></d:prop>
+//	   <d:status>HTTP/1.1 200 OK</d:status>
+//	  </d:propstat>
+//	 </d:response>
+//	 <d:response>
+//	  <d:href>/dav/dir/deleted.txt</d:href>
+//	  <d:status>HTTP/1.1 404 Not Found</d:status>
+//	 </d:response>
+//	 <d:sync-token>http://example.com/ns/sync/1234</d:sync-token>
+//	</d:multistatus>
+type SyncMultistatus struct {
+	Responses []SyncResponse `xml:"response"`
+	SyncToken string         `xml:"sync-token"`
+}
+
+// SyncResponse is a changed or removed member in a SyncMultistatus
+type SyncResponse struct {
+	Href   string `xml:"href"`
+	Status string `xml:"status"` // only set for removed members or a truncated result
+	Props  Prop   `xml:"propstat"`
+}
+
+// StatusCode extracts the status code from a status of the form
+// "HTTP/1.1 200 OK" returning 0 if it couldn't be parsed
+func StatusCode(status string) int {
+	match := parseStatus.FindStringSubmatch(status)
+	if len(match) < 2 {
+		return 0
+	}
+	code, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
 // Response contains an Href the response it about and its properties
 type Response struct {
 	Href  string `xml:"href"`
@@ -88,15 +149,7 @@ func (p *Prop) Code() int {
 	if len(p.Status) == 0 {
 		return -1
 	}
-	match := parseStatus.FindStringSubmatch(p.Status[0])
-	if len(match) < 2 {
-		return 0
-	}
-	code, err := strconv.Atoi(match[1])
-	if err != nil {
-		return 0
-	}
-	return code
+	return StatusCode(p.Status[0])
 }
 
 // Parse a status of the form "HTTP/1.1 2xx"
@@ -0,0 +1,192 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/webdav/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// syncCollectionBody is the body of a sync-collection REPORT
+//
+// The sync-token is empty for the initial request
+const syncCollectionBody = `<?xml version="1.0" encoding="utf-8" ?>
+<d:sync-collection xmlns:d="DAV:">
+ <d:sync-token>%s</d:sync-token>
+ <d:sync-level>infinite</d:sync-level>
+ <d:prop>
+  <d:resourcetype/>
+ </d:prop>
+</d:sync-collection>
+`
+
+// errSyncNotSupported is returned if the server refuses sync-collection
+var errSyncNotSupported = errors.New("server doesn't support sync-collection")
+
+// ChangeNotify calls the passed function with a path that has had
+// changes. If the implementation uses polling, it should adhere to
+// the given interval.
+//
+// Changes are found with RFC 6578 sync-collection REPORTs on the root
+// which need the sync_collection option to be set.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	go func() {
+		// get the sync token early so all changes from now on get processed
+		syncToken, err := f.changeNotifySync(ctx, "", nil)
+		if err != nil {
+			fs.Infof(f, "Failed to get sync token: %v", err)
+		}
+		supported := !errors.Is(err, errSyncNotSupported)
+
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					if ticker != nil {
+						ticker.Stop()
+					}
+					return
+				}
+				if ticker != nil {
+					ticker.Stop()
+					ticker, tickerC = nil, nil
+				}
+				if pollInterval != 0 && supported {
+					ticker = time.NewTicker(pollInterval)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				if syncToken == "" {
+					syncToken, err = f.changeNotifySync(ctx, "", nil)
+					if err != nil {
+						fs.Infof(f, "Failed to get sync token: %v", err)
+					}
+					continue
+				}
+				newSyncToken, err := f.changeNotifySync(ctx, syncToken, notifyFunc)
+				if err != nil {
+					fs.Infof(f, "Change notify listener failure: %v", err)
+					continue
+				}
+				syncToken = newSyncToken
+			}
+		}
+	}()
+}
+
+// changeNotifySync reads the changes since syncToken calling
+// notifyFunc for each one and returns the new sync token.
+//
+// If notifyFunc is nil the changes are discarded, which is used to
+// read the initial sync token.
+func (f *Fs) changeNotifySync(ctx context.Context, syncToken string, notifyFunc func(string, fs.EntryType)) (newSyncToken string, err error) {
+	baseURL, err := rest.URLJoin(f.endpoint, f.dirPath(""))
+	if err != nil {
+		return "", fmt.Errorf("couldn't join URL: %w", err)
+	}
+	for {
+		var token bytes.Buffer
+		err = xml.EscapeText(&token, []byte(syncToken))
+		if err != nil {
+			return "", err
+		}
+		opts := rest.Opts{
+			Method: "REPORT",
+			Path:   f.dirPath(""),
+			ExtraHeaders: map[string]string{
+				"Depth": "0",
+			},
+			Body:         strings.NewReader(fmt.Sprintf(syncCollectionBody, token.String())),
+			AuthRedirect: f.opt.AuthRedirect,
+		}
+		var result api.SyncMultistatus
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallXML(ctx, &opts, nil, &result)
+			return f.shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			var apiErr *api.Error
+			if errors.As(err, &apiErr) {
+				switch apiErr.StatusCode {
+				case http.StatusBadRequest, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusUnsupportedMediaType:
+					if syncToken == "" {
+						return "", fmt.Errorf("%w: %v", errSyncNotSupported, err)
+					}
+					// The token may have expired so start again
+					// and tell the caller everything may have changed
+					fs.Debugf(f, "Sync token rejected, getting a new one: %v", err)
+					if notifyFunc != nil {
+						notifyFunc("", fs.EntryDirectory)
+					}
+					return f.changeNotifySync(ctx, "", nil)
+				}
+			}
+			return "", fmt.Errorf("sync-collection failed: %w", err)
+		}
+		if result.SyncToken == "" {
+			return "", fmt.Errorf("%w: no sync-token returned", errSyncNotSupported)
+		}
+		truncated := false
+		for i := range result.Responses {
+			item := &result.Responses[i]
+			if api.StatusCode(item.Status) == http.StatusInsufficientStorage {
+				// The results were truncated - read some more
+				truncated = true
+				continue
+			}
+			if notifyFunc == nil {
+				continue
+			}
+			remote, ok := f.hrefToRemote(baseURL, item.Href)
+			if !ok {
+				continue
+			}
+			entryType := fs.EntryObject
+			if itemIsDir(&api.Response{Href: item.Href, Props: item.Props}) || strings.HasSuffix(item.Href, "/") {
+				entryType = fs.EntryDirectory
+			}
+			fs.Debugf(f, "Change notify: %v %q changed", entryType, remote)
+			notifyFunc(remote, entryType)
+		}
+		syncToken = result.SyncToken
+		if !truncated {
+			return syncToken, nil
+		}
+	}
+}
+
+// hrefToRemote converts an href returned by the server into a remote
+// path relative to the root returning false if it isn't under it
+func (f *Fs) hrefToRemote(base *url.URL, href string) (remote string, ok bool) {
+	u, err := rest.URLJoin(base, href)
+	if err != nil {
+		fs.Debugf(f, "URL Join failed for %q and %q: %v", base, href, err)
+		return "", false
+	}
+	if !strings.HasPrefix(u.Path, base.Path) {
+		fs.Debugf(f, "Change notify: ignoring item with unknown path %q", u.Path)
+		return "", false
+	}
+	subPath := strings.Trim(u.Path[len(base.Path):], "/")
+	if f.opt.Enc != encoder.EncodeZero {
+		subPath = f.opt.Enc.ToStandardPath(subPath)
+	}
+	if subPath == "" {
+		return "", false
+	}
+	return path.Clean(subPath), true
+}
@@ -0,0 +1,215 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/webdav/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// system metadata keys which this backend owns
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"mtime": {
+		Help:    "Time of last modification",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05Z07:00",
+	},
+	"content-type": {
+		Help:     "MIME type of the object",
+		Type:     "string",
+		Example:  "text/plain",
+		ReadOnly: true,
+	},
+	"etag": {
+		Help:     "ETag of the object",
+		Type:     "string",
+		Example:  `"5e8f4a3c2b1d"`,
+		ReadOnly: true,
+	},
+}
+
+// metadataHelp is the help for the metadata of this backend
+const metadataHelp = `User metadata is stored as WebDAV dead properties in the
+` + "`" + api.MetadataNamespace + "`" + ` namespace using PROPPATCH and read
+back with PROPFIND. The server must support storing arbitrary dead
+properties, which Nextcloud, ownCloud and ` + "`rclone serve webdav`" + `
+(when run with ` + "`--metadata`" + `) do.
+
+Metadata keys must be valid XML names, for example they can't contain
+spaces or start with a digit. Keys which aren't are skipped with a
+log message when writing.
+
+Setting "mtime" only works with vendors which can set modification
+times and with ` + "`rclone serve webdav`" + `. Metadata is only supported on
+files, not directories.
+`
+
+// matches metadata keys which can be used as an XML element name
+var validMetadataKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// allPropsBody is a PROPFIND body asking for all the properties
+var allPropsBody = []byte(`<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:">
+ <d:allprop/>
+</d:propfind>
+`)
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	opts := rest.Opts{
+		Method: "PROPFIND",
+		Path:   o.filePath(),
+		ExtraHeaders: map[string]string{
+			"Depth": "0",
+		},
+		Body:          bytes.NewBuffer(allPropsBody),
+		CheckRedirect: rest.PreserveMethodRedirectFn,
+	}
+	var result api.AllPropMultistatus
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.CallXML(ctx, &opts, nil, &result)
+		return o.fs.shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if apiErr, ok := err.(*api.Error); ok && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, fmt.Errorf("read metadata failed: %w", err)
+	}
+	metadata = make(fs.Metadata)
+	for _, response := range result.Responses {
+		for _, propstat := range response.Propstat {
+			if code := api.StatusCode(propstat.Status); code < 200 || code > 299 {
+				continue
+			}
+			for _, value := range propstat.Prop.Values {
+				switch {
+				case value.XMLName.Space == api.MetadataNamespace:
+					metadata[value.XMLName.Local] = value.Value
+				case value.XMLName.Space == "DAV:" && value.XMLName.Local == "getcontenttype":
+					metadata["content-type"] = value.Value
+				case value.XMLName.Space == "DAV:" && value.XMLName.Local == "getetag":
+					metadata["etag"] = value.Value
+				}
+			}
+		}
+	}
+	metadata["mtime"] = o.ModTime(ctx).Format(time.RFC3339Nano)
+	return metadata, nil
+}
+
+// writeMetadata stores the metadata in m as dead properties on the
+// object with PROPPATCH. The read only system metadata is ignored.
+//
+// mtime is stored too as servers like rclone serve webdav use it to
+// set the modification time.
+func (o *Object) writeMetadata(ctx context.Context, m fs.Metadata) (err error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if systemMetadataInfo[key].ReadOnly {
+			continue
+		}
+		if !validMetadataKey.MatchString(key) {
+			fs.Logf(o, "Not storing metadata key %q as it isn't a valid XML name", key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8" ?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:r="` + api.MetadataNamespace + `">
+ <d:set>
+  <d:prop>
+`)
+	for _, key := range keys {
+		body.WriteString("   <r:" + key + ">")
+		err = xml.EscapeText(&body, []byte(m[key]))
+		if err != nil {
+			return err
+		}
+		body.WriteString("</r:" + key + ">\n")
+	}
+	body.WriteString(`  </d:prop>
+ </d:set>
+</d:propertyupdate>
+`)
+
+	opts := rest.Opts{
+		Method:     "PROPPATCH",
+		Path:       o.filePath(),
+		NoRedirect: true,
+		Body:       &body,
+	}
+	var result api.Multistatus
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.CallXML(ctx, &opts, nil, &result)
+		return o.fs.shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if apiErr, ok := err.(*api.Error); ok && apiErr.StatusCode == http.StatusNotFound {
+			return fs.ErrorObjectNotFound
+		}
+		return fmt.Errorf("couldn't set metadata: %w", err)
+	}
+	for _, response := range result.Responses {
+		for _, status := range response.Props.Status {
+			if code := api.StatusCode(status); code < 200 || code > 299 {
+				return fmt.Errorf("couldn't set metadata: %s", strings.TrimSpace(status))
+			}
+		}
+	}
+	return nil
+}
+
+// SetMetadata sets metadata for an Object
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	err := o.writeMetadata(ctx, metadata)
+	if err != nil {
+		return fmt.Errorf("SetMetadata failed on Object: %w", err)
+	}
+	if value, ok := metadata["mtime"]; ok {
+		modTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("SetMetadata: failed to parse metadata mtime: %q: %w", value, err)
+		}
+		err = o.SetModTime(ctx, modTime)
+		if err == fs.ErrorCantSetModTime {
+			// The server may have set it from the mtime property
+			// so read it back
+			o.hasMetaData = false
+			err = o.readMetaData(ctx)
+			if err != nil {
+				return fmt.Errorf("SetMetadata failed to read mtime: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("SetMetadata failed to set mtime: %w", err)
+		}
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Metadataer    = (*Object)(nil)
+	_ fs.SetMetadataer = (*Object)(nil)
+)
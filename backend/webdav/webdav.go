@@ -187,7 +187,23 @@ which rclone otherwise refuses to do.
 `,
 				Advanced: true,
 				Default:  false,
+			}, {
+				Name: "sync_collection",
+				Help: `Use sync-collection reports to notice changes.
+
+If set rclone uses RFC 6578 sync-collection REPORT requests on the
+root to find changes made on the server, so mounts and other users of
+--poll-interval notice them.
+
+The server must support sync-collection with infinite depth. If it
+doesn't rclone logs a message and won't notice changes.`,
+				Advanced: true,
+				Default:  false,
 			}},
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   metadataHelp,
+		},
 	})
 }
 
@@ -207,6 +223,7 @@ type Options struct {
 	ExcludeMounts      bool                 `config:"owncloud_exclude_mounts"`
 	UnixSocket         string               `config:"unix_socket"`
 	AuthRedirect       bool                 `config:"auth_redirect"`
+	SyncCollection     bool                 `config:"sync_collection"`
 }
 
 // Fs represents a remote webdav
@@ -521,7 +538,13 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
 	}).Fill(ctx, f)
+	if !opt.SyncCollection {
+		f.features.ChangeNotify = nil
+	}
 	if opt.User != "" || opt.Pass != "" {
 		f.srv.SetUserPass(opt.User, opt.Pass)
 	} else if opt.BearerToken != "" {
@@ -1176,6 +1199,11 @@ func (f *Fs) copyOrMove(ctx context.Context, src fs.Object, remote string, metho
 	if err != nil {
 		return nil, fmt.Errorf("copyOrMove couldn't join URL: %w", err)
 	}
+	// Fetch metadata if --metadata is in use
+	meta, err := fs.GetMetadataOptions(ctx, f, src, fs.MetadataAsOpenOptions(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("copyOrMove failed to read metadata: %w", err)
+	}
 	var resp *http.Response
 	opts := rest.Opts{
 		Method:     method,
@@ -1208,6 +1236,12 @@ func (f *Fs) copyOrMove(ctx context.Context, src fs.Object, remote string, metho
 			return nil, fmt.Errorf("failed to set modtime: %w", err)
 		}
 	}
+	if meta != nil {
+		err = dstObj.(*Object).SetMetadata(ctx, meta)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return dstObj, nil
 }
 
@@ -1606,6 +1640,19 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 			return fmt.Errorf("unchunked simple update failed: %w", err)
 		}
 	}
+
+	// Set the metadata if required
+	meta, err := fs.GetMetadataOptions(ctx, o.fs, src, options)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata from source object: %w", err)
+	}
+	if meta != nil {
+		err = o.writeMetadata(ctx, meta)
+		if err != nil {
+			return fmt.Errorf("failed to set metadata: %w", err)
+		}
+	}
+
 	// read metadata from remote
 	o.hasMetaData = false
 	return o.readMetaData(ctx)
@@ -1687,13 +1734,14 @@ func (o *Object) Remove(ctx context.Context) error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = (*Fs)(nil)
-	_ fs.Purger      = (*Fs)(nil)
-	_ fs.PutStreamer = (*Fs)(nil)
-	_ fs.Copier      = (*Fs)(nil)
-	_ fs.Mover       = (*Fs)(nil)
-	_ fs.DirMover    = (*Fs)(nil)
-	_ fs.ListPer     = (*Fs)(nil)
-	_ fs.Abouter     = (*Fs)(nil)
-	_ fs.Object      = (*Object)(nil)
+	_ fs.Fs             = (*Fs)(nil)
+	_ fs.Purger         = (*Fs)(nil)
+	_ fs.PutStreamer    = (*Fs)(nil)
+	_ fs.Copier         = (*Fs)(nil)
+	_ fs.Mover          = (*Fs)(nil)
+	_ fs.DirMover       = (*Fs)(nil)
+	_ fs.ListPer        = (*Fs)(nil)
+	_ fs.Abouter        = (*Fs)(nil)
+	_ fs.ChangeNotifier = (*Fs)(nil)
+	_ fs.Object         = (*Object)(nil)
 )
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/backend/webdav"
@@ -16,9 +17,11 @@ import (
 	"github.com/rclone/rclone/fs/config/configfile"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	xwebdav "golang.org/x/net/webdav"
 )
 
 var (
@@ -284,3 +287,128 @@ func TestCopyFallsBackWhenRangeIgnored(t *testing.T) {
 	assert.LessOrEqual(t, rangeRequests.Load(), int32(3))
 	assert.Equal(t, int32(1), fullRequests.Load())
 }
+
+func TestMetadata(t *testing.T) {
+	ctx := context.Background()
+	// The x/net/webdav memory file system stores dead properties
+	ts := httptest.NewServer(&xwebdav.Handler{
+		FileSystem: xwebdav.NewMemFS(),
+		LockSystem: xwebdav.NewMemLS(),
+	})
+	defer ts.Close()
+
+	configfile.Install()
+	f, err := webdav.NewFs(ctx, remoteName, "", configmap.Simple{
+		"type": "webdav",
+		"url":  ts.URL,
+	})
+	require.NoError(t, err)
+	assert.True(t, f.Features().UserMetadata)
+
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Metadata = true
+	contents := "hello"
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src, fs.MetadataOption{"potato": "jersey <royal> & co", "bad key": "ignored"})
+	require.NoError(t, err)
+
+	m, err := o.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "jersey <royal> & co", m["potato"])
+	assert.NotContains(t, m, "bad key")
+	assert.NotEmpty(t, m["mtime"])
+	assert.NotEmpty(t, m["etag"])
+
+	err = o.(fs.SetMetadataer).SetMetadata(ctx, fs.Metadata{"potato": "maris piper", "colour": "white"})
+	require.NoError(t, err)
+	m, err = o.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "maris piper", m["potato"])
+	assert.Equal(t, "white", m["colour"])
+}
+
+// syncResponse is the body of a fake sync-collection response
+const syncResponse = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:">%s
+ <d:sync-token>%s</d:sync-token>
+</d:multistatus>`
+
+func TestChangeNotify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var reports atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" {
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "<d:sync-collection")
+		reports.Add(1)
+		w.WriteHeader(http.StatusMultiStatus)
+		switch {
+		case strings.Contains(string(body), "<d:sync-token></d:sync-token>"):
+			// Initial request returns everything which should be ignored
+			_, _ = fmt.Fprintf(w, syncResponse, `
+ <d:response><d:href>/dav/old.txt</d:href><d:propstat><d:prop><d:resourcetype/></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, "token1")
+		case strings.Contains(string(body), "token1"):
+			// This result is truncated
+			_, _ = fmt.Fprintf(w, syncResponse, `
+ <d:response><d:href>/dav/dir/new%20file.txt</d:href><d:propstat><d:prop><d:resourcetype/></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+ <d:response><d:href>/dav/</d:href><d:status>HTTP/1.1 507 Insufficient Storage</d:status></d:response>`, "token2")
+		case strings.Contains(string(body), "token2"):
+			_, _ = fmt.Fprintf(w, syncResponse, `
+ <d:response><d:href>/dav/subdir/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+ <d:response><d:href>/dav/old.txt</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, "token3")
+		default:
+			_, _ = fmt.Fprintf(w, syncResponse, "", "token3")
+		}
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	configfile.Install()
+	f, err := webdav.NewFs(ctx, remoteName, "", configmap.Simple{
+		"type":            "webdav",
+		"url":             ts.URL + "/dav/",
+		"sync_collection": "true",
+	})
+	require.NoError(t, err)
+	doChangeNotify := f.Features().ChangeNotify
+	require.NotNil(t, doChangeNotify)
+
+	type change struct {
+		path      string
+		entryType fs.EntryType
+	}
+	changes := make(chan change, 10)
+	pollInterval := make(chan time.Duration)
+	doChangeNotify(ctx, func(path string, entryType fs.EntryType) {
+		changes <- change{path, entryType}
+	}, pollInterval)
+	pollInterval <- 10 * time.Millisecond
+
+	var got []change
+	for len(got) < 3 {
+		select {
+		case c := <-changes:
+			got = append(got, c)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for changes, got %v", got)
+		}
+	}
+	close(pollInterval)
+	assert.Equal(t, []change{
+		{"dir/new file.txt", fs.EntryObject},
+		{"subdir", fs.EntryDirectory},
+		{"old.txt", fs.EntryObject},
+	}, got)
+	assert.GreaterOrEqual(t, reports.Load(), int32(3))
+}
+
+func TestChangeNotifyDisabled(t *testing.T) {
+	f, tidy := prepare(t)
+	defer tidy()
+	assert.Nil(t, f.Features().ChangeNotify)
+}
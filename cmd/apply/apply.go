@@ -0,0 +1,90 @@
+// Package apply provides the apply command.
+package apply
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyOpt = sync.ApplyOpt{}
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &applyOpt.SkipChanged, "skip-changed", "", applyOpt.SkipChanged, "Skip actions whose files have changed since the plan was made instead of failing", "")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "apply plan.json [source:path dest:path]",
+	Short: `Carry out the actions in a sync plan made with --plan-out.`,
+	// Warning! "|" will be replaced by backticks below
+	Long: strings.ReplaceAll(`Carries out exactly the actions in a plan written by |rclone sync|,
+|copy| or |move| with the |--plan-out| flag, so the plan can be
+reviewed before anything is changed.
+
+|||sh
+rclone sync --plan-out plan.json source:path dest:path
+# review plan.json
+rclone apply plan.json
+|||
+
+The plan records the source and destination, and for each action the
+size, modification time and (where cheap to read, or if |--checksum|
+was used) the hash of the files it was based on.
+
+The source and destination are recorded without any connection string
+parameters as these might contain credentials. If the plan was made
+with remotes like |:s3,access_key_id=XXX:bucket| or |remote,param=value:path|,
+give the source and destination after the plan.
+
+|||sh
+rclone apply plan.json source:path dest:path
+|||
+
+Before doing anything, rclone checks every action in the plan is still
+valid. The files to be copied, moved, renamed or deleted must be
+unchanged, and files which didn't exist on the destination must still
+not exist. If any of these checks fail, nothing is done and the
+command returns an error. Use |--skip-changed| to skip those actions
+and carry out the rest.
+
+The actions are carried out in this order: renames, making
+directories, copies and moves, updating modification times, deleting
+files then removing empty directories. Files aren't deleted from the destination if there were
+errors earlier unless |--ignore-errors| is set.
+
+Use |--dry-run| to check the plan can still be applied without
+changing anything.
+`, "|", "`"),
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.76",
+		"groups":            "Sync,Copy,Important",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 3, command, args)
+		if len(args) == 2 {
+			fs.Fatalf(nil, "Give both the source and the destination or neither")
+		}
+		plan, err := sync.ReadPlan(args[0])
+		if err != nil {
+			fs.Fatalf(nil, "%v", err)
+		}
+		src, dst := plan.Src, plan.Dst
+		if len(args) == 3 {
+			src, dst = args[1], args[2]
+		}
+		fsrc := cmd.NewFsDir([]string{src})
+		fdst := cmd.NewFsDir([]string{dst})
+		cmd.Run(false, true, command, func() error {
+			return sync.ApplyPlan(context.Background(), fdst, fsrc, plan, applyOpt)
+		})
+	},
+}
@@ -107,6 +107,9 @@ func altName(name string, src, dst fs.DirEntry) string {
 
 // WriteResults is Bisync's LoggerFn
 func (b *bisyncRun) WriteResults(ctx context.Context, sigil operations.Sigil, src, dst fs.DirEntry, err error) {
+	if sigil == operations.Verified {
+		return // the transfer has already been recorded
+	}
 	b.queueOpt.lock.Lock()
 	defer b.queueOpt.lock.Unlock()
 
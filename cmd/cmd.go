@@ -33,6 +33,7 @@ import (
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fs/rc/rcserver"
 	fssync "github.com/rclone/rclone/fs/sync"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/buildinfo"
 	"github.com/rclone/rclone/lib/exitcode"
@@ -421,6 +422,18 @@ func initConfig() {
 		fs.Debugf("rclone", "systemd logging support activated")
 	}
 
+	// Start tracing if configured
+	err = tracing.Init(ctx, &tracing.Opt)
+	if err != nil {
+		fs.Fatalf(nil, "Failed to start tracing: %v", err)
+	}
+	atexit.Register(func() {
+		err := tracing.Shutdown(ctx)
+		if err != nil {
+			fs.Errorf(nil, "%v", err)
+		}
+	})
+
 	// Start the remote control server if configured
 	_, err = rcserver.Start(ctx, &rc.Opt)
 	if err != nil {
@@ -39,7 +39,9 @@ names.
 However if ` + "`--by-hash`" + ` is passed in then dedupe will find files with
 duplicate hashes instead which will work on any backend which supports
 at least one hash. This can be used to find files with duplicate
-content. This is known as deduping by hash.
+content. This is known as deduping by hash. To find files with
+duplicate content across several remotes or paths use
+` + "`rclone dupes`" + ` instead.
 
 If deduping by name, first rclone will merge directories with the same
 name.  It will do this iteratively until all the identically named
@@ -0,0 +1,96 @@
+// Package dupes provides the dupes command.
+package dupes
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dupesOpt = operations.DupesOpt{
+		Keep: operations.DeduplicateFirst,
+	}
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.FVarP(cmdFlags, &dupesOpt.Mode, "dupes-mode", "", "What to do with duplicates: "+dupesOpt.Mode.Help(), "")
+	flags.FVarP(cmdFlags, &dupesOpt.Keep, "dupes-keep", "", "Which duplicate to keep first|newest|oldest|largest|smallest", "")
+	flags.BoolVarP(cmdFlags, &dupesOpt.Download, "download", "", dupesOpt.Download, "Download every file to hash it rather than asking the remote", "")
+	flags.BoolVarP(cmdFlags, &dupesOpt.JSON, "json", "", dupesOpt.JSON, "List the duplicates as JSON", "")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "dupes remote:path [remote:path]...",
+	Short: `Find files with identical content across remotes.`,
+	// Warning! "|" will be replaced by backticks below
+	Long: strings.ReplaceAll(`Finds files with identical content in one or more remotes and paths,
+whatever their names, then lists them, deletes all but one of each
+group of duplicates or replaces the others with links to it.
+
+Files are grouped by size first, so only files which share a size are
+hashed. Each group of files of the same size is compared using the
+hash supported by most of the remotes involved. Files on remotes
+which don't support that hash, or which can't supply it for a
+particular file, are downloaded and hashed locally. Use |--download|
+to download and hash every file. Wrapping a remote with the |hasher|
+backend avoids downloading files more than once.
+
+Empty files are ignored.
+
+|||sh
+rclone dupes drive:projects s3:archive/projects /mnt/backup
+|||
+
+What is done with the duplicates depends on |--dupes-mode|:
+
+- |list| - list the duplicates and change nothing (the default).
+  Use |--json| for output in JSON.
+- |delete| - delete all but one of each group.
+- |link| - replace all but one of each group with a link to the one
+  kept. This uses hard links on the local backend and shortcuts on
+  Google Drive, and only works for duplicates in the same remote and
+  path.
+
+Which file of each group is kept is chosen by |--dupes-keep|:
+
+- |first| - keep the file in the first remote given, then the
+  first by path (the default).
+- |newest| - keep the newest file.
+- |oldest| - keep the oldest file.
+- |largest| - keep the largest file.
+- |smallest| - keep the smallest file.
+
+These choose the file to keep in the same way as the modes of the
+same name in |rclone dedupe|. As the files in each group have the
+same size, |largest| and |smallest| keep the first file.
+
+The file to keep is listed first in each group, and in JSON output it
+has |"keep": true|.
+
+**Important**: Since this can cause data loss, test first with the
+|--dry-run| or the |--interactive|/|-i| flag.
+`, "|", "`"),
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.76",
+		"groups":            "Important",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 256, command, args)
+		var fses []fs.Fs
+		for _, arg := range args {
+			fses = append(fses, cmd.NewFsDir([]string{arg}))
+		}
+		cmd.Run(false, false, command, func() error {
+			return operations.Dupes(context.Background(), fses, dupesOpt, os.Stdout)
+		})
+	},
+}
@@ -0,0 +1,163 @@
+// Package lifecycle provides the lifecycle command.
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jsonOutput = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "Output the report as JSON", "")
+}
+
+// rulesFile is the format of the rules file
+type rulesFile struct {
+	Rules []rc.Params `json:"rules"`
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "lifecycle rules.json remote:path",
+	Short: `Apply storage tiering and archiving rules to objects in remote.`,
+	Long: `Applies the lifecycle rules in a JSON file to every object in the
+remote. Rules can change the storage tier of objects, move them to
+another remote or delete them.
+
+The rules file looks like this
+
+` + "```json" + `
+{
+  "rules": [
+    {
+      "name": "archive-projects",
+      "include": ["/projects/**"],
+      "min_age": "1y",
+      "action": "move",
+      "dst": "archive:projects",
+      "stub": true
+    },
+    {
+      "name": "cold",
+      "min_age": "90d",
+      "action": "settier",
+      "tier": "GLACIER"
+    }
+  ]
+}
+` + "```" + `
+
+Each rule has these keys
+
+- ` + "`name`" + ` - name of the rule shown in the report
+- ` + "`action`" + ` - one of ` + "`settier`, `move` or `delete`" + `
+- ` + "`tier`" + ` - the storage tier to set with ` + "`settier`" + `
+- ` + "`dst`" + ` - the remote to move objects to with ` + "`move`" + `
+- ` + "`stub`" + ` - set to leave a stub in place of objects moved with ` + "`move`" + `
+
+Which objects a rule applies to is chosen with the same options as the
+[filtering](/filtering/) flags using their config names, for example
+` + "`min_age`, `max_age`, `min_size`, `max_size`, `include`, `exclude`" + `
+and ` + "`filter`" + `. These are combined with any filtering flags given
+on the command line.
+
+Each object is acted on by the first rule which matches it, so put the
+most specific rules first.
+
+Objects are moved with server-side moves where possible, keeping their
+path relative to ` + "`dst`" + `. If ` + "`stub`" + ` is set a small
+text object called the original name plus ` + "`" + operations.LifecycleStubSuffix + "`" + `
+is left behind saying where the object went. Stubs are ignored by the
+rules.
+
+Objects already in the requested tier are skipped by ` + "`settier`" + `
+so it is cheap to run the same rules regularly.
+
+**Important**: Since this can cause data loss, test first with the
+` + "`--dry-run` or the `--interactive`/`-i`" + ` flag. With
+` + "`--dry-run`" + ` the report shows what would be done.
+
+Use ` + "`--json`" + ` to output the report as JSON. The same rules can
+be applied with the [operations/lifecycle](/rc/#operations-lifecycle)
+rc command.`,
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.76",
+		"groups":            "Filter,Listing,Important",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		rulesPath := args[0]
+		fsrc := cmd.NewFsSrc(args[1:])
+		cmd.Run(false, true, command, func() error {
+			rules, err := readRules(rulesPath)
+			if err != nil {
+				return err
+			}
+			report, err := operations.Lifecycle(context.Background(), fsrc, rules)
+			if report != nil {
+				if outErr := printReport(report); outErr != nil {
+					return outErr
+				}
+			}
+			return err
+		})
+	},
+}
+
+// readRules reads the lifecycle rules from path
+func readRules(path string) ([]*operations.LifecycleRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	var in rulesFile
+	err = json.Unmarshal(data, &in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules %q: %w", path, err)
+	}
+	return operations.ParseLifecycleRules(in.Rules)
+}
+
+// printReport writes the report to stdout
+func printReport(report *operations.LifecycleReport) error {
+	if jsonOutput {
+		out, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			return err
+		}
+		operations.SyncPrintf("%s\n", out)
+		return nil
+	}
+	verb := "Did"
+	if report.DryRun {
+		verb = "Would do"
+	}
+	for _, a := range report.Actions {
+		detail := a.Dst
+		if a.Action == operations.LifecycleSetTier {
+			detail = a.Tier
+		}
+		if detail != "" {
+			detail = " to " + detail
+		}
+		status := ""
+		if a.Error != "" {
+			status = " FAILED: " + a.Error
+		}
+		operations.SyncPrintf("%s %s %q%s (rule %q)%s\n", verb, a.Action, a.Remote, detail, a.Rule, status)
+	}
+	operations.SyncPrintf("Checked %d objects, %d actions, %d errors\n", report.Checked, len(report.Actions), report.Errors)
+	return nil
+}
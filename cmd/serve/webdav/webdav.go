@@ -2,14 +2,18 @@
 package webdav
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"mime"
 	"net"
 	"net/http"
 	"os"
 	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +27,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/rc"
 	libhttp "github.com/rclone/rclone/lib/http"
 	"github.com/rclone/rclone/lib/http/serve"
@@ -47,6 +52,10 @@ var OptionsInfo = fs.Options{{
 	Name:    "disable_zip",
 	Default: false,
 	Help:    "Disable zip download of directories",
+}, {
+	Name:    "allow_system_metadata",
+	Default: false,
+	Help:    "Allow clients to set system metadata such as mode, uid and gid",
 }}.
 	Add(libhttp.ConfigInfo).
 	Add(libhttp.AuthConfigInfo).
@@ -54,12 +63,13 @@ var OptionsInfo = fs.Options{{
 
 // Options required for http server
 type Options struct {
-	Auth           libhttp.AuthConfig
-	HTTP           libhttp.Config
-	Template       libhttp.TemplateConfig
-	EtagHash       string `config:"etag_hash"`
-	DisableDirList bool   `config:"disable_dir_list"`
-	DisableZip     bool   `config:"disable_zip"`
+	Auth                libhttp.AuthConfig
+	HTTP                libhttp.Config
+	Template            libhttp.TemplateConfig
+	EtagHash            string `config:"etag_hash"`
+	DisableDirList      bool   `config:"disable_dir_list"`
+	DisableZip          bool   `config:"disable_zip"`
+	AllowSystemMetadata bool   `config:"allow_system_metadata"`
 }
 
 // Opt is options set by command line flags
@@ -180,6 +190,23 @@ rclone --webdav-unix-socket /tmp/my.socket --webdav-url http://localhost lsf :we
 Note that there is no authentication on http protocol - this is expected to be
 done by the permissions on the socket.
 
+### Metadata
+
+If ` + "`--metadata`" + ` is set the metadata of files is served as dead
+properties in the ` + "`http://rclone.org/ns/metadata`" + ` namespace and
+setting properties in that namespace with PROPPATCH sets the metadata.
+This is what the webdav backend uses, so metadata round trips through
+this server.
+
+Metadata is set through the VFS, so it can't be set with ` + "`--read-only`" + `,
+and it is set once a file has been uploaded if it is still being
+written. Clients can't set the system metadata of the backend being
+served, such as the ` + "`mode`" + `, ` + "`uid`" + ` and ` + "`gid`" + ` metadata which change
+the permissions and ownership of files, unless
+` + "`--allow-system-metadata`" + ` is set. The ` + "`mtime`" + ` and ` + "`content-type`" + `
+metadata can always be set as clients can set those when uploading
+anyway.
+
 ### Symlinks / Junction points
 
 The webdav protocol does not support symlinks or junction points and
@@ -647,16 +674,103 @@ func (h Handle) DeadProps() (map[xml.Name]webdav.Property, error) {
 	property.InnerXML = strconv.AppendInt(nil, h.Handle.Node().ModTime().Unix(), 10)
 	properties[xmlName] = property
 
+	if fs.GetConfig(h.ctx).Metadata {
+		h.metadataProps(properties)
+	}
+
 	return properties, nil
 }
 
+// metadataNamespace is the XML namespace metadata is served in - this
+// must match the one the webdav backend uses
+const metadataNamespace = "http://rclone.org/ns/metadata"
+
+// matches metadata keys which can be used as an XML element name
+var validMetadataKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// systemMetadataKeys returns the metadata keys of f which can only be
+// set with --allow-system-metadata.
+//
+// These are the system metadata keys the backend can write, as these
+// change things like the ownership and permissions of the file, apart
+// from mtime and content-type which clients can set when uploading.
+func systemMetadataKeys(f fs.Fs) (keys []string) {
+	info := operations.GetFsInfo(f).MetadataInfo
+	if info == nil {
+		return nil
+	}
+	for key, help := range info.System {
+		if !help.ReadOnly && key != "mtime" && key != "content-type" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// metadataProps adds the metadata of the handle's object to properties
+func (h Handle) metadataProps(properties map[xml.Name]webdav.Property) {
+	o, ok := h.Handle.Node().DirEntry().(fs.Object)
+	if !ok {
+		return
+	}
+	metadata, err := fs.GetMetadata(h.ctx, o)
+	if err != nil {
+		fs.Errorf(o, "failed to read metadata: %v", err)
+		return
+	}
+	for key, value := range metadata {
+		if !validMetadataKey.MatchString(key) {
+			continue
+		}
+		xmlName := xml.Name{Space: metadataNamespace, Local: key}
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(value))
+		properties[xmlName] = webdav.Property{
+			XMLName:  xmlName,
+			InnerXML: buf.Bytes(),
+		}
+	}
+}
+
+// setMetadata sets the metadata from the properties in the metadata
+// namespace on the handle's file
+func (h Handle) setMetadata(props []webdav.Property) error {
+	file, ok := h.Handle.Node().(*vfs.File)
+	if !ok {
+		return fs.ErrorNotAFile
+	}
+	var systemKeys []string
+	if !h.w.opt.AllowSystemMetadata {
+		systemKeys = systemMetadataKeys(file.Fs())
+	}
+	metadata := make(fs.Metadata, len(props))
+	for _, prop := range props {
+		key := prop.XMLName.Local
+		if slices.Contains(systemKeys, key) {
+			return fmt.Errorf("setting metadata %q needs --allow-system-metadata", key)
+		}
+		// Decode the text of the property
+		var value string
+		err := xml.Unmarshal(append(append([]byte("<v>"), prop.InnerXML...), "</v>"...), &value)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata %q: %w", key, err)
+		}
+		metadata[key] = value
+	}
+	return file.SetMetadata(metadata)
+}
+
 // Patch changes modtime of the underlying resources, it returns ok for all properties, the error is from setModtime if any
+//
+// Properties in the metadata namespace are set as metadata on the
+// object if --metadata is in use.
 // FIXME does not check for invalid property and SetModTime error
 func (h Handle) Patch(proppatches []webdav.Proppatch) ([]webdav.Propstat, error) {
 	var (
 		stat webdav.Propstat
 		err  error
 	)
+	var metadataProps []webdav.Property
 	stat.Status = http.StatusOK
 	for _, patch := range proppatches {
 		for _, prop := range patch.Props {
@@ -668,6 +782,21 @@ func (h Handle) Patch(proppatches []webdav.Proppatch) ([]webdav.Propstat, error)
 					err = h.Handle.Node().SetModTime(time.Unix(modtimeUnix, 0))
 				}
 			}
+			if prop.XMLName.Space == metadataNamespace && !patch.Remove {
+				metadataProps = append(metadataProps, prop)
+			}
+		}
+	}
+	if len(metadataProps) > 0 {
+		var metadataErr error
+		if fs.GetConfig(h.ctx).Metadata {
+			metadataErr = h.setMetadata(metadataProps)
+		} else {
+			metadataErr = errors.New("metadata is disabled - use --metadata")
+		}
+		if metadataErr != nil {
+			fs.Errorf(h.Handle.Node(), "failed to set metadata: %v", metadataErr)
+			stat.Status = http.StatusForbidden
 		}
 	}
 	return []webdav.Propstat{stat}, err
@@ -10,6 +10,7 @@ package webdav
 import (
 	"compress/gzip"
 	"context"
+	"encoding/xml"
 	"flag"
 	"io"
 	"net/http"
@@ -48,6 +49,12 @@ var (
 // TestWebDav runs the webdav server then runs the unit tests for the
 // webdav remote against it.
 func TestWebDav(t *testing.T) {
+	// Serve metadata so it round trips through the webdav remote
+	ci := fs.GetConfig(context.Background())
+	oldMetadata := ci.Metadata
+	ci.Metadata = true
+	defer func() { ci.Metadata = oldMetadata }()
+
 	// Configure and start the server
 	start := func(f fs.Fs) (configmap.Simple, func()) {
 		opt := Opt
@@ -57,6 +64,7 @@ func TestWebDav(t *testing.T) {
 		opt.Auth.BasicPass = testPass
 		opt.Template.Path = testTemplate
 		opt.EtagHash = "MD5"
+		opt.AllowSystemMetadata = true
 
 		// Start the server
 		w, err := newWebDAV(context.Background(), f, &opt, &vfscommon.Opt, &proxy.Opt)
@@ -362,6 +370,14 @@ func startWritableServer(t *testing.T) string {
 	f, err := fs.NewFs(context.Background(), t.TempDir())
 	require.NoError(t, err)
 
+	return startWritableServerFs(t, f)
+}
+
+// startWritableServerFs starts a writable server serving f and returns
+// its URL
+func startWritableServerFs(t *testing.T, f fs.Fs) string {
+	t.Helper()
+
 	opt := Opt
 	opt.HTTP.ListenAddr = []string{testBindAddress}
 
@@ -438,3 +454,89 @@ func TestMoveOverwriteFalseStillRejects(t *testing.T) {
 	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode,
 		"MOVE with explicit Overwrite: F must still return 412 when destination exists")
 }
+
+// proppatch sets the metadata key to value on path returning the
+// status of the property
+func proppatch(t *testing.T, baseURL, path, key, value string) string {
+	t.Helper()
+	body := `<?xml version="1.0"?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:m="` + metadataNamespace + `">
+<d:set><d:prop><m:` + key + `>` + value + `</m:` + key + `></d:prop></d:set>
+</d:propertyupdate>`
+	req, err := http.NewRequest("PROPPATCH", baseURL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	var result struct {
+		Status string `xml:"response>propstat>status"`
+	}
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&result))
+	return result.Status
+}
+
+func TestPatchMetadata(t *testing.T) {
+	ci := fs.GetConfig(context.Background())
+	oldMetadata := ci.Metadata
+	ci.Metadata = true
+	defer func() { ci.Metadata = oldMetadata }()
+
+	testURL := startWritableServer(t)
+	req, err := http.NewRequest("PUT", testURL+"file.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// System metadata which changes ownership or permissions is refused
+	for _, key := range []string{"mode", "uid", "gid", "atime"} {
+		assert.Contains(t, proppatch(t, testURL, "file.txt", key, "0"), "403", key)
+	}
+
+	// But the modification time is set
+	assert.Contains(t, proppatch(t, testURL, "file.txt", "mtime", "2001-02-03T04:05:06Z"), "200")
+}
+
+func TestPatchMetadataBackendSystemKeys(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	oldMetadata := ci.Metadata
+	ci.Metadata = true
+	defer func() { ci.Metadata = oldMetadata }()
+
+	// Register a copy of the local backend with extra system metadata
+	localInfo, err := fs.Find("local")
+	require.NoError(t, err)
+	info := *localInfo
+	info.Name = "webdavmetadatatest"
+	info.Prefix = info.Name
+	info.MetadataInfo = &fs.MetadataInfo{
+		System: map[string]fs.MetadataHelp{
+			"owner":   {Help: "Owner of the file"},
+			"version": {Help: "Version of the file", ReadOnly: true},
+			"mtime":   {Help: "Time of last modification"},
+		},
+	}
+	oldRegistry := fs.Registry
+	fs.Registry = append(fs.Registry, &info)
+	defer func() { fs.Registry = oldRegistry }()
+
+	f, err := fs.NewFs(ctx, ":webdavmetadatatest:"+t.TempDir())
+	require.NoError(t, err)
+	testURL := startWritableServerFs(t, f)
+	req, err := http.NewRequest("PUT", testURL+"file.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// The writable system metadata of the backend is refused
+	assert.Contains(t, proppatch(t, testURL, "file.txt", "owner", "potato"), "403")
+
+	// But keys the backend doesn't own are set
+	assert.Contains(t, proppatch(t, testURL, "file.txt", "mode", "0100644"), "200")
+	assert.Contains(t, proppatch(t, testURL, "file.txt", "mtime", "2001-02-03T04:05:06Z"), "200")
+}
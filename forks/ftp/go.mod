@@ -0,0 +1,15 @@
+module github.com/jlaffaye/ftp
+
+go 1.17
+
+require (
+	github.com/hashicorp/go-multierror v1.1.1
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -71,7 +71,8 @@ type Account struct {
 	withBuf  bool          // is using a buffered in
 	checking bool          // set if attached transfer is checking
 
-	tokenBucket buckets // per file bandwidth limiter (may be nil)
+	tokenBucket  buckets       // per file bandwidth limiter (may be nil)
+	remoteLimits []remoteLimit // per remote bandwidth limiters (may be nil)
 
 	values accountValues
 }
@@ -354,6 +355,19 @@ func (acc *Account) limitPerFileBandwidth(n int) {
 	}
 }
 
+// Account for n bytes from the bandwidth limits of the remotes (if any)
+func (acc *Account) limitRemoteBandwidth(n int) {
+	for _, limit := range acc.remoteLimits {
+		tokenBucket := limit.rtb.limiter(limit.slot)
+		if tokenBucket != nil {
+			err := tokenBucket.WaitN(context.Background(), n)
+			if err != nil {
+				fs.Errorf(nil, "Token bucket error: %v", err)
+			}
+		}
+	}
+}
+
 // Account the read
 func (acc *Account) accountReadN(n int64) {
 	// Update Stats
@@ -371,6 +385,7 @@ func (acc *Account) accountRead(n int) {
 
 	TokenBucket.LimitBandwidth(TokenBucketSlotAccounting, n)
 	acc.limitPerFileBandwidth(n)
+	acc.limitRemoteBandwidth(n)
 }
 
 // Account the read if not using network (eg for server side copies)
@@ -565,6 +580,15 @@ func (acc *Account) AccountReadN(n int64) {
 	acc.accountReadN(n)
 }
 
+// AccountReadNoNetwork accounts n bytes which didn't need to be
+// transferred, for example the parts of a resumed upload which were
+// already on the destination
+func (acc *Account) AccountReadNoNetwork(n int64) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.accountReadNoNetwork(n)
+}
+
 // Close the object
 func (acc *Account) Close() error {
 	acc.mu.Lock()
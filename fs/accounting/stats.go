@@ -66,6 +66,8 @@ type StatsInfo struct {
 	serverSideCopyBytes   int64
 	serverSideMoves       int64
 	serverSideMoveBytes   int64
+	verified              int64
+	verifyFailures        int64
 	maxCompletedTransfers int
 }
 
@@ -135,6 +137,8 @@ func (s *StatsInfo) RemoteStats(short bool) (out rc.Params, err error) {
 	out["serverSideCopyBytes"] = s.serverSideCopyBytes
 	out["serverSideMoves"] = s.serverSideMoves
 	out["serverSideMoveBytes"] = s.serverSideMoveBytes
+	out["verified"] = s.verified
+	out["verifyFailures"] = s.verifyFailures
 	eta, etaOK := eta(s.bytes, ts.totalBytes, ts.speed)
 	if etaOK {
 		out["eta"] = eta.Seconds()
@@ -506,6 +510,9 @@ func (s *StatsInfo) String() string {
 				s.serverSideMoves, fs.SizeSuffix(s.serverSideMoveBytes).ByteUnit(),
 			)
 		}
+		if s.verified != 0 || s.verifyFailures != 0 {
+			_, _ = fmt.Fprintf(buf, "Verified:      %10d, %d failed\n", s.verified, s.verifyFailures)
+		}
 		_, _ = fmt.Fprintf(buf, "Elapsed time:  %10ss\n", strings.TrimRight(fs.Duration(elapsedTime.Truncate(time.Minute)).ReadableString(), "0s")+fmt.Sprintf("%.1f", elapsedTimeSecondsOnly.Seconds()))
 	}
 
@@ -700,6 +707,22 @@ func (s *StatsInfo) Renames(renames int64) int64 {
 	return s.renames
 }
 
+// Verified updates the stats for files read back and verified with --verify
+func (s *StatsInfo) Verified(verified int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verified += verified
+	return s.verified
+}
+
+// VerifyFailures updates the stats for files which failed read back verification
+func (s *StatsInfo) VerifyFailures(failures int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifyFailures += failures
+	return s.verifyFailures
+}
+
 // Listed updates the stats for listed objects
 func (s *StatsInfo) Listed(listed int64) int64 {
 	s.mu.Lock()
@@ -708,7 +731,7 @@ func (s *StatsInfo) Listed(listed int64) int64 {
 	return s.listed
 }
 
-// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, renames, listed) to 0 and resets lastError, fatalError and retryError
+// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, renames, verified, listed) to 0 and resets lastError, fatalError and retryError
 func (s *StatsInfo) ResetCounters() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -724,6 +747,8 @@ func (s *StatsInfo) ResetCounters() {
 	s.deletesSize = 0
 	s.deletedDirs = 0
 	s.renames = 0
+	s.verified = 0
+	s.verifyFailures = 0
 	s.listed = 0
 	s.startedTransfers = nil
 	s.oldDuration = 0
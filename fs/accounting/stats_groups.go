@@ -111,6 +111,8 @@ Returns the following values:
 	"totalTransfers": total number of transfers in the group,
 	"transferTime" : total time spent on running jobs,
 	"transfers": number of transferred files,
+	"verified": number of files read back and verified with --verify,
+	"verifyFailures": number of files which failed --verify read back,
 	"transferring": an array of currently active file transfers:
 		[
 			{
@@ -416,6 +418,8 @@ func (sg *statsGroups) sum(ctx context.Context) *StatsInfo {
 			sum.serverSideCopyBytes += stats.serverSideCopyBytes
 			sum.serverSideMoves += stats.serverSideMoves
 			sum.serverSideMoveBytes += stats.serverSideMoveBytes
+			sum.verified += stats.verified
+			sum.verifyFailures += stats.verifyFailures
 		}
 		stats.mu.RUnlock()
 	}
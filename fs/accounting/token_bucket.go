@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -35,6 +36,24 @@ type tokenBucket struct {
 	prev       buckets
 	toggledOff bool
 	currLimit  fs.BwTimeSlot
+	remotes    map[string]*remoteTokenBucket // per remote limiters, protected by mu
+}
+
+// remoteTokenBucket holds the rate limiters for a single remote set
+// with its bwlimit option
+type remoteTokenBucket struct {
+	name      string
+	mu        sync.Mutex // protects the below
+	curr      buckets
+	timetable fs.BwTimetable
+	currLimit fs.BwTimeSlot
+	checked   time.Time // when currLimit was last read from the timetable
+}
+
+// remoteLimit is a remote's rate limiter and the slot to use in it
+type remoteLimit struct {
+	rtb  *remoteTokenBucket
+	slot TokenBucketSlot
 }
 
 // Return true if limit is disabled
@@ -191,8 +210,179 @@ func (tb *tokenBucket) SetBwLimit(bandwidth fs.BwPair) {
 	}
 }
 
+// newRemoteTokenBucket makes the rate limiters for the remote called
+// name using the bandwidth timetable passed in
+func newRemoteTokenBucket(name string, timetable fs.BwTimetable) *remoteTokenBucket {
+	rtb := &remoteTokenBucket{name: name}
+	rtb.setTimetable(timetable)
+	return rtb
+}
+
+// setTimetable sets the bandwidth timetable for the remote
+func (rtb *remoteTokenBucket) setTimetable(timetable fs.BwTimetable) {
+	rtb.mu.Lock()
+	defer rtb.mu.Unlock()
+	rtb.timetable = timetable
+	rtb._update(time.Now(), true)
+}
+
+// Read the current limit from the timetable and set the limiters
+// from it if it has changed or force is set.
+//
+// Call with lock held
+func (rtb *remoteTokenBucket) _update(now time.Time, force bool) {
+	rtb.checked = now
+	limitNow := rtb.timetable.LimitAt(now)
+	if !force && limitNow.Bandwidth == rtb.currLimit.Bandwidth {
+		return
+	}
+	rtb.currLimit = limitNow
+	if limitNow.Bandwidth.IsSet() {
+		rtb.curr = newTokenBucket(limitNow.Bandwidth)
+		fs.Infof(rtb.name, "Bandwidth limit for remote set to %v Byte/s", &limitNow.Bandwidth)
+	} else {
+		rtb.curr._setOff()
+		fs.Infof(rtb.name, "Bandwidth limit for remote disabled")
+	}
+}
+
+// limiter returns the rate limiter for slot i, updating the limiters
+// from the timetable once a minute.
+func (rtb *remoteTokenBucket) limiter(i TokenBucketSlot) *rate.Limiter {
+	rtb.mu.Lock()
+	defer rtb.mu.Unlock()
+	if now := time.Now(); len(rtb.timetable) > 1 && now.Sub(rtb.checked) >= time.Minute {
+		rtb._update(now, false)
+	}
+	return rtb.curr[i]
+}
+
+// getRemote returns the rate limiters for the remote called name or
+// nil if it doesn't have a bandwidth limit.
+//
+// If create is set then it always returns the rate limiters for name.
+func (tb *tokenBucket) getRemote(name string, create bool) *remoteTokenBucket {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if rtb, ok := tb.remotes[name]; ok {
+		return rtb
+	}
+	timetable := fs.RemoteBwLimit(name)
+	if timetable == nil && !create {
+		return nil
+	}
+	if tb.remotes == nil {
+		tb.remotes = make(map[string]*remoteTokenBucket)
+	}
+	rtb := newRemoteTokenBucket(name, timetable)
+	tb.remotes[name] = rtb
+	return rtb
+}
+
+// remoteBwLimitChanged updates the rate limiters for the remote
+// called name when its bwlimit option has changed.
+func (tb *tokenBucket) remoteBwLimitChanged(name string) {
+	timetable := fs.RemoteBwLimit(name)
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	rtb, ok := tb.remotes[name]
+	if !ok {
+		return
+	}
+	rtb.setTimetable(timetable)
+	if timetable == nil {
+		delete(tb.remotes, name)
+	}
+}
+
+// remoteNames returns the name of f followed by the names of the
+// remotes it wraps, if any
+func remoteNames(f fs.Fs) (names []string) {
+	for f != nil && !slices.Contains(names, f.Name()) {
+		names = append(names, f.Name())
+		unwrap := f.Features().UnWrap
+		if unwrap == nil {
+			break
+		}
+		f = unwrap()
+	}
+	return names
+}
+
+// remoteLimits returns the rate limiters to use for a transfer from
+// srcFs to dstFs, either of which may be nil.
+//
+// Downloads are limited by the source remote and uploads by the
+// destination remote, and by any remotes these wrap, such as the
+// remote under a crypt.
+func (tb *tokenBucket) remoteLimits(srcFs, dstFs fs.Fs) (limits []remoteLimit) {
+	for _, name := range remoteNames(srcFs) {
+		if rtb := tb.getRemote(name, false); rtb != nil {
+			limits = append(limits, remoteLimit{rtb: rtb, slot: TokenBucketSlotTransportRx})
+		}
+	}
+	for _, name := range remoteNames(dstFs) {
+		if rtb := tb.getRemote(name, false); rtb != nil {
+			limits = append(limits, remoteLimit{rtb: rtb, slot: TokenBucketSlotTransportTx})
+		}
+	}
+	return limits
+}
+
+// _rcParams returns the limits in bs for returning from the rc
+//
+// Call with lock held
+func (bs *buckets) _rcParams() rc.Params {
+	bytesPerSecond := int64(-1)
+	if bs[TokenBucketSlotAccounting] != nil {
+		bytesPerSecond = int64(bs[TokenBucketSlotAccounting].Limit())
+	}
+	var bp = fs.BwPair{Tx: -1, Rx: -1}
+	if bs[TokenBucketSlotTransportTx] != nil {
+		bp.Tx = fs.SizeSuffix(bs[TokenBucketSlotTransportTx].Limit())
+	}
+	if bs[TokenBucketSlotTransportRx] != nil {
+		bp.Rx = fs.SizeSuffix(bs[TokenBucketSlotTransportRx].Limit())
+	}
+	return rc.Params{
+		"rate":             bp.String(),
+		"bytesPerSecond":   bytesPerSecond,
+		"bytesPerSecondTx": int64(bp.Tx),
+		"bytesPerSecondRx": int64(bp.Rx),
+	}
+}
+
+// read and set the bandwidth limits of a single remote
+func (tb *tokenBucket) rcRemoteBwlimit(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(ctx, in)
+	if err != nil {
+		return out, err
+	}
+	rtb := tb.getRemote(f.Name(), true)
+	if in["rate"] != nil {
+		bwlimit, err := in.GetString("rate")
+		if err != nil {
+			return out, err
+		}
+		var bws fs.BwTimetable
+		err = bws.Set(bwlimit)
+		if err != nil {
+			return out, fmt.Errorf("bad bwlimit: %w", err)
+		}
+		rtb.setTimetable(bws)
+	}
+	rtb.mu.Lock()
+	out = rtb.curr._rcParams()
+	out["timetable"] = rtb.timetable.String()
+	rtb.mu.Unlock()
+	return out, nil
+}
+
 // read and set the bandwidth limits
 func (tb *tokenBucket) rcBwlimit(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	if in["fs"] != nil {
+		return tb.rcRemoteBwlimit(ctx, in)
+	}
 	if in["rate"] != nil {
 		bwlimit, err := in.GetString("rate")
 		if err != nil {
@@ -210,29 +400,14 @@ func (tb *tokenBucket) rcBwlimit(ctx context.Context, in rc.Params) (out rc.Para
 		tb.SetBwLimit(bw.Bandwidth)
 	}
 	tb.mu.RLock()
-	bytesPerSecond := int64(-1)
-	if tb.curr[TokenBucketSlotAccounting] != nil {
-		bytesPerSecond = int64(tb.curr[TokenBucketSlotAccounting].Limit())
-	}
-	var bp = fs.BwPair{Tx: -1, Rx: -1}
-	if tb.curr[TokenBucketSlotTransportTx] != nil {
-		bp.Tx = fs.SizeSuffix(tb.curr[TokenBucketSlotTransportTx].Limit())
-	}
-	if tb.curr[TokenBucketSlotTransportRx] != nil {
-		bp.Rx = fs.SizeSuffix(tb.curr[TokenBucketSlotTransportRx].Limit())
-	}
+	out = tb.curr._rcParams()
 	tb.mu.RUnlock()
-	out = rc.Params{
-		"rate":             bp.String(),
-		"bytesPerSecond":   bytesPerSecond,
-		"bytesPerSecondTx": int64(bp.Tx),
-		"bytesPerSecondRx": int64(bp.Rx),
-	}
 	return out, nil
 }
 
 // Remote control for the token bucket
 func init() {
+	fs.RemoteBwLimitChanged = TokenBucket.remoteBwLimitChanged
 	rc.Add(rc.Call{
 		Path:  "core/bwlimit",
 		Fn:    TokenBucket.rcBwlimit,
@@ -281,6 +456,20 @@ except only one bandwidth may be specified.
 
 In either case "rate" is returned as a human-readable string, and
 "bytesPerSecond" is returned as a number.
+
+If the "fs" parameter is passed then this sets or queries the
+bandwidth limit of that remote only, as set by its bwlimit option.
+In this case "rate" may be a full timetable and the timetable in use
+is returned in "timetable".
+
+    rclone rc core/bwlimit fs=remote: rate=512k:off
+    {
+        "bytesPerSecond": -1,
+        "bytesPerSecondTx": 524288,
+        "bytesPerSecondRx": -1,
+        "rate": "512Ki:off",
+        "timetable": "512Ki:off"
+    }
 `,
 	})
 }
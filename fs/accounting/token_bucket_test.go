@@ -3,8 +3,11 @@ package accounting
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
@@ -90,3 +93,176 @@ func TestRcBwLimit(t *testing.T) {
 	}, out)
 
 }
+
+// wrapFs is a remote wrapping another like crypt does
+type wrapFs struct {
+	fs.Fs
+	features *fs.Features
+}
+
+func newWrapFs(ctx context.Context, f fs.Fs) *wrapFs {
+	w := &wrapFs{Fs: f}
+	w.features = (&fs.Features{}).Fill(ctx, w)
+	return w
+}
+
+func (w *wrapFs) Name() string           { return "wrap" }
+func (w *wrapFs) Features() *fs.Features { return w.features }
+func (w *wrapFs) UnWrap() fs.Fs          { return w.Fs }
+
+func TestRemoteBwLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// Register mockfs temporarily
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+
+	fLimited, err := fs.NewFs(ctx, ":mockfs,bwlimit='1M:2M':/tmp")
+	require.NoError(t, err)
+	fUnlimited, err := fs.NewFs(ctx, ":mockfs:/tmp")
+	require.NoError(t, err)
+	_, err = fs.NewFs(ctx, ":mockfs,bwlimit=potato:/tmp")
+	assert.ErrorContains(t, err, "bad bwlimit")
+
+	var tb tokenBucket
+
+	// Downloads use the source limit and uploads the destination limit
+	limits := tb.remoteLimits(fLimited, fUnlimited)
+	require.Len(t, limits, 1)
+	assert.Equal(t, TokenBucketSlotTransportRx, limits[0].slot)
+	assert.Equal(t, rate.Limit(2*1024*1024), limits[0].rtb.limiter(limits[0].slot).Limit())
+	limits = tb.remoteLimits(nil, fLimited)
+	require.Len(t, limits, 1)
+	assert.Equal(t, TokenBucketSlotTransportTx, limits[0].slot)
+	assert.Equal(t, rate.Limit(1024*1024), limits[0].rtb.limiter(limits[0].slot).Limit())
+	assert.Len(t, tb.remoteLimits(fUnlimited, fUnlimited), 0)
+	assert.Len(t, tb.remoteLimits(nil, nil), 0)
+
+	// Remotes wrapping a limited remote use its limits
+	limits = tb.remoteLimits(newWrapFs(ctx, fLimited), newWrapFs(ctx, fUnlimited))
+	require.Len(t, limits, 1)
+	assert.Equal(t, TokenBucketSlotTransportRx, limits[0].slot)
+	assert.Equal(t, rate.Limit(2*1024*1024), limits[0].rtb.limiter(limits[0].slot).Limit())
+
+	// Check the timetable is followed
+	rtb := newRemoteTokenBucket("test", nil)
+	assert.Nil(t, rtb.limiter(TokenBucketSlotTransportTx))
+	var timetable fs.BwTimetable
+	require.NoError(t, timetable.Set("00:00,1M 12:00,off"))
+	rtb.setTimetable(timetable)
+	now := time.Now()
+	rtb.mu.Lock()
+	rtb._update(time.Date(2026, 1, 5, 10, 0, 0, 0, now.Location()), false)
+	assert.NotNil(t, rtb.curr[TokenBucketSlotTransportTx])
+	rtb._update(time.Date(2026, 1, 5, 13, 0, 0, 0, now.Location()), false)
+	assert.Nil(t, rtb.curr[TokenBucketSlotTransportTx])
+	rtb.mu.Unlock()
+}
+
+func TestRemoteBwLimitChanged(t *testing.T) {
+	ctx := context.Background()
+
+	// Register mockfs temporarily
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+
+	// Use a fake config file so the name of the remote doesn't
+	// change with its options
+	const name = "TestBwLimitChanged"
+	config := map[string]string{
+		"type":    "mockfs",
+		"bwlimit": "1M",
+	}
+	oldConfigFileGet := fs.ConfigFileGet
+	fs.ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := config[key]
+		return value, ok && section == name
+	}
+	defer func() {
+		fs.ConfigFileGet = oldConfigFileGet
+	}()
+
+	_, err := fs.NewFs(ctx, name+":/tmp")
+	require.NoError(t, err)
+	rtb := TokenBucket.getRemote(name, false)
+	require.NotNil(t, rtb)
+	assert.Equal(t, rate.Limit(1024*1024), rtb.limiter(TokenBucketSlotTransportTx).Limit())
+
+	// Changing the option updates the cached limiters
+	config["bwlimit"] = "2M"
+	_, err = fs.NewFs(ctx, name+":/tmp")
+	require.NoError(t, err)
+	assert.Equal(t, rtb, TokenBucket.getRemote(name, false))
+	assert.Equal(t, rate.Limit(2*1024*1024), rtb.limiter(TokenBucketSlotTransportTx).Limit())
+
+	// Removing the option removes the limit
+	delete(config, "bwlimit")
+	_, err = fs.NewFs(ctx, name+":/tmp")
+	require.NoError(t, err)
+	assert.Nil(t, rtb.limiter(TokenBucketSlotTransportTx))
+	assert.Nil(t, TokenBucket.getRemote(name, false))
+}
+
+func TestRcRemoteBwLimit(t *testing.T) {
+	ctx := context.Background()
+	call := rc.Calls.Get("core/bwlimit")
+	assert.NotNil(t, call)
+
+	// Register mockfs temporarily
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+
+	// Query a remote with a bwlimit option
+	out, err := call.Fn(ctx, rc.Params{
+		"fs": ":mockfs,bwlimit='10M:1M':/tmp",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"bytesPerSecond":   int64(10485760),
+		"bytesPerSecondTx": int64(10485760),
+		"bytesPerSecondRx": int64(1048576),
+		"rate":             "10Mi:1Mi",
+		"timetable":        "10Mi:1Mi",
+	}, out)
+
+	// Set the limit of a remote
+	out, err = call.Fn(ctx, rc.Params{
+		"fs":   ":mockfs:/tmp",
+		"rate": "512k:off",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"bytesPerSecond":   int64(-1),
+		"bytesPerSecondTx": int64(524288),
+		"bytesPerSecondRx": int64(-1),
+		"rate":             "512Ki:off",
+		"timetable":        "512Ki:off",
+	}, out)
+
+	// The global limit isn't changed
+	assert.Nil(t, TokenBucket.curr[TokenBucketSlotTransportTx])
+
+	// Query the limit
+	out, err = call.Fn(ctx, rc.Params{
+		"fs": ":mockfs:/tmp",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "512Ki:off", out["rate"])
+
+	// Reset the limit
+	out, err = call.Fn(ctx, rc.Params{
+		"fs":   ":mockfs:/tmp",
+		"rate": "off",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "off", out["rate"])
+}
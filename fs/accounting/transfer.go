@@ -162,6 +162,7 @@ func (tr *Transfer) Account(ctx context.Context, in io.ReadCloser) *Account {
 	tr.mu.Lock()
 	if tr.acc == nil {
 		tr.acc = newAccountSizeName(ctx, tr.stats, in, tr.size, tr.remote)
+		tr.acc.remoteLimits = TokenBucket.remoteLimits(tr.srcFs, tr.dstFs)
 	} else {
 		tr.acc.UpdateReader(ctx, in)
 	}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/rclone/rclone/fs/config/configmap"
 )
 
 // BwPair represents an upload and a download bandwidth
@@ -283,3 +286,49 @@ func (x BwTimetable) MarshalJSON() ([]byte, error) {
 	s := x.String()
 	return json.Marshal(s)
 }
+
+// Bandwidth timetables set with the bwlimit option of each remote
+var (
+	remoteBwLimitsMu sync.Mutex
+	remoteBwLimits   = make(map[string]BwTimetable)
+)
+
+// RemoteBwLimitChanged is called when the bwlimit option of the
+// remote called name has changed.
+//
+// This is a function pointer to decouple the accounting
+// implementation from the fs
+var RemoteBwLimitChanged = func(name string) {}
+
+// setRemoteBwLimit reads the bwlimit option of the remote called name
+// from config and records it for RemoteBwLimit.
+func setRemoteBwLimit(name string, config configmap.Getter) error {
+	var timetable BwTimetable
+	value, ok := config.Get(optBwLimit.Name)
+	if ok && value != "" {
+		err := timetable.Set(value)
+		if err != nil {
+			return fmt.Errorf("bad %s %q: %w", optBwLimit.Name, value, err)
+		}
+	}
+	remoteBwLimitsMu.Lock()
+	old := remoteBwLimits[name]
+	if timetable == nil {
+		delete(remoteBwLimits, name)
+	} else {
+		remoteBwLimits[name] = timetable
+	}
+	remoteBwLimitsMu.Unlock()
+	if old.String() != timetable.String() {
+		RemoteBwLimitChanged(name)
+	}
+	return nil
+}
+
+// RemoteBwLimit returns the bandwidth timetable set with the bwlimit
+// option of the remote called name, or nil if it wasn't set.
+func RemoteBwLimit(name string) BwTimetable {
+	remoteBwLimitsMu.Lock()
+	defer remoteBwLimitsMu.Unlock()
+	return remoteBwLimits[name]
+}
@@ -66,6 +66,11 @@ var ConfigOptionsInfo = Options{{
 	Default: 4,
 	Help:    "Number of file transfers to run in parallel",
 	Groups:  "Performance",
+}, {
+	Name:    "adaptive_concurrency",
+	Default: false,
+	Help:    "Adjust the number of transfers and checkers while syncing, up to --transfers and --checkers",
+	Groups:  "Performance",
 }, {
 	Name:     "checksum",
 	ShortOpt: "c",
@@ -161,6 +166,31 @@ var ConfigOptionsInfo = Options{{
 	Default: "hash",
 	Help:    "Strategies to use when synchronizing using track-renames hash|modtime|leaf",
 	Groups:  "Sync",
+}, {
+	Name:    "track_renames_dir_threshold",
+	Default: 0.0,
+	Help:    "Fraction of files (0-1) which must match to rename a whole directory with track-renames, 0 to disable",
+	Groups:  "Sync",
+}, {
+	Name:    "checkpoint_file",
+	Default: "",
+	Help:    "Record sync progress in this file so an interrupted sync can skip directories already done",
+	Groups:  "Sync",
+}, {
+	Name:    "dst_list_cache",
+	Default: false,
+	Help:    "Use a local cache of the destination listing written by previous syncs",
+	Groups:  "Sync",
+}, {
+	Name:    "dst_list_cache_max_age",
+	Default: 7 * 24 * time.Hour,
+	Help:    "List the whole destination again if the listing cache was last verified longer ago than this",
+	Groups:  "Sync",
+}, {
+	Name:    "plan_out",
+	Default: "",
+	Help:    "Write the actions the sync would take to this file for rclone apply, without changing anything",
+	Groups:  "Sync",
 }, {
 	Name:    "retries",
 	Default: 3,
@@ -207,6 +237,11 @@ var ConfigOptionsInfo = Options{{
 	Default: false,
 	Help:    "Skip post copy check of checksums",
 	Groups:  "Copy",
+}, {
+	Name:    "verify",
+	Default: false,
+	Help:    "Read back files after copying to check them if source and destination have no hash in common",
+	Groups:  "Copy",
 }, {
 	Name:    "ignore_case_sync",
 	Default: false,
@@ -464,6 +499,11 @@ var ConfigOptionsInfo = Options{{
 	Default: SizeSuffix(64 * 1024 * 1024),
 	Help:    "Chunk size for multi-thread downloads / uploads, if not set by filesystem",
 	Groups:  "Copy",
+}, {
+	Name:    "multi_thread_resume",
+	Default: false,
+	Help:    "Keep the parts of interrupted multi-thread uploads and resume them next time",
+	Groups:  "Copy",
 }, {
 	Name:    "use_json_log",
 	Default: false,
@@ -584,6 +624,7 @@ type ConfigInfo struct {
 	ModifyWindow               Duration          `config:"modify_window"`
 	Checkers                   int               `config:"checkers"`
 	Transfers                  int               `config:"transfers"`
+	AdaptiveConcurrency        bool              `config:"adaptive_concurrency"`
 	ConnectTimeout             Duration          `config:"contimeout"` // Connect timeout
 	Timeout                    Duration          `config:"timeout"`    // Data channel timeout
 	ExpectContinueTimeout      Duration          `config:"expect_continue_timeout"`
@@ -592,9 +633,14 @@ type ConfigInfo struct {
 	DeleteMode                 DeleteMode        `config:"delete_mode"`
 	MaxDelete                  int64             `config:"max_delete"`
 	MaxDeleteSize              SizeSuffix        `config:"max_delete_size"`
-	TrackRenames               bool              `config:"track_renames"`          // Track file renames.
-	TrackRenamesStrategy       string            `config:"track_renames_strategy"` // Comma separated list of strategies used to track renames
-	Retries                    int               `config:"retries"`                // High-level retries
+	TrackRenames               bool              `config:"track_renames"`               // Track file renames.
+	TrackRenamesStrategy       string            `config:"track_renames_strategy"`      // Comma separated list of strategies used to track renames
+	TrackRenamesDirThreshold   float64           `config:"track_renames_dir_threshold"` // Fraction of files which must match to rename a directory
+	CheckpointFile             string            `config:"checkpoint_file"`             // File to record sync progress in
+	DstListCache               bool              `config:"dst_list_cache"`              // Use a persistent cache of the destination listing
+	DstListCacheMaxAge         Duration          `config:"dst_list_cache_max_age"`      // Verify the destination listing cache after this long
+	PlanOut                    string            `config:"plan_out"`                    // File to write the sync plan to
+	Retries                    int               `config:"retries"`                     // High-level retries
 	RetriesInterval            Duration          `config:"retries_sleep"`
 	LowLevelRetries            int               `config:"low_level_retries"`
 	UpdateOlder                bool              `config:"update"`           // Skip files that are newer on the destination
@@ -602,6 +648,7 @@ type ConfigInfo struct {
 	MaxDepth                   int               `config:"max_depth"`
 	IgnoreSize                 bool              `config:"ignore_size"`
 	IgnoreChecksum             bool              `config:"ignore_checksum"`
+	Verify                     bool              `config:"verify"`
 	IgnoreCaseSync             bool              `config:"ignore_case_sync"`
 	FixCase                    bool              `config:"fix_case"`
 	NoTraverse                 bool              `config:"no_traverse"`
@@ -656,6 +703,7 @@ type ConfigInfo struct {
 	MultiThreadSet             bool              `config:"multi_thread_set"`        // whether MultiThreadStreams was set (set in fs/config/configflags)
 	MultiThreadChunkSize       SizeSuffix        `config:"multi_thread_chunk_size"` // Chunk size for multi-thread downloads / uploads, if not set by filesystem
 	MultiThreadWriteBufferSize SizeSuffix        `config:"multi_thread_write_buffer_size"`
+	MultiThreadResume          bool              `config:"multi_thread_resume"`
 	OrderBy                    string            `config:"order_by"` // instructions on how to order the transfer
 	UploadHeaders              []*HTTPOption     `config:"upload_headers"`
 	DownloadHeaders            []*HTTPOption     `config:"download_headers"`
@@ -191,6 +191,13 @@ type Features struct {
 	//
 	OpenChunkWriter func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
 
+	// ResumeChunkWriter reopens the upload with uploadID started by
+	// OpenChunkWriter and returns the chunks already uploaded
+	//
+	// Chunks which should be kept must be added to the writer with
+	// AddChunk, the others must be written again.
+	ResumeChunkWriter func(ctx context.Context, remote string, src ObjectInfo, uploadID string, options ...OpenOption) (info ChunkWriterInfo, writer ResumableChunkWriter, parts []ChunkWriterPart, err error)
+
 	// UserInfo returns info about the connected user
 	UserInfo func(ctx context.Context) (map[string]string, error)
 
@@ -354,6 +361,9 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(OpenChunkWriter); ok {
 		ft.OpenChunkWriter = do.OpenChunkWriter
 	}
+	if do, ok := f.(ChunkWriterResumer); ok {
+		ft.ResumeChunkWriter = do.ResumeChunkWriter
+	}
 	if do, ok := f.(UserInfoer); ok {
 		ft.UserInfo = do.UserInfo
 	}
@@ -465,6 +475,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.OpenChunkWriter == nil {
 		ft.OpenChunkWriter = nil
 	}
+	if mask.ResumeChunkWriter == nil {
+		ft.ResumeChunkWriter = nil
+	}
 	if mask.UserInfo == nil {
 		ft.UserInfo = nil
 	}
@@ -767,6 +780,41 @@ type ChunkWriter interface {
 	Abort(ctx context.Context) error
 }
 
+// ChunkWriterPart describes a chunk written to an upload
+type ChunkWriterPart struct {
+	ChunkNumber int    // number of the chunk, starting from 0
+	Size        int64  // size of the chunk
+	ID          string // what the remote knows the chunk's contents by, eg ETag or SHA-1
+}
+
+// ResumableChunkWriter is an optional interface for a ChunkWriter
+// whose upload can be resumed by ResumeChunkWriter after rclone has
+// been stopped
+type ResumableChunkWriter interface {
+	ChunkWriter
+
+	// UploadID returns the ID to pass to ResumeChunkWriter
+	UploadID() string
+
+	// WrittenChunk returns the part for chunkNumber once it has
+	// been written by WriteChunk
+	WrittenChunk(chunkNumber int) (part ChunkWriterPart, ok bool)
+
+	// AddChunk adds a part returned by ResumeChunkWriter to the
+	// upload so it doesn't need to be written again
+	AddChunk(part ChunkWriterPart) error
+}
+
+// ChunkWriterResumer is an optional interface for Fs
+type ChunkWriterResumer interface {
+	// ResumeChunkWriter reopens the upload with uploadID started by
+	// OpenChunkWriter and returns the chunks already uploaded
+	//
+	// Chunks which should be kept must be added to the writer with
+	// AddChunk, the others must be written again.
+	ResumeChunkWriter(ctx context.Context, remote string, src ObjectInfo, uploadID string, options ...OpenOption) (info ChunkWriterInfo, writer ResumableChunkWriter, parts []ChunkWriterPart, err error)
+}
+
 // UserInfoer is an optional interface for Fs
 type UserInfoer interface {
 	// UserInfo returns info about the connected user
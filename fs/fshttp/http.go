@@ -25,6 +25,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/structs"
 	"github.com/youmark/pkcs8"
 	"golang.org/x/net/publicsuffix"
@@ -340,8 +341,10 @@ func NewClientWithUnixSocket(ctx context.Context, path string) *http.Client {
 // * Sets the User Agent
 // * Does logging
 // * Updates metrics
+// * Makes trace spans
 type Transport struct {
 	*http.Transport
+	roundTripper  http.RoundTripper // the Transport, traced if required
 	ci            *fs.ConfigInfo
 	dump          fs.DumpFlags
 	filterRequest func(req *http.Request)
@@ -356,12 +359,13 @@ type Transport struct {
 // roundtrips including the body if logBody is set.
 func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
 	return &Transport{
-		Transport: transport,
-		ci:        ci,
-		dump:      ci.Dump,
-		userAgent: ci.UserAgent,
-		headers:   ci.Headers,
-		metrics:   DefaultMetrics,
+		Transport:    transport,
+		roundTripper: tracing.Transport(transport),
+		ci:           ci,
+		dump:         ci.Dump,
+		userAgent:    ci.UserAgent,
+		headers:      ci.Headers,
+		metrics:      DefaultMetrics,
 	}
 }
 
@@ -672,7 +676,7 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 		traceReq = req.WithContext(httptrace.WithClientTrace(req.Context(), newClientTrace(req)))
 	}
 	// Do round trip
-	resp, err = t.Transport.RoundTrip(traceReq)
+	resp, err = t.roundTripper.RoundTrip(traceReq)
 	// Dump response, and the request too if we deferred it for --dump errors
 	if wantDump && (!onError || isRetryableResponse(resp, err)) {
 		logMutex.Lock()
@@ -10,6 +10,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/bucket"
 )
 
@@ -23,7 +24,9 @@ import (
 // Files will be returned in sorted order
 func DirSorted(ctx context.Context, f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(ctx, dir)
+	spanCtx, span := tracing.StartFs(ctx, "List", f, dir)
+	entries, err = f.List(spanCtx, dir)
+	tracing.End(span, err)
 	accounting.Stats(ctx).Listed(int64(len(entries)))
 	if err != nil {
 		return nil, err
@@ -40,12 +43,16 @@ func DirSorted(ctx context.Context, f fs.Fs, includeAll bool, dir string) (entri
 }
 
 // listP for every backend
-func listP(ctx context.Context, f fs.Fs, dir string, callback fs.ListRCallback) error {
+func listP(ctx context.Context, f fs.Fs, dir string, callback fs.ListRCallback) (err error) {
 	if doListP := f.Features().ListP; doListP != nil {
+		ctx, span := tracing.StartFs(ctx, "ListP", f, dir)
+		defer func() { tracing.End(span, err) }()
 		return doListP(ctx, dir, callback)
 	}
 	// Fallback to List
-	entries, err := f.List(ctx, dir)
+	spanCtx, span := tracing.StartFs(ctx, "List", f, dir)
+	entries, err := f.List(spanCtx, dir)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
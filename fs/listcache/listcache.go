@@ -0,0 +1,517 @@
+// Package listcache implements a persistent cache of the directory
+// listings of a sync destination.
+//
+// The cache holds the state of the destination as last written by
+// rclone so that a sync to a destination which nothing else writes
+// to doesn't need to list it every time.
+package listcache
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+const (
+	facility           = "listcache"      // name of the kv database
+	notifyPollInterval = 10 * time.Second // how often to poll for changes
+)
+
+// metaRecord describes the state of the cache for an Fs
+type metaRecord struct {
+	Verified time.Time // when the whole destination was last listed
+	Running  bool      // set while a sync is using the cache
+	Root     string    // fingerprint of the root listing
+}
+
+// entryRecord is a cached directory entry
+type entryRecord struct {
+	Name    string               // leaf name
+	Dir     bool                 // set if this is a directory
+	Size    int64                // size of the entry
+	ModTime time.Time            // zero if not cached
+	Hashes  map[hash.Type]string // cached hashes, if any
+}
+
+// Cache is a persistent cache of the directory listings of an Fs
+type Cache struct {
+	f          fs.Fs
+	db         *kv.DB
+	keyPrefix  string          // prefix for the keys of this Fs
+	useCache   bool            // set if the cached listings are valid
+	hashes     []hash.Type     // hashes to store in the cache
+	modTime    bool            // set if modification times should be stored
+	stopNotify func()          // stop the ChangeNotify if set
+	mu         sync.Mutex      // protect the below
+	meta       metaRecord      // state of the cache
+	dirty      map[string]bool // directories changed - true if the whole subtree changed
+	invalid    bool            // set if the cache was found to be wrong
+}
+
+// New opens the listing cache for f.
+//
+// This lists the root of f to check whether anything else has
+// written to it since the cache was last used. If the cache can't be
+// trusted, or was last verified longer ago than maxAge, then the
+// cache is emptied and the listings are read from f and stored.
+func New(ctx context.Context, f fs.Fs, maxAge time.Duration) (c *Cache, err error) {
+	db, err := kv.Start(ctx, facility, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open destination listing cache: %w", err)
+	}
+	c = &Cache{
+		f:         f,
+		db:        db,
+		keyPrefix: fs.ConfigString(f),
+		dirty:     make(map[string]bool),
+	}
+	features := f.Features()
+	c.modTime = !features.SlowModTime && f.Precision() != fs.ModTimeNotSupported
+	if !features.SlowHash {
+		c.hashes = f.Hashes().Array()
+	}
+	defer func() {
+		if err != nil {
+			_ = db.Stop(false)
+		}
+	}()
+
+	getMeta := &kv.OpGet{Key: c.metaKey()}
+	err = db.Do(false, getMeta)
+	data := getMeta.Value
+	if err != nil && err != kv.ErrEmpty {
+		return nil, fmt.Errorf("failed to read destination listing cache: %w", err)
+	}
+	if data != nil {
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&c.meta); err != nil {
+			fs.Debugf(f, "Failed to decode destination listing cache state: %v", err)
+			c.meta = metaRecord{}
+		}
+	}
+
+	rootEntries, err := c.listLive(ctx, "")
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, err
+	}
+	rootFingerprint := fingerprint(ctx, rootEntries)
+	switch {
+	case c.meta.Verified.IsZero():
+		fs.Infof(f, "No destination listing cache - listing the whole destination")
+	case c.meta.Running:
+		fs.Logf(f, "Last sync using the destination listing cache didn't finish - listing the whole destination")
+	case maxAge > 0 && time.Since(c.meta.Verified) > maxAge:
+		fs.Infof(f, "Destination listing cache last verified %v ago - listing the whole destination", time.Since(c.meta.Verified).Truncate(time.Second))
+	case c.meta.Root != rootFingerprint:
+		fs.Logf(f, "Destination has been changed by something else - listing the whole destination")
+	default:
+		fs.Infof(f, "Using destination listing cache last verified %v", c.meta.Verified.Format(time.RFC3339))
+		c.useCache = true
+	}
+	if !c.useCache {
+		// Only a full listing which completes verifies the cache
+		c.meta.Verified = time.Time{}
+		if err = db.Do(true, &kv.OpDelete{Prefixes: []string{c.dirKey("")}}); err != nil {
+			return nil, fmt.Errorf("failed to empty destination listing cache: %w", err)
+		}
+	}
+
+	// Mark the cache as in use so an interrupted sync is noticed
+	c.meta.Running = true
+	if err = c.saveMeta(); err != nil {
+		return nil, err
+	}
+	if rootEntries != nil {
+		c.store("", rootEntries)
+	}
+	c.startNotify(ctx)
+	return c, nil
+}
+
+// metaKey returns the key for the metaRecord
+func (c *Cache) metaKey() string {
+	return "m:" + c.keyPrefix
+}
+
+// dirKey returns the key for the listing of dir
+func (c *Cache) dirKey(dir string) string {
+	return "d:" + c.keyPrefix + "\x00" + dir
+}
+
+// saveMeta writes the metaRecord to the database
+func (c *Cache) saveMeta() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&c.meta); err != nil {
+		return err
+	}
+	err := c.db.Do(true, &kv.OpPut{Key: c.metaKey(), Value: buf.Bytes()})
+	if err != nil {
+		return fmt.Errorf("failed to write destination listing cache: %w", err)
+	}
+	return nil
+}
+
+// startNotify invalidates directories changed while the sync is
+// running if the Fs supports ChangeNotify.
+func (c *Cache) startNotify(ctx context.Context) {
+	do := c.f.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pollInterval := make(chan time.Duration, 1)
+	pollInterval <- notifyPollInterval
+	do(ctx, func(remote string, entryType fs.EntryType) {
+		fs.Debugf(remote, "Change notified - removing from destination listing cache")
+		if entryType == fs.EntryDirectory {
+			c.Purge(remote)
+		}
+		c.Invalidate(parentDir(remote))
+	}, pollInterval)
+	c.stopNotify = func() {
+		close(pollInterval)
+		cancel()
+	}
+}
+
+// parentDir returns the directory remote is in
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// fingerprint returns a fingerprint of a listing so it can be checked
+// for changes.
+func fingerprint(ctx context.Context, entries fs.DirEntries) string {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return cmp.Compare(a.Remote(), b.Remote())
+	})
+	h := sha1.New()
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			_, _ = fmt.Fprintf(h, "o %q %s\n", x.Remote(), fs.Fingerprint(ctx, x, true))
+		case fs.Directory:
+			_, _ = fmt.Fprintf(h, "d %q %v\n", x.Remote(), x.ModTime(ctx).UTC())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isDirty returns true if dir or its parents have been invalidated
+// in this run - call with the lock held
+func (c *Cache) isDirty(dir string) bool {
+	if _, ok := c.dirty[dir]; ok {
+		return true
+	}
+	for dir != "" {
+		dir = parentDir(dir)
+		if c.dirty[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// Invalidate removes the cached listing of dir as it has been changed
+func (c *Cache) Invalidate(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.dirty[dir]; !ok {
+		c.dirty[dir] = false
+	}
+}
+
+// Purge removes the cached listings of dir and everything below it
+func (c *Cache) Purge(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty[dir] = true
+}
+
+// setInvalid marks the whole cache as wrong so it is emptied at the
+// end of the sync.
+func (c *Cache) setInvalid(remote string, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.invalid {
+		fs.Logf(remote, "Destination listing cache is out of date (%s) - will list the whole destination next time", reason)
+		c.invalid = true
+	}
+}
+
+// listLive lists dir from the Fs
+func (c *Cache) listLive(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = c.f.List(ctx, dir)
+	accounting.Stats(ctx).Listed(int64(len(entries)))
+	return entries, err
+}
+
+// store the listing of dir in the cache unless it has been changed
+func (c *Cache) store(dir string, entries fs.DirEntries) {
+	ctx := context.Background()
+	records := make([]entryRecord, 0, len(entries))
+	for _, entry := range entries {
+		rec := entryRecord{
+			Name: path.Base(entry.Remote()),
+		}
+		switch x := entry.(type) {
+		case fs.Object:
+			rec.Size = x.Size()
+			if c.modTime {
+				rec.ModTime = x.ModTime(ctx)
+			}
+			for _, ht := range c.hashes {
+				sum, err := x.Hash(ctx, ht)
+				if err == nil && sum != "" {
+					if rec.Hashes == nil {
+						rec.Hashes = make(map[hash.Type]string, len(c.hashes))
+					}
+					rec.Hashes[ht] = sum
+				}
+			}
+		case fs.Directory:
+			rec.Dir = true
+			rec.Size = x.Size()
+			rec.ModTime = x.ModTime(ctx)
+		default:
+			continue
+		}
+		records = append(records, rec)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(records); err != nil {
+		fs.Debugf(dir, "Failed to encode destination listing: %v", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isDirty(dir) {
+		return
+	}
+	if err := c.db.Do(true, &kv.OpPut{Key: c.dirKey(dir), Value: buf.Bytes()}); err != nil {
+		fs.Errorf(dir, "Failed to write destination listing cache: %v", err)
+	}
+}
+
+// load the listing of dir from the cache returning nil if not found
+func (c *Cache) load(dir string) (entries fs.DirEntries) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.useCache || c.isDirty(dir) {
+		return nil
+	}
+	op := &kv.OpGet{Key: c.dirKey(dir)}
+	err := c.db.Do(false, op)
+	data := op.Value
+	if err != nil || data == nil {
+		return nil
+	}
+	var records []entryRecord
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&records); err != nil {
+		fs.Debugf(dir, "Failed to decode destination listing: %v", err)
+		return nil
+	}
+	entries = make(fs.DirEntries, 0, len(records))
+	for _, rec := range records {
+		remote := path.Join(dir, rec.Name)
+		if rec.Dir {
+			entries = append(entries, &Directory{
+				Dir: fs.NewDir(remote, rec.ModTime).SetSize(rec.Size),
+				c:   c,
+			})
+			continue
+		}
+		entries = append(entries, &Object{
+			c:       c,
+			remote:  remote,
+			size:    rec.Size,
+			modTime: rec.ModTime,
+			hashes:  rec.Hashes,
+		})
+	}
+	return entries
+}
+
+// Resolve returns the entry read from the Fs for an entry returned by
+// ListDir.
+//
+// Entries read from the cache don't have the optional methods of the
+// entries of the Fs and can't be passed to its methods, so they must
+// be resolved before they are changed.
+//
+// It returns fs.ErrorObjectNotFound or fs.ErrorDirNotFound if the
+// entry no longer exists.
+func (c *Cache) Resolve(ctx context.Context, entry fs.DirEntry) (fs.DirEntry, error) {
+	switch x := entry.(type) {
+	case *Object:
+		obj, err := x.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case *Directory:
+		dir, err := x.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dir, nil
+	}
+	return entry, nil
+}
+
+// ListDir lists dir from the cache if possible, otherwise from the
+// Fs storing the result in the cache.
+//
+// If includeAll is specified all files will be returned, otherwise
+// only files and directories passing the filter will be returned.
+//
+// The entries are returned through callback sorted with keyFn.
+func (c *Cache) ListDir(ctx context.Context, dir string, includeAll bool, callback fs.ListRCallback, keyFn list.KeyFn) (err error) {
+	entries := c.load(dir)
+	if entries != nil {
+		fs.Debugf(dir, "Using destination listing cache")
+		accounting.Stats(ctx).Listed(int64(len(entries)))
+	} else {
+		entries, err = c.listLive(ctx, dir)
+		if err != nil {
+			return err
+		}
+		c.store(dir, entries)
+		// Wrap the objects so we notice if they are changed
+		for i, entry := range entries {
+			if o, ok := entry.(fs.Object); ok {
+				entries[i] = &Object{
+					c:      c,
+					remote: o.Remote(),
+					size:   o.Size(),
+					obj:    o,
+				}
+			}
+		}
+	}
+
+	// Filter the entries
+	fi := filter.GetConfig(ctx)
+	if !includeAll {
+		if fi.ListContainsExcludeFile(entries) {
+			fs.Debugf(dir, "Excluded")
+			return nil
+		}
+		includeDirectory := fi.IncludeDirectory(ctx, c.f)
+		newEntries := entries[:0]
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case fs.Object:
+				if !fi.IncludeObject(ctx, x) {
+					fs.Debugf(x, "Excluded")
+					continue
+				}
+			case fs.Directory:
+				include, err := includeDirectory(x.Remote())
+				if err != nil {
+					return err
+				}
+				if !include {
+					fs.Debugf(x, "Excluded")
+					continue
+				}
+			}
+			newEntries = append(newEntries, entry)
+		}
+		entries = newEntries
+	}
+
+	slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+		return cmp.Compare(keyFn(a), keyFn(b))
+	})
+	return callback(entries)
+}
+
+// Close the cache after the sync has finished.
+//
+// If success is set and the whole destination was listed then the
+// cache is marked as verified.
+func (c *Cache) Close(ctx context.Context, success bool) (err error) {
+	if c == nil {
+		return nil
+	}
+	if c.stopNotify != nil {
+		c.stopNotify()
+	}
+	defer func() {
+		stopErr := c.db.Stop(false)
+		if err == nil {
+			err = stopErr
+		}
+	}()
+	c.mu.Lock()
+	op := &kv.OpDelete{}
+	if c.invalid {
+		op.Prefixes = append(op.Prefixes, c.dirKey(""))
+	} else {
+		for dir, subtree := range c.dirty {
+			switch {
+			case subtree && dir == "":
+				op.Prefixes = append(op.Prefixes, c.dirKey(""))
+			case subtree:
+				op.Prefixes = append(op.Prefixes, c.dirKey(dir)+"/")
+				fallthrough
+			default:
+				op.Keys = append(op.Keys, c.dirKey(dir))
+			}
+		}
+	}
+	c.dirty = make(map[string]bool)
+	c.mu.Unlock()
+	if err = c.db.Do(true, op); err != nil {
+		return fmt.Errorf("failed to update destination listing cache: %w", err)
+	}
+
+	if c.invalid {
+		c.meta = metaRecord{}
+		return c.saveMeta()
+	}
+
+	// Record the state of the root after our changes
+	rootEntries, err := c.listLive(ctx, "")
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		c.meta = metaRecord{}
+		_ = c.saveMeta()
+		return err
+	}
+	if rootEntries != nil {
+		c.store("", rootEntries)
+	}
+	c.meta.Root = fingerprint(ctx, rootEntries)
+	c.meta.Running = false
+	if success && !c.useCache {
+		c.meta.Verified = time.Now()
+	}
+	return c.saveMeta()
+}
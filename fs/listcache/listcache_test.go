@@ -0,0 +1,154 @@
+package listcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Some times used in the tests
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+)
+
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// listDir lists dir through the cache
+func listDir(ctx context.Context, t *testing.T, c *Cache, dir string) (entries fs.DirEntries) {
+	keyFn := func(entry fs.DirEntry) string { return entry.Remote() }
+	err := c.ListDir(ctx, dir, true, func(newEntries fs.DirEntries) error {
+		entries = append(entries, newEntries...)
+		return nil
+	}, list.KeyFn(keyFn))
+	require.NoError(t, err)
+	return entries
+}
+
+// remotes returns the remotes of the entries
+func remotes(entries fs.DirEntries) (out []string) {
+	for _, entry := range entries {
+		out = append(out, entry.Remote())
+	}
+	return out
+}
+
+// isCached returns true if the object was read from the cache
+func isCached(t *testing.T, entry fs.DirEntry) bool {
+	o, ok := entry.(*Object)
+	require.True(t, ok, "expecting *Object got %T", entry)
+	return o.UnWrap() == nil
+}
+
+func TestCache(t *testing.T) {
+	if !kv.Supported() {
+		t.Skip("kv not supported on this OS")
+	}
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+
+	// Keep the database open so it isn't removed between runs
+	db, err := kv.Start(ctx, facility, r.Fremote)
+	require.NoError(t, err)
+	defer func() { _ = db.Stop(false) }()
+
+	r.WriteObject(ctx, "top", "top", t1)
+	r.WriteObject(ctx, "dir/sub/one", "one", t1)
+	r.WriteObject(ctx, "dir/sub/two", "two", t1)
+
+	// First use lists everything
+	c, err := New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	assert.Equal(t, []string{"dir", "top"}, remotes(listDir(ctx, t, c, "")))
+	assert.Equal(t, []string{"dir/sub"}, remotes(listDir(ctx, t, c, "dir")))
+	entries := listDir(ctx, t, c, "dir/sub")
+	assert.Equal(t, []string{"dir/sub/one", "dir/sub/two"}, remotes(entries))
+	assert.False(t, isCached(t, entries[0]))
+	require.NoError(t, c.Close(ctx, true))
+
+	// Second use reads from the cache
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, c.useCache)
+	entries = listDir(ctx, t, c, "dir/sub")
+	assert.Equal(t, []string{"dir/sub/one", "dir/sub/two"}, remotes(entries))
+	require.True(t, isCached(t, entries[0]))
+	assert.Equal(t, int64(3), entries[0].Size())
+	assert.True(t, t1.Equal(entries[0].(fs.Object).ModTime(ctx)))
+
+	// Changing an object through the cache invalidates its directory
+	require.NoError(t, entries[1].(fs.Object).Remove(ctx))
+	entries = listDir(ctx, t, c, "dir/sub")
+	assert.Equal(t, []string{"dir/sub/one"}, remotes(entries))
+	assert.False(t, isCached(t, entries[0]))
+	require.NoError(t, c.Close(ctx, true))
+
+	// The changed directory is read and cached again next time
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, c.useCache)
+	entries = listDir(ctx, t, c, "dir/sub")
+	assert.Equal(t, []string{"dir/sub/one"}, remotes(entries))
+	assert.False(t, isCached(t, entries[0]))
+	require.NoError(t, c.Close(ctx, true))
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, c.useCache)
+
+	// Changes made behind the cache's back aren't noticed...
+	obj, err := r.Fremote.NewObject(ctx, "dir/sub/one")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+	entries = listDir(ctx, t, c, "dir/sub")
+	assert.Equal(t, []string{"dir/sub/one"}, remotes(entries))
+	assert.True(t, isCached(t, entries[0]))
+
+	// ...until the object is needed which invalidates the whole cache
+	_, err = entries[0].(fs.Object).Open(ctx)
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	require.NoError(t, c.Close(ctx, true))
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	assert.Empty(t, listDir(ctx, t, c, "dir/sub"))
+	require.NoError(t, c.Close(ctx, true))
+
+	// A change to the root is noticed
+	r.WriteObject(ctx, "top2", "top2", t1)
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	require.NoError(t, c.Close(ctx, true))
+
+	// A sync which doesn't finish isn't trusted
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, c.useCache)
+	require.NoError(t, c.db.Stop(false)) // rclone was killed
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	require.NoError(t, c.Close(ctx, false))
+
+	// A full listing which failed isn't trusted
+	c, err = New(ctx, r.Fremote, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	require.NoError(t, c.Close(ctx, true))
+
+	// The cache is verified again when it gets too old
+	c, err = New(ctx, r.Fremote, time.Nanosecond)
+	require.NoError(t, err)
+	assert.False(t, c.useCache)
+	require.NoError(t, c.Close(ctx, true))
+}
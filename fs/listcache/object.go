@@ -0,0 +1,284 @@
+package listcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Object is an object listed through the listing cache.
+//
+// If it was read from the cache then it reads the real object from
+// the Fs when it is needed, for example to read or write its data.
+//
+// Changing the object invalidates the cached listing of its
+// directory.
+type Object struct {
+	c       *Cache
+	remote  string
+	size    int64
+	modTime time.Time            // zero if not cached
+	hashes  map[hash.Type]string // cached hashes
+	mu      sync.Mutex           // protect obj
+	obj     fs.Object            // the real object if read
+}
+
+// resolve reads the real object from the Fs if necessary
+func (o *Object) resolve(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.obj != nil {
+		return o.obj, nil
+	}
+	obj, err := o.c.f.NewObject(ctx, o.remote)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		o.c.setInvalid(o.remote, "object not found")
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	if obj.Size() != o.size {
+		o.c.setInvalid(o.remote, "size changed")
+	}
+	o.obj = obj
+	return obj, nil
+}
+
+// cached returns the real object if it has been read, or nil
+func (o *Object) cached() fs.Object {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.obj
+}
+
+// changed is called when the object has been changed
+func (o *Object) changed() {
+	o.c.Invalidate(parentDir(o.remote))
+}
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.c.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if obj := o.cached(); obj != nil {
+		return obj.ModTime(ctx)
+	}
+	if !o.modTime.IsZero() {
+		return o.modTime
+	}
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		fs.Logf(o, "Failed to read modification time: %v", err)
+		return time.Now()
+	}
+	return obj.ModTime(ctx)
+}
+
+// Size returns the size of the object in bytes
+func (o *Object) Size() int64 {
+	if obj := o.cached(); obj != nil {
+		return obj.Size()
+	}
+	return o.size
+}
+
+// Hash returns the selected checksum of the object
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if obj := o.cached(); obj != nil {
+		return obj.Hash(ctx, ht)
+	}
+	if sum, ok := o.hashes[ht]; ok {
+		return sum, nil
+	}
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ht)
+}
+
+// Storable returns whether the object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	o.changed()
+	return obj.SetModTime(ctx, t)
+}
+
+// Open opens the object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update the object with the contents of the io.Reader
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	o.changed()
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove the object
+func (o *Object) Remove(ctx context.Context) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	o.changed()
+	return obj.Remove(ctx)
+}
+
+// Metadata returns the metadata of the object
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	do, ok := obj.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// SetMetadata sets metadata for an Object
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	do, ok := obj.(fs.SetMetadataer)
+	if !ok {
+		return fs.ErrorNotImplemented
+	}
+	o.changed()
+	return do.SetMetadata(ctx, metadata)
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return ""
+	}
+	if do, ok := obj.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	obj, err := o.resolve(context.Background())
+	if err != nil {
+		return ""
+	}
+	if do, ok := obj.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// GetTier returns the storage tier of the Object
+func (o *Object) GetTier() string {
+	obj, err := o.resolve(context.Background())
+	if err != nil {
+		return ""
+	}
+	if do, ok := obj.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// SetTier changes the storage tier of the Object
+func (o *Object) SetTier(tier string) error {
+	obj, err := o.resolve(context.Background())
+	if err != nil {
+		return err
+	}
+	do, ok := obj.(fs.SetTierer)
+	if !ok {
+		return errors.New("listcache: underlying remote does not support SetTier")
+	}
+	return do.SetTier(tier)
+}
+
+// UnWrap returns the real object if it has been read
+func (o *Object) UnWrap() fs.Object {
+	return o.cached()
+}
+
+// Directory is a directory read from the listing cache.
+//
+// Use Cache.Resolve to read the directory from the Fs before changing
+// it.
+type Directory struct {
+	*fs.Dir
+	c *Cache
+}
+
+// resolve reads the directory from the listing of its parent
+func (d *Directory) resolve(ctx context.Context) (fs.Directory, error) {
+	remote := d.Remote()
+	entries, err := d.c.listLive(ctx, parentDir(remote))
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(fs.Directory); ok && dir.Remote() == remote {
+			return dir, nil
+		}
+	}
+	d.c.setInvalid(remote, "directory not found")
+	return nil, fs.ErrorDirNotFound
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+	_ fmt.Stringer       = (*Object)(nil)
+	_ fs.Directory       = (*Directory)(nil)
+)
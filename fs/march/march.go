@@ -14,6 +14,7 @@ import (
 	"github.com/rclone/rclone/fs/dirtree"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/listcache"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/transform"
 	"golang.org/x/sync/semaphore"
@@ -34,17 +35,19 @@ type listDirFn func(ctx context.Context, dir string, callback fs.ListRCallback)
 // calling Callback for each match
 type March struct {
 	// parameters
-	Ctx                    context.Context // context for background goroutines
-	Fdst                   fs.Fs           // source Fs
-	Fsrc                   fs.Fs           // dest Fs
-	Dir                    string          // directory
-	NoTraverse             bool            // don't traverse the destination
-	SrcIncludeAll          bool            // don't include all files in the src
-	DstIncludeAll          bool            // don't include all files in the destination
-	Callback               Marcher         // object to call with results
-	NoCheckDest            bool            // transfer all objects regardless without checking dst
-	NoUnicodeNormalization bool            // don't normalize unicode characters in filenames
-	NoProcessDstOnly       bool            // if set, when source listing finishes, cancel the dst listing
+	Ctx                    context.Context  // context for background goroutines
+	Fdst                   fs.Fs            // source Fs
+	Fsrc                   fs.Fs            // dest Fs
+	Dir                    string           // directory
+	NoTraverse             bool             // don't traverse the destination
+	SrcIncludeAll          bool             // don't include all files in the src
+	DstIncludeAll          bool             // don't include all files in the destination
+	Callback               Marcher          // object to call with results
+	NoCheckDest            bool             // transfer all objects regardless without checking dst
+	NoUnicodeNormalization bool             // don't normalize unicode characters in filenames
+	NoProcessDstOnly       bool             // if set, when source listing finishes, cancel the dst listing
+	DirCallback            DirMarcher       // if set, called with whole directories
+	DstListCache           *listcache.Cache // if set, list the destination from this cache
 	// internal state
 	srcListDir   listDirFn // function to call to list a directory in the src
 	dstListDir   listDirFn // function to call to list a directory in the dst
@@ -62,12 +65,33 @@ type Marcher interface {
 	Match(ctx context.Context, dst, src fs.DirEntry) (recurse bool)
 }
 
+// DirMarcher is called with whole directories if set in DirCallback
+//
+// Note that setting this means the source listing of each directory
+// is read completely before the destination is listed.
+type DirMarcher interface {
+	// SrcDir is called with the complete source listing of dir
+	// before any of its entries are matched. If it returns true
+	// then the entries of dir aren't matched and the destination
+	// isn't listed, but the subdirectories are still traversed.
+	SrcDir(dir string, entries fs.DirEntries) (skip bool)
+	// DirDone is called when all the entries of the source
+	// directory dir have been passed to the Marcher. dstOnly is
+	// the number of entries found only in the destination and err
+	// is set if either listing failed.
+	DirDone(dir string, dstOnly int, err error)
+}
+
 // init sets up a march over opt.Fsrc, and opt.Fdst calling back callback for each match
 // Note: this will flag filter-aware backends on the source side
 func (m *March) init(ctx context.Context) {
 	ci := fs.GetConfig(ctx)
 	m.srcListDir = m.makeListDir(ctx, m.Fsrc, m.SrcIncludeAll, m.srcKey)
-	if !m.NoTraverse {
+	if !m.NoTraverse && m.DstListCache != nil {
+		m.dstListDir = func(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+			return m.DstListCache.ListDir(ctx, dir, m.DstIncludeAll, callback, m.dstKey)
+		}
+	} else if !m.NoTraverse {
 		m.dstListDir = m.makeListDir(ctx, m.Fdst, m.DstIncludeAll, m.dstKey)
 	}
 	// Now create the matching transform
@@ -408,11 +432,45 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 		wg                     sync.WaitGroup
 		ci                     = fs.GetConfig(m.Ctx)
 		dstCtx, dstCancel      = context.WithCancel(m.Ctx)
+		dstOnly                int
 	)
 	defer dstCancel()
 
+	// If the Marcher wants whole directories then read the source
+	// listing first so it can decide whether to skip it.
+	dirMarcher := m.DirCallback
+	if job.noSrc {
+		dirMarcher = nil
+	}
+	var srcEntries fs.DirEntries
+	if dirMarcher != nil {
+		srcListErr = m.srcListDir(m.Ctx, job.srcRemote, func(entries fs.DirEntries) error {
+			srcEntries = append(srcEntries, entries...)
+			return nil
+		})
+		if srcListErr != nil {
+			dirMarcher.DirDone(job.srcRemote, 0, srcListErr)
+			return nil, m.srcListError(job, srcListErr)
+		}
+		if dirMarcher.SrcDir(job.srcRemote, srcEntries) {
+			return m.skipJob(job, srcEntries), nil
+		}
+	}
+
 	// List the src and dst directories
-	if !job.noSrc {
+	if dirMarcher != nil {
+		srcChan := srcChan // duplicate this as we may override it later
+		go func() {
+			defer close(srcChan)
+			for _, entry := range srcEntries {
+				select {
+				case <-m.Ctx.Done():
+					return
+				case srcChan <- entry:
+				}
+			}
+		}()
+	} else if !job.noSrc {
 		srcChan := srcChan // duplicate this as we may override it later
 		wg.Go(func() {
 			srcListErr = m.srcListDir(m.Ctx, job.srcRemote, func(entries fs.DirEntries) error {
@@ -535,6 +593,7 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 			})
 		}
 	}, func(dst fs.DirEntry) {
+		dstOnly++
 		recurse := m.Callback.DstOnly(dst)
 		if recurse && job.dstDepth > 0 {
 			jobs = append(jobs, listDirJob{
@@ -556,24 +615,22 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 		}
 	})
 	if err != nil {
+		if dirMarcher != nil {
+			dirMarcher.DirDone(job.srcRemote, dstOnly, err)
+		}
 		return nil, err
 	}
 
 	// Wait for listings to complete and report errors
 	wg.Wait()
 	if srcListErr != nil {
-		if job.srcRemote != "" {
-			fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
-		} else {
-			fs.Errorf(m.Fsrc, "error reading source root directory: %v", srcListErr)
-		}
-		srcListErr = fs.CountError(m.Ctx, srcListErr)
-		return nil, srcListErr
+		return nil, m.srcListError(job, srcListErr)
 	}
 	if dstListCancelled {
 		// Ignore dst listing errors if we cancelled it
 	} else if dstListErr == fs.ErrorDirNotFound {
 		// Copy the stuff anyway
+		dstListErr = nil
 	} else if dstListErr != nil {
 		if job.dstRemote != "" {
 			fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
@@ -581,8 +638,48 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 			fs.Errorf(m.Fdst, "error reading destination root directory: %v", dstListErr)
 		}
 		dstListErr = fs.CountError(m.Ctx, dstListErr)
+	}
+	if dirMarcher != nil {
+		dirMarcher.DirDone(job.srcRemote, dstOnly, dstListErr)
+	}
+	if dstListErr != nil {
 		return nil, dstListErr
 	}
 
 	return jobs, nil
 }
+
+// srcListError logs and counts an error listing the source of job
+func (m *March) srcListError(job listDirJob, err error) error {
+	if job.srcRemote != "" {
+		fs.Errorf(job.srcRemote, "error reading source directory: %v", err)
+	} else {
+		fs.Errorf(m.Fsrc, "error reading source root directory: %v", err)
+	}
+	return fs.CountError(m.Ctx, err)
+}
+
+// skipJob returns the jobs needed to traverse the subdirectories of a
+// source directory whose entries are not being matched.
+func (m *March) skipJob(job listDirJob, srcEntries fs.DirEntries) (jobs []listDirJob) {
+	if job.srcDepth <= 0 {
+		return nil
+	}
+	for _, entry := range srcEntries {
+		if _, ok := entry.(fs.Directory); !ok {
+			continue
+		}
+		newJob := listDirJob{
+			srcRemote: entry.Remote(),
+			dstRemote: entry.Remote(),
+			srcDepth:  job.srcDepth - 1,
+		}
+		if job.noDst || job.dstDepth <= 0 {
+			newJob.noDst = true
+		} else {
+			newJob.dstDepth = job.dstDepth - 1
+		}
+		jobs = append(jobs, newJob)
+	}
+	return jobs
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -380,6 +381,70 @@ func TestMarchNoProcessDstOnly(t *testing.T) {
 	}
 }
 
+// dirMarchTester records the calls to the DirMarcher interface
+type dirMarchTester struct {
+	mu      sync.Mutex
+	skip    string         // directory to skip
+	srcDirs []string       // directories passed to SrcDir
+	done    map[string]int // dstOnly count for directories passed to DirDone
+}
+
+// SrcDir is called with the source listing of each directory
+func (dm *dirMarchTester) SrcDir(dir string, entries fs.DirEntries) (skip bool) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.srcDirs = append(dm.srcDirs, dir)
+	return dir == dm.skip
+}
+
+// DirDone is called when a directory is finished
+func (dm *dirMarchTester) DirDone(dir string, dstOnly int, err error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if err == nil {
+		dm.done[dir] = dstOnly
+	}
+}
+
+func TestMarchDirCallback(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	match := r.WriteBoth(ctx, "match", "hello world", t1)
+	r.WriteBoth(ctx, "skip dir/file", "hello world", t1)
+	subMatch := r.WriteBoth(ctx, "skip dir/sub/file2", "hello world", t1)
+	dstOnly := r.WriteObject(ctx, "dstOnly", "hello world", t1)
+
+	mt := &marchTester{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	dm := &dirMarchTester{
+		skip: "skip dir",
+		done: make(map[string]int),
+	}
+	m := &March{
+		Ctx:         ctx,
+		Fdst:        r.Fremote,
+		Fsrc:        r.Flocal,
+		Dir:         "",
+		Callback:    mt,
+		DirCallback: dm,
+	}
+	mt.processError(m.Run(ctx))
+	mt.cancel()
+	require.NoError(t, mt.currentError())
+
+	// The entries of the skipped directory aren't matched but its
+	// subdirectories are still traversed.
+	precision := fs.GetModifyWindow(ctx, r.Fremote, r.Flocal)
+	fstest.CompareItems(t, mt.match, []fstest.Item{match, subMatch}, []string{"skip dir"}, precision, "match")
+	fstest.CompareItems(t, mt.dstOnly, []fstest.Item{dstOnly}, nil, precision, "dstOnly")
+	slices.Sort(dm.srcDirs)
+	assert.Equal(t, []string{"", "skip dir", "skip dir/sub"}, dm.srcDirs)
+	assert.Equal(t, map[string]int{"": 1, "skip dir/sub": 0}, dm.done)
+}
+
 // TestMarchNoGoroutineLeak checks that a march which completes normally (its
 // context is never cancelled, as with an async rc job) does not strand the
 // janitor goroutine that drains the job channel (See #9620)
@@ -88,6 +88,11 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = setRemoteBwLimit(configName, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configName, err)
+	}
+	ctx = context.WithValue(ctx, pacerRemoteKey, configName)
 	f, err := fsInfo.NewFs(ctx, configName, fsPath, config)
 	if f != nil && (err == nil || err == ErrorIsFile) {
 		addReverse(f, fsInfo)
@@ -19,9 +19,11 @@ import (
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/transform"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // State of the copy
@@ -39,8 +41,15 @@ type copy struct {
 	tr            *accounting.Transfer // accounting for the transfer
 	inplace       bool                 // set if we are updating inplace and not using a partial name
 	remoteForCopy string               // the name used for the transfer, either remote or remote+".partial"
+	verifyType    hash.Type            // hash to read the copy back with for --verify, or hash.None
+	srcHasher     *hash.MultiHasher    // hash of the source made as it was read for --verify, may be nil
+	verified      bool                 // set if the copy was read back and verified
+	verifyTries   int                  // number of times the copy has been redone after failing verification
 }
 
+// errVerifyFailed is returned when --verify fails to read back a copy
+var errVerifyFailed = errors.New("read back verification failed")
+
 // Used to remove a failed copy
 func (c *copy) removeFailedCopy(ctx context.Context, o fs.Object) {
 	if o == nil {
@@ -152,7 +161,9 @@ func (c *copy) serverSideCopy(ctx context.Context) (actionTaken string, newDst f
 	in := c.tr.Account(ctx, nil) // account the transfer
 	in.ServerSideTransferStart()
 	newCtx, ta := in.NewServerSideCopyAccounter(ctx)
-	newDst, err = doCopy(newCtx, c.src, c.remoteForCopy)
+	spanCtx, span := tracing.StartFs(newCtx, "Copy", c.f, c.remoteForCopy)
+	newDst, err = doCopy(spanCtx, c.src, c.remoteForCopy)
+	tracing.End(span, err)
 	if err == nil {
 		var n int64
 		if !ta.Started() {
@@ -220,13 +231,17 @@ func (c *copy) updateOrPut(ctx context.Context, in io.ReadCloser, uploadOptions
 		wrappedSrc = fs.NewOverrideRemote(c.src, c.remoteForCopy)
 	}
 	if c.doUpdate && c.inplace {
-		err = c.dst.Update(ctx, inAcc, wrappedSrc, uploadOptions...)
+		spanCtx, span := tracing.StartFs(ctx, "Update", c.f, c.remoteForCopy)
+		err = c.dst.Update(spanCtx, inAcc, wrappedSrc, uploadOptions...)
+		tracing.End(span, err)
 		// Make sure newDst is c.dst since we updated it
 		if err == nil {
 			newDst = c.dst
 		}
 	} else {
-		newDst, err = c.f.Put(ctx, inAcc, wrappedSrc, uploadOptions...)
+		spanCtx, span := tracing.StartFs(ctx, "Put", c.f, c.remoteForCopy)
+		newDst, err = c.f.Put(spanCtx, inAcc, wrappedSrc, uploadOptions...)
+		tracing.End(span, err)
 	}
 	closeErr := inAcc.Close()
 	if err == nil {
@@ -279,6 +294,9 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 	if err != nil {
 		return actionTaken, nil, fmt.Errorf("failed to open source object: %w", err)
 	}
+	if c.verifyType != hash.None {
+		in = c.hashSource(in)
+	}
 
 	// Note that c.rcat and c.updateOrPut close in
 	if c.src.Size() == -1 {
@@ -287,6 +305,69 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 	return c.updateOrPut(ctx, in, uploadOptions)
 }
 
+// Hash the source as it is read so the copy can be read back and
+// compared with it without reading the source again.
+func (c *copy) hashSource(in io.ReadCloser) io.ReadCloser {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(c.verifyType))
+	if err != nil {
+		fs.Debugf(c.src, "Failed to hash source while copying: %v", err)
+		return in
+	}
+	c.srcHasher = hasher
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(in, hasher), in}
+}
+
+// Read o and return its hash of type ht
+func readBackHash(ctx context.Context, o fs.Object, ht hash.Type) (sum string, err error) {
+	in, err := Open(ctx, o)
+	if err != nil {
+		return "", err
+	}
+	defer fs.CheckClose(in, &err)
+	sums, err := hash.StreamTypes(in, hash.NewHashSet(ht))
+	if err != nil {
+		return "", err
+	}
+	return sums[ht], nil
+}
+
+// Read newDst back and check it against the hash of the source
+//
+// This is used with --verify when the source and destination don't
+// have a hash in common.
+func (c *copy) verifyReadBack(ctx context.Context, newDst fs.Object) (err error) {
+	defer func() {
+		if err != nil {
+			accounting.Stats(ctx).VerifyFailures(1)
+		}
+	}()
+	var srcSum string
+	if c.srcHasher != nil && (c.src.Size() < 0 || c.srcHasher.Size() == c.src.Size()) {
+		srcSum, err = c.srcHasher.SumString(c.verifyType, false)
+	} else {
+		// The source wasn't streamed through the copy, for
+		// example a server-side or multi-thread copy, so read it
+		srcSum, err = readBackHash(ctx, c.src, c.verifyType)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to hash source: %w", errVerifyFailed, err)
+	}
+	dstSum, err := readBackHash(ctx, newDst, c.verifyType)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read back: %w", errVerifyFailed, err)
+	}
+	if srcSum != dstSum {
+		return fmt.Errorf("%w: corrupted on transfer: %v hashes differ src(%s) %q vs read back dst(%s) %q", errVerifyFailed, c.verifyType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
+	}
+	fs.Debugf(newDst, "Verified by reading back: %v = %s", c.verifyType, dstSum)
+	accounting.Stats(ctx).Verified(1)
+	c.verified = true
+	return nil
+}
+
 // Verify the copy
 func (c *copy) verify(ctx context.Context, newDst fs.Object) (err error) {
 	// Verify sizes are the same after transfer
@@ -301,6 +382,10 @@ func (c *copy) verify(ctx context.Context, newDst fs.Object) (err error) {
 			return fmt.Errorf("corrupted on transfer: %v hashes differ src(%s) %q vs dst(%s) %q", c.hashType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
 		}
 	}
+	// Read the copy back if there is no common hash
+	if c.verifyType != hash.None {
+		return c.verifyReadBack(ctx, newDst)
+	}
 	return nil
 }
 
@@ -360,8 +445,20 @@ func (c *copy) copy(ctx context.Context) (newDst fs.Object, err error) {
 	err = c.verify(ctx, newDst)
 	if err != nil {
 		fs.Errorf(newDst, "%v", err)
-		err = fs.CountError(ctx, err)
 		c.removeFailedCopy(ctx, newDst)
+		if errors.Is(err, errVerifyFailed) && c.verifyTries+1 < c.maxTries {
+			c.verifyTries++
+			fs.Logf(c.src, "Copying again after read back verification failed: retry %d/%d", c.verifyTries, c.maxTries-1)
+			c.tr.Reset(ctx) // skip the failed accounting - will be overwritten by the new copy
+			c.srcHasher = nil
+			if c.inplace {
+				// newDst was c.dst and has been removed
+				c.dst = nil
+				c.doUpdate = false
+			}
+			return c.copy(ctx)
+		}
+		err = fs.CountError(ctx, err)
 		return nil, err
 	}
 
@@ -378,6 +475,11 @@ func (c *copy) copy(ctx context.Context) (newDst fs.Object, err error) {
 		newDst = movedNewDst
 	}
 
+	if c.verified {
+		logger, _ := GetLogger(ctx)
+		logger(ctx, Verified, c.src, newDst, nil)
+	}
+
 	// Log what we have done
 	if newDst != nil && c.src.String() != newDst.String() {
 		actionTaken = fmt.Sprintf("%s to: %s", actionTaken, newDst.String())
@@ -393,6 +495,13 @@ func (c *copy) copy(ctx context.Context) (newDst fs.Object, err error) {
 // It returns the destination object if possible.  Note that this may
 // be nil.
 func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+	ctx, span := tracing.Start(ctx, "transfer",
+		attribute.String("rclone.src", fs.ConfigString(src.Fs())),
+		attribute.String("rclone.dst", fs.ConfigString(f)),
+		attribute.String("rclone.remote", remote),
+		attribute.Int64("rclone.size", src.Size()),
+	)
+	defer func() { tracing.End(span, err) }()
 	ci := fs.GetConfig(ctx)
 	tr := accounting.Stats(ctx).NewTransfer(src, f)
 	defer func() {
@@ -415,6 +524,9 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 		doUpdate:    dst != nil,
 	}
 	c.hashType, c.hashOption = CommonHash(ctx, f, src.Fs())
+	if ci.Verify && c.hashType == hash.None && !ci.IgnoreChecksum {
+		c.verifyType = hash.Supported().GetOne()
+	}
 	if c.dst != nil {
 		c.remote = transform.Path(ctx, c.dst.Remote(), false)
 	}
@@ -477,7 +477,7 @@ func TestCopyFileMaxTransfer(t *testing.T) {
 	ci.MaxTransfer = sizeCutoff
 	ci.CutoffMode = fs.CutoffModeHard
 
-	if runtime.GOOS == "darwin" {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
 		// disable server-side copies as they don't count towards transfer size stats
 		r.Flocal.Features().Disable("Copy")
 		if r.Fremote.Features().IsLocal {
@@ -532,3 +532,33 @@ func TestCopyFileMaxTransfer(t *testing.T) {
 	r.CheckLocalItems(t, file1, file2, file3, file4)
 	r.CheckRemoteItems(t, file1, file4)
 }
+
+func TestCopyFileVerify(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer accounting.Stats(ctx).ResetCounters()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	r.CheckLocalItems(t, file1)
+
+	// Make a source with no hashes so there is nothing in common
+	fsrc, err := fs.NewFs(ctx, ":local,hashes=none:"+r.LocalName)
+	require.NoError(t, err)
+	require.Equal(t, 0, fsrc.Hashes().Count())
+
+	ci.Verify = true
+	opt := operations.NewLoggerOpt()
+	opt.DestAfter = nil
+	opt.LoggerFn = operations.NewDefaultLoggerFn(&opt)
+	ctx = operations.WithSyncLogger(ctx, opt)
+	accounting.Stats(ctx).ResetCounters()
+
+	err = operations.CopyFile(ctx, r.Fremote, fsrc, file1.Path, file1.Path)
+	require.NoError(t, err)
+	r.CheckRemoteItems(t, file1)
+
+	assert.Equal(t, int64(1), accounting.Stats(ctx).Verified(0))
+	assert.Equal(t, int64(0), accounting.Stats(ctx).VerifyFailures(0))
+	assert.Contains(t, opt.Combined.(fmt.Stringer).String(), "v file1\n")
+}
@@ -384,18 +384,49 @@ func dedupeMergeDuplicateDirs(ctx context.Context, f fs.Fs, duplicateDirs [][]*d
 
 // sort oldest first
 func sortOldestFirst(objs []fs.Object) {
-	sort.Slice(objs, func(i, j int) bool {
+	sort.SliceStable(objs, func(i, j int) bool {
 		return objs[i].ModTime(context.TODO()).Before(objs[j].ModTime(context.TODO()))
 	})
 }
 
 // sort smallest first
 func sortSmallestFirst(objs []fs.Object) {
-	sort.Slice(objs, func(i, j int) bool {
+	sort.SliceStable(objs, func(i, j int) bool {
 		return objs[i].Size() < objs[j].Size()
 	})
 }
 
+// sort largest first
+func sortLargestFirst(objs []fs.Object) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		return objs[i].Size() > objs[j].Size()
+	})
+}
+
+// dedupeChooseKeep sorts objs as necessary and returns the index of
+// the object mode chooses to keep.
+//
+// It returns -1 if mode doesn't choose an object to keep.
+func dedupeChooseKeep(mode DeduplicateMode, objs []fs.Object) int {
+	switch mode {
+	case DeduplicateFirst:
+		return 0
+	case DeduplicateNewest:
+		sortOldestFirst(objs)
+		return len(objs) - 1
+	case DeduplicateOldest:
+		sortOldestFirst(objs)
+		return 0
+	case DeduplicateLargest:
+		sortLargestFirst(objs)
+		return 0
+	case DeduplicateSmallest:
+		sortSmallestFirst(objs)
+		return 0
+	}
+	return -1
+}
+
 // Deduplicate interactively finds duplicate files and offers to
 // delete all but one or rename them to be different. Only useful with
 // Google Drive which can have duplicate file names.
@@ -478,22 +509,10 @@ func Deduplicate(ctx context.Context, f fs.Fs, mode DeduplicateMode, byHash bool
 			if !dedupeInteractive(ctx, f, ht, remote, objs, byHash) {
 				return nil
 			}
-		case DeduplicateFirst:
-			dedupeDeleteAllButOne(ctx, 0, remote, objs)
-		case DeduplicateNewest:
-			sortOldestFirst(objs)
-			dedupeDeleteAllButOne(ctx, len(objs)-1, remote, objs)
-		case DeduplicateOldest:
-			sortOldestFirst(objs)
-			dedupeDeleteAllButOne(ctx, 0, remote, objs)
+		case DeduplicateFirst, DeduplicateNewest, DeduplicateOldest, DeduplicateLargest, DeduplicateSmallest:
+			dedupeDeleteAllButOne(ctx, dedupeChooseKeep(mode, objs), remote, objs)
 		case DeduplicateRename:
 			dedupeRename(ctx, f, remote, objs)
-		case DeduplicateLargest:
-			sortSmallestFirst(objs)
-			dedupeDeleteAllButOne(ctx, len(objs)-1, remote, objs)
-		case DeduplicateSmallest:
-			sortSmallestFirst(objs)
-			dedupeDeleteAllButOne(ctx, 0, remote, objs)
 		case DeduplicateSkip:
 			fs.Logf(remote, "Skipping %d files with duplicate %s", len(objs), what)
 		case DeduplicateList:
@@ -0,0 +1,366 @@
+// dupes - finds files with identical content across remotes
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/walk"
+	"golang.org/x/sync/errgroup"
+)
+
+// DupesMode says what Dupes does with the duplicates it finds
+type DupesMode = fs.Enum[dupesModeChoices]
+
+// DupesMode constants
+const (
+	DupesList   DupesMode = iota // list the duplicates only
+	DupesDelete                  // delete all but one of each group
+	DupesLink                    // replace all but one of each group with links
+)
+
+type dupesModeChoices struct{}
+
+func (dupesModeChoices) Choices() []string {
+	return []string{
+		DupesList:   "list",
+		DupesDelete: "delete",
+		DupesLink:   "link",
+	}
+}
+
+func (dupesModeChoices) Type() string {
+	return "string"
+}
+
+// DupesOpt controls what Dupes does
+type DupesOpt struct {
+	Mode     DupesMode       // what to do with the duplicates
+	Keep     DeduplicateMode // which of each group of duplicates to keep - first, newest, oldest, largest or smallest
+	Download bool            // hash every file by downloading it
+	JSON     bool            // list the duplicates as JSON
+}
+
+// DupeGroup is a group of files with identical content
+type DupeGroup struct {
+	Size     int64       // size of each file
+	HashType hash.Type   // the hash used to compare the files
+	Hash     string      // the hash of each file
+	Objects  []fs.Object // the files, the one to keep first
+}
+
+// dupesObject is an object found by FindDupes and the index of the
+// remote it was found in
+type dupesObject struct {
+	o     fs.Object
+	index int
+}
+
+// dupesPath returns the full path of o for showing to the user
+func dupesPath(o fs.Object) string {
+	return fspath.JoinRootPath(fs.ConfigString(o.Fs()), o.Remote())
+}
+
+// dupesHashType returns the hash supported by most of objs.
+//
+// It returns hash.None if none of them support any hashes.
+func dupesHashType(objs []dupesObject) hash.Type {
+	best, bestCount := hash.None, 0
+	for _, ht := range hash.Supported().Array() {
+		count := 0
+		for _, do := range objs {
+			if do.o.Fs().Hashes().Contains(ht) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = ht, count
+		}
+	}
+	return best
+}
+
+// dupesHash returns the hash of type ht for o, downloading o to
+// calculate it if the backend can't supply it or download is set.
+func dupesHash(ctx context.Context, ht hash.Type, download bool, o fs.Object) (string, error) {
+	if !download && o.Fs().Hashes().Contains(ht) {
+		sum, err := HashSum(ctx, ht, false, false, o)
+		if err != nil || sum != "" {
+			return sum, err
+		}
+		fs.Debugf(o, "No %v hash available from the backend - downloading to calculate it", ht)
+	}
+	return HashSum(ctx, ht, false, true, o)
+}
+
+// FindDupes finds files with identical content in the remotes passed
+// in.
+//
+// Files are grouped by size first, then the files of the same size
+// are compared using the hash most of them support, downloading any
+// files whose backend doesn't support it. Empty files are ignored.
+//
+// The objects in each group are returned in the order of the remotes
+// passed in, then by path.
+func FindDupes(ctx context.Context, fses []fs.Fs, download bool) (groups []*DupeGroup, err error) {
+	ci := fs.GetConfig(ctx)
+
+	// Find the files and group them by size
+	bySize := map[int64][]dupesObject{}
+	seen := map[string]struct{}{}
+	for i, f := range fses {
+		err := walk.ListR(ctx, f, "", false, ci.MaxDepth, walk.ListObjects, func(entries fs.DirEntries) error {
+			entries.ForObject(func(o fs.Object) {
+				size := o.Size()
+				if size <= 0 {
+					return
+				}
+				// Don't count a file twice if the remotes overlap
+				key := dupesPath(o)
+				if _, found := seen[key]; found {
+					return
+				}
+				seen[key] = struct{}{}
+				bySize[size] = append(bySize[size], dupesObject{o: o, index: i})
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %v: %w", f, err)
+		}
+	}
+
+	// Hash the files which share a size
+	type sizeHash struct {
+		size int64
+		sum  string
+	}
+	var (
+		mu        sync.Mutex
+		g         errgroup.Group
+		byHash    = map[sizeHash][]dupesObject{}
+		hashTypes = map[int64]hash.Type{}
+	)
+	g.SetLimit(ci.Checkers)
+	for size, objs := range bySize {
+		if len(objs) < 2 {
+			continue
+		}
+		ht := dupesHashType(objs)
+		if ht == hash.None || download {
+			ht = hash.MD5
+		}
+		hashTypes[size] = ht
+		for _, do := range objs {
+			g.Go(func() error {
+				sum, err := dupesHash(ctx, ht, download, do.o)
+				if err != nil {
+					err = fs.CountError(ctx, err)
+					fs.Errorf(do.o, "Failed to hash: %v", err)
+					return nil
+				}
+				key := sizeHash{size: size, sum: sum}
+				mu.Lock()
+				byHash[key] = append(byHash[key], do)
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+
+	// Files with the same size and hash are duplicates
+	for key, objs := range byHash {
+		if len(objs) < 2 {
+			continue
+		}
+		sort.Slice(objs, func(i, j int) bool {
+			if objs[i].index != objs[j].index {
+				return objs[i].index < objs[j].index
+			}
+			return objs[i].o.Remote() < objs[j].o.Remote()
+		})
+		group := &DupeGroup{
+			Size:     key.size,
+			HashType: hashTypes[key.size],
+			Hash:     key.sum,
+			Objects:  make([]fs.Object, len(objs)),
+		}
+		for i, do := range objs {
+			group.Objects[i] = do.o
+		}
+		groups = append(groups, group)
+	}
+
+	// Largest files first
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+	return groups, nil
+}
+
+// sortKeepFirst sorts the objects in the group so the one to keep is
+// first, choosing it in the same way as Deduplicate
+func (group *DupeGroup) sortKeepFirst(keep DeduplicateMode) {
+	i := dedupeChooseKeep(keep, group.Objects)
+	if i > 0 {
+		o := group.Objects[i]
+		group.Objects = slices.Insert(slices.Delete(group.Objects, i, i+1), 0, o)
+	}
+}
+
+// dupesListJSON is the JSON output of a DupeGroup
+type dupesListJSON struct {
+	Size     int64               `json:"size"`
+	HashType string              `json:"hashType"`
+	Hash     string              `json:"hash"`
+	Files    []dupesListFileJSON `json:"files"`
+}
+
+// dupesListFileJSON is the JSON output of a file in a DupeGroup
+type dupesListFileJSON struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"modTime"`
+	Keep    bool      `json:"keep"`
+}
+
+// dupesList writes the groups of duplicates to out
+func dupesList(ctx context.Context, groups []*DupeGroup, asJSON bool, out io.Writer) error {
+	if asJSON {
+		list := make([]dupesListJSON, 0, len(groups))
+		for _, group := range groups {
+			item := dupesListJSON{
+				Size:     group.Size,
+				HashType: group.HashType.String(),
+				Hash:     group.Hash,
+			}
+			for i, o := range group.Objects {
+				item.Files = append(item.Files, dupesListFileJSON{
+					Path:    dupesPath(o),
+					ModTime: o.ModTime(ctx),
+					Keep:    i == 0,
+				})
+			}
+			list = append(list, item)
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "\t")
+		return enc.Encode(list)
+	}
+	ci := fs.GetConfig(ctx)
+	for _, group := range groups {
+		SyncFprintf(out, "%d files of %s with %v %s\n", len(group.Objects), SizeString(group.Size, ci.HumanReadable), group.HashType, group.Hash)
+		for _, o := range group.Objects {
+			SyncFprintf(out, "  %s\n", dupesPath(o))
+		}
+	}
+	return nil
+}
+
+// errDupesLinkUnsupported is returned if a duplicate can't be
+// replaced with a link
+var errDupesLinkUnsupported = errors.New("can't replace duplicates with links on this remote")
+
+// dupesLink replaces dup with a link to keep.
+//
+// This uses the "hardlink" or "shortcut" backend command, so both
+// files must be in the same remote.
+func dupesLink(ctx context.Context, keep, dup fs.Object) error {
+	f, ok := dup.Fs().(fs.Fs)
+	if !ok || !Same(keep.Fs(), f) {
+		return fmt.Errorf("can't link to %s as it isn't in the same remote", dupesPath(keep))
+	}
+	doCommand := f.Features().Command
+	if doCommand == nil {
+		return errDupesLinkUnsupported
+	}
+	regInfo, _, _, _, err := fs.ConfigFs(fs.ConfigString(f))
+	if err != nil {
+		return err
+	}
+	hasCommand := func(name string) bool {
+		return slices.ContainsFunc(regInfo.CommandHelp, func(c fs.CommandHelp) bool { return c.Name == name })
+	}
+	switch {
+	case hasCommand("hardlink"):
+		if SkipDestructive(ctx, dup, "replace with a hard link") {
+			return nil
+		}
+		_, err = doCommand(ctx, "hardlink", []string{keep.Remote(), dup.Remote()}, nil)
+	case hasCommand("shortcut"):
+		if SkipDestructive(ctx, dup, "replace with a shortcut") {
+			return nil
+		}
+		// The shortcut can't be made until the duplicate has gone
+		err = dup.Remove(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to remove before making shortcut: %w", err)
+		}
+		_, err = doCommand(ctx, "shortcut", []string{keep.Remote(), dup.Remote()}, nil)
+	default:
+		return errDupesLinkUnsupported
+	}
+	if err != nil {
+		return err
+	}
+	fs.Infof(dup, "Replaced with a link to %s", dupesPath(keep))
+	return nil
+}
+
+// Dupes finds files with identical content in the remotes passed in
+// and lists them, deletes all but one of each group or replaces the
+// others with links to it, according to opt.
+//
+// The list is written to out.
+func Dupes(ctx context.Context, fses []fs.Fs, opt DupesOpt, out io.Writer) error {
+	switch opt.Keep {
+	case DeduplicateFirst, DeduplicateNewest, DeduplicateOldest, DeduplicateLargest, DeduplicateSmallest:
+	default:
+		return fmt.Errorf("can't choose which duplicate to keep with %q - use first, newest, oldest, largest or smallest", opt.Keep)
+	}
+	fs.Infof(nil, "Looking for files with duplicate content in %d remotes", len(fses))
+	groups, err := FindDupes(ctx, fses, opt.Download)
+	if err != nil {
+		return err
+	}
+	var wasted int64
+	for _, group := range groups {
+		group.sortKeepFirst(opt.Keep)
+		wasted += group.Size * int64(len(group.Objects)-1)
+	}
+	fs.Infof(nil, "Found %d groups of duplicates using %s", len(groups), SizeString(wasted, true))
+	if opt.Mode == DupesList {
+		return dupesList(ctx, groups, opt.JSON, out)
+	}
+	for _, group := range groups {
+		keep := group.Objects[0]
+		for _, dup := range group.Objects[1:] {
+			switch opt.Mode {
+			case DupesDelete:
+				// DeleteFile counts and logs any errors
+				_ = DeleteFile(ctx, dup)
+			case DupesLink:
+				err := dupesLink(ctx, keep, dup)
+				if err != nil {
+					err = fs.CountError(ctx, err)
+					fs.Errorf(dup, "Failed to replace with a link: %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package operations_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check flags satisfy the interface
+var (
+	_ pflag.Value = (*operations.DupesMode)(nil)
+)
+
+// dupesPaths returns the remotes of each group of duplicates
+func dupesPaths(groups []*operations.DupeGroup) (paths [][]string) {
+	for _, group := range groups {
+		var remotes []string
+		for _, o := range group.Objects {
+			remotes = append(remotes, fs.ConfigString(o.Fs())+" "+o.Remote())
+		}
+		paths = append(paths, remotes)
+	}
+	return paths
+}
+
+func TestFindDupes(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	local, remote := fs.ConfigString(r.Flocal)+" ", fs.ConfigString(r.Fremote)+" "
+
+	r.WriteFile("a/one", "Duplicate one", t1)
+	r.WriteFile("b/one", "Duplicate one", t2)
+	r.WriteFile("same-size", "Not the same!", t1)
+	r.WriteFile("empty", "", t1)
+	r.WriteObject(ctx, "copy/one", "Duplicate one", t3)
+	r.WriteObject(ctx, "empty", "", t1)
+	r.WriteObject(ctx, "two", "Duplicate two - longer", t1)
+	r.WriteObject(ctx, "dir/two", "Duplicate two - longer", t2)
+	r.WriteObject(ctx, "unique", "Unique", t1)
+
+	for _, download := range []bool{false, true} {
+		groups, err := operations.FindDupes(ctx, []fs.Fs{r.Flocal, r.Fremote}, download)
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{remote + "dir/two", remote + "two"},
+			{local + "a/one", local + "b/one", remote + "copy/one"},
+		}, dupesPaths(groups))
+		assert.Equal(t, int64(22), groups[0].Size)
+		assert.NotEqual(t, "", groups[0].Hash)
+	}
+}
+
+func TestDupesList(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	skipIfNoModTime(t, r.Fremote)
+
+	r.WriteObject(ctx, "one", "Duplicate", t1)
+	r.WriteObject(ctx, "two", "Duplicate", t2)
+	r.WriteObject(ctx, "three", "Duplicate", t3)
+
+	var buf bytes.Buffer
+	opt := operations.DupesOpt{JSON: true, Keep: operations.DeduplicateNewest}
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote}, opt, &buf))
+	var list []struct {
+		Size  int64 `json:"size"`
+		Files []struct {
+			Path string `json:"path"`
+			Keep bool   `json:"keep"`
+		} `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, int64(9), list[0].Size)
+	require.Len(t, list[0].Files, 3)
+	assert.Contains(t, list[0].Files[0].Path, "three")
+	assert.True(t, list[0].Files[0].Keep)
+	assert.False(t, list[0].Files[1].Keep)
+
+	buf.Reset()
+	opt = operations.DupesOpt{Keep: operations.DeduplicateFirst}
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote}, opt, &buf))
+	assert.Contains(t, buf.String(), "3 files of 9 ")
+	assert.Contains(t, buf.String(), "one\n")
+
+	// Modes which don't choose a file to keep are rejected
+	opt = operations.DupesOpt{Keep: operations.DeduplicateRename}
+	assert.Error(t, operations.Dupes(ctx, []fs.Fs{r.Fremote}, opt, &buf))
+}
+
+func TestDupesDelete(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	skipIfNoModTime(t, r.Fremote)
+
+	f1 := r.WriteFile("one", "Duplicate", t1)
+	r.WriteObject(ctx, "two", "Duplicate", t2)
+	r.WriteObject(ctx, "three", "Duplicate", t3)
+	f4 := r.WriteObject(ctx, "four", "Different", t1)
+
+	opt := operations.DupesOpt{Mode: operations.DupesDelete, Keep: operations.DeduplicateOldest}
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote, r.Flocal}, opt, nil))
+	r.CheckLocalItems(t, f1)
+	r.CheckRemoteItems(t, f4)
+}
+
+func TestDupesKeepLargest(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+
+	f1 := r.WriteObject(ctx, "one", "Duplicate", t1)
+	r.WriteObject(ctx, "three", "Duplicate", t2)
+	r.WriteObject(ctx, "two", "Duplicate", t3)
+
+	// All the files are the same size so the first by path is kept
+	opt := operations.DupesOpt{Mode: operations.DupesDelete, Keep: operations.DeduplicateLargest}
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote}, opt, nil))
+	r.CheckRemoteItems(t, f1)
+}
+
+func TestDupesLink(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	regInfo, _, _, _, err := fs.ConfigFs(fs.ConfigString(r.Fremote))
+	require.NoError(t, err)
+	if regInfo.Name != "local" {
+		t.Skip("Can only test linking duplicates on local")
+	}
+
+	f1 := r.WriteObject(ctx, "one", "Duplicate", t1)
+	f2 := r.WriteObject(ctx, "dir/two", "Duplicate", t2)
+
+	opt := operations.DupesOpt{Mode: operations.DupesLink, Keep: operations.DeduplicateFirst}
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote}, opt, nil))
+
+	// "dir/two" is kept as it is first by path and the link
+	// shares its modification time
+	f1.ModTime = t2
+	r.CheckRemoteItems(t, f1, f2)
+	groups, err := operations.FindDupes(ctx, []fs.Fs{r.Fremote}, false)
+	require.NoError(t, err)
+	assert.Len(t, groups, 1, "links still show as duplicates")
+
+	// Linking files on different remotes fails
+	f3 := r.WriteFile("three", "Duplicate", t1)
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, operations.Dupes(ctx, []fs.Fs{r.Fremote, r.Flocal}, opt, nil))
+	assert.Equal(t, int64(1), accounting.GlobalStats().GetErrors())
+	accounting.GlobalStats().ResetCounters()
+	r.CheckLocalItems(t, f3)
+}
@@ -0,0 +1,260 @@
+// lifecycle - apply storage tiering and archiving rules to a remote
+
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// LifecycleStubSuffix is added to the name of the stub left in place
+// of an object moved by a lifecycle rule. Objects with this suffix
+// are never matched by lifecycle rules.
+const LifecycleStubSuffix = ".rclonestub"
+
+// Lifecycle actions
+const (
+	LifecycleSetTier = "settier"
+	LifecycleMove    = "move"
+	LifecycleDelete  = "delete"
+)
+
+// LifecycleRule describes what to do with the objects it matches
+type LifecycleRule struct {
+	Name   string         `json:"name"`   // name of the rule for reporting
+	Filter filter.Options `json:"filter"` // which objects the rule applies to
+	Action string         `json:"action"` // one of settier, move or delete
+	Tier   string         `json:"tier"`   // tier for settier
+	Dst    string         `json:"dst"`    // remote to move objects to
+	Stub   bool           `json:"stub"`   // leave a stub in place of moved objects
+
+	fi   *filter.Filter // parsed Filter
+	fdst fs.Fs          // parsed Dst
+}
+
+// newLifecycleRule makes a rule with the default filter options
+func newLifecycleRule() *LifecycleRule {
+	return &LifecycleRule{
+		Filter: filter.Options{
+			MinAge:  fs.DurationOff,
+			MaxAge:  fs.DurationOff,
+			MinSize: fs.SizeSuffix(-1),
+			MaxSize: fs.SizeSuffix(-1),
+		},
+	}
+}
+
+// ParseLifecycleRule parses a rule from its parameters
+//
+// The filter options may be given at the top level using their
+// config names, e.g. "min_age" or "include", or in a "filter" block
+// in the same format as the _filter rc parameter.
+func ParseLifecycleRule(in rc.Params) (*LifecycleRule, error) {
+	in = in.Copy()
+	r := newLifecycleRule()
+	var err error
+	for _, p := range []struct {
+		key string
+		out *string
+	}{
+		{"name", &r.Name},
+		{"action", &r.Action},
+		{"tier", &r.Tier},
+		{"dst", &r.Dst},
+	} {
+		*p.out, err = in.GetString(p.key)
+		if err != nil && !rc.IsErrParamNotFound(err) {
+			return nil, err
+		}
+		delete(in, p.key)
+	}
+	r.Stub, err = in.GetBool("stub")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	delete(in, "stub")
+	if err = rc.ParseOptions(in, "filter", &r.Filter); err != nil {
+		return nil, fmt.Errorf("lifecycle rule %q: %w", r.Name, err)
+	}
+	if err = rc.CheckParamsUsed(in); err != nil {
+		return nil, fmt.Errorf("lifecycle rule %q: %w", r.Name, err)
+	}
+	return r, nil
+}
+
+// ParseLifecycleRules parses a list of rules from their parameters
+func ParseLifecycleRules(in []rc.Params) (rules []*LifecycleRule, err error) {
+	for _, p := range in {
+		r, err := ParseLifecycleRule(p)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// prepare checks the rule can be applied to f and sets up its filter
+// and destination
+func (r *LifecycleRule) prepare(ctx context.Context, f fs.Fs) (err error) {
+	if r.Name == "" {
+		return errors.New("lifecycle rule needs a name")
+	}
+	switch r.Action {
+	case LifecycleSetTier:
+		if r.Tier == "" {
+			return fmt.Errorf("lifecycle rule %q: settier needs a tier", r.Name)
+		}
+		if !f.Features().SetTier {
+			return fmt.Errorf("lifecycle rule %q: %v doesn't support settier", r.Name, f)
+		}
+	case LifecycleMove:
+		if r.Dst == "" {
+			return fmt.Errorf("lifecycle rule %q: move needs a dst", r.Name)
+		}
+		r.fdst, err = cache.Get(ctx, r.Dst)
+		if err != nil {
+			return fmt.Errorf("lifecycle rule %q: failed to make dst: %w", r.Name, err)
+		}
+	case LifecycleDelete:
+	default:
+		return fmt.Errorf("lifecycle rule %q: unknown action %q", r.Name, r.Action)
+	}
+	if r.Stub && r.Action != LifecycleMove {
+		return fmt.Errorf("lifecycle rule %q: stub can only be used with move", r.Name)
+	}
+	r.fi, err = filter.NewFilter(&r.Filter)
+	if err != nil {
+		return fmt.Errorf("lifecycle rule %q: %w", r.Name, err)
+	}
+	return nil
+}
+
+// LifecycleAction is an action taken (or which would be taken with
+// --dry-run) by a lifecycle rule
+type LifecycleAction struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Remote string `json:"remote"`
+	Dst    string `json:"dst,omitempty"`
+	Tier   string `json:"tier,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LifecycleReport is the result of applying lifecycle rules
+type LifecycleReport struct {
+	DryRun  bool              `json:"dryRun"`
+	Checked int64             `json:"checked"`
+	Actions []LifecycleAction `json:"actions"`
+	Errors  int64             `json:"errors"`
+}
+
+// Lifecycle applies the rules to all the objects in f
+//
+// Each object is acted on by the first rule which matches it. With
+// --dry-run nothing is changed but the report shows what would be
+// done.
+func Lifecycle(ctx context.Context, f fs.Fs, rules []*LifecycleRule) (report *LifecycleReport, err error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no lifecycle rules supplied")
+	}
+	for _, r := range rules {
+		if err = r.prepare(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	ci := fs.GetConfig(ctx)
+	report = &LifecycleReport{
+		DryRun:  ci.DryRun,
+		Actions: []LifecycleAction{},
+	}
+	var objs []fs.Object
+	err = walk.ListR(ctx, f, "", false, ConfigMaxDepth(ctx, true), walk.ListObjects, func(entries fs.DirEntries) error {
+		entries.ForObject(func(o fs.Object) {
+			objs = append(objs, o)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objs {
+		if strings.HasSuffix(o.Remote(), LifecycleStubSuffix) {
+			continue
+		}
+		report.Checked++
+		for _, r := range rules {
+			if !r.fi.IncludeObject(ctx, o) {
+				continue
+			}
+			if action := r.apply(ctx, f, o); action != nil {
+				if action.Error != "" {
+					report.Errors++
+				}
+				report.Actions = append(report.Actions, *action)
+			}
+			break
+		}
+	}
+	if report.Errors > 0 {
+		return report, fmt.Errorf("lifecycle: %d actions failed", report.Errors)
+	}
+	return report, nil
+}
+
+// apply the rule to o returning the action taken or nil if there was
+// nothing to do
+func (r *LifecycleRule) apply(ctx context.Context, f fs.Fs, o fs.Object) *LifecycleAction {
+	action := &LifecycleAction{
+		Rule:   r.Name,
+		Action: r.Action,
+		Remote: o.Remote(),
+	}
+	var err error
+	switch r.Action {
+	case LifecycleSetTier:
+		if do, ok := o.(fs.GetTierer); ok && strings.EqualFold(do.GetTier(), r.Tier) {
+			return nil
+		}
+		action.Tier = r.Tier
+		if !SkipDestructive(ctx, o, "set tier") {
+			err = SetTierFile(ctx, o, r.Tier)
+		}
+	case LifecycleMove:
+		action.Dst = fspath.JoinRootPath(fs.ConfigString(r.fdst), o.Remote())
+		_, err = Move(ctx, r.fdst, nil, o.Remote(), o)
+		if err == nil && r.Stub && !SkipDestructive(ctx, o, "leave stub") {
+			err = r.leaveStub(ctx, f, o, action.Dst)
+		}
+	case LifecycleDelete:
+		err = DeleteFile(ctx, o)
+	}
+	if err != nil {
+		err = fs.CountError(ctx, err)
+		fs.Errorf(o, "lifecycle rule %q failed to %s: %v", r.Name, r.Action, err)
+		action.Error = err.Error()
+	}
+	return action
+}
+
+// leaveStub writes a small object in place of o saying where it was
+// moved to
+func (r *LifecycleRule) leaveStub(ctx context.Context, f fs.Fs, o fs.Object, dst string) error {
+	content := fmt.Sprintf("Moved to %s by lifecycle rule %q\n", dst, r.Name)
+	in := io.NopCloser(strings.NewReader(content))
+	_, err := Rcat(ctx, f, o.Remote()+LifecycleStubSuffix, in, o.ModTime(ctx), nil)
+	if err != nil {
+		return fmt.Errorf("failed to leave stub: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLifecycleRule(t *testing.T) {
+	r, err := operations.ParseLifecycleRule(rc.Params{
+		"name":    "old",
+		"action":  "move",
+		"dst":     "archive:",
+		"stub":    true,
+		"min_age": "90d",
+		"include": []string{"*.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "old", r.Name)
+	assert.Equal(t, operations.LifecycleMove, r.Action)
+	assert.Equal(t, "archive:", r.Dst)
+	assert.True(t, r.Stub)
+	assert.Equal(t, fs.Duration(90*24*time.Hour), r.Filter.MinAge)
+	assert.Equal(t, fs.DurationOff, r.Filter.MaxAge)
+	assert.Equal(t, fs.SizeSuffix(-1), r.Filter.MinSize)
+	assert.Equal(t, []string{"*.txt"}, r.Filter.IncludeRule)
+
+	r, err = operations.ParseLifecycleRule(rc.Params{
+		"name":   "big",
+		"action": "delete",
+		"filter": rc.Params{"MinSize": "1M"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fs.SizeSuffix(1024*1024), r.Filter.MinSize)
+
+	_, err = operations.ParseLifecycleRule(rc.Params{
+		"name":   "bad",
+		"potato": true,
+	})
+	assert.ErrorContains(t, err, "unknown parameters: potato")
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	file1 := r.WriteObject(ctx, "old.txt", "old text", old)
+	file2 := r.WriteObject(ctx, "dir/old.jpg", "old picture", old)
+	file3 := r.WriteObject(ctx, "new.txt", "new text", now)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	parse := func(in ...rc.Params) []*operations.LifecycleRule {
+		rules, err := operations.ParseLifecycleRules(in)
+		require.NoError(t, err)
+		return rules
+	}
+	rules := func() []*operations.LifecycleRule {
+		return parse(rc.Params{
+			"name":    "delete-old-text",
+			"action":  "delete",
+			"min_age": "1d",
+			"include": []string{"*.txt"},
+		}, rc.Params{
+			"name":    "archive-old",
+			"action":  "move",
+			"min_age": "1d",
+			"dst":     r.LocalName,
+			"stub":    true,
+		})
+	}
+
+	// Check errors in the rules are found before doing anything
+	_, err := operations.Lifecycle(ctx, r.Fremote, parse(rc.Params{"name": "x", "action": "potato"}))
+	assert.ErrorContains(t, err, "unknown action")
+	if !r.Fremote.Features().SetTier {
+		_, err = operations.Lifecycle(ctx, r.Fremote, parse(rc.Params{"name": "x", "action": "settier", "tier": "COLD"}))
+		assert.ErrorContains(t, err, "doesn't support settier")
+	}
+
+	// Dry run reports but doesn't change anything
+	ci.DryRun = true
+	report, err := operations.Lifecycle(ctx, r.Fremote, rules())
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(3), report.Checked)
+	require.Len(t, report.Actions, 2)
+	r.CheckRemoteItems(t, file1, file2, file3)
+	r.CheckLocalItems(t)
+
+	// Now for real
+	ci.DryRun = false
+	report, err = operations.Lifecycle(ctx, r.Fremote, rules())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Checked)
+	assert.Equal(t, int64(0), report.Errors)
+	require.Len(t, report.Actions, 2)
+	actions := map[string]operations.LifecycleAction{}
+	for _, a := range report.Actions {
+		actions[a.Remote] = a
+	}
+	assert.Equal(t, "delete-old-text", actions["old.txt"].Rule)
+	assert.Equal(t, operations.LifecycleDelete, actions["old.txt"].Action)
+	assert.Equal(t, "archive-old", actions["dir/old.jpg"].Rule)
+	assert.Equal(t, operations.LifecycleMove, actions["dir/old.jpg"].Action)
+
+	r.CheckLocalItems(t, file2)
+	o, err := r.Fremote.NewObject(ctx, "dir/old.jpg"+operations.LifecycleStubSuffix)
+	require.NoError(t, err)
+	assert.Greater(t, o.Size(), int64(0))
+	_, err = r.Fremote.NewObject(ctx, "dir/old.jpg")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	_, err = r.Fremote.NewObject(ctx, "new.txt")
+	assert.NoError(t, err)
+
+	// Running again does nothing as the stub is ignored
+	report, err = operations.Lifecycle(ctx, r.Fremote, rules())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Checked)
+	assert.Len(t, report.Actions, 0)
+}
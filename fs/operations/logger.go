@@ -13,7 +13,7 @@ import (
 	"github.com/spf13/pflag"
 )
 
-// Sigil represents the rune (-+=*!?) used by Logger to categorize files by their match/differ/missing status.
+// Sigil represents the rune (-+=*!?v) used by Logger to categorize files by their match/differ/missing status.
 type Sigil rune
 
 // String converts sigil to more human-readable string
@@ -29,6 +29,8 @@ func (sigil Sigil) String() string {
 		return "Differ"
 	case '!':
 		return "Error"
+	case 'v':
+		return "Verified"
 	// case '.':
 	// 	return "Completed"
 	case '?':
@@ -62,6 +64,7 @@ const (
 	Differ        Sigil = '*'
 	TransferError Sigil = '!'
 	Other         Sigil = '?' // reserved but not currently used
+	Verified      Sigil = 'v' // copy was read back and verified with --verify
 )
 
 // LoggerFn uses fs.DirEntry instead of fs.Object so it can include Dirs
@@ -138,7 +141,7 @@ func NewDefaultLoggerFn(opt *LoggerOpt) LoggerFn {
 			SyncFprintf(opt.Combined, "%c %s\n", sigil, filename)
 			fs.Debugf(nil, "Sync Logger: %s: %c %s\n", sigil.String(), sigil, filename)
 		}
-		if opt.DestAfter != nil {
+		if opt.DestAfter != nil && sigil != Verified {
 			opt.PrintDestAfter(ctx, sigil, src, dst, err)
 		}
 	}
@@ -154,7 +154,16 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		return nil, fmt.Errorf("multi-thread copy: can't copy zero sized file")
 	}
 
-	info, chunkWriter, err := openChunkWriter(ctx, remote, src, options...)
+	var (
+		info        fs.ChunkWriterInfo
+		chunkWriter fs.ChunkWriter
+		resumer     *uploadResumer
+	)
+	if ci.MultiThreadResume && !usingOpenWriterAt && f.Features().ResumeChunkWriter != nil {
+		resumer, info, chunkWriter, err = openResumableChunkWriter(ctx, f, remote, src, options...)
+	} else {
+		info, chunkWriter, err = openChunkWriter(ctx, remote, src, options...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("multi-thread copy: failed to open chunk writer: %w", err)
 	}
@@ -162,9 +171,19 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 	uploadCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	uploadedOK := false
+	defer resumer.finish()
 	defer atexit.OnError(&err, func() {
 		cancel()
-		if info.LeavePartsOnError || uploadedOK {
+		if uploadedOK {
+			return
+		}
+		// Leave the chunks of an interrupted upload so it can be
+		// resumed, but abort it if it failed for any other reason
+		if resumer != nil && (atexit.Signalled() || ctx.Err() != nil) {
+			resumer.interrupted()
+			return
+		}
+		if info.LeavePartsOnError {
 			return
 		}
 		fs.Debugf(src, "multi-thread copy: cancelling transfer on exit")
@@ -225,6 +244,13 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		end := min(start+mc.partSize, mc.size)
 		size := end - start
 
+		// Skip chunks already uploaded by an earlier run
+		if resumer.skip(chunk) {
+			fs.Debugf(src, "multi-thread copy: chunk %d/%d already uploaded", chunk+1, mc.numChunks)
+			mc.acc.AccountReadNoNetwork(size)
+			continue
+		}
+
 		// Reserve the memory first so we don't open the source and wait for memory buffers for ages
 		// This also avoids creating an excess of goroutines all waiting on memory.
 		var rw *pool.RW
@@ -233,7 +259,11 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		}
 
 		g.Go(func() error {
-			return mc.copyChunk(gCtx, chunk, chunkWriter, start, end, size, rw)
+			err := mc.copyChunk(gCtx, chunk, chunkWriter, start, end, size, rw)
+			if err == nil {
+				resumer.written(chunk)
+			}
+			return err
 		})
 	}
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"sync"
 	"testing"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/kv"
 	"github.com/rclone/rclone/lib/random"
 
 	"github.com/rclone/rclone/fs"
@@ -342,3 +344,180 @@ func TestMultithreadCopyAbort(t *testing.T) {
 		require.NoError(t, o.Remove(ctx))
 	}
 }
+
+// testUploadServer is a fake multipart upload server which can
+// resume uploads
+type testUploadServer struct {
+	f         *mockfs.Fs
+	mu        sync.Mutex
+	uploads   map[string]map[int][]byte // chunks of each upload
+	nextID    int
+	written   []int              // chunks written by WriteChunk
+	failChunk int                // fail writing this chunk if >= 0
+	cancel    context.CancelFunc // if set called when failing a chunk
+}
+
+func (s *testUploadServer) newWriter(remote, id string) *testChunkWriter {
+	return &testChunkWriter{s: s, remote: remote, id: id, parts: make(map[int]fs.ChunkWriterPart)}
+}
+
+func (s *testUploadServer) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("upload-%d", s.nextID)
+	s.uploads[id] = make(map[int][]byte)
+	return fs.ChunkWriterInfo{ChunkSize: 4, Concurrency: 1}, s.newWriter(remote, id), nil
+}
+
+func (s *testUploadServer) ResumeChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, uploadID string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ResumableChunkWriter, parts []fs.ChunkWriterPart, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunks, ok := s.uploads[uploadID]
+	if !ok {
+		return info, nil, nil, errors.New("upload not found")
+	}
+	for chunk, data := range chunks {
+		parts = append(parts, fs.ChunkWriterPart{ChunkNumber: chunk, Size: int64(len(data)), ID: string(data)})
+	}
+	return fs.ChunkWriterInfo{ChunkSize: 4, Concurrency: 1}, s.newWriter(remote, uploadID), parts, nil
+}
+
+type testChunkWriter struct {
+	s      *testUploadServer
+	remote string
+	id     string
+	parts  map[int]fs.ChunkWriterPart
+}
+
+func (w *testChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return -1, err
+	}
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	if chunkNumber == w.s.failChunk {
+		if w.s.cancel != nil {
+			w.s.cancel()
+		}
+		return -1, errors.New("BOOM: simulated write failure")
+	}
+	w.s.uploads[w.id][chunkNumber] = data
+	w.s.written = append(w.s.written, chunkNumber)
+	w.parts[chunkNumber] = fs.ChunkWriterPart{ChunkNumber: chunkNumber, Size: int64(len(data)), ID: string(data)}
+	return int64(len(data)), nil
+}
+
+func (w *testChunkWriter) Close(ctx context.Context) error {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	var data []byte
+	for chunk := range len(w.parts) {
+		if _, ok := w.parts[chunk]; !ok {
+			return fmt.Errorf("missing chunk %d", chunk)
+		}
+		data = append(data, w.s.uploads[w.id][chunk]...)
+	}
+	delete(w.s.uploads, w.id)
+	w.s.f.AddObject(mockobject.New(w.remote).WithContent(data, mockobject.SeekModeNone))
+	return nil
+}
+
+func (w *testChunkWriter) Abort(ctx context.Context) error {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	delete(w.s.uploads, w.id)
+	return nil
+}
+
+func (w *testChunkWriter) UploadID() string {
+	return w.id
+}
+
+func (w *testChunkWriter) WrittenChunk(chunkNumber int) (part fs.ChunkWriterPart, ok bool) {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	part, ok = w.parts[chunkNumber]
+	return part, ok
+}
+
+func (w *testChunkWriter) AddChunk(part fs.ChunkWriterPart) error {
+	w.parts[part.ChunkNumber] = part
+	return nil
+}
+
+func TestMultithreadCopyResume(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadResume = true
+
+	fsrc, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	fdst, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	s := &testUploadServer{
+		f:         fdst.(*mockfs.Fs),
+		uploads:   make(map[string]map[int][]byte),
+		failChunk: 2,
+	}
+	fdst.Features().OpenChunkWriter = s.OpenChunkWriter
+	fdst.Features().ResumeChunkWriter = s.ResumeChunkWriter
+
+	// Keep the database open between copies as it is removed
+	// when first opened by a test binary
+	db, err := kv.Start(ctx, resumeFacility, fdst)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop(true))
+	}()
+
+	const remote = "file.txt"
+	contents := []byte("0123456789abcdefghij")
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	src.SetFs(fsrc)
+
+	doCopy := func(ctx context.Context) (fs.Object, error) {
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		dst, err := multiThreadCopy(ctx, fdst, remote, src, 1, tr)
+		tr.Done(ctx, err)
+		return dst, err
+	}
+	r := &uploadResumer{db: db, key: path.Join(fs.ConfigString(fdst), remote), src: src}
+
+	// A copy which fails on chunk 2 should be aborted
+	_, err = doCopy(ctx)
+	require.Error(t, err)
+	assert.Equal(t, []int{0, 1}, s.written)
+	assert.Len(t, s.uploads, 0)
+	assert.False(t, r.load())
+
+	// A copy interrupted on chunk 2 leaves chunks 0 and 1 uploaded
+	s.written = nil
+	copyCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	_, err = doCopy(copyCtx)
+	require.Error(t, err)
+	assert.Equal(t, []int{0, 1}, s.written)
+	require.Len(t, s.uploads, 1)
+	require.True(t, r.load())
+	assert.Len(t, r.rec.Parts, 2)
+
+	// The next copy should only upload the missing chunks
+	s.failChunk = -1
+	s.cancel = nil
+	s.written = nil
+	dst, err := doCopy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, s.written)
+	assert.Len(t, s.uploads, 0)
+	in, err := dst.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, got)
+
+	// The record should be removed once the upload is complete
+	assert.False(t, r.load())
+}
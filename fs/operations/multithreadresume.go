@@ -0,0 +1,262 @@
+// Resuming interrupted multi-thread uploads
+
+package operations
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+const (
+	resumeFacility     = "multipart"     // name of the kv database
+	resumeSaveInterval = 5 * time.Second // how often to save the record as chunks are written
+)
+
+// resumeRecord is the state of a multi-thread upload which is stored
+// so the upload can be resumed if rclone is stopped
+type resumeRecord struct {
+	Fingerprint string                     // fingerprint of the source
+	UploadID    string                     // ID of the upload on the destination
+	ChunkSize   int64                      // size of the chunks
+	Parts       map[int]fs.ChunkWriterPart // chunks uploaded so far
+	Started     time.Time                  // when the upload was started
+}
+
+// uploadResumer keeps the resumeRecord of an upload up to date
+type uploadResumer struct {
+	db     *kv.DB
+	key    string
+	src    fs.Object
+	writer fs.ResumableChunkWriter
+	kept   map[int]bool // chunks kept from an earlier upload
+	mu     sync.Mutex   // protect the below
+	rec    resumeRecord
+	saved  time.Time // when rec was last saved
+	dirty  bool      // set if rec has changed since it was saved
+	keep   bool      // set if the upload was interrupted so should be kept
+}
+
+// openResumableChunkWriter opens a chunk writer for (f, remote)
+// which carries on an earlier upload of src if there is one.
+//
+// If the upload can't be resumed later then the returned
+// uploadResumer is nil and the chunk writer is opened with
+// OpenChunkWriter as normal.
+func openResumableChunkWriter(ctx context.Context, f fs.Fs, remote string, src fs.Object, options ...fs.OpenOption) (r *uploadResumer, info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	features := f.Features()
+	db, err := kv.Start(ctx, resumeFacility, f)
+	if err != nil {
+		fs.Debugf(src, "multi-thread copy: can't record upload to resume it: %v", err)
+		info, writer, err = features.OpenChunkWriter(ctx, remote, src, options...)
+		return nil, info, writer, err
+	}
+	r = &uploadResumer{
+		db:   db,
+		key:  path.Join(fs.ConfigString(f), remote),
+		src:  src,
+		kept: make(map[int]bool),
+	}
+	defer func() {
+		if r == nil {
+			_ = db.Stop(false)
+		}
+	}()
+	fingerprint := fs.Fingerprint(ctx, src, true)
+
+	// Carry on with the earlier upload if it is still there
+	if r.load() {
+		info, writer, err = r.resume(ctx, f, remote, fingerprint, options...)
+		if err == nil {
+			return r, info, writer, nil
+		}
+		fs.Infof(src, "multi-thread copy: starting upload again: %v", err)
+		r.kept = make(map[int]bool)
+		if err = r.delete(); err != nil {
+			return nil, info, nil, err
+		}
+	}
+
+	info, writer, err = features.OpenChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return nil, info, nil, err
+	}
+	rw, ok := writer.(fs.ResumableChunkWriter)
+	if !ok {
+		fs.Debugf(src, "multi-thread copy: upload can't be resumed")
+		return nil, info, writer, nil
+	}
+	r.writer = rw
+	r.rec = resumeRecord{
+		Fingerprint: fingerprint,
+		UploadID:    rw.UploadID(),
+		ChunkSize:   info.ChunkSize,
+		Parts:       make(map[int]fs.ChunkWriterPart),
+		Started:     time.Now(),
+	}
+	if err = r.save(); err != nil {
+		_ = writer.Abort(ctx)
+		return nil, info, nil, err
+	}
+	return r, info, writer, nil
+}
+
+// resume reopens the upload in r.rec and keeps the chunks which are
+// still on the destination.
+func (r *uploadResumer) resume(ctx context.Context, f fs.Fs, remote string, fingerprint string, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	info, rw, parts, err := f.Features().ResumeChunkWriter(ctx, remote, r.src, r.rec.UploadID, options...)
+	if err != nil {
+		return info, nil, fmt.Errorf("failed to resume upload %q: %w", r.rec.UploadID, err)
+	}
+	if r.rec.Fingerprint != fingerprint {
+		_ = rw.Abort(ctx)
+		return info, nil, fmt.Errorf("source has changed since upload %q was started", r.rec.UploadID)
+	}
+	if info.ChunkSize != r.rec.ChunkSize {
+		_ = rw.Abort(ctx)
+		return info, nil, fmt.Errorf("chunk size has changed from %v to %v", fs.SizeSuffix(r.rec.ChunkSize), fs.SizeSuffix(info.ChunkSize))
+	}
+
+	// Only keep the chunks we recorded which the destination agrees with
+	uploaded := make(map[int]fs.ChunkWriterPart, len(parts))
+	for _, part := range parts {
+		uploaded[part.ChunkNumber] = part
+	}
+	for chunk, part := range r.rec.Parts {
+		if uploaded[chunk] != part {
+			fs.Debugf(r.src, "multi-thread copy: chunk %d doesn't match the destination - uploading it again", chunk+1)
+			delete(r.rec.Parts, chunk)
+			continue
+		}
+		if err = rw.AddChunk(part); err != nil {
+			_ = rw.Abort(ctx)
+			return info, nil, fmt.Errorf("failed to add chunk %d to resumed upload: %w", chunk+1, err)
+		}
+		r.kept[chunk] = true
+	}
+	r.writer = rw
+	if err = r.save(); err != nil {
+		return info, nil, err
+	}
+	fs.Infof(r.src, "multi-thread copy: resuming upload started %v with %d chunks already uploaded", r.rec.Started.Format(time.RFC3339), len(r.kept))
+	return info, rw, nil
+}
+
+// skip returns true if chunk was kept from an earlier upload
+func (r *uploadResumer) skip(chunk int) bool {
+	if r == nil {
+		return false
+	}
+	return r.kept[chunk]
+}
+
+// written records that chunk has been uploaded
+//
+// The record is saved at most every resumeSaveInterval so the
+// database isn't written for every chunk.
+func (r *uploadResumer) written(chunk int) {
+	if r == nil {
+		return
+	}
+	part, ok := r.writer.WrittenChunk(chunk)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Parts[chunk] = part
+	r.dirty = true
+	if time.Since(r.saved) >= resumeSaveInterval {
+		r.flush()
+	}
+}
+
+// interrupted marks the upload as interrupted so its chunks are kept
+// to resume it later and saves the record.
+func (r *uploadResumer) interrupted() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keep = true
+	r.flush()
+}
+
+// flush saves the record if it has changed
+//
+// Call with mu held
+func (r *uploadResumer) flush() {
+	if !r.dirty {
+		return
+	}
+	if err := r.save(); err != nil {
+		fs.Errorf(r.src, "multi-thread copy: %v", err)
+	}
+}
+
+// finish stops recording the upload.
+//
+// The record is kept if the upload was interrupted, otherwise it is
+// forgotten as the upload either completed or was aborted.
+func (r *uploadResumer) finish() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keep {
+		fs.Infof(r.src, "multi-thread copy: keeping %d uploaded chunks to resume the upload later", len(r.rec.Parts))
+	} else if err := r.delete(); err != nil {
+		fs.Errorf(r.src, "multi-thread copy: %v", err)
+	}
+	_ = r.db.Stop(false)
+}
+
+// load reads the record for the upload returning true if found
+func (r *uploadResumer) load() bool {
+	op := &kv.OpGet{Key: r.key}
+	err := r.db.Do(false, op)
+	if err != nil || op.Value == nil {
+		return false
+	}
+	if err = gob.NewDecoder(bytes.NewReader(op.Value)).Decode(&r.rec); err != nil {
+		fs.Debugf(r.src, "multi-thread copy: failed to decode resume record: %v", err)
+		return false
+	}
+	if r.rec.Parts == nil {
+		r.rec.Parts = make(map[int]fs.ChunkWriterPart)
+	}
+	return true
+}
+
+// save writes the record for the upload
+//
+// Call with mu held if chunks are being written
+func (r *uploadResumer) save() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&r.rec); err != nil {
+		return fmt.Errorf("failed to encode resume record: %w", err)
+	}
+	if err := r.db.Do(true, &kv.OpPut{Key: r.key, Value: buf.Bytes()}); err != nil {
+		return fmt.Errorf("failed to save resume record: %w", err)
+	}
+	r.saved = time.Now()
+	r.dirty = false
+	return nil
+}
+
+// delete removes the record for the upload
+func (r *uploadResumer) delete() error {
+	if err := r.db.Do(true, &kv.OpDelete{Keys: []string{r.key}}); err != nil {
+		return fmt.Errorf("failed to remove resume record: %w", err)
+	}
+	return nil
+}
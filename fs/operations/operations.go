@@ -34,6 +34,7 @@ import (
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/errcount"
@@ -244,6 +245,21 @@ func WithEqualFn(ctx context.Context, equalFn EqualFn) context.Context {
 	return context.WithValue(ctx, equalFnKey, equalFn)
 }
 
+// ModTimeUpdateFn is called when equal() updates the modification time
+// of dst to that of src instead of transferring it, or would do if
+// not for --dry-run
+type (
+	ModTimeUpdateFn           func(ctx context.Context, src fs.ObjectInfo, dst fs.Object)
+	modTimeUpdateFnContextKey struct{}
+)
+
+var modTimeUpdateFnKey = modTimeUpdateFnContextKey{}
+
+// WithModTimeUpdateFn stores modTimeUpdateFn in ctx and returns a copy of ctx in which modTimeUpdateFnKey = modTimeUpdateFn
+func WithModTimeUpdateFn(ctx context.Context, modTimeUpdateFn ModTimeUpdateFn) context.Context {
+	return context.WithValue(ctx, modTimeUpdateFnKey, modTimeUpdateFn)
+}
+
 func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt) bool {
 	ci := fs.GetConfig(ctx)
 	logger, _ := GetLogger(ctx)
@@ -319,6 +335,9 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 
 	// mod time differs but hash is the same to reset mod time if required
 	if opt.updateModTime {
+		if modTimeUpdateFn, ok := ctx.Value(modTimeUpdateFnKey).(ModTimeUpdateFn); ok && !ci.Immutable {
+			modTimeUpdateFn(ctx, src, dst)
+		}
 		if !SkipDestructive(ctx, src, "update modification time") {
 			// Size and hash the same but mtime different
 			// Error if objects are treated as immutable
@@ -487,7 +506,9 @@ func move(ctx context.Context, fdst fs.Fs, dst fs.Object, remote string, src fs.
 		// Move dst <- src
 		in := tr.Account(ctx, nil) // account the transfer
 		in.ServerSideTransferStart()
-		newDst, err = doMove(ctx, src, remote)
+		spanCtx, span := tracing.StartFs(ctx, "Move", fdst, remote)
+		newDst, err = doMove(spanCtx, src, remote)
+		tracing.End(span, err)
 		switch err {
 		case nil:
 			if newDst != nil && src.String() != newDst.String() {
@@ -573,7 +594,9 @@ func DeleteFileWithBackupDir(ctx context.Context, dst fs.Object, backupDir fs.Fs
 	} else if backupDir != nil {
 		err = MoveBackupDir(ctx, backupDir, dst)
 	} else {
-		err = dst.Remove(ctx)
+		spanCtx, span := tracing.StartFs(ctx, "Remove", dst.Fs(), dst.Remote())
+		err = dst.Remove(spanCtx)
+		tracing.End(span, err)
 	}
 	if err != nil {
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
@@ -1062,7 +1085,9 @@ func Mkdir(ctx context.Context, f fs.Fs, dir string) error {
 		return nil
 	}
 	fs.Infof(fs.LogDirName(f, dir), "Making directory")
-	err := f.Mkdir(ctx, dir)
+	spanCtx, span := tracing.StartFs(ctx, "Mkdir", f, dir)
+	err := f.Mkdir(spanCtx, dir)
+	tracing.End(span, err)
 	if err != nil {
 		err = fs.CountError(ctx, err)
 		return err
@@ -1137,7 +1162,10 @@ func TryRmdir(ctx context.Context, f fs.Fs, dir string) error {
 		return nil
 	}
 	fs.Infof(fs.LogDirName(f, dir), "Removing directory")
-	return f.Rmdir(ctx, dir)
+	spanCtx, span := tracing.StartFs(ctx, "Rmdir", f, dir)
+	err := f.Rmdir(spanCtx, dir)
+	tracing.End(span, err)
+	return err
 }
 
 // Rmdir removes a container but not if not empty
@@ -1159,7 +1187,9 @@ func Purge(ctx context.Context, f fs.Fs, dir string) (err error) {
 		if SkipDestructive(ctx, fs.LogDirName(f, dir), "purge directory") {
 			return nil
 		}
-		err = doPurge(ctx, dir)
+		spanCtx, span := tracing.StartFs(ctx, "Purge", f, dir)
+		err = doPurge(spanCtx, dir)
+		tracing.End(span, err)
 		if errors.Is(err, fs.ErrorCantPurge) {
 			doFallbackPurge = true
 		}
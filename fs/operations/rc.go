@@ -1004,3 +1004,72 @@ func rcHashsumFile(ctx context.Context, in rc.Params) (out rc.Params, err error)
 	}
 	return out, err
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "operations/lifecycle",
+		Fn:    rcLifecycle,
+		Title: "Apply lifecycle rules to the objects in a remote",
+		Help: `This changes the storage tier of, moves or deletes objects according
+to a list of rules.
+
+This takes the following parameters:
+
+- fs - a remote name string e.g. "s3:bucket"
+- rules - a list of rules, each an object with these keys
+    - name - name of the rule used in the report
+    - action - one of "settier", "move" or "delete"
+    - tier - the storage tier to set for settier
+    - dst - the remote to move objects to for move
+    - stub - set to leave a stub in place of moved objects
+    - any filter options, e.g. "min_age", "max_size" or "include"
+
+Each object is acted on by the first rule it matches. Pass "dryRun":
+true in _config to see what would be done.
+
+Returns:
+
+- dryRun - true if nothing was changed
+- checked - the number of objects checked
+- actions - a list of the actions taken with rule, action, remote,
+  dst, tier and error keys
+- errors - the number of actions which failed
+
+The call succeeds even if some of the actions fail, so check errors.
+
+See the [lifecycle](/commands/rclone_lifecycle/) command for more information on the above.
+`,
+	})
+}
+
+// Apply lifecycle rules to the objects in a remote
+func rcLifecycle(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	rulesIn, err := in.Get("rules")
+	if err != nil {
+		return nil, err
+	}
+	var params []rc.Params
+	err = rc.Reshape(&params, rulesIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rules, err := ParseLifecycleRules(params)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Lifecycle(ctx, f, rules)
+	if report == nil {
+		return nil, err
+	}
+	// Failed actions are returned in the report
+	out = rc.Params{}
+	err = rc.Reshape(&out, report)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/tracing"
 )
 
 // AccountFn is a function which will be called after every read
@@ -145,7 +146,9 @@ func (h *ReOpen) open() error {
 	if h.tries > h.maxTries {
 		h.err = errTooManyTries
 	} else {
-		h.rc, h.err = h.src.Open(h.ctx, opts...)
+		ctx, span := tracing.StartFs(h.ctx, "Open", h.src.Fs(), h.src.Remote())
+		h.rc, h.err = h.src.Open(ctx, opts...)
+		tracing.End(span, h.err)
 	}
 	if h.err != nil {
 		if h.tries > 1 {
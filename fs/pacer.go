@@ -4,6 +4,8 @@ package fs
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs/fserrors"
@@ -19,14 +21,56 @@ type logCalculator struct {
 	pacer.Calculator
 }
 
+// pacerRemoteKey is the context key for the name of the remote being
+// made by NewFs so pacers can count their retries against it.
+type pacerRemoteKeyType struct{}
+
+var pacerRemoteKey = pacerRemoteKeyType{}
+
+// Low level retries made by the pacers of each remote
+var (
+	pacerRetriesMu sync.Mutex
+	pacerRetries   = make(map[string]*atomic.Int64)
+)
+
+// pacerRetryCounter returns the retry counter for the remote called name
+func pacerRetryCounter(name string) *atomic.Int64 {
+	pacerRetriesMu.Lock()
+	defer pacerRetriesMu.Unlock()
+	counter := pacerRetries[name]
+	if counter == nil {
+		counter = new(atomic.Int64)
+		pacerRetries[name] = counter
+	}
+	return counter
+}
+
+// LowLevelRetries returns the number of low level retries the pacers
+// of the remote called name have made so far.
+//
+// Backends ask for a retry when they are rate limited or the service
+// is overloaded, so an increase in this is a sign to slow down.
+func LowLevelRetries(name string) int64 {
+	return pacerRetryCounter(name).Load()
+}
+
 // NewPacer creates a Pacer for the given Fs and Calculator.
 func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 	ci := GetConfig(ctx)
 	retries := max(ci.LowLevelRetries, 1)
 	maxConnections := max(ci.MaxConnections, 0)
+	name, _ := ctx.Value(pacerRemoteKey).(string)
+	counter := pacerRetryCounter(name)
+	invoker := func(try, retries int, f pacer.Paced) (retry bool, err error) {
+		retry, err = pacerInvoker(try, retries, f)
+		if retry {
+			counter.Add(1)
+		}
+		return retry, err
+	}
 	p := &Pacer{
 		Pacer: pacer.New(
-			pacer.InvokerOption(pacerInvoker),
+			pacer.InvokerOption(invoker),
 			pacer.MaxConnectionsOption(maxConnections),
 			pacer.RetriesOption(retries),
 			pacer.CalculatorOption(c),
@@ -55,3 +55,19 @@ func TestPacerCallNoRetry(t *testing.T) {
 	require.Equal(t, 1, dp.called)
 	require.Implements(t, (*fserrors.Retrier)(nil), err)
 }
+
+func TestPacerLowLevelRetries(t *testing.T) {
+	ctx := context.WithValue(context.Background(), pacerRemoteKey, "TestPacerLowLevelRetries")
+	ctx, ci := AddConfig(ctx)
+	ci.LowLevelRetries = 3
+	p := NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(1*time.Millisecond), pacer.MaxSleep(2*time.Millisecond)))
+
+	before := LowLevelRetries("TestPacerLowLevelRetries")
+	dp := &dummyPaced{retry: true}
+	_ = p.Call(dp.fn)
+	require.Equal(t, int64(3), LowLevelRetries("TestPacerLowLevelRetries")-before)
+
+	dp = &dummyPaced{retry: false}
+	_ = p.Call(dp.fn)
+	require.Equal(t, int64(3), LowLevelRetries("TestPacerLowLevelRetries")-before)
+}
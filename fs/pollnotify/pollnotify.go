@@ -0,0 +1,288 @@
+// Package pollnotify implements ChangeNotify for backends which can't
+// notify changes themselves by polling the directories in use.
+//
+// Directories are marked in use with Poller.Touch, normally when they
+// are listed, and are re-listed periodically until they haven't been
+// touched for Options.HotTime. Each listing is compared with the
+// previous one and any differences are passed to the notify function
+// in the same way as a backend's ChangeNotify would.
+//
+// Directories which don't change are polled less often, up to
+// Options.MaxInterval, and the total number of listings is limited to
+// Options.ListsPerMinute.
+package pollnotify
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+	"golang.org/x/time/rate"
+)
+
+// Options control the Poller
+type Options struct {
+	MaxInterval    time.Duration // longest time between listings of an unchanging directory
+	HotTime        time.Duration // stop polling a directory this long after it was last touched
+	ListsPerMinute int           // maximum number of listings per minute - 0 for unlimited
+}
+
+// Poller polls directory listings for changes
+type Poller struct {
+	f       fs.Fs
+	opt     Options
+	limiter *rate.Limiter // nil if unlimited
+
+	mu          sync.Mutex
+	dirs        map[string]*dirState // directories being polled by path
+	minInterval time.Duration        // shortest time between listings - 0 if paused
+}
+
+// dirState is the polling state of one directory
+type dirState struct {
+	entries  map[string]entryState // the last listing or nil if not listed yet
+	interval time.Duration         // current time between listings
+	next     time.Time             // when to list it next
+	touched  time.Time             // when it was last touched
+}
+
+// entryState is what we remember about an entry to spot changes
+type entryState struct {
+	entryType fs.EntryType
+	size      int64
+	modTime   time.Time
+}
+
+// New makes a Poller for f
+func New(f fs.Fs, opt Options) *Poller {
+	p := &Poller{
+		f:    f,
+		opt:  opt,
+		dirs: make(map[string]*dirState),
+	}
+	if opt.ListsPerMinute > 0 {
+		p.limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(opt.ListsPerMinute)), 1)
+	}
+	return p
+}
+
+// makeEntries makes the state of a listing
+func makeEntries(ctx context.Context, entries fs.DirEntries) map[string]entryState {
+	states := make(map[string]entryState, len(entries))
+	for _, entry := range entries {
+		state := entryState{
+			size:    entry.Size(),
+			modTime: entry.ModTime(ctx),
+		}
+		switch entry.(type) {
+		case fs.Object:
+			state.entryType = fs.EntryObject
+		case fs.Directory:
+			state.entryType = fs.EntryDirectory
+		}
+		states[entry.Remote()] = state
+	}
+	return states
+}
+
+// Touch marks dir as in use so it is polled for at least another
+// Options.HotTime.
+//
+// If entries is not nil it should be the listing of dir just read
+// and changes are reported relative to it. Otherwise the next poll
+// of a new directory sets the baseline without reporting changes.
+//
+// Thread safe.
+func (p *Poller) Touch(ctx context.Context, dir string, entries fs.DirEntries) {
+	var states map[string]entryState
+	if entries != nil {
+		states = makeEntries(ctx, entries)
+	}
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d, ok := p.dirs[dir]
+	if !ok {
+		d = &dirState{}
+		p.dirs[dir] = d
+	}
+	d.touched = now
+	if states != nil {
+		d.entries = states
+	}
+	if d.interval == 0 {
+		d.interval = p.minInterval
+		d.next = now.Add(d.interval)
+	}
+	if d.entries == nil {
+		// Poll new directories soon to find the baseline
+		d.next = now
+	}
+}
+
+// Forget stops polling dir and any directories below it.
+//
+// Thread safe.
+func (p *Poller) Forget(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forget(dir)
+}
+
+// forget stops polling dir and any directories below it - call with
+// the lock held
+func (p *Poller) forget(dir string) {
+	prefix := dir + "/"
+	for name := range p.dirs {
+		if name == dir || dir == "" || strings.HasPrefix(name, prefix) {
+			delete(p.dirs, name)
+		}
+	}
+}
+
+// due returns the directories which should be listed now, oldest
+// first, and drops any which haven't been touched recently.
+func (p *Poller) due(now time.Time) (dirs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, d := range p.dirs {
+		if p.opt.HotTime > 0 && now.Sub(d.touched) > p.opt.HotTime {
+			delete(p.dirs, name)
+			continue
+		}
+		if !d.next.After(now) {
+			dirs = append(dirs, name)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return p.dirs[dirs[i]].next.Before(p.dirs[dirs[j]].next)
+	})
+	return dirs
+}
+
+// poll lists dir and reports any changes since the last listing
+func (p *Poller) poll(ctx context.Context, dir string, notifyFunc func(string, fs.EntryType)) {
+	entries, err := list.DirSorted(ctx, p.f, false, dir)
+	var states map[string]entryState
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		states = map[string]entryState{}
+	} else if err != nil {
+		fs.Debugf(p.f, "Change notify: failed to list %q: %v", dir, err)
+		states = nil
+	} else {
+		states = makeEntries(ctx, entries)
+	}
+
+	now := time.Now()
+	var changes []string
+	var changeTypes []fs.EntryType
+	p.mu.Lock()
+	d, ok := p.dirs[dir]
+	if !ok {
+		// Forgotten while we were listing it
+		p.mu.Unlock()
+		return
+	}
+	if states != nil && d.entries != nil {
+		for remote, state := range states {
+			old, found := d.entries[remote]
+			if !found || old != state {
+				changes = append(changes, remote)
+				changeTypes = append(changeTypes, state.entryType)
+			}
+		}
+		for remote, old := range d.entries {
+			if _, found := states[remote]; !found {
+				changes = append(changes, remote)
+				changeTypes = append(changeTypes, old.entryType)
+				if old.entryType == fs.EntryDirectory {
+					p.forget(remote)
+				}
+			}
+		}
+	}
+	if states != nil {
+		d.entries = states
+	}
+	// Poll changing directories often and unchanging ones less
+	// often, up to MaxInterval
+	if len(changes) > 0 || d.interval < p.minInterval {
+		d.interval = p.minInterval
+	} else {
+		d.interval = min(2*d.interval, max(p.opt.MaxInterval, p.minInterval))
+	}
+	d.next = now.Add(d.interval)
+	p.mu.Unlock()
+
+	for i, remote := range changes {
+		fs.Debugf(p.f, "Change notify: %v %q changed", changeTypes[i], remote)
+		notifyFunc(remote, changeTypes[i])
+	}
+}
+
+// pollDue lists any directories which are due within the budget
+func (p *Poller) pollDue(ctx context.Context, notifyFunc func(string, fs.EntryType)) {
+	for _, dir := range p.due(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+		if p.limiter != nil && !p.limiter.Allow() {
+			// Out of budget - the rest stay due for next time
+			return
+		}
+		p.poll(ctx, dir, notifyFunc)
+	}
+}
+
+// ChangeNotify calls the passed function with a path that has had
+// changes. If the implementation uses polling, it should adhere to
+// the given interval.
+//
+// It has the same signature as fs.Features.ChangeNotify so it can be
+// used in its place.
+//
+// The interval received on pollIntervalChan is the shortest time
+// between listings of a directory. Sending 0 pauses polling and
+// closing the channel stops it.
+func (p *Poller) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	go func() {
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		stop := func() {
+			if ticker != nil {
+				ticker.Stop()
+				ticker = nil
+				tickerC = nil
+			}
+		}
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					return
+				}
+				stop()
+				p.mu.Lock()
+				p.minInterval = pollInterval
+				p.mu.Unlock()
+				if pollInterval > 0 {
+					// Check for due directories more often than
+					// the interval so they are listed on time
+					tick := min(pollInterval, time.Second)
+					ticker = time.NewTicker(tick)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				p.pollDue(ctx, notifyFunc)
+			}
+		}
+	}()
+}
@@ -0,0 +1,192 @@
+package pollnotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// change is a notification received from the Poller
+type change struct {
+	path      string
+	entryType fs.EntryType
+}
+
+// newPoller makes a Poller on a local temporary directory
+func newPoller(t *testing.T, opt Options) (p *Poller, dir string) {
+	dir = t.TempDir()
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+	p = New(f, opt)
+	p.minInterval = time.Second
+	return p, dir
+}
+
+// writeFile writes content to name in dir
+func writeFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o666))
+}
+
+// pollChanges polls dir and returns the sorted changes seen
+func pollChanges(ctx context.Context, p *Poller, dir string) (changes []change) {
+	p.poll(ctx, dir, func(path string, entryType fs.EntryType) {
+		changes = append(changes, change{path, entryType})
+	})
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+	return changes
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	p, root := newPoller(t, Options{})
+	writeFile(t, root, "unchanged", "unchanged")
+	writeFile(t, root, "changed", "changed")
+	writeFile(t, root, "removed", "removed")
+	require.NoError(t, os.Mkdir(filepath.Join(root, "subdir"), 0o777))
+
+	// The first poll sets the baseline
+	p.Touch(ctx, "", nil)
+	assert.Equal(t, []string{""}, p.due(time.Now()))
+	assert.Nil(t, pollChanges(ctx, p, ""))
+
+	// No changes
+	assert.Nil(t, pollChanges(ctx, p, ""))
+
+	writeFile(t, root, "added", "added")
+	writeFile(t, root, "changed", "changed more")
+	require.NoError(t, os.Remove(filepath.Join(root, "removed")))
+	require.NoError(t, os.Remove(filepath.Join(root, "subdir")))
+	assert.Equal(t, []change{
+		{"added", fs.EntryObject},
+		{"changed", fs.EntryObject},
+		{"removed", fs.EntryObject},
+		{"subdir", fs.EntryDirectory},
+	}, pollChanges(ctx, p, ""))
+
+	// Changes are only reported once
+	assert.Nil(t, pollChanges(ctx, p, ""))
+}
+
+func TestTouchWithEntries(t *testing.T) {
+	ctx := context.Background()
+	p, root := newPoller(t, Options{})
+	writeFile(t, root, "file", "file")
+	entries, err := list.DirSorted(ctx, p.f, false, "")
+	require.NoError(t, err)
+
+	// A directory touched with its listing isn't due until the interval
+	now := time.Now()
+	p.Touch(ctx, "", entries)
+	assert.Nil(t, p.due(now))
+	assert.Equal(t, []string{""}, p.due(now.Add(2*time.Second)))
+
+	// and changes are reported relative to it
+	writeFile(t, root, "file2", "file2")
+	assert.Equal(t, []change{{"file2", fs.EntryObject}}, pollChanges(ctx, p, ""))
+}
+
+func TestPollRemovedDir(t *testing.T) {
+	ctx := context.Background()
+	p, root := newPoller(t, Options{})
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0o777))
+	p.Touch(ctx, "", nil)
+	p.Touch(ctx, "a", nil)
+	p.Touch(ctx, "a/b", nil)
+	assert.Nil(t, pollChanges(ctx, p, ""))
+	assert.Nil(t, pollChanges(ctx, p, "a"))
+	assert.Nil(t, pollChanges(ctx, p, "a/b"))
+
+	// Removing a directory stops it and its children being polled
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "a")))
+	assert.Equal(t, []change{{"a", fs.EntryDirectory}}, pollChanges(ctx, p, ""))
+	assert.Len(t, p.dirs, 1)
+	assert.Contains(t, p.dirs, "")
+}
+
+func TestPollInterval(t *testing.T) {
+	ctx := context.Background()
+	p, root := newPoller(t, Options{MaxInterval: 5 * time.Second})
+	p.Touch(ctx, "", nil)
+	var intervals []time.Duration
+	for range 4 {
+		pollChanges(ctx, p, "")
+		intervals = append(intervals, p.dirs[""].interval)
+	}
+	// Unchanging directories are polled less often up to MaxInterval
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, intervals)
+
+	// A change resets the interval
+	writeFile(t, root, "file", "file")
+	pollChanges(ctx, p, "")
+	assert.Equal(t, time.Second, p.dirs[""].interval)
+}
+
+func TestHotTime(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newPoller(t, Options{HotTime: time.Minute})
+	p.Touch(ctx, "", nil)
+	p.Touch(ctx, "other", nil)
+	now := time.Now()
+	assert.Equal(t, 2, len(p.due(now)))
+
+	// Directories not touched for HotTime are dropped
+	p.dirs["other"].touched = now.Add(-2 * time.Minute)
+	assert.Equal(t, []string{""}, p.due(now))
+	assert.NotContains(t, p.dirs, "other")
+
+	p.Forget("")
+	assert.Len(t, p.dirs, 0)
+}
+
+func TestListsPerMinute(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newPoller(t, Options{ListsPerMinute: 1})
+	for _, dir := range []string{"", "a", "b"} {
+		p.Touch(ctx, dir, nil)
+	}
+
+	// Only one directory is listed within the budget
+	p.pollDue(ctx, func(string, fs.EntryType) {})
+	assert.Len(t, p.due(time.Now()), 2)
+}
+
+func TestChangeNotify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, root := newPoller(t, Options{})
+	p.Touch(ctx, "", nil)
+
+	changes := make(chan change, 10)
+	pollInterval := make(chan time.Duration)
+	p.ChangeNotify(ctx, func(path string, entryType fs.EntryType) {
+		changes <- change{path, entryType}
+	}, pollInterval)
+	pollInterval <- 10 * time.Millisecond
+
+	// Wait for the baseline listing
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.dirs[""].entries != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeFile(t, root, "file", "file")
+	select {
+	case got := <-changes:
+		assert.Equal(t, change{"file", fs.EntryObject}, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+	close(pollInterval)
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -106,6 +108,11 @@ func (job *Job) OnFinish(fn func()) func() {
 
 // run the job until completion writing the return status
 func (job *Job) run(ctx context.Context, fn rc.Func, in rc.Params) {
+	ctx, span := tracing.Start(ctx, "rc job",
+		attribute.Int64("rclone.job.id", job.ID),
+		attribute.String("rclone.job.group", job.Group),
+	)
+	defer func() { tracing.End(span, job.realErr) }()
 	defer func() {
 		if r := recover(); r != nil {
 			// Log the full stack trace server-side only - it must not
@@ -239,8 +246,9 @@ func getAsync(ctx context.Context, in rc.Params) (context.Context, bool, error)
 	}
 	delete(in, "_async") // remove the async parameter after parsing
 	if isAsync {
-		// unlink this job from the current context
-		ctx = context.Background()
+		// unlink this job from the current context but keep
+		// it in the same trace
+		ctx = tracing.Detach(ctx)
 	}
 	return ctx, isAsync, nil
 }
@@ -30,6 +30,26 @@ var optDescription = Option{
 	Advanced: true,
 }
 
+// optBwLimit is the bandwidth limit option for each remote
+var optBwLimit = Option{
+	Name: "bwlimit",
+	Help: `Bandwidth limit for this remote.
+
+This takes the same values as the global --bwlimit flag, including a
+full timetable, and limits transfers to and from this remote only.
+Any global --bwlimit still applies on top of it.
+
+Transfers through remotes which wrap this one, such as crypt, chunker,
+compress or hasher, are limited too. Transfers through union and
+combine remotes aren't, as they don't wrap a single remote, so set the
+limit on those remotes as well.
+
+Use "UP:DOWN" to set different limits for uploads to and downloads
+from this remote.`,
+	Default:  BwTimetable{},
+	Advanced: true,
+}
+
 // RegInfo provides information about a filesystem
 type RegInfo struct {
 	// Name of this fs
@@ -432,7 +452,7 @@ func Register(info *RegInfo) {
 	if info.Prefix == "" {
 		info.Prefix = info.Name
 	}
-	info.Options = append(info.Options, optDescription)
+	info.Options = append(info.Options, optBwLimit, optDescription)
 	Registry = append(Registry, info)
 	var err error
 	info.Overview, err = overview.GetBackendConfig(strings.ReplaceAll(info.Name, " ", ""))
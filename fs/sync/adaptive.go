@@ -0,0 +1,293 @@
+package sync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// adaptiveInterval is how often the number of workers is adjusted
+// with --adaptive-concurrency
+var adaptiveInterval = 5 * time.Second
+
+const (
+	adaptiveStart   = 4    // number of workers to start with if not learned
+	adaptiveHold    = 3    // number of intervals to hold the limit after backing off
+	adaptiveGain    = 1.05 // an increase must improve the rate by this much to be kept
+	adaptiveLatency = 2    // back off if latency grows by this factor without the rate improving
+)
+
+// Limits learned by previous syncs between the same remotes so later
+// syncs in the same process start from them.
+var (
+	adaptiveLearnedMu sync.Mutex
+	adaptiveLearned   = make(map[string]int)
+)
+
+// adaptiveSample is what was observed over one interval
+type adaptiveSample struct {
+	rate     float64       // throughput in bytes or items per second
+	latency  time.Duration // average time to process an item
+	backoffs int64         // number of rate limit or overload signals
+	queued   bool          // set if there was work waiting for a worker
+}
+
+// adaptiveLimit limits the number of workers reading from a pipe
+// which can be active at once, adjusting the limit according to the
+// throughput, latency and rate limiting observed.
+type adaptiveLimit struct {
+	what  string       // kind of worker for logging
+	key   string       // key into adaptiveLearned
+	min   int          // minimum limit
+	max   int          // maximum limit - the number of workers started
+	queue *pipe        // the pipe the workers read from
+	rate  func() int64 // returns the running total of work done, or nil to count items
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int           // number of workers allowed to be active
+	active  int           // number of workers active
+	stopped bool          // set when the workers should stop waiting
+	items   int64         // items finished this interval
+	elapsed time.Duration // time spent on items this interval
+	errors  int64         // retryable errors this interval
+
+	// state of the controller
+	prevLimit   int           // limit before the last change
+	prevRate    float64       // rate observed at prevLimit
+	hold        int           // intervals left to hold the limit
+	bestLatency time.Duration // lowest latency seen
+}
+
+// newAdaptiveLimit makes an adaptiveLimit for up to workers workers of
+// the kind what reading from queue.
+//
+// It returns nil, meaning no limit, if --adaptive-concurrency isn't
+// in use.
+func newAdaptiveLimit(ctx context.Context, what string, fdst, fsrc fs.Fs, workers int, queue *pipe, rate func() int64) *adaptiveLimit {
+	ci := fs.GetConfig(ctx)
+	if !ci.AdaptiveConcurrency || workers <= 1 {
+		return nil
+	}
+	l := &adaptiveLimit{
+		what:  what,
+		key:   what + " " + fsrc.Name() + " " + fdst.Name(),
+		min:   1,
+		max:   workers,
+		queue: queue,
+		rate:  rate,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	adaptiveLearnedMu.Lock()
+	l.limit = adaptiveLearned[l.key]
+	adaptiveLearnedMu.Unlock()
+	if l.limit == 0 {
+		l.limit = adaptiveStart
+	}
+	l.limit = min(max(l.limit, l.min), l.max)
+	l.prevLimit = l.limit
+	fs.Debugf(nil, "Adaptive concurrency: starting with %d of %d %s", l.limit, l.max, l.what)
+	return l
+}
+
+// acquire waits until the worker may be active, returning false if
+// it should stop.
+func (l *adaptiveLimit) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit && !l.stopped {
+		l.cond.Wait()
+	}
+	if l.stopped {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// release is called when the worker is no longer active
+func (l *adaptiveLimit) release(elapsed time.Duration) {
+	l.mu.Lock()
+	l.active--
+	l.items++
+	l.elapsed += elapsed
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// observe is called with the result of processing an item so that
+// retryable errors, which may mean rate limiting, slow things down.
+func (l *adaptiveLimit) observe(err error) {
+	if l == nil || err == nil {
+		return
+	}
+	if fserrors.IsRetryError(err) || !fserrors.RetryAfterErrorTime(err).IsZero() {
+		l.mu.Lock()
+		l.errors++
+		l.mu.Unlock()
+	}
+}
+
+// adaptiveWorker is the state of a single worker using an adaptiveLimit
+type adaptiveWorker struct {
+	l       *adaptiveLimit
+	started time.Time // when the current item was started, zero if none
+}
+
+// worker returns the state for a new worker
+func (l *adaptiveLimit) worker() *adaptiveWorker {
+	return &adaptiveWorker{l: l}
+}
+
+// finish releases the item the worker was processing, if any
+func (w *adaptiveWorker) finish() {
+	if w.l == nil || w.started.IsZero() {
+		return
+	}
+	w.l.release(time.Since(w.started))
+	w.started = time.Time{}
+}
+
+// get finishes the previous item and waits until the worker may be
+// active before reading the next pair from in.
+func (w *adaptiveWorker) get(ctx context.Context, in *pipe, fraction int) (pair fs.ObjectPair, ok bool) {
+	w.finish()
+	if w.l == nil {
+		return in.GetMax(ctx, fraction)
+	}
+	if !w.l.acquire() {
+		return pair, false
+	}
+	pair, ok = in.GetMax(ctx, fraction)
+	if !ok {
+		w.l.mu.Lock()
+		w.l.active--
+		w.l.mu.Unlock()
+		w.l.cond.Signal()
+		return pair, false
+	}
+	w.started = time.Now()
+	return pair, true
+}
+
+// run adjusts the limit every adaptiveInterval until ctx is done.
+//
+// retries should return the running total of low level retries of the
+// remotes in use.
+func (l *adaptiveLimit) run(ctx context.Context, retries func() int64) {
+	if l == nil {
+		return
+	}
+	ticker := time.NewTicker(adaptiveInterval)
+	defer ticker.Stop()
+	lastRetries := retries()
+	var lastWork int64
+	if l.rate != nil {
+		lastWork = l.rate()
+	}
+	lastTime := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			l.stop()
+			return
+		case now := <-ticker.C:
+			var sample adaptiveSample
+			totalRetries := retries()
+			sample.backoffs = totalRetries - lastRetries
+			lastRetries = totalRetries
+			queued, _ := l.queue.Stats()
+			sample.queued = queued > 0
+
+			l.mu.Lock()
+			items, elapsed := l.items, l.elapsed
+			sample.backoffs += l.errors
+			l.items, l.elapsed, l.errors = 0, 0, 0
+			sample.queued = sample.queued && l.active >= l.limit
+			l.mu.Unlock()
+
+			work := items
+			if l.rate != nil {
+				total := l.rate()
+				work = total - lastWork
+				lastWork = total
+			}
+			dt := now.Sub(lastTime).Seconds()
+			lastTime = now
+			if dt > 0 {
+				sample.rate = float64(work) / dt
+			}
+			if items > 0 {
+				sample.latency = elapsed / time.Duration(items)
+			}
+			l.adjust(sample)
+		}
+	}
+}
+
+// stop wakes any waiting workers and remembers the limit reached
+func (l *adaptiveLimit) stop() {
+	l.mu.Lock()
+	l.stopped = true
+	limit := l.limit
+	l.mu.Unlock()
+	l.cond.Broadcast()
+	adaptiveLearnedMu.Lock()
+	adaptiveLearned[l.key] = limit
+	adaptiveLearnedMu.Unlock()
+	fs.Debugf(nil, "Adaptive concurrency: finished with %d of %d %s", limit, l.max, l.what)
+}
+
+// setLimit changes the limit and wakes the workers if it went up
+func (l *adaptiveLimit) setLimit(limit int, why string, sample adaptiveSample) {
+	limit = min(max(limit, l.min), l.max)
+	l.mu.Lock()
+	old := l.limit
+	l.limit = limit
+	l.mu.Unlock()
+	l.prevLimit, l.prevRate = old, sample.rate
+	if limit == old {
+		return
+	}
+	l.cond.Broadcast()
+	if limit < old {
+		fs.Infof(nil, "Adaptive concurrency: reducing %s from %d to %d as %s", l.what, old, limit, why)
+	} else {
+		fs.Debugf(nil, "Adaptive concurrency: increasing %s from %d to %d as %s", l.what, old, limit, why)
+	}
+}
+
+// adjust decides the new limit from what was observed in the last
+// interval.
+//
+// It backs off quickly on rate limiting and otherwise climbs one
+// worker at a time while that keeps improving the throughput.
+func (l *adaptiveLimit) adjust(sample adaptiveSample) {
+	if sample.latency > 0 && (l.bestLatency == 0 || sample.latency < l.bestLatency) {
+		l.bestLatency = sample.latency
+	}
+	increased := l.limit > l.prevLimit
+	improved := sample.rate >= l.prevRate*adaptiveGain
+	switch {
+	case sample.backoffs > 0:
+		l.setLimit(l.limit-max(1, l.limit/4), "the remote is rate limiting", sample)
+		l.hold = adaptiveHold
+	case l.hold > 0:
+		l.hold--
+	case increased && !improved:
+		l.setLimit(l.prevLimit, "more didn't increase the throughput", sample)
+		l.hold = adaptiveHold
+	case !improved && l.bestLatency > 0 && sample.latency > adaptiveLatency*l.bestLatency:
+		l.setLimit(l.limit-1, "the latency has increased", sample)
+		l.hold = adaptiveHold
+	case sample.queued:
+		l.setLimit(l.limit+1, "there is work waiting", sample)
+	default:
+		// Not limited by the number of workers so keep the rate
+		// to compare against
+		l.prevLimit, l.prevRate = l.limit, sample.rate
+	}
+}
@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAdaptiveLimit makes an adaptiveLimit for testing
+func newTestAdaptiveLimit(t *testing.T, workers int) *adaptiveLimit {
+	p, err := newPipe("", func(int, int64) {}, -1)
+	require.NoError(t, err)
+	l := &adaptiveLimit{
+		what:  "workers",
+		key:   t.Name(),
+		min:   1,
+		max:   workers,
+		queue: p,
+		limit: 4,
+	}
+	l.prevLimit = l.limit
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func TestAdaptiveAdjust(t *testing.T) {
+	l := newTestAdaptiveLimit(t, 16)
+
+	// Work waiting and the rate improving keeps increasing the limit
+	l.adjust(adaptiveSample{rate: 100, queued: true})
+	assert.Equal(t, 5, l.limit)
+	l.adjust(adaptiveSample{rate: 120, queued: true})
+	assert.Equal(t, 6, l.limit)
+
+	// An increase which doesn't help is undone and held
+	l.adjust(adaptiveSample{rate: 121, queued: true})
+	assert.Equal(t, 5, l.limit)
+	for range adaptiveHold {
+		l.adjust(adaptiveSample{rate: 120, queued: true})
+		assert.Equal(t, 5, l.limit)
+	}
+	l.adjust(adaptiveSample{rate: 130, queued: true})
+	assert.Equal(t, 6, l.limit)
+
+	// Rate limiting backs off quickly
+	l.limit = 12
+	l.adjust(adaptiveSample{rate: 130, queued: true, backoffs: 2})
+	assert.Equal(t, 9, l.limit)
+	for range adaptiveHold {
+		l.adjust(adaptiveSample{rate: 130, queued: true})
+		assert.Equal(t, 9, l.limit)
+	}
+
+	// Nothing changes if there isn't any work waiting
+	l.adjust(adaptiveSample{rate: 130})
+	assert.Equal(t, 9, l.limit)
+
+	// Increasing latency without more throughput backs off
+	l.adjust(adaptiveSample{rate: 130, latency: time.Second})
+	l.adjust(adaptiveSample{rate: 130, latency: 3 * time.Second})
+	assert.Equal(t, 8, l.limit)
+
+	// The limits are respected
+	l.limit, l.hold = 16, 0
+	l.adjust(adaptiveSample{rate: 1000, queued: true})
+	assert.Equal(t, 16, l.limit)
+	l.limit = 1
+	l.adjust(adaptiveSample{rate: 1000, queued: true, backoffs: 1})
+	assert.Equal(t, 1, l.limit)
+}
+
+func TestAdaptiveLimitWorkers(t *testing.T) {
+	ctx := context.Background()
+	l := newTestAdaptiveLimit(t, 8)
+	l.limit = 2
+
+	var (
+		wg        sync.WaitGroup
+		active    atomic.Int32
+		maxActive atomic.Int32
+		processed atomic.Int32
+	)
+	for range l.max {
+		wg.Go(func() {
+			w := l.worker()
+			defer w.finish()
+			for {
+				_, ok := w.get(ctx, l.queue, -1)
+				if !ok {
+					return
+				}
+				n := active.Add(1)
+				for {
+					old := maxActive.Load()
+					if n <= old || maxActive.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				active.Add(-1)
+				processed.Add(1)
+			}
+		})
+	}
+	for i := range 50 {
+		require.True(t, l.queue.Put(ctx, fs.ObjectPair{Src: mockobject.Object("test"), Dst: nil}), i)
+	}
+	l.queue.Close()
+	wg.Wait()
+	assert.Equal(t, int32(50), processed.Load())
+	assert.Equal(t, int32(2), maxActive.Load())
+	assert.Equal(t, int64(50), l.items)
+	assert.Equal(t, 0, l.active)
+}
+
+// Test a sync with --adaptive-concurrency
+func TestSyncAdaptiveConcurrency(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	ci.AdaptiveConcurrency = true
+	ci.Transfers = 16
+	ci.Checkers = 16
+	oldInterval := adaptiveInterval
+	adaptiveInterval = time.Millisecond
+	defer func() { adaptiveInterval = oldInterval }()
+
+	var items []fstest.Item
+	for i := range 20 {
+		items = append(items, r.WriteFile(fmt.Sprintf("dir%d/file%d", i%3, i), "Hello", t1))
+	}
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, items...)
+
+	// The limit reached is remembered for the next sync
+	adaptiveLearnedMu.Lock()
+	_, found := adaptiveLearned["transfers "+r.Flocal.Name()+" "+r.Fremote.Name()]
+	adaptiveLearnedMu.Unlock()
+	assert.True(t, found)
+}
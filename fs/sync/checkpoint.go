@@ -0,0 +1,343 @@
+package sync
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// checkpointVersion is written into the header of the checkpoint
+// file and must match for the checkpoint to be used.
+const checkpointVersion = 1
+
+// Types of record in the checkpoint file
+const (
+	checkpointHeader = "header" // identifies the sync the checkpoint is for
+	checkpointDone   = "dir"    // a directory which was completely synced
+	checkpointStart  = "start"  // a transfer was started
+	checkpointEnd    = "end"    // a transfer finished
+)
+
+// checkpointRecord is one line of the checkpoint file
+type checkpointRecord struct {
+	Type        string `json:"type"`
+	Version     int    `json:"version,omitempty"`
+	Src         string `json:"src,omitempty"`
+	Dst         string `json:"dst,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	Dir         string `json:"dir,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Remote      string `json:"remote,omitempty"`
+}
+
+// checkpointDir tracks the progress of a directory in this run
+type checkpointDir struct {
+	fingerprint string // fingerprint of the source listing
+	pending     int    // number of objects queued but not finished
+	listed      bool   // set when all the entries have been queued
+	failed      bool   // set if anything in the directory failed
+}
+
+// checkpoint records which directories a sync has completed so that
+// an interrupted sync can skip them when it is run again.
+//
+// The checkpoint file is a series of JSON records, one per line. It
+// is only ever appended to while the sync is running so it is
+// consistent whenever the sync is interrupted.
+type checkpoint struct {
+	ctx      context.Context
+	path     string                    // path of the checkpoint file
+	mu       sync.Mutex                // protect the below
+	out      *os.File                  // checkpoint file being written
+	enc      *json.Encoder             // encoder writing to out
+	err      error                     // first error writing the checkpoint
+	previous map[string]string         // dir => fingerprint of dirs done in previous runs
+	dirs     map[string]*checkpointDir // dirs in progress in this run
+	skipped  int                       // number of directories skipped
+}
+
+// checkpointMode describes the kind of sync for the checkpoint header
+func checkpointMode(deleteMode fs.DeleteMode, doMove bool) string {
+	switch {
+	case doMove:
+		return "move"
+	case deleteMode != fs.DeleteModeOff:
+		return "sync"
+	}
+	return "copy"
+}
+
+// newCheckpoint opens the checkpoint file at filePath reading the
+// directories completed by a previous run of the same sync.
+//
+// The checkpoint file is rewritten so it only contains the completed
+// directories.
+func newCheckpoint(ctx context.Context, filePath string, fdst, fsrc fs.Fs, mode string) (*checkpoint, error) {
+	c := &checkpoint{
+		ctx:      ctx,
+		path:     filePath,
+		previous: make(map[string]string),
+		dirs:     make(map[string]*checkpointDir),
+	}
+	header := checkpointRecord{
+		Type:    checkpointHeader,
+		Version: checkpointVersion,
+		Src:     fs.ConfigString(fsrc),
+		Dst:     fs.ConfigString(fdst),
+		Mode:    mode,
+	}
+	err := c.load(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.previous) > 0 {
+		fs.Infof(nil, "Resuming from checkpoint file %q with %d directories done", filePath, len(c.previous))
+	}
+
+	// Write the compacted checkpoint to a temporary file and
+	// rename it into place so we never lose the old one.
+	tmpPath := filePath + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	c.out = out
+	c.enc = json.NewEncoder(out)
+	c.write(header)
+	for dir, fingerprint := range c.previous {
+		c.write(checkpointRecord{Type: checkpointDone, Dir: dir, Fingerprint: fingerprint})
+	}
+	if c.err == nil {
+		c.err = os.Rename(tmpPath, filePath)
+	}
+	if c.err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write checkpoint file: %w", c.err)
+	}
+	return c, nil
+}
+
+// load reads the checkpoint file if it exists and is for the sync
+// described by header.
+func (c *checkpoint) load(header checkpointRecord) (err error) {
+	in, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+	var (
+		dec      = json.NewDecoder(bufio.NewReader(in))
+		inFlight = make(map[string]struct{})
+		rec      checkpointRecord
+	)
+	if err = dec.Decode(&rec); err != nil || rec != header {
+		fs.Logf(nil, "Ignoring checkpoint file %q as it is for a different sync", c.path)
+		return nil
+	}
+	for {
+		rec = checkpointRecord{}
+		err = dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// The last record may be truncated if rclone was killed
+			fs.Debugf(nil, "Stopped reading checkpoint file %q: %v", c.path, err)
+			break
+		}
+		switch rec.Type {
+		case checkpointDone:
+			c.previous[rec.Dir] = rec.Fingerprint
+		case checkpointStart:
+			inFlight[rec.Remote] = struct{}{}
+		case checkpointEnd:
+			delete(inFlight, rec.Remote)
+		}
+	}
+	for remote := range inFlight {
+		fs.Infof(remote, "Transfer was interrupted in a previous run - will check it again")
+	}
+	return nil
+}
+
+// write a record to the checkpoint file - call with the lock held
+func (c *checkpoint) write(rec checkpointRecord) {
+	if c.err != nil {
+		return
+	}
+	c.err = c.enc.Encode(rec)
+	if c.err != nil {
+		fs.Errorf(nil, "Failed to write checkpoint file %q: %v", c.path, c.err)
+	}
+}
+
+// checkpointFingerprint returns a fingerprint of a source listing so
+// we can tell whether it has changed since a previous run.
+func checkpointFingerprint(ctx context.Context, entries fs.DirEntries) string {
+	h := sha1.New()
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			_, _ = fmt.Fprintf(h, "o %q %s\n", x.Remote(), fs.Fingerprint(ctx, x, true))
+		case fs.Directory:
+			_, _ = fmt.Fprintf(h, "d %q\n", x.Remote())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// entryDir returns the directory the entry is in
+func entryDir(entry fs.DirEntry) string {
+	dir := path.Dir(entry.Remote())
+	if dir == "." {
+		dir = ""
+	}
+	return dir
+}
+
+// srcDir is called with the source listing of dir and returns true
+// if it was completed by a previous run and can be skipped.
+func (c *checkpoint) srcDir(dir string, entries fs.DirEntries) (skip bool) {
+	fingerprint := checkpointFingerprint(c.ctx, entries)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if previous, ok := c.previous[dir]; ok {
+		if previous == fingerprint {
+			fs.Debugf(dir, "Skipping directory as it was completed in a previous run")
+			c.skipped++
+			return true
+		}
+		fs.Debugf(dir, "Directory has changed since it was completed in a previous run")
+	}
+	c.dirs[dir] = &checkpointDir{fingerprint: fingerprint}
+	return false
+}
+
+// dirDone is called when all the entries of dir have been queued
+func (c *checkpoint) dirDone(dir string, incomplete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.dirs[dir]
+	if d == nil {
+		return
+	}
+	d.listed = true
+	if incomplete {
+		d.failed = true
+	}
+	c.finish(dir, d)
+}
+
+// finish writes a completed directory - call with the lock held
+func (c *checkpoint) finish(dir string, d *checkpointDir) {
+	if !d.listed || d.pending > 0 {
+		return
+	}
+	delete(c.dirs, dir)
+	if d.failed {
+		return
+	}
+	c.write(checkpointRecord{Type: checkpointDone, Dir: dir, Fingerprint: d.fingerprint})
+}
+
+// add records that the source entry has been queued
+func (c *checkpoint) add(src fs.DirEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := c.dirs[entryDir(src)]; d != nil {
+		d.pending++
+	}
+}
+
+// done records that the source entry queued with add has finished
+func (c *checkpoint) done(src fs.DirEntry, failed bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dir := entryDir(src)
+	if d := c.dirs[dir]; d != nil {
+		d.pending--
+		if failed {
+			d.failed = true
+		}
+		c.finish(dir, d)
+	}
+}
+
+// fail records that something in the directory of the source entry
+// failed so the directory will be checked again next time
+func (c *checkpoint) fail(src fs.DirEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := c.dirs[entryDir(src)]; d != nil {
+		d.failed = true
+	}
+}
+
+// startTransfer records that a transfer of src has started
+func (c *checkpoint) startTransfer(src fs.Object) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.write(checkpointRecord{Type: checkpointStart, Remote: src.Remote()})
+}
+
+// endTransfer records that the transfer of src has finished
+func (c *checkpoint) endTransfer(src fs.Object, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.write(checkpointRecord{Type: checkpointEnd, Remote: src.Remote()})
+	c.mu.Unlock()
+	c.done(src, err != nil)
+}
+
+// close the checkpoint file, removing it if the sync was successful
+func (c *checkpoint) close(success bool) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.skipped > 0 {
+		fs.Infof(nil, "Skipped %d directories completed in a previous run", c.skipped)
+	}
+	err := c.out.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+	if !success {
+		fs.Infof(nil, "Sync did not complete - run it again to resume from checkpoint file %q", c.path)
+		return nil
+	}
+	fs.Debugf(nil, "Removing checkpoint file %q as sync completed", c.path)
+	err = os.Remove(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to remove checkpoint file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package sync
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/errcount"
+	"github.com/rclone/rclone/lib/transform"
+	"golang.org/x/sync/errgroup"
+)
+
+// PlanVersion is the version of the plan file format written by
+// --plan-out and read by ApplyPlan.
+const PlanVersion = 1
+
+// Actions which can appear in a Plan.
+//
+// These are listed in the order ApplyPlan carries them out.
+const (
+	PlanRename    = "rename"      // rename Remote to NewRemote on the destination
+	PlanMkdir     = "mkdir"       // make directory Remote on the destination
+	PlanCopy      = "copy"        // copy Remote from the source to the destination
+	PlanMove      = "move"        // move Remote from the source to the destination
+	PlanModTime   = "set_modtime" // set the modification time of Remote on the destination to the source's
+	PlanDeleteSrc = "delete_src"  // delete Remote from the source as it is already on the destination
+	PlanDelete    = "delete"      // delete Remote from the destination
+	PlanRmdir     = "rmdir"       // remove directory Remote from the destination if empty
+	PlanRmdirSrc  = "rmdir_src"   // remove directory Remote from the source if empty
+)
+
+// planOrder is the order actions are carried out in
+var planOrder = []string{PlanRename, PlanMkdir, PlanCopy, PlanMove, PlanModTime, PlanDeleteSrc, PlanDelete, PlanRmdir, PlanRmdirSrc}
+
+// Plan is a list of the actions a sync would carry out, written by
+// --plan-out so it can be reviewed and then carried out with
+// ApplyPlan.
+//
+// Src and Dst don't include any connection string parameters as the
+// plan is meant to be shared, so these remotes must be given to
+// ApplyPlan explicitly.
+type Plan struct {
+	Version int          `json:"version"`
+	Src     string       `json:"src"`     // source remote
+	Dst     string       `json:"dst"`     // destination remote
+	Mode    string       `json:"mode"`    // sync, copy or move
+	Created time.Time    `json:"created"` // when the plan was made
+	Actions []PlanAction `json:"actions"`
+}
+
+// PlanAction is a single action in a Plan.
+//
+// Src and Dst record the state of the source and destination objects
+// the action was based on. A nil Dst for a copy or move means the
+// object didn't exist on the destination.
+type PlanAction struct {
+	Action    string      `json:"action"`
+	Remote    string      `json:"remote"`
+	NewRemote string      `json:"new_remote,omitempty"`
+	Src       *PlanObject `json:"src,omitempty"`
+	Dst       *PlanObject `json:"dst,omitempty"`
+}
+
+// PlanObject records the state of an object when the plan was made.
+type PlanObject struct {
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modtime,omitzero"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// planRecorder collects the actions for --plan-out
+type planRecorder struct {
+	ctx  context.Context
+	ht   hash.Type // hash to record if cheap or --checksum is set
+	mu   sync.Mutex
+	plan Plan
+}
+
+// newPlanRecorder checks the sync can be planned and returns a
+// recorder for its actions.
+func newPlanRecorder(ctx context.Context, fdst, fsrc fs.Fs, mode string) (*planRecorder, error) {
+	ci := fs.GetConfig(ctx)
+	switch {
+	case ci.BackupDir != "" || ci.Suffix != "":
+		return nil, errors.New("can't use --plan-out with --backup-dir or --suffix")
+	case len(ci.CompareDest) > 0 || len(ci.CopyDest) > 0:
+		return nil, errors.New("can't use --plan-out with --compare-dest or --copy-dest")
+	case transform.Transforming(ctx):
+		return nil, errors.New("can't use --plan-out with --name-transform")
+	}
+	for _, f := range []fs.Fs{fsrc, fdst} {
+		if strings.ContainsRune(f.Name(), '{') {
+			fs.Logf(f, "Connection string parameters aren't written to the plan so give the source and destination to rclone apply")
+		}
+	}
+	return &planRecorder{
+		ctx: ctx,
+		ht:  fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
+		plan: Plan{
+			Version: PlanVersion,
+			Src:     fs.ConfigString(fsrc),
+			Dst:     fs.ConfigString(fdst),
+			Mode:    mode,
+			Created: time.Now(),
+		},
+	}, nil
+}
+
+// planObject records the state of o
+func (p *planRecorder) planObject(o fs.Object) *PlanObject {
+	if o == nil {
+		return nil
+	}
+	ci := fs.GetConfig(p.ctx)
+	features := o.Fs().Features()
+	po := &PlanObject{Size: o.Size()}
+	if !features.SlowModTime {
+		po.ModTime = o.ModTime(p.ctx).UTC()
+	}
+	if p.ht != hash.None && (ci.CheckSum || !features.SlowHash) {
+		sum, err := o.Hash(p.ctx, p.ht)
+		if err != nil {
+			fs.Debugf(o, "Not recording %v in plan: %v", p.ht, err)
+		} else if sum != "" {
+			po.Hashes = map[string]string{p.ht.String(): sum}
+		}
+	}
+	return po
+}
+
+// add records an action in the plan
+func (p *planRecorder) add(action, remote, newRemote string, src, dst fs.Object) {
+	if p == nil {
+		return
+	}
+	a := PlanAction{
+		Action:    action,
+		Remote:    remote,
+		NewRemote: newRemote,
+		Src:       p.planObject(src),
+		Dst:       p.planObject(dst),
+	}
+	p.mu.Lock()
+	p.plan.Actions = append(p.plan.Actions, a)
+	p.mu.Unlock()
+}
+
+// addModTime records that the modification time of dst is set to
+// that of src. It is called by operations via WithModTimeUpdateFn.
+func (p *planRecorder) addModTime(ctx context.Context, src fs.ObjectInfo, dst fs.Object) {
+	srcObj, ok := src.(fs.Object)
+	if !ok {
+		return
+	}
+	p.add(PlanModTime, dst.Remote(), "", srcObj, dst)
+}
+
+// addDir records an action on a directory in the plan
+func (p *planRecorder) addDir(action, dir string) {
+	p.add(action, dir, "", nil, nil)
+}
+
+// write the plan to filePath
+func (p *planRecorder) write(filePath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sortPlan(&p.plan)
+	data, err := json.MarshalIndent(&p.plan, "", "\t")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so an old plan
+	// isn't left half overwritten
+	tmpPath := filePath + ".tmp"
+	err = os.WriteFile(tmpPath, append(data, '\n'), 0666)
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	fs.Infof(nil, "Wrote plan with %d actions to %q", len(p.plan.Actions), filePath)
+	return nil
+}
+
+// planRank returns the position of action in planOrder
+func planRank(action string) int {
+	i := slices.Index(planOrder, action)
+	if i < 0 {
+		return len(planOrder)
+	}
+	return i
+}
+
+// sortPlan sorts the actions into the order they will be carried out
+func sortPlan(plan *Plan) {
+	slices.SortStableFunc(plan.Actions, func(a, b PlanAction) int {
+		if c := cmp.Compare(planRank(a.Action), planRank(b.Action)); c != 0 {
+			return c
+		}
+		if a.Action == PlanRmdir || a.Action == PlanRmdirSrc {
+			// Remove the deepest directories first
+			return cmp.Compare(b.Remote, a.Remote)
+		}
+		return cmp.Compare(a.Remote, b.Remote)
+	})
+}
+
+// ReadPlan reads a plan written by --plan-out from filePath
+func ReadPlan(filePath string) (*Plan, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var plan Plan
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plan %q: %w", filePath, err)
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("plan %q has version %d but only version %d is supported", filePath, plan.Version, PlanVersion)
+	}
+	for _, a := range plan.Actions {
+		if planRank(a.Action) == len(planOrder) {
+			return nil, fmt.Errorf("plan %q has unknown action %q", filePath, a.Action)
+		}
+	}
+	return &plan, nil
+}
+
+// ApplyOpt are the options for ApplyPlan
+type ApplyOpt struct {
+	SkipChanged bool // skip actions whose preconditions no longer hold rather than failing
+}
+
+// applyAction is a PlanAction with the objects it acts on
+type applyAction struct {
+	PlanAction
+	src fs.Object // source object, if any
+	dst fs.Object // destination object, if any
+}
+
+// errPlanChanged is returned when an object no longer matches the plan
+var errPlanChanged = errors.New("changed since the plan was made")
+
+// checkPlanObject checks o matches the state recorded in want. If
+// want is nil then o should not exist.
+func checkPlanObject(ctx context.Context, f fs.Fs, remote string, want *PlanObject) (o fs.Object, err error) {
+	o, err = f.NewObject(ctx, remote)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		if want == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s on %v: not found: %w", remote, f, errPlanChanged)
+	} else if err != nil {
+		return nil, err
+	}
+	if want == nil {
+		return nil, fmt.Errorf("%s on %v: now exists: %w", remote, f, errPlanChanged)
+	}
+	if o.Size() != want.Size {
+		return nil, fmt.Errorf("%s on %v: size %d, was %d: %w", remote, f, o.Size(), want.Size, errPlanChanged)
+	}
+	if !want.ModTime.IsZero() {
+		modifyWindow := fs.GetModifyWindow(ctx, f)
+		if modifyWindow != fs.ModTimeNotSupported {
+			dt := o.ModTime(ctx).Sub(want.ModTime)
+			if dt < -modifyWindow || dt > modifyWindow {
+				return nil, fmt.Errorf("%s on %v: modification time changed by %v: %w", remote, f, dt, errPlanChanged)
+			}
+		}
+	}
+	for name, wantSum := range want.Hashes {
+		var ht hash.Type
+		if ht.Set(name) != nil || !f.Hashes().Contains(ht) {
+			continue
+		}
+		sum, err := o.Hash(ctx, ht)
+		if err != nil {
+			return nil, err
+		}
+		if !hash.Equals(sum, wantSum) {
+			return nil, fmt.Errorf("%s on %v: %v changed: %w", remote, f, ht, errPlanChanged)
+		}
+	}
+	return o, nil
+}
+
+// check checks the preconditions of the action still hold and finds
+// the objects it acts on.
+func (a *applyAction) check(ctx context.Context, fdst, fsrc fs.Fs) (err error) {
+	switch a.Action {
+	case PlanCopy, PlanMove, PlanModTime:
+		if a.src, err = checkPlanObject(ctx, fsrc, a.Remote, a.Src); err != nil {
+			return err
+		}
+		a.dst, err = checkPlanObject(ctx, fdst, a.Remote, a.Dst)
+	case PlanDeleteSrc:
+		if a.src, err = checkPlanObject(ctx, fsrc, a.Remote, a.Src); err != nil {
+			return err
+		}
+		// Only delete the source if the destination still has it
+		a.dst, err = checkPlanObject(ctx, fdst, a.Remote, a.Dst)
+	case PlanDelete:
+		a.dst, err = checkPlanObject(ctx, fdst, a.Remote, a.Dst)
+	case PlanRename:
+		if a.dst, err = checkPlanObject(ctx, fdst, a.Remote, a.Dst); err != nil {
+			return err
+		}
+		_, err = checkPlanObject(ctx, fdst, a.NewRemote, nil)
+	}
+	return err
+}
+
+// do carries out the action
+func (a *applyAction) do(ctx context.Context, fdst, fsrc fs.Fs) (err error) {
+	switch a.Action {
+	case PlanRename:
+		_, err = operations.Move(ctx, fdst, nil, a.NewRemote, a.dst)
+	case PlanMkdir:
+		err = operations.Mkdir(ctx, fdst, a.Remote)
+	case PlanCopy:
+		_, err = operations.Copy(ctx, fdst, a.dst, a.Remote, a.src)
+	case PlanMove:
+		_, err = operations.Move(ctx, fdst, a.dst, a.Remote, a.src)
+	case PlanModTime:
+		if operations.SkipDestructive(ctx, a.dst, "update modification time") {
+			return nil
+		}
+		err = a.dst.SetModTime(ctx, a.src.ModTime(ctx))
+		if errors.Is(err, fs.ErrorCantSetModTime) || errors.Is(err, fs.ErrorCantSetModTimeWithoutDelete) {
+			// As the sync would, upload the file again to set its modification time
+			fs.Infof(a.dst, "Can't set modification time without re-uploading: %v", err)
+			_, err = operations.Copy(ctx, fdst, a.dst, a.Remote, a.src)
+		} else if err == nil {
+			fs.Infof(a.src, "Updated modification time in destination")
+		}
+	case PlanDeleteSrc:
+		err = operations.DeleteFile(ctx, a.src)
+	case PlanDelete:
+		err = operations.DeleteFile(ctx, a.dst)
+	case PlanRmdir, PlanRmdirSrc:
+		f := fdst
+		if a.Action == PlanRmdirSrc {
+			f = fsrc
+		}
+		// TryRmdir only removes empty directories
+		if rmErr := operations.TryRmdir(ctx, f, a.Remote); rmErr != nil {
+			fs.Debugf(fs.LogDirName(f, a.Remote), "Failed to Rmdir: %v", rmErr)
+		}
+	}
+	return err
+}
+
+// ApplyPlan carries out the actions in plan on fdst and fsrc.
+//
+// The preconditions of every action are checked before anything is
+// done. If any of them no longer hold then nothing is done unless
+// opt.SkipChanged is set, in which case those actions are skipped.
+func ApplyPlan(ctx context.Context, fdst, fsrc fs.Fs, plan *Plan, opt ApplyOpt) error {
+	ci := fs.GetConfig(ctx)
+	actions := make([]*applyAction, 0, len(plan.Actions))
+	for _, a := range plan.Actions {
+		actions = append(actions, &applyAction{PlanAction: a})
+	}
+
+	// Check the preconditions of all the actions
+	var (
+		mu      sync.Mutex
+		changed int
+	)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(ci.Checkers)
+	for _, a := range actions {
+		g.Go(func() error {
+			tr := accounting.Stats(gCtx).NewCheckingTransfer(fs.NewDir(a.Remote, time.Time{}), "checking")
+			err := a.check(gCtx, fdst, fsrc)
+			tr.Done(gCtx, nil)
+			if errors.Is(err, errPlanChanged) {
+				mu.Lock()
+				changed++
+				mu.Unlock()
+				if opt.SkipChanged {
+					fs.Logf(nil, "Skipping %s: %v", a.Action, err)
+					a.Action = "" // skip
+				} else {
+					fs.Errorf(nil, "Can't %s: %v", a.Action, err)
+				}
+				return nil
+			}
+			return err
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to check plan: %w", err)
+	}
+	if changed > 0 && !opt.SkipChanged {
+		return fmt.Errorf("%d actions have changed since the plan was made - not applying the plan", changed)
+	}
+
+	// Carry out the actions in order, one kind of action at a time
+	errCount := errcount.New()
+	for _, action := range planOrder {
+		if action == PlanDelete && errCount.Err("failed to apply plan") != nil && !ci.IgnoreErrors {
+			fs.Errorf(fdst, "%v", fs.ErrorNotDeleting)
+			errCount.Add(fs.ErrorNotDeleting)
+			break
+		}
+		limit := ci.Transfers
+		switch action {
+		case PlanRename, PlanMkdir, PlanRmdir, PlanRmdirSrc:
+			// Directory actions and renames depend on each other so do them in order
+			limit = 1
+		case PlanDelete, PlanDeleteSrc:
+			limit = ci.Checkers
+		}
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(limit)
+		for _, a := range actions {
+			if a.Action != action {
+				continue
+			}
+			g.Go(func() error {
+				if err := a.do(gCtx, fdst, fsrc); err != nil {
+					fs.Errorf(a.Remote, "Failed to %s: %v", a.Action, err)
+					errCount.Add(err)
+				}
+				return gCtx.Err()
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	if changed > 0 {
+		fs.Logf(nil, "Skipped %d actions which changed since the plan was made", changed)
+	}
+	return errCount.Err("failed to apply plan")
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"path"
 	"slices"
 	"sort"
@@ -17,10 +18,13 @@ import (
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/listcache"
 	"github.com/rclone/rclone/fs/march"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/errcount"
 	"github.com/rclone/rclone/lib/transform"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -83,6 +87,10 @@ type syncCopyMove struct {
 	trackRenamesWg         sync.WaitGroup         // wg for background track renames
 	trackRenamesCh         chan fs.Object         // objects are pumped in here
 	renameCheck            []fs.Object            // accumulate files to check for rename here
+	trackDirRenames        bool                   // set if we should look for renamed directories
+	onlyDirsMu             sync.Mutex             // protect srcOnlyDirs and dstOnlyDirs
+	srcOnlyDirs            map[string]struct{}    // dirs only in the src - only used by trackDirRenames
+	dstOnlyDirs            map[string]struct{}    // dirs only in the dst - only used by trackDirRenames
 	compareCopyDest        []fs.Fs                // place to check for files to server side copy
 	backupDir              fs.Fs                  // place to store overwrites/deletes
 	checkFirst             bool                   // if set run all the checkers before starting transfers
@@ -97,6 +105,11 @@ type syncCopyMove struct {
 	setDirModTimesMaxLevel int                    // max level of the directories to set
 	modifiedDirs           map[string]struct{}    // dirs with changed contents (if s.setDirModTimeAfter)
 	allowOverlap           bool                   // whether we allow src and dst to overlap (i.e. for convmv)
+	checkpoint             *checkpoint            // records progress if --checkpoint-file is set
+	dstListCache           *listcache.Cache       // destination listing cache if --dst-list-cache is set
+	plan                   *planRecorder          // records the actions if --plan-out is set
+	checkerLimit           *adaptiveLimit         // limits the active checkers if --adaptive-concurrency is set
+	transferLimit          *adaptiveLimit         // limits the active transfers if --adaptive-concurrency is set
 }
 
 // For keeping track of delayed modtime sets
@@ -158,6 +171,8 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		commonHash:             fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
 		modifyWindow:           fs.GetModifyWindow(ctx, fsrc, fdst),
 		trackRenamesCh:         make(chan fs.Object, ci.Checkers),
+		srcOnlyDirs:            make(map[string]struct{}),
+		dstOnlyDirs:            make(map[string]struct{}),
 		checkFirst:             ci.CheckFirst,
 		setDirMetadata:         ci.Metadata && fsrc.Features().ReadDirMetadata && fdst.Features().WriteDirMetadata,
 		setDirModTime:          (!ci.NoUpdateDirModTime && fsrc.Features().CanHaveEmptyDirectories) && (fdst.Features().WriteDirSetModTime || fdst.Features().MkdirMetadata != nil || fdst.Features().DirSetModTime != nil),
@@ -193,6 +208,8 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 	if err != nil {
 		return nil, err
 	}
+	s.checkerLimit = newAdaptiveLimit(ctx, "checkers", fdst, fsrc, ci.Checkers, s.toBeChecked, nil)
+	s.transferLimit = newAdaptiveLimit(ctx, "transfers", fdst, fsrc, ci.Transfers, s.toBeUploaded, accounting.Stats(ctx).GetBytes)
 	if ci.MaxDuration > 0 {
 		s.maxDurationEndTime = time.Now().Add(time.Duration(ci.MaxDuration))
 		fs.Infof(s.fdst, "Transfer session %v deadline: %s", ci.CutoffMode, s.maxDurationEndTime.Format("2006/01/02 15:04:05"))
@@ -267,6 +284,23 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			fs.Errorf(nil, "Ignoring --no-traverse with --track-renames")
 			s.noTraverse = false
 		}
+		if ci.TrackRenamesDirThreshold < 0 || ci.TrackRenamesDirThreshold > 1 {
+			return nil, errors.New("--track-renames-dir-threshold must be between 0 and 1")
+		}
+		if ci.TrackRenamesDirThreshold > 0 {
+			switch {
+			case fdst.Features().DirMove == nil:
+				fs.Errorf(fdst, "Ignoring --track-renames-dir-threshold as the destination does not support server-side directory move")
+			case !fi.InActive():
+				fs.Errorf(fdst, "Ignoring --track-renames-dir-threshold as it doesn't work with filters")
+			case transform.Transforming(ctx):
+				fs.Errorf(fdst, "Ignoring --track-renames-dir-threshold as it doesn't work with --name-transform")
+			case ci.PlanOut != "":
+				fs.Errorf(fdst, "Ignoring --track-renames-dir-threshold as it doesn't work with --plan-out")
+			default:
+				s.trackDirRenames = true
+			}
+		}
 	}
 	// Make Fs for --backup-dir if required
 	if ci.BackupDir != "" || ci.Suffix != "" {
@@ -289,6 +323,39 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return nil, err
 		}
 	}
+	if ci.CheckpointFile != "" && s.deleteMode != fs.DeleteModeOnly {
+		switch {
+		case ci.DryRun:
+			fs.Errorf(fdst, "Ignoring --checkpoint-file with --dry-run")
+		case s.trackRenames:
+			fs.Errorf(fdst, "Ignoring --checkpoint-file as it doesn't work with --track-renames")
+		case transform.Transforming(ctx):
+			fs.Errorf(fdst, "Ignoring --checkpoint-file as it doesn't work with --name-transform")
+		default:
+			s.checkpoint, err = newCheckpoint(ctx, ci.CheckpointFile, fdst, fsrc, checkpointMode(s.deleteMode, s.DoMove))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ci.DstListCache {
+		switch {
+		case s.noTraverse:
+			fs.Errorf(fdst, "Ignoring --dst-list-cache as the destination isn't being listed")
+		case s.trackRenames:
+			fs.Errorf(fdst, "Ignoring --dst-list-cache as it doesn't work with --track-renames")
+		case s.backupDir != nil:
+			fs.Errorf(fdst, "Ignoring --dst-list-cache as it doesn't work with --backup-dir")
+		case transform.Transforming(ctx):
+			fs.Errorf(fdst, "Ignoring --dst-list-cache as it doesn't work with --name-transform")
+		default:
+			s.dstListCache, err = listcache.New(ctx, fdst, time.Duration(ci.DstListCacheMaxAge))
+			if err != nil {
+				_ = s.checkpoint.close(false)
+				return nil, err
+			}
+		}
+	}
 	return s, nil
 }
 
@@ -370,20 +437,38 @@ func (s *syncCopyMove) currentError() error {
 // FIXME potentially doing lots of hashes at once
 func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.WaitGroup) {
 	defer wg.Done()
+	w := s.checkerLimit.worker()
+	defer w.finish()
 	for {
-		pair, ok := in.GetMax(s.inCtx, fraction)
+		pair, ok := w.get(s.inCtx, in, fraction)
 		if !ok {
 			return
 		}
 		src := pair.Src
 		var err error
+		failed := false // set if checking src failed
+		queued := false // set if src was passed on to out
 		tr := accounting.Stats(s.ctx).NewCheckingTransfer(src, "checking")
 		// Check to see if can store this
 		if src.Storable() {
 			needTransfer := operations.NeedTransfer(s.ctx, pair.Dst, pair.Src)
+			fixCase := s.ci.FixCase && !s.ci.Immutable && pair.Dst != nil && src.Remote() != pair.Dst.Remote()
+			if needTransfer || fixCase {
+				// Entries from the listing cache can't be changed directly
+				pair.Dst, err = s.resolveDstObject(s.ctx, pair.Dst)
+				if err != nil {
+					s.processError(err)
+					s.logger(s.ctx, operations.TransferError, pair.Src, pair.Dst, err)
+					s.checkpoint.done(src, true)
+					tr.Done(s.ctx, err)
+					continue
+				}
+			}
 			if needTransfer {
+				s.markDstChanged(src.Remote())
 				NoNeedTransfer, err := operations.CompareOrCopyDest(s.ctx, s.fdst, pair.Dst, pair.Src, s.compareCopyDest, s.backupDir)
 				if err != nil {
+					failed = true
 					s.processError(err)
 					s.logger(s.ctx, operations.TransferError, pair.Src, pair.Dst, err)
 				}
@@ -392,7 +477,7 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 				}
 			}
 			// Fix case for case insensitive filesystems
-			if s.ci.FixCase && !s.ci.Immutable && pair.Dst != nil && src.Remote() != pair.Dst.Remote() {
+			if fixCase && pair.Dst != nil {
 				// NeedTransfer's equality check may have deleted pair.Dst as a precursor
 				// to re-uploading it (the way modtime updates are done on backends like
 				// Dropbox that can't set modtime in place). If so, there is nothing to
@@ -405,8 +490,11 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 					}
 				}
 				if pair.Dst != nil {
+					s.markDstChanged(pair.Dst.Remote())
+					s.plan.add(PlanRename, pair.Dst.Remote(), src.Remote(), nil, pair.Dst)
 					if newDst, err := operations.Move(s.ctx, s.fdst, nil, src.Remote(), pair.Dst); err != nil {
 						fs.Errorf(pair.Dst, "Error while attempting to rename to %s: %v", src.Remote(), err)
+						failed = true
 						s.processError(err)
 					} else {
 						fs.Infof(pair.Dst, "Fixed case by renaming to: %s", src.Remote())
@@ -419,6 +507,7 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 				if s.ci.Immutable && pair.Dst != nil {
 					err := fs.CountError(s.ctx, fserrors.NoRetryError(fs.ErrorImmutableModified))
 					fs.Errorf(pair.Dst, "Source and destination exist but do not match: %v", err)
+					failed = true
 					s.processError(err)
 				} else {
 					if pair.Dst != nil {
@@ -430,6 +519,7 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 					if pair.Dst != nil && s.backupDir != nil {
 						err := operations.MoveBackupDir(s.ctx, s.backupDir, pair.Dst)
 						if err != nil {
+							failed = true
 							s.processError(err)
 							s.logger(s.ctx, operations.TransferError, pair.Src, pair.Dst, err)
 						} else {
@@ -439,12 +529,14 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 							if !ok {
 								return
 							}
+							queued = true
 						}
 					} else {
 						ok = out.Put(s.inCtx, pair)
 						if !ok {
 							return
 						}
+						queued = true
 					}
 				}
 			} else {
@@ -459,18 +551,25 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 						// If we want perfect ordering then use the transfers to delete the file
 						//
 						// We send src == dst, to say we want the src deleted
+						s.plan.add(PlanDeleteSrc, src.Remote(), "", src, pair.Dst)
 						ok = out.Put(s.inCtx, fs.ObjectPair{Src: src, Dst: src})
 						if !ok {
 							return
 						}
+						queued = true
 					} else {
+						s.plan.add(PlanDeleteSrc, src.Remote(), "", src, pair.Dst)
 						deleteFileErr := operations.DeleteFile(s.ctx, src)
+						failed = deleteFileErr != nil
 						s.processError(deleteFileErr)
 						s.logger(s.ctx, operations.TransferError, pair.Src, pair.Dst, deleteFileErr)
 					}
 				}
 			}
 		}
+		if !queued {
+			s.checkpoint.done(src, failed)
+		}
 		tr.Done(s.ctx, err)
 	}
 }
@@ -499,24 +598,31 @@ func (s *syncCopyMove) pairRenamer(in *pipe, out *pipe, fraction int, wg *sync.W
 // pairCopyOrMove reads Objects on in and moves or copies them.
 func (s *syncCopyMove) pairCopyOrMove(ctx context.Context, in *pipe, fdst fs.Fs, fraction int, wg *sync.WaitGroup) {
 	defer wg.Done()
+	w := s.transferLimit.worker()
+	defer w.finish()
 	var err error
 	for {
-		pair, ok := in.GetMax(s.inCtx, fraction)
+		pair, ok := w.get(s.inCtx, in, fraction)
 		if !ok {
 			return
 		}
 		src := pair.Src
 		dst := pair.Dst
+		s.checkpoint.startTransfer(src)
 		if s.DoMove {
 			if src != dst {
+				s.plan.add(PlanMove, src.Remote(), "", src, dst)
 				_, err = operations.MoveTransfer(ctx, fdst, dst, src.Remote(), src)
 			} else {
 				// src == dst signals delete the src
 				err = operations.DeleteFile(ctx, src)
 			}
 		} else {
+			s.plan.add(PlanCopy, src.Remote(), "", src, dst)
 			_, err = operations.Copy(ctx, fdst, dst, src.Remote(), src)
 		}
+		s.checkpoint.endTransfer(src, err)
+		s.transferLimit.observe(err)
 		s.processError(err)
 		if err != nil {
 			s.logger(ctx, operations.TransferError, src, dst, err)
@@ -654,6 +760,7 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 			if s.aborting() {
 				break
 			}
+			s.plan.add(PlanDelete, remote, "", nil, o)
 			select {
 			case <-s.ctx.Done():
 				break outer
@@ -687,6 +794,11 @@ func (s *syncCopyMove) deleteEmptyDirectories(ctx context.Context, f fs.Fs, entr
 	for _, entry := range slices.Backward(entries) {
 		dir, ok := entry.(fs.Directory)
 		if ok {
+			if f == s.fdst {
+				s.plan.addDir(PlanRmdir, dir.Remote())
+			} else {
+				s.plan.addDir(PlanRmdirSrc, dir.Remote())
+			}
 			// TryRmdir only deletes empty directories
 			err := operations.TryRmdir(ctx, f, dir.Remote())
 			if err != nil {
@@ -910,6 +1022,7 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
 
 	// Rename dst to have name src.Remote()
+	s.plan.add(PlanRename, dst.Remote(), src.Remote(), nil, dst)
 	_, err := operations.Move(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
 	if err != nil {
 		fs.Debugf(src, "Failed to rename to %q: %v", dst.Remote(), err)
@@ -925,6 +1038,197 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	return true
 }
 
+// topDirs returns the directories in dirs whose parents aren't in dirs
+// sorted by name
+func topDirs(dirs map[string]struct{}) (top []string) {
+	for dir := range dirs {
+		parent := path.Dir(dir)
+		if parent == "." || parent == "/" {
+			parent = ""
+		}
+		if _, found := dirs[parent]; !found {
+			top = append(top, dir)
+		}
+	}
+	slices.Sort(top)
+	return top
+}
+
+// filesInDirs groups objs by which of dirs they are in, keyed by their
+// path relative to that directory. Objects in none of dirs are ignored.
+func filesInDirs(objs []fs.Object, dirs []string) map[string]map[string]fs.Object {
+	files := make(map[string]map[string]fs.Object, len(dirs))
+	for _, dir := range dirs {
+		files[dir] = map[string]fs.Object{}
+	}
+	for _, obj := range objs {
+		for dir := path.Dir(obj.Remote()); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if dirFiles, found := files[dir]; found {
+				dirFiles[obj.Remote()[len(dir)+1:]] = obj
+				break
+			}
+		}
+	}
+	return files
+}
+
+// sameForRename returns true if dst could be renamed to src according
+// to the track renames strategy
+func (s *syncCopyMove) sameForRename(src, dst fs.Object) bool {
+	if src.Size() != dst.Size() {
+		return false
+	}
+	srcID := s.renameID(src, s.trackRenamesStrategy, s.modifyWindow)
+	if srcID == "" || srcID != s.renameID(dst, s.trackRenamesStrategy, s.modifyWindow) {
+		return false
+	}
+	if s.trackRenamesStrategy.modTime() {
+		dt := dst.ModTime(s.ctx).Sub(src.ModTime(s.ctx))
+		if dt >= s.modifyWindow || dt <= -s.modifyWindow {
+			return false
+		}
+	}
+	return true
+}
+
+// dirMatch returns the fraction of files in the src and dst
+// directories which match by relative path and the track renames
+// strategy.
+//
+// The sizes are compared first so the hashes are only read for
+// directories which could match closely enough.
+func (s *syncCopyMove) dirMatch(srcFiles, dstFiles map[string]fs.Object) float64 {
+	total := max(len(srcFiles), len(dstFiles))
+	if total == 0 {
+		return 0
+	}
+	threshold := s.ci.TrackRenamesDirThreshold
+	sameSize := 0
+	for rel, src := range srcFiles {
+		if dst, found := dstFiles[rel]; found && dst.Size() == src.Size() {
+			sameSize++
+		}
+	}
+	if float64(sameSize)/float64(total) < threshold {
+		return 0
+	}
+	same := 0
+	for rel, src := range srcFiles {
+		if dst, found := dstFiles[rel]; found && s.sameForRename(src, dst) {
+			same++
+		}
+	}
+	return float64(same) / float64(total)
+}
+
+// renameDirs looks for directories which are only in the source whose
+// contents match a directory which is only in the destination closely
+// enough, and renames each destination directory into place with a
+// single server-side DirMove.
+//
+// This must be called before makeRenameMap. Files which were moved
+// into place are removed from the rename checks, the remaining ones
+// are renamed or transferred one by one as usual.
+func (s *syncCopyMove) renameDirs() {
+	srcDirs := topDirs(s.srcOnlyDirs)
+	dstDirs := topDirs(s.dstOnlyDirs)
+	if len(srcDirs) == 0 || len(dstDirs) == 0 {
+		return
+	}
+	fs.Infof(s.fdst, "Looking for renamed directories for --track-renames")
+	srcFiles := filesInDirs(s.renameCheck, srcDirs)
+	dstFiles := filesInDirs(slices.Collect(maps.Values(s.dstFiles)), dstDirs)
+	done := map[fs.Object]struct{}{}
+	used := map[string]struct{}{}
+	for _, srcDir := range srcDirs {
+		bestDir, bestMatch := "", 0.0
+		for _, dstDir := range dstDirs {
+			if _, found := used[dstDir]; found {
+				continue
+			}
+			if match := s.dirMatch(srcFiles[srcDir], dstFiles[dstDir]); match > bestMatch {
+				bestDir, bestMatch = dstDir, match
+			}
+		}
+		if bestDir == "" || bestMatch < s.ci.TrackRenamesDirThreshold {
+			continue
+		}
+		if s.renameDir(bestDir, srcDir, bestMatch, srcFiles[srcDir], dstFiles[bestDir], done) {
+			used[bestDir] = struct{}{}
+		}
+	}
+	if len(done) > 0 {
+		s.renameCheck = slices.DeleteFunc(s.renameCheck, func(o fs.Object) bool {
+			_, found := done[o]
+			return found
+		})
+	}
+}
+
+// renameDir renames dstDir to srcDir on the destination and fixes up
+// the sync state for the files in them, adding the source files which
+// no longer need renaming to done.
+//
+// It returns true if the directory was renamed.
+func (s *syncCopyMove) renameDir(dstDir, srcDir string, match float64, srcFiles, dstFiles map[string]fs.Object, done map[fs.Object]struct{}) bool {
+	// Remove the empty directories made for srcDir by the march
+	// as DirMove needs the destination not to exist.
+	if _, err := s.fdst.List(s.ctx, srcDir); err == nil {
+		if err := operations.Rmdirs(s.ctx, s.fdst, srcDir, false); err != nil {
+			fs.Debugf(fs.LogDirName(s.fdst, srcDir), "Failed to remove directories before rename: %v", err)
+		}
+	}
+	dryRun := operations.SkipDestructive(s.ctx, fs.LogDirName(s.fdst, dstDir), "rename directory")
+	if !dryRun {
+		err := s.fdst.Features().DirMove(s.ctx, s.fdst, dstDir, srcDir)
+		if err != nil {
+			fs.Infof(fs.LogDirName(s.fdst, dstDir), "Failed to rename directory to %q, renaming files instead: %v", srcDir, err)
+			return false
+		}
+	}
+	accounting.Stats(s.ctx).Renames(1)
+	fs.Infof(fs.LogDirName(s.fdst, srcDir), "Renamed directory from %q with %.0f%% of files matching", dstDir, match*100)
+
+	s.dstEmptyDirsMu.Lock()
+	for dir := range s.dstEmptyDirs {
+		if dir == dstDir || strings.HasPrefix(dir, dstDir+"/") {
+			delete(s.dstEmptyDirs, dir)
+		}
+	}
+	s.dstEmptyDirsMu.Unlock()
+
+	s.dstFilesMu.Lock()
+	defer s.dstFilesMu.Unlock()
+	for rel, dst := range dstFiles {
+		src := srcFiles[rel]
+		if src != nil && s.sameForRename(src, dst) {
+			// Moved into place
+			delete(s.dstFiles, dst.Remote())
+			done[src] = struct{}{}
+			continue
+		}
+		if dryRun {
+			continue
+		}
+		// The file was moved with the directory so find it at its new
+		// path, then check it against the source or delete it later.
+		delete(s.dstFiles, dst.Remote())
+		newDst, err := s.fdst.NewObject(s.ctx, path.Join(srcDir, rel))
+		if err != nil {
+			s.processError(fmt.Errorf("failed to find %q after renaming directory: %w", rel, err))
+			continue
+		}
+		if src == nil {
+			s.dstFiles[newDst.Remote()] = newDst
+			continue
+		}
+		if s.toBeChecked.Put(s.inCtx, fs.ObjectPair{Src: src, Dst: newDst}) {
+			done[src] = struct{}{}
+		}
+	}
+	return true
+}
+
 // Syncs fsrc into fdst
 //
 // If Delete is true then it deletes any files in fdst that aren't in fsrc
@@ -935,7 +1239,18 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 func (s *syncCopyMove) run() error {
 	if operations.Same(s.fdst, s.fsrc) && !s.allowOverlap {
 		fs.Errorf(s.fdst, "Nothing to do as source and destination are the same")
-		return nil
+		s.processError(s.dstListCache.Close(s.ctx, true))
+		s.processError(s.checkpoint.close(true))
+		return s.currentError()
+	}
+
+	// Adjust the number of active checkers and transfers if required
+	adaptiveCtx, adaptiveCancel := context.WithCancel(s.ctx)
+	var adaptiveWg sync.WaitGroup
+	for _, l := range []*adaptiveLimit{s.checkerLimit, s.transferLimit} {
+		if l != nil {
+			adaptiveWg.Go(func() { l.run(adaptiveCtx, s.lowLevelRetries) })
+		}
 	}
 
 	// Start background checking and transferring pipeline
@@ -962,10 +1277,20 @@ func (s *syncCopyMove) run() error {
 		NoUnicodeNormalization: s.noUnicodeNormalization,
 		NoProcessDstOnly:       s.deleteMode == fs.DeleteModeOff && !s.usingLogger,
 	}
+	if s.checkpoint != nil {
+		m.DirCallback = s
+	}
+	if s.dstListCache != nil {
+		m.DstListCache = s.dstListCache
+	}
 	s.processError(m.Run(s.ctx))
 
 	s.stopTrackRenames()
 	if s.trackRenames {
+		// Rename whole directories first if possible
+		if s.trackDirRenames {
+			s.renameDirs()
+		}
 		// Build the map of the remaining dstFiles by hash
 		s.makeRenameMap()
 		// Attempt renames for all the files which don't have a matching dst
@@ -986,6 +1311,8 @@ func (s *syncCopyMove) run() error {
 	s.stopRenamers()
 	s.stopTransfers()
 	s.stopDeleters()
+	adaptiveCancel()
+	adaptiveWg.Wait()
 
 	// Delete files after
 	if s.deleteMode == fs.DeleteModeAfter {
@@ -1032,12 +1359,38 @@ func (s *syncCopyMove) run() error {
 		fs.Infof(nil, "There was nothing to transfer")
 	}
 
+	// Remove the checkpoint if we finished successfully
+	s.processError(s.checkpoint.close(s.currentError() == nil))
+
+	// Save the state of the destination listing cache
+	s.processError(s.dstListCache.Close(s.ctx, s.currentError() == nil))
+
 	// cancel the contexts to free resources
 	s.inCancel()
 	s.cancel()
 	return s.currentError()
 }
 
+// lowLevelRetries returns the total low level retries made by the
+// source and destination remotes and the remotes they wrap.
+func (s *syncCopyMove) lowLevelRetries() (total int64) {
+	seen := make(map[string]struct{})
+	for _, f := range []fs.Fs{s.fsrc, s.fdst} {
+		for f != nil {
+			if _, found := seen[f.Name()]; !found {
+				seen[f.Name()] = struct{}{}
+				total += fs.LowLevelRetries(f.Name())
+			}
+			unWrap := f.Features().UnWrap
+			if unWrap == nil {
+				break
+			}
+			f = unWrap()
+		}
+	}
+	return total
+}
+
 // DstOnly have an object which is in the destination only
 func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	if s.deleteMode == fs.DeleteModeOff {
@@ -1055,6 +1408,7 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 		}
 		return false
 	}
+	s.markDstChanged(dst.Remote())
 	switch x := dst.(type) {
 	case fs.Object:
 		s.logger(s.ctx, operations.MissingOnSrc, nil, x, nil)
@@ -1065,6 +1419,7 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 			s.dstFiles[x.Remote()] = x
 			s.dstFilesMu.Unlock()
 		case fs.DeleteModeDuring, fs.DeleteModeOnly:
+			s.plan.add(PlanDelete, x.Remote(), "", nil, x)
 			select {
 			case <-s.ctx.Done():
 				return
@@ -1074,6 +1429,12 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 			panic(fmt.Sprintf("unexpected delete mode %d", s.deleteMode))
 		}
 	case fs.Directory:
+		if s.trackDirRenames {
+			s.onlyDirsMu.Lock()
+			s.dstOnlyDirs[x.Remote()] = struct{}{}
+			s.onlyDirsMu.Unlock()
+		}
+		s.dstListCache.Purge(x.Remote())
 		// Do the same thing to the entire contents of the directory
 		// Record directory as it is potentially empty and needs deleting
 		if s.fdst.Features().CanHaveEmptyDirectories {
@@ -1110,6 +1471,49 @@ func (s *syncCopyMove) markDirModifiedObject(o fs.Object) {
 	s.markDirModified(dir)
 }
 
+// markDstChanged marks the destination directory containing remote
+// as changed in the destination listing cache.
+func (s *syncCopyMove) markDstChanged(remote string) {
+	if s.dstListCache == nil {
+		return
+	}
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	s.dstListCache.Invalidate(dir)
+}
+
+// resolveDstObject returns the object from fdst for dst if it was read
+// from the destination listing cache, or nil if it no longer exists.
+func (s *syncCopyMove) resolveDstObject(ctx context.Context, dst fs.Object) (fs.Object, error) {
+	if dst == nil || s.dstListCache == nil {
+		return dst, nil
+	}
+	entry, err := s.dstListCache.Resolve(ctx, dst)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return dst, err
+	}
+	return entry.(fs.Object), nil
+}
+
+// resolveDstDir returns the directory from fdst for dst if it was read
+// from the destination listing cache, or nil if it no longer exists.
+func (s *syncCopyMove) resolveDstDir(ctx context.Context, dst fs.Directory) (fs.Directory, error) {
+	if dst == nil || s.dstListCache == nil {
+		return dst, nil
+	}
+	entry, err := s.dstListCache.Resolve(ctx, dst)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return entry.(fs.Directory), nil
+}
+
 // copyDirMetadata copies the src directory modTime or Metadata to dst
 // or f if nil. If dst is nil then it uses dir as the name of the new
 // directory.
@@ -1127,6 +1531,20 @@ func (s *syncCopyMove) copyDirMetadata(ctx context.Context, f fs.Fs, dst fs.Dire
 	}
 	newDst = dst
 	if !equal {
+		if dst != nil {
+			s.markDstChanged(dst.Remote())
+			dst, err = s.resolveDstDir(ctx, dst)
+			if err != nil {
+				s.processError(err)
+				return nil
+			}
+			newDst = dst
+		} else {
+			s.markDstChanged(dir)
+		}
+		if dst == nil && s.copyEmptySrcDirs {
+			s.plan.addDir(PlanMkdir, dir)
+		}
 		if s.setDirMetadata && s.copyEmptySrcDirs {
 			newDst, err = operations.CopyDirMetadata(ctx, f, dst, dir, src)
 		} else if dst == nil && s.setDirModTime && s.copyEmptySrcDirs {
@@ -1202,6 +1620,7 @@ func (s *syncCopyMove) setDelayedDirModTimes(ctx context.Context) error {
 				}
 			}
 			item := item
+			s.markDstChanged(item.dir)
 			if s.setDirModTimeAfter { // mark dir's parent as modified
 				dir := path.Dir(item.dir)
 				if dir == "." {
@@ -1210,11 +1629,11 @@ func (s *syncCopyMove) setDelayedDirModTimes(ctx context.Context) error {
 				s.modifiedDirs[dir] = struct{}{} // lock is already held
 			}
 			g.Go(func() error {
-				var err error
-				if s.setDirMetadata {
-					_, err = operations.CopyDirMetadata(gCtx, s.fdst, item.dst, item.dir, item.src)
-				} else {
-					_, err = operations.SetDirModTime(gCtx, s.fdst, item.dst, item.dir, item.modTime)
+				dst, err := s.resolveDstDir(gCtx, item.dst)
+				if err == nil && s.setDirMetadata {
+					_, err = operations.CopyDirMetadata(gCtx, s.fdst, dst, item.dir, item.src)
+				} else if err == nil {
+					_, err = operations.SetDirModTime(gCtx, s.fdst, dst, item.dir, item.modTime)
 				}
 				if err != nil {
 					err = fs.CountError(ctx, err)
@@ -1241,6 +1660,7 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 	case fs.Object:
 		s.logger(s.ctx, operations.MissingOnDst, x, nil, nil)
 		s.markParentNotEmpty(src)
+		s.markDstChanged(x.Remote())
 
 		if s.trackRenames {
 			// Save object to check for a rename later
@@ -1253,6 +1673,7 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 			// Check CompareDest && CopyDest
 			NoNeedTransfer, err := operations.CompareOrCopyDest(s.ctx, s.fdst, nil, x, s.compareCopyDest, s.backupDir)
 			if err != nil {
+				s.checkpoint.fail(x)
 				s.processError(err)
 				s.logger(s.ctx, operations.TransferError, x, nil, err)
 			}
@@ -1260,6 +1681,7 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 				// No need to check since doesn't exist
 				fs.Debugf(src, "Need to transfer - File not found at Destination")
 				s.markDirModifiedObject(x)
+				s.checkpoint.add(x)
 				ok := s.toBeUploaded.Put(s.inCtx, fs.ObjectPair{Src: x, Dst: nil})
 				if !ok {
 					return
@@ -1267,8 +1689,14 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 			}
 		}
 	case fs.Directory:
+		if s.trackDirRenames {
+			s.onlyDirsMu.Lock()
+			s.srcOnlyDirs[x.Remote()] = struct{}{}
+			s.onlyDirsMu.Unlock()
+		}
 		// Do the same thing to the entire contents of the directory
 		s.markParentNotEmpty(src)
+		s.markDstChanged(x.Remote())
 		s.logger(s.ctx, operations.MissingOnDst, src, nil, fs.ErrorIsDir)
 
 		// Create the directory and make sure the Metadata/ModTime is correct
@@ -1293,6 +1721,7 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 		dstX, ok := dst.(fs.Object)
 		if ok {
 			// No logger here because we'll handle it in equal()
+			s.checkpoint.add(srcX)
 			ok = s.toBeChecked.Put(s.inCtx, fs.ObjectPair{Src: srcX, Dst: dstX})
 			if !ok {
 				return false
@@ -1301,6 +1730,7 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 			// FIXME src is file, dst is directory
 			err := errors.New("can't overwrite directory with file")
 			fs.Errorf(dst, "%v", err)
+			s.checkpoint.fail(src)
 			s.processError(err)
 			s.logger(ctx, operations.TransferError, srcX, dstX, err)
 		}
@@ -1320,6 +1750,8 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 			if s.ci.FixCase && !s.ci.Immutable && src.Remote() != dst.Remote() {
 				// Fix case for case insensitive filesystems
 				// Fix each dir before recursing into subdirs and files
+				s.markDstChanged(dst.Remote())
+				s.dstListCache.Purge(dst.Remote())
 				err := operations.DirMoveCaseInsensitive(s.ctx, s.fdst, dst.Remote(), src.Remote())
 				if err != nil {
 					fs.Errorf(dst, "Error while attempting to rename to %s: %v", src.Remote(), err)
@@ -1334,6 +1766,7 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 		// FIXME src is dir, dst is file
 		err := errors.New("can't overwrite file with directory")
 		fs.Errorf(dst, "%v", err)
+		s.checkpoint.fail(src)
 		s.processError(err)
 		s.logger(ctx, operations.TransferError, src.(fs.ObjectInfo), dst.(fs.ObjectInfo), err)
 	default:
@@ -1342,6 +1775,29 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 	return false
 }
 
+// SrcDir is called with the complete source listing of dir when
+// --checkpoint-file is in use. It returns true if the directory was
+// completed by a previous run so doesn't need checking again.
+func (s *syncCopyMove) SrcDir(dir string, entries fs.DirEntries) (skip bool) {
+	skip = s.checkpoint.srcDir(dir, entries)
+	if skip {
+		// Keep the empty directory tracking up to date
+		for _, entry := range entries {
+			s.markParentNotEmpty(entry)
+		}
+	}
+	return skip
+}
+
+// DirDone is called when all the entries of the source directory dir
+// have been processed when --checkpoint-file is in use.
+func (s *syncCopyMove) DirDone(dir string, dstOnly int, err error) {
+	// If syncing, entries only in the destination are deleted at
+	// some point in the future so don't count the directory as done.
+	incomplete := err != nil || (dstOnly > 0 && s.deleteMode != fs.DeleteModeOff)
+	s.checkpoint.dirDone(dir, incomplete)
+}
+
 // Syncs fsrc into fdst
 //
 // If Delete is true then it deletes any files in fdst that aren't in fsrc
@@ -1349,11 +1805,31 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 // If DoMove is true then files will be moved instead of copied.
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool, allowOverlap bool) error {
+func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool, allowOverlap bool) (err error) {
+	ctx, span := tracing.Start(ctx, checkpointMode(deleteMode, DoMove),
+		attribute.String("rclone.src", fs.ConfigString(fsrc)),
+		attribute.String("rclone.dst", fs.ConfigString(fdst)),
+	)
+	defer func() { tracing.End(span, err) }()
 	ci := fs.GetConfig(ctx)
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
+	// If making a plan, record the actions of a dry run
+	var plan *planRecorder
+	if ci.PlanOut != "" {
+		var err error
+		plan, err = newPlanRecorder(ctx, fdst, fsrc, checkpointMode(deleteMode, DoMove))
+		if err != nil {
+			return fserrors.FatalError(err)
+		}
+		if !ci.DryRun {
+			fs.Infof(nil, "Making plan %q - nothing will be changed", ci.PlanOut)
+			ctx, ci = fs.AddConfig(ctx)
+			ci.DryRun = true
+		}
+		ctx = operations.WithModTimeUpdateFn(ctx, plan.addModTime)
+	}
 	// Run an extra pass to delete only
 	if deleteMode == fs.DeleteModeBefore {
 		if ci.TrackRenames {
@@ -1364,6 +1840,7 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		if err != nil {
 			return err
 		}
+		do.plan = plan
 		err = do.run()
 		if err != nil {
 			return err
@@ -1375,7 +1852,12 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 	if err != nil {
 		return err
 	}
-	return do.run()
+	do.plan = plan
+	err = do.run()
+	if err != nil || plan == nil {
+		return err
+	}
+	return plan.write(ci.PlanOut)
 }
 
 // Sync fsrc into fdst
@@ -1401,14 +1883,16 @@ func Transform(ctx context.Context, fdst fs.Fs, deleteEmptySrcDirs bool, copyEmp
 
 // MoveDir moves fsrc into fdst
 func MoveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	ci := fs.GetConfig(ctx)
 	fi := filter.GetConfig(ctx)
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
 	}
 
-	// First attempt to use DirMover if exists, same Fs and no filters are active
-	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && fi.InActive() {
+	// First attempt to use DirMover if exists, same Fs and no filters
+	// are active. A plan records the moves of the individual files.
+	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && fi.InActive() && ci.PlanOut == "" {
 		if operations.SkipDestructive(ctx, fdst, "server-side directory move") {
 			return nil
 		}
@@ -5,11 +5,13 @@ package sync
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path"
 	"runtime"
 	"slices"
 	"sort"
@@ -27,7 +29,9 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/kv"
 	"github.com/rclone/rclone/lib/transform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1515,10 +1519,10 @@ func testSyncWithMaxDuration(t *testing.T, cutoffMode fs.CutoffMode) {
 	r.CheckLocalItems(t, file1, file2)
 	r.CheckRemoteItems(t)
 
-	if runtime.GOOS == "darwin" {
-		r.Flocal.Features().Disable("Copy") // macOS cloning is too fast for this test!
+	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
+		r.Flocal.Features().Disable("Copy") // local server-side copies are too fast for this test!
 		if r.Fremote.Features().IsLocal {
-			r.Fremote.Features().Disable("Copy") // macOS cloning is too fast for this test!
+			r.Fremote.Features().Disable("Copy") // local server-side copies are too fast for this test!
 		}
 	}
 	accounting.GlobalStats().ResetCounters()
@@ -1596,6 +1600,400 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	}
 }
 
+func TestSyncWithTrackRenamesDir(t *testing.T) {
+	for _, test := range []struct {
+		threshold   float64
+		wantRenames int64
+	}{
+		{threshold: 0.5, wantRenames: 1},
+		{threshold: 0.9, wantRenames: 2},
+	} {
+		t.Run(fmt.Sprint(test.threshold), func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			r := fstest.NewRun(t)
+
+			ci.TrackRenames = true
+			ci.TrackRenamesDirThreshold = test.threshold
+
+			haveHash := r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).GetOne() != hash.None
+			if !haveHash || r.Fremote.Features().DirMove == nil {
+				t.Skip("Can't track directory renames")
+			}
+
+			f1 := r.WriteFile("dir/one", "One", t1)
+			f2 := r.WriteFile("dir/sub/two", "Two", t1)
+			f3 := r.WriteFile("dir/three", "Three", t1)
+			f4 := r.WriteFile("dir/four", "Four", t1)
+			f5 := r.WriteFile("potato", "Potato", t2)
+
+			accounting.GlobalStats().ResetCounters()
+			require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+			r.CheckRemoteItems(t, f1, f2, f3, f4, f5)
+
+			// Rename the directory, changing one file and removing another
+			require.NoError(t, os.Rename(path.Join(r.LocalName, "dir"), path.Join(r.LocalName, "renamed")))
+			f1.Path = "renamed/one"
+			f2.Path = "renamed/sub/two"
+			f3 = r.WriteFile("renamed/three", "Changed", t1)
+			require.NoError(t, os.Remove(path.Join(r.LocalName, "renamed", "four")))
+			f6 := r.WriteFile("renamed/six", "Six", t1)
+
+			accounting.GlobalStats().ResetCounters()
+			require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+
+			r.CheckLocalListing(t, []fstest.Item{f1, f2, f3, f5, f6}, []string{"renamed", "renamed/sub"})
+			r.CheckRemoteListing(t, []fstest.Item{f1, f2, f3, f5, f6}, []string{"renamed", "renamed/sub"})
+			assert.Equal(t, test.wantRenames, accounting.GlobalStats().Renames(0))
+		})
+	}
+}
+
+// Test that --checkpoint-file lets an interrupted sync skip the
+// directories it has already done
+func TestSyncWithCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	ci.CheckpointFile = path.Join(t.TempDir(), "checkpoint.json")
+
+	f1 := r.WriteFile("a/one", "One", t1)
+	f2 := r.WriteFile("b/two", "Two", t1)
+	f3 := r.WriteFile("three", "Three", t1)
+
+	// A successful sync removes the checkpoint file
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2, f3)
+	assert.NoFileExists(t, ci.CheckpointFile)
+
+	// Make the sync fail in "b" only
+	ci.Immutable = true
+	r.WriteObject(ctx, "b/two", "Two changed", t2)
+	accounting.GlobalStats().ResetCounters()
+	require.Error(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	assert.FileExists(t, ci.CheckpointFile)
+	ci.Immutable = false
+
+	// Remove a file from the destination of the completed
+	// directory which the next sync shouldn't notice
+	obj, err := r.Fremote.NewObject(ctx, "a/one")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+
+	// The sync should skip "a" but fix "b"
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f2, f3)
+	assert.NoFileExists(t, ci.CheckpointFile)
+
+	// Without the checkpoint file the missing file is noticed
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2, f3)
+}
+
+// Test sync with the destination listing cache
+func TestSyncWithDstListCache(t *testing.T) {
+	if !kv.Supported() {
+		t.Skip("kv not supported on this OS")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	ci.DstListCache = true
+
+	// Keep the database open so it isn't removed between syncs
+	db, err := kv.Start(ctx, "listcache", r.Fremote)
+	require.NoError(t, err)
+	defer func() { _ = db.Stop(false) }()
+
+	f1 := r.WriteFile("a/sub/one", "One", t1)
+	f2 := r.WriteFile("b/two", "Two", t1)
+	f3 := r.WriteFile("c/three", "Three", t1)
+
+	// The first sync lists the whole destination and the second
+	// caches the directories the first one created
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2, f3)
+
+	// Remove a file from the destination behind the cache's back
+	// which the next sync shouldn't notice. Only the modification
+	// time of "a/sub" changes so the root listing is unchanged.
+	obj, err := r.Fremote.NewObject(ctx, "a/sub/one")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+
+	// Changes to the source are synced using the cached listing
+	f2 = r.WriteFile("b/two", "Two changed", t2)
+	obj, err = r.Flocal.NewObject(ctx, "c/three")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteListing(t, []fstest.Item{f2}, []string{"a", "a/sub", "b", "c"})
+
+	// Verifying the cache notices the missing file
+	ci.DstListCacheMaxAge = fs.Duration(time.Nanosecond)
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2)
+}
+
+// Test sync with the destination listing cache copying metadata
+func TestSyncWithDstListCacheMetadata(t *testing.T) {
+	if !kv.Supported() {
+		t.Skip("kv not supported on this OS")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	if !r.Fremote.Features().WriteDirMetadata {
+		t.Skip("Skipping as destination can't write directory metadata")
+	}
+	ci.DstListCache = true
+	ci.Metadata = true
+
+	// Keep the database open so it isn't removed between syncs
+	db, err := kv.Start(ctx, "listcache", r.Fremote)
+	require.NoError(t, err)
+	defer func() { _ = db.Stop(false) }()
+
+	f1 := r.WriteFile("d1/one", "One", t1)
+	f2 := r.WriteFile("d1/d2/two", "Two", t1)
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2)
+
+	// Change the files and the directory times so the entries read
+	// from the cache have their data and metadata updated
+	f1 = r.WriteFile("d1/one", "One changed", t2)
+	f2 = r.WriteFile("d1/d2/two", "Two changed", t2)
+	for _, dir := range []string{"d1/d2", "d1"} {
+		_, err = operations.SetDirModTime(ctx, r.Flocal, nil, dir, t3)
+		require.NoError(t, err)
+	}
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	r.CheckRemoteItems(t, f1, f2)
+	for _, dir := range []string{"d1/d2", "d1"} {
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		entries, err := r.Fremote.List(ctx, parent)
+		require.NoError(t, err)
+		found := false
+		for _, entry := range entries {
+			if entry.Remote() == dir {
+				found = true
+				fstest.AssertTimeEqualWithPrecision(t, dir, t3, entry.ModTime(ctx), fs.GetModifyWindow(ctx, r.Fremote))
+			}
+		}
+		assert.True(t, found, dir)
+	}
+}
+
+// Test a checkpoint file for a different sync is ignored
+func TestSyncCheckpointDifferentSync(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	checkpointFile := path.Join(t.TempDir(), "checkpoint.json")
+
+	c, err := newCheckpoint(ctx, checkpointFile, r.Fremote, r.Flocal, "copy")
+	require.NoError(t, err)
+	assert.False(t, c.srcDir("dir", nil))
+	c.dirDone("dir", false)
+	require.NoError(t, c.close(false))
+
+	c, err = newCheckpoint(ctx, checkpointFile, r.Fremote, r.Flocal, "copy")
+	require.NoError(t, err)
+	assert.True(t, c.srcDir("dir", nil))
+	require.NoError(t, c.close(false))
+
+	c, err = newCheckpoint(ctx, checkpointFile, r.Fremote, r.Flocal, "sync")
+	require.NoError(t, err)
+	assert.False(t, c.srcDir("dir", nil))
+	require.NoError(t, c.close(true))
+	assert.NoFileExists(t, checkpointFile)
+}
+
+// planActions returns the plan's actions as "action remote" strings
+func planActions(plan *Plan) (out []string) {
+	for _, a := range plan.Actions {
+		out = append(out, a.Action+" "+a.Remote)
+	}
+	return out
+}
+
+// Test a sync makes trace spans with --tracing-file
+func TestSyncTracing(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	f1 := r.WriteFile("one", "One", t1)
+	d2 := r.WriteObject(ctx, "two", "Two", t1)
+	r.CheckRemoteItems(t, d2)
+	if r.Fremote.Features().IsLocal {
+		r.Fremote.Features().Disable("Copy") // make sure the data is streamed with Open and Put
+	}
+
+	traceFile := path.Join(t.TempDir(), "traces.json")
+	require.NoError(t, tracing.Init(ctx, &tracing.Options{File: traceFile, ServiceName: "rclone"}))
+	defer func() { _ = tracing.Shutdown(ctx) }()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	require.NoError(t, tracing.Shutdown(ctx))
+	r.CheckRemoteItems(t, f1)
+
+	// Read the names of the spans and their parents
+	data, err := os.ReadFile(traceFile)
+	require.NoError(t, err)
+	var traces struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	names := map[string]string{}
+	parents := map[string]string{}
+	for line := range strings.Lines(string(data)) {
+		require.NoError(t, json.Unmarshal([]byte(line), &traces))
+		for _, rs := range traces.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					names[span.SpanID] = span.Name
+					parents[span.Name] = span.ParentSpanID
+				}
+			}
+		}
+	}
+	for _, name := range []string{"sync", "List", "transfer", "Open", "Put", "Remove"} {
+		assert.Contains(t, parents, name)
+	}
+	assert.Equal(t, "", parents["sync"])
+	assert.Equal(t, "sync", names[parents["List"]])
+	assert.Equal(t, "sync", names[parents["transfer"]])
+	assert.Equal(t, "sync", names[parents["Remove"]])
+	assert.Equal(t, "transfer", names[parents["Open"]])
+	assert.Equal(t, "transfer", names[parents["Put"]])
+}
+
+// Test making a plan with --plan-out and applying it
+func TestSyncPlanOutAndApply(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	planFile := path.Join(t.TempDir(), "plan.json")
+
+	f1 := r.WriteFile("a/one", "One", t1)
+	f2 := r.WriteFile("b/two", "Two", t1)
+	f3 := r.WriteFile("three", "Three changed", t2)
+	d3 := r.WriteObject(ctx, "three", "Three", t1)
+	d4 := r.WriteObject(ctx, "four", "Four", t1)
+	r.CheckRemoteItems(t, d3, d4)
+
+	// Making the plan doesn't change anything
+	ci.PlanOut = planFile
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	ci.PlanOut = ""
+	r.CheckRemoteItems(t, d3, d4)
+
+	plan, err := ReadPlan(planFile)
+	require.NoError(t, err)
+	assert.Equal(t, "sync", plan.Mode)
+	assert.Equal(t, fs.ConfigString(r.Flocal), plan.Src)
+	assert.Equal(t, fs.ConfigString(r.Fremote), plan.Dst)
+	assert.Equal(t, []string{"copy a/one", "copy b/two", "copy three", "delete four"}, planActions(plan))
+	copyThree := plan.Actions[2]
+	require.NotNil(t, copyThree.Src)
+	assert.Equal(t, int64(13), copyThree.Src.Size)
+	require.NotNil(t, copyThree.Dst)
+	assert.Equal(t, int64(5), copyThree.Dst.Size)
+	assert.True(t, t1.Equal(copyThree.Dst.ModTime))
+	assert.Nil(t, plan.Actions[0].Dst)
+
+	// Applying the plan makes the destination match
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan, ApplyOpt{}))
+	r.CheckRemoteItems(t, f1, f2, f3)
+
+	// Applying it again fails as the destination has changed
+	accounting.GlobalStats().ResetCounters()
+	err = ApplyPlan(ctx, r.Fremote, r.Flocal, plan, ApplyOpt{})
+	assert.ErrorContains(t, err, "4 actions have changed")
+	r.CheckRemoteItems(t, f1, f2, f3)
+}
+
+// Test a plan updates the modification times of files which are
+// otherwise unchanged
+func TestSyncPlanModTime(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	if r.Fremote.Precision() == fs.ModTimeNotSupported {
+		t.Skip("Can't update modification times")
+	}
+	if r.Flocal.Hashes().Overlap(r.Fremote.Hashes()).Count() == 0 {
+		t.Skip("Modification times are only updated if the hashes match")
+	}
+	planFile := path.Join(t.TempDir(), "plan.json")
+
+	f1 := r.WriteFile("one", "One", t2)
+	d1 := r.WriteObject(ctx, "one", "One", t1)
+
+	ci.PlanOut = planFile
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	ci.PlanOut = ""
+	r.CheckRemoteItems(t, d1)
+	plan, err := ReadPlan(planFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"set_modtime one"}, planActions(plan))
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan, ApplyOpt{}))
+	r.CheckRemoteItems(t, f1)
+}
+
+// Test applying a plan after some of the files have changed
+func TestApplyPlanChanged(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	planFile := path.Join(t.TempDir(), "plan.json")
+
+	f1 := r.WriteFile("one", "One", t1)
+	r.WriteFile("two", "Two", t1)
+	d3 := r.WriteObject(ctx, "three", "Three", t1)
+
+	ci.PlanOut = planFile
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	ci.PlanOut = ""
+	plan, err := ReadPlan(planFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copy one", "copy two", "delete three"}, planActions(plan))
+
+	// Change a source file and a destination file
+	f2 := r.WriteFile("two", "Two changed", t2)
+	d3 = r.WriteObject(ctx, "three", "Three changed", t2)
+
+	// Nothing is done if anything has changed
+	accounting.GlobalStats().ResetCounters()
+	err = ApplyPlan(ctx, r.Fremote, r.Flocal, plan, ApplyOpt{})
+	assert.ErrorContains(t, err, "2 actions have changed")
+	r.CheckRemoteItems(t, d3)
+
+	// Unless the changed actions are skipped
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan, ApplyOpt{SkipChanged: true}))
+	r.CheckRemoteItems(t, f1, d3)
+	r.CheckLocalItems(t, f1, f2)
+}
+
 func TestParseRenamesStrategyModtime(t *testing.T) {
 	for _, test := range []struct {
 		in      string
@@ -2712,7 +3110,7 @@ func TestMaxTransfer(t *testing.T) {
 		r.CheckLocalItems(t, file1, file2, file3)
 		r.CheckRemoteItems(t)
 
-		if runtime.GOOS == "darwin" {
+		if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
 			// disable server-side copies as they don't count towards transfer size stats
 			r.Flocal.Features().Disable("Copy")
 			if r.Fremote.Features().IsLocal {
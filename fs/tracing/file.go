@@ -0,0 +1,267 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// fileExporter writes spans to a file as OTLP JSON, one
+// ExportTraceServiceRequest per line, as read by the OpenTelemetry
+// collector's otlpjsonfile receiver.
+type fileExporter struct {
+	mu  sync.Mutex
+	out *os.File
+	enc *json.Encoder
+}
+
+// newFileExporter creates or appends to the file at path
+func newFileExporter(path string) (*fileExporter, error) {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &fileExporter{
+		out: out,
+		enc: json.NewEncoder(out),
+	}, nil
+}
+
+// ExportSpans writes the spans as a single line of OTLP JSON
+func (e *fileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	req := otlpRequest(spans)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.out == nil {
+		return nil
+	}
+	if err := e.enc.Encode(req); err != nil {
+		return fmt.Errorf("failed to write traces: %w", err)
+	}
+	return nil
+}
+
+// Shutdown closes the file
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.out == nil {
+		return nil
+	}
+	err := e.out.Close()
+	e.out = nil
+	if err != nil {
+		return fmt.Errorf("failed to close trace file: %w", err)
+	}
+	return nil
+}
+
+// The OTLP JSON encoding - see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpTraces struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+	SchemaURL  string            `json:"schemaUrl,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope     otlpScope   `json:"scope"`
+	Spans     []*otlpSpan `json:"spans"`
+	SchemaURL string      `json:"schemaUrl,omitempty"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    string          `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpValue `json:"values"`
+}
+
+// OTLP status codes which differ from the otel ones
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// otlpRequest groups the spans by resource and scope
+func otlpRequest(spans []sdktrace.ReadOnlySpan) *otlpTraces {
+	var req otlpTraces
+	resources := map[*resource.Resource]*otlpResourceSpans{}
+	type scopeKey struct {
+		res   *resource.Resource
+		scope instrumentation.Scope
+	}
+	scopes := map[scopeKey]*otlpScopeSpans{}
+	for _, span := range spans {
+		res := span.Resource()
+		rs := resources[res]
+		if rs == nil {
+			rs = &otlpResourceSpans{
+				Resource:  otlpResource{Attributes: otlpAttributes(res.Attributes())},
+				SchemaURL: res.SchemaURL(),
+			}
+			resources[res] = rs
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+		scope := span.InstrumentationScope()
+		key := scopeKey{res: res, scope: scope}
+		ss := scopes[key]
+		if ss == nil {
+			ss = &otlpScopeSpans{
+				Scope:     otlpScope{Name: scope.Name, Version: scope.Version},
+				SchemaURL: scope.SchemaURL,
+			}
+			scopes[key] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, otlpFromSpan(span))
+	}
+	return &req
+}
+
+// otlpFromSpan converts a single span
+func otlpFromSpan(span sdktrace.ReadOnlySpan) *otlpSpan {
+	sc := span.SpanContext()
+	out := &otlpSpan{
+		TraceID:           sc.TraceID().String(),
+		SpanID:            sc.SpanID().String(),
+		TraceState:        sc.TraceState().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes()),
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		out.ParentSpanID = parent.SpanID().String()
+	}
+	for _, event := range span.Events() {
+		out.Events = append(out.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+	for _, link := range span.Links() {
+		out.Links = append(out.Links, otlpLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			Attributes: otlpAttributes(link.Attributes),
+		})
+	}
+	switch status := span.Status(); status.Code {
+	case codes.Ok:
+		out.Status.Code = otlpStatusOK
+	case codes.Error:
+		out.Status.Code = otlpStatusError
+		out.Status.Message = status.Description
+	}
+	return out
+}
+
+// otlpAttributes converts a list of attributes
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, len(attrs))
+	for i, attr := range attrs {
+		out[i] = otlpKeyValue{Key: string(attr.Key), Value: otlpFromValue(attr.Value)}
+	}
+	return out
+}
+
+// otlpFromValue converts an attribute value
+func otlpFromValue(v attribute.Value) (out otlpValue) {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		out.BoolValue = &b
+	case attribute.INT64:
+		out.IntValue = strconv.FormatInt(v.AsInt64(), 10)
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		out.DoubleValue = &f
+	case attribute.STRING:
+		s := v.AsString()
+		out.StringValue = &s
+	case attribute.STRINGSLICE:
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, s := range v.AsStringSlice() {
+			array.Values = append(array.Values, otlpValue{StringValue: &s})
+		}
+		out.ArrayValue = array
+	default:
+		s := v.Emit()
+		out.StringValue = &s
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+// Package tracing provides OpenTelemetry tracing for rclone
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rclone/rclone/fs"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// OptionsInfo describes the Options in use
+var OptionsInfo = fs.Options{{
+	Name:    "tracing_endpoint",
+	Default: "",
+	Help:    "Send OpenTelemetry traces to this OTLP/HTTP endpoint, e.g. http://localhost:4318",
+	Groups:  "Tracing",
+}, {
+	Name:    "tracing_file",
+	Default: "",
+	Help:    "Write OpenTelemetry traces to this file as OTLP JSON",
+	Groups:  "Tracing",
+}, {
+	Name:    "tracing_service_name",
+	Default: "rclone",
+	Help:    "Service name to report in OpenTelemetry traces",
+	Groups:  "Tracing",
+}}
+
+// Options contains options for controlling tracing
+type Options struct {
+	Endpoint    string `config:"tracing_endpoint"`     // OTLP/HTTP endpoint to send traces to
+	File        string `config:"tracing_file"`         // file to write OTLP JSON traces to
+	ServiceName string `config:"tracing_service_name"` // service.name for the traces
+}
+
+func init() {
+	fs.RegisterGlobalOptions(fs.OptionsInfo{Name: "tracing", Opt: &Opt, Options: OptionsInfo})
+}
+
+// Opt is the options for tracing
+var Opt Options
+
+// tracerName is the name of the instrumentation scope
+const tracerName = "github.com/rclone/rclone"
+
+var (
+	enabled  atomic.Bool
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer = noop.NewTracerProvider().Tracer(tracerName)
+)
+
+// Enabled returns true if traces are being exported
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Init starts exporting traces if the options ask for it.
+//
+// Shutdown should be called before exiting to flush the traces.
+func Init(ctx context.Context, opt *Options) error {
+	var exporters []sdktrace.SpanExporter
+	if opt.Endpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opt.Endpoint))
+		if err != nil {
+			return fmt.Errorf("failed to make OTLP trace exporter: %w", err)
+		}
+		exporters = append(exporters, exporter)
+	}
+	if opt.File != "" {
+		exporter, err := newFileExporter(opt.File)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(exporters) == 0 {
+		return nil
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", opt.ServiceName),
+			attribute.String("service.version", fs.Version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to make trace resource: %w", err)
+	}
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	for _, exporter := range exporters {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	provider = sdktrace.NewTracerProvider(options...)
+	tracer = provider.Tracer(tracerName, trace.WithInstrumentationVersion(fs.Version))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled.Store(true)
+	fs.Debugf(nil, "OpenTelemetry tracing started")
+	return nil
+}
+
+// Shutdown flushes any traces not yet exported and stops tracing
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		return nil
+	}
+	enabled.Store(false)
+	err := provider.Shutdown(ctx)
+	provider = nil
+	tracer = noop.NewTracerProvider().Tracer(tracerName)
+	if err != nil {
+		return fmt.Errorf("failed to flush traces: %w", err)
+	}
+	return nil
+}
+
+// getTracer returns the tracer in use
+func getTracer() trace.Tracer {
+	mu.Lock()
+	defer mu.Unlock()
+	return tracer
+}
+
+// Start starts a span called name as a child of any span in ctx.
+//
+// The span returned must be ended, usually with End. If tracing isn't
+// enabled this does nothing.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !Enabled() {
+		return ctx, noop.Span{}
+	}
+	return getTracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartFs starts a span for calling the backend method on f.
+//
+// remote is the path of the object or directory in f the method is
+// being called on.
+func StartFs(ctx context.Context, method string, f fs.Info, remote string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !Enabled() {
+		return ctx, noop.Span{}
+	}
+	attrs = append(attrs,
+		attribute.String("rclone.fs", fs.ConfigString(f)),
+		attribute.String("rclone.remote", remote),
+	)
+	return getTracer().Start(ctx, method, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// End ends span, marking it as failed if err is not nil.
+//
+// If tracing isn't enabled this does nothing.
+func End(span trace.Span, err error) {
+	if !Enabled() {
+		return
+	}
+	if err != nil && !errors.Is(err, fs.ErrorObjectNotFound) && !errors.Is(err, fs.ErrorDirNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach returns a new context with no deadline or cancellation
+// which is still part of the trace in ctx.
+//
+// This is for work started by a request which outlives it.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// Transport wraps the http.RoundTripper passed in so it makes a span
+// for each request and passes the trace context on to the server.
+//
+// If tracing isn't enabled it returns the http.RoundTripper unchanged.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if !Enabled() {
+		return rt
+	}
+	return otelhttp.NewTransport(rt)
+}
+
+// Middleware makes a span for each request to an rclone server,
+// carrying on any trace passed in by the client.
+func Middleware(next http.Handler) http.Handler {
+	if !Enabled() {
+		return next
+	}
+	return otelhttp.NewHandler(next, "serve", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	}))
+}
@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// startFile starts tracing to a file returning a function to stop
+// tracing and read the spans written
+func startFile(t *testing.T) (stop func() map[string]*otlpSpan) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "traces.json")
+	require.NoError(t, Init(ctx, &Options{File: path, ServiceName: "rclone-test"}))
+	t.Cleanup(func() { _ = Shutdown(ctx) })
+	return func() map[string]*otlpSpan {
+		require.NoError(t, Shutdown(ctx))
+		in, err := os.Open(path)
+		require.NoError(t, err)
+		defer func() { _ = in.Close() }()
+		spans := map[string]*otlpSpan{}
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			var req otlpTraces
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &req))
+			for _, rs := range req.ResourceSpans {
+				assert.Contains(t, rs.Resource.Attributes, otlpKeyValue{Key: "service.name", Value: otlpFromValue(attribute.StringValue("rclone-test"))})
+				for _, ss := range rs.ScopeSpans {
+					assert.NotEqual(t, "", ss.Scope.Name)
+					for _, span := range ss.Spans {
+						spans[span.Name] = span
+					}
+				}
+			}
+		}
+		require.NoError(t, scanner.Err())
+		return spans
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, Init(ctx, &Options{}))
+	assert.False(t, Enabled())
+	newCtx, span := Start(ctx, "test")
+	assert.Equal(t, ctx, newCtx)
+	assert.False(t, span.IsRecording())
+	End(span, nil)
+	rt := http.DefaultTransport
+	assert.Equal(t, rt, Transport(rt))
+}
+
+func TestFileSpans(t *testing.T) {
+	stop := startFile(t)
+	assert.True(t, Enabled())
+
+	f, err := mockfs.NewFs(context.Background(), "remote", "root", nil)
+	require.NoError(t, err)
+	ctx, parent := Start(context.Background(), "parent", attribute.String("key", "value"))
+	_, child := StartFs(ctx, "Put", f, "dir/file.txt")
+	End(child, errors.New("upload failed"))
+	End(parent, nil)
+
+	spans := stop()
+	assert.False(t, Enabled())
+	require.Contains(t, spans, "parent")
+	require.Contains(t, spans, "Put")
+	p, c := spans["parent"], spans["Put"]
+
+	assert.Len(t, p.TraceID, 32)
+	assert.Len(t, p.SpanID, 16)
+	assert.Equal(t, "", p.ParentSpanID)
+	assert.Equal(t, 0, p.Status.Code)
+	assert.Contains(t, p.Attributes, otlpKeyValue{Key: "key", Value: otlpFromValue(attribute.StringValue("value"))})
+
+	assert.Equal(t, p.TraceID, c.TraceID)
+	assert.Equal(t, p.SpanID, c.ParentSpanID)
+	assert.Equal(t, otlpStatusError, c.Status.Code)
+	assert.Equal(t, "upload failed", c.Status.Message)
+	assert.Contains(t, c.Attributes, otlpKeyValue{Key: "rclone.fs", Value: otlpFromValue(attribute.StringValue("remote:root"))})
+	assert.Contains(t, c.Attributes, otlpKeyValue{Key: "rclone.remote", Value: otlpFromValue(attribute.StringValue("dir/file.txt"))})
+	require.Len(t, c.Events, 1)
+	assert.Equal(t, "exception", c.Events[0].Name)
+}
+
+func TestHTTPPropagation(t *testing.T) {
+	stop := startFile(t)
+
+	var serverTraceparent string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverTraceparent = r.Header.Get("traceparent")
+		_, span := Start(r.Context(), "handler")
+		End(span, nil)
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	ctx, parent := Start(context.Background(), "client")
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/path", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	End(parent, nil)
+
+	spans := stop()
+	assert.NotEqual(t, "", serverTraceparent)
+	require.Contains(t, spans, "client")
+	require.Contains(t, spans, "GET /path")
+	require.Contains(t, spans, "handler")
+	clientSpan, serverSpan, handlerSpan := spans["client"], spans["GET /path"], spans["handler"]
+
+	// The whole request is in a single trace
+	assert.Equal(t, clientSpan.TraceID, serverSpan.TraceID)
+	assert.Equal(t, clientSpan.TraceID, handlerSpan.TraceID)
+	assert.Equal(t, serverSpan.SpanID, handlerSpan.ParentSpanID)
+
+	// The server span is a child of the HTTP client span
+	var httpSpan *otlpSpan
+	for _, span := range spans {
+		if span.ParentSpanID == clientSpan.SpanID {
+			httpSpan = span
+		}
+	}
+	require.NotNil(t, httpSpan)
+	assert.Equal(t, httpSpan.SpanID, serverSpan.ParentSpanID)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/rclone/rclone/fs/dirtree"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/tracing"
 )
 
 // ErrorSkipDir is used as a return value from Walk to indicate that the
@@ -296,7 +297,8 @@ func listR(ctx context.Context, f fs.Fs, path string, includeAll bool, listType
 		dm = newDirMap(path)
 	}
 	var mu sync.Mutex
-	err := doListR(ctx, path, func(entries fs.DirEntries) (err error) {
+	spanCtx, span := tracing.StartFs(ctx, "ListR", f, path)
+	err := doListR(spanCtx, path, func(entries fs.DirEntries) (err error) {
 		accounting.Stats(ctx).Listed(int64(len(entries)))
 		if synthesizeDirs {
 			err = dm.addEntries(entries)
@@ -330,6 +332,7 @@ func listR(ctx context.Context, f fs.Fs, path string, includeAll bool, listType
 		defer mu.Unlock()
 		return fn(entries)
 	})
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
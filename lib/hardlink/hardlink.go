@@ -0,0 +1,89 @@
+// Package hardlink keeps track of hard linked files as they are
+// written so that backends can recreate later links as hard links
+// instead of writing the data again.
+package hardlink
+
+import (
+	"sync"
+)
+
+// Tracker remembers where the first file with each hard link ID was
+// written.
+//
+// A hard link ID identifies all the names of one file in the source,
+// for example the device and inode number of a local file.
+type Tracker struct {
+	mu    sync.Mutex
+	links map[string]*link // links by hard link ID
+	paths map[string]*link // links by the path they were written to
+}
+
+// link is the state for one hard link ID
+type link struct {
+	mu   sync.Mutex // held while the first file is being written
+	path string     // where the file was written or "" if not yet - Tracker.mu must be held
+}
+
+// New makes a new Tracker
+func New() *Tracker {
+	return &Tracker{
+		links: make(map[string]*link),
+		paths: make(map[string]*link),
+	}
+}
+
+// Acquire looks up the hard link ID id.
+//
+// If a file with this ID has already been written it returns its
+// path and the caller should hard link to it. Otherwise it returns ""
+// and the caller should write the file itself.
+//
+// Callers with the same ID are serialised until done is called, so a
+// later link waits for the first file to be written rather than
+// writing its own copy. done must be called exactly once with the
+// path of the file written, or "" if nothing new was written. If a
+// caller couldn't link to path and wrote the file itself then passing
+// its path to done replaces the old one.
+//
+// Thread safe.
+func (t *Tracker) Acquire(id string) (path string, done func(path string)) {
+	t.mu.Lock()
+	l, ok := t.links[id]
+	if !ok {
+		l = new(link)
+		t.links[id] = l
+	}
+	t.mu.Unlock()
+
+	l.mu.Lock()
+	t.mu.Lock()
+	path = l.path
+	t.mu.Unlock()
+	return path, func(path string) {
+		if path != "" {
+			t.mu.Lock()
+			delete(t.paths, l.path)
+			l.path = path
+			t.paths[path] = l
+			t.mu.Unlock()
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Rename tells the tracker that the file written at oldPath is now
+// at newPath, for example when a partial upload is renamed into
+// place.
+//
+// Thread safe.
+func (t *Tracker) Rename(oldPath, newPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.paths[oldPath]
+	if !ok {
+		return
+	}
+	delete(t.paths, oldPath)
+	l.path = newPath
+	t.paths[newPath] = l
+}
@@ -0,0 +1,70 @@
+package hardlink
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tr := New()
+
+	// First file with an ID is written
+	path, done := tr.Acquire("1:2")
+	assert.Equal(t, "", path)
+	done("dir/a")
+
+	// Later files link to it
+	path, done = tr.Acquire("1:2")
+	assert.Equal(t, "dir/a", path)
+	done("")
+
+	// Other IDs are independent
+	path, done = tr.Acquire("1:3")
+	assert.Equal(t, "", path)
+	done("")
+	path, done = tr.Acquire("1:3")
+	assert.Equal(t, "", path)
+	done("dir/c")
+
+	// A file written after a failed link replaces the old path
+	path, done = tr.Acquire("1:2")
+	assert.Equal(t, "dir/a", path)
+	done("dir/b")
+	path, done = tr.Acquire("1:2")
+	assert.Equal(t, "dir/b", path)
+	done("")
+
+	// Renamed files are followed
+	tr.Rename("dir/b", "dir/b2")
+	tr.Rename("unknown", "dir/d")
+	path, done = tr.Acquire("1:2")
+	assert.Equal(t, "dir/b2", path)
+	done("")
+	path, done = tr.Acquire("1:3")
+	assert.Equal(t, "dir/c", path)
+	done("")
+}
+
+func TestTrackerWaits(t *testing.T) {
+	tr := New()
+	_, done := tr.Acquire("id")
+
+	var wg sync.WaitGroup
+	var got string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var done2 func(string)
+		got, done2 = tr.Acquire("id")
+		done2("")
+	}()
+
+	// The second caller must wait for the first to finish writing
+	time.Sleep(50 * time.Millisecond)
+	done("first")
+	wg.Wait()
+	assert.Equal(t, "first", got)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/tracing"
 	"github.com/rclone/rclone/lib/atexit"
 	sdActivation "github.com/rclone/rclone/lib/sdactivation"
 	"github.com/spf13/pflag"
@@ -339,6 +340,9 @@ func NewServer(ctx context.Context, options ...Option) (*Server, error) {
 		opt(s)
 	}
 
+	// Trace requests, carrying on any trace the client started
+	s.mux.Use(tracing.Middleware)
+
 	// Build base router
 	s.mux.MethodNotAllowed(func(w http.ResponseWriter, _ *http.Request) {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
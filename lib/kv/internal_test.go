@@ -66,3 +66,29 @@ func TestKvExit(t *testing.T) {
 	Exit()
 	assert.Equal(t, 0, len(dbMap))
 }
+
+func TestKvOps(t *testing.T) {
+	require.Equal(t, 0, len(dbMap), "no databases can be started initially")
+	ctx := context.Background()
+	db, err := Start(ctx, "test-ops", nil)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Stop(true))
+	}()
+
+	get := func(key string) []byte {
+		op := &OpGet{Key: key}
+		require.NoError(t, db.Do(false, op))
+		return op.Value
+	}
+	for _, key := range []string{"a", "b/1", "b/2", "bc", "c"} {
+		require.NoError(t, db.Do(true, &OpPut{Key: key, Value: []byte("value " + key)}))
+	}
+	assert.Equal(t, []byte("value b/1"), get("b/1"))
+	assert.Nil(t, get("missing"))
+
+	require.NoError(t, db.Do(true, &OpDelete{Keys: []string{"a", "missing"}, Prefixes: []string{"b/"}}))
+	for key, want := range map[string]bool{"a": false, "b/1": false, "b/2": false, "bc": true, "c": true} {
+		assert.Equal(t, want, get(key) != nil, key)
+	}
+}
@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"strings"
+)
+
+// OpGet reads the value of Key into Value, which is left nil if the
+// key is not found
+type OpGet struct {
+	Key   string
+	Value []byte
+}
+
+// Do the operation
+func (op *OpGet) Do(ctx context.Context, b Bucket) error {
+	if data := b.Get([]byte(op.Key)); data != nil {
+		op.Value = bytes.Clone(data)
+	}
+	return nil
+}
+
+// OpPut writes Value to Key
+type OpPut struct {
+	Key   string
+	Value []byte
+}
+
+// Do the operation
+func (op *OpPut) Do(ctx context.Context, b Bucket) error {
+	return b.Put([]byte(op.Key), op.Value)
+}
+
+// OpDelete removes Keys and all the keys starting with any of Prefixes
+type OpDelete struct {
+	Keys     []string
+	Prefixes []string
+}
+
+// Do the operation
+func (op *OpDelete) Do(ctx context.Context, b Bucket) error {
+	keys := op.Keys
+	for _, prefix := range op.Prefixes {
+		cur := b.Cursor()
+		for bkey, _ := cur.Seek([]byte(prefix)); bkey != nil && strings.HasPrefix(string(bkey), prefix); bkey, _ = cur.Next() {
+			keys = append(keys, string(bkey))
+		}
+	}
+	for _, key := range keys {
+		if err := b.Delete([]byte(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ Op = (*OpGet)(nil)
+	_ Op = (*OpPut)(nil)
+	_ Op = (*OpDelete)(nil)
+)
@@ -282,6 +282,13 @@ func (d *Dir) changeNotify(relativePath string, entryType fs.EntryType) {
 	d.mu.RLock()
 	absPath := path.Join(d.path, relativePath)
 	d.mu.RUnlock()
+	// Changes to files and directories the VFS is writing are caused
+	// by the VFS so re-reading the directory would only lose track of
+	// them.
+	if d.vfs.isWriting(absPath) {
+		fs.Debugf(absPath, "Ignoring change notification as it is being written")
+		return
+	}
 	d.invalidateDir(vfscommon.FindParent(absPath))
 	if entryType == fs.EntryDirectory {
 		d.invalidateDir(absPath)
@@ -535,6 +542,10 @@ func (d *Dir) _readDir() error {
 	} else if err != nil {
 		return err
 	}
+	if d.vfs.poller != nil {
+		// Poll this directory for changes while it is in use
+		d.vfs.poller.Touch(d.vfs.ctx, d.path, entries)
+	}
 
 	if d.vfs.Opt.BlockNormDupes { // do this only if requested, as it will have a performance hit
 		ci := fs.GetConfig(d.vfs.ctx)
@@ -141,6 +141,35 @@ func TestDirForgetPath(t *testing.T) {
 	assert.Equal(t, 0, len(dir.items))
 }
 
+func TestDirChangeNotifyWriting(t *testing.T) {
+	_, vfs, dir, file1 := dirCreate(t)
+
+	// Make sure / and dir are in cache
+	_, err := vfs.Stat(file1.Path)
+	require.NoError(t, err)
+	root, err := vfs.Root()
+	require.NoError(t, err)
+
+	// Changes to a file being written are ignored
+	fd, err := vfs.OpenFile("dir/potato", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = fd.Write([]byte("hello"))
+	require.NoError(t, err)
+	root.changeNotify("dir/potato", fs.EntryObject)
+	assert.False(t, dir.read.IsZero())
+
+	// As are directories which have just been made
+	_, err = dir.Mkdir("sub")
+	require.NoError(t, err)
+	root.changeNotify("dir/sub", fs.EntryDirectory)
+	assert.False(t, dir.read.IsZero())
+
+	// But not once it has been written
+	require.NoError(t, fd.Close())
+	root.changeNotify("dir/potato", fs.EntryObject)
+	assert.True(t, dir.read.IsZero())
+}
+
 func TestDirWalk(t *testing.T) {
 	r, vfs, _, file1 := dirCreate(t)
 
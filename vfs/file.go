@@ -55,6 +55,7 @@ type File struct {
 	writers          []Handle                        // writers for this file
 	virtualModTime   *time.Time                      // modtime for backends with Precision == fs.ModTimeNotSupported
 	pendingModTime   time.Time                       // will be applied once o becomes available, i.e. after file was written
+	pendingMetadata  fs.Metadata                     // will be applied once o has been uploaded
 	pendingRenameFun func(ctx context.Context) error // will be run/renamed after all writers close
 	nwriters         atomic.Int32                    // len(writers)
 	appendMode       bool                            // file was opened with O_APPEND
@@ -471,6 +472,64 @@ func (f *File) SetModTime(modTime time.Time) error {
 	return nil
 }
 
+// SetMetadata sets the metadata of the file's object
+//
+// If the file is being written or is waiting to be uploaded from the
+// cache then the metadata is set once it has been uploaded.
+func (f *File) SetMetadata(metadata fs.Metadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.d.vfs.Opt.ReadOnly {
+		return EROFS
+	}
+
+	f.pendingMetadata.Merge(metadata)
+
+	// Only set the metadata when there is nothing to upload, setObject will do it
+	if !f._writingInProgress() && (f.d.vfs.cache == nil || f.d.vfs.cache.DirtyItem(f._cachePath()) == nil) {
+		return f._applyPendingMetadata()
+	}
+
+	// queue up for later, hoping f.o becomes available
+	return nil
+}
+
+// Apply pending metadata
+// Call with the mutex held
+func (f *File) _applyPendingMetadata() error {
+	if f.pendingMetadata == nil {
+		return nil
+	}
+	defer func() { f.pendingMetadata = nil }()
+
+	if f.o == nil {
+		return errors.New("cannot apply metadata, file object is not available")
+	}
+	do, ok := f.o.(fs.SetMetadataer)
+	if !ok {
+		return ENOSYS
+	}
+	err := do.SetMetadata(f.ctx, f.pendingMetadata)
+	if err != nil {
+		fs.Errorf(f.o, "Failed to apply pending metadata: %v", err)
+		return err
+	}
+	fs.Debugf(f.o, "Applied pending metadata OK")
+	return nil
+}
+
+// applyPendingMetadata applies any pending metadata unless the file
+// is waiting to be uploaded from the cache, in which case setObject
+// will do it
+func (f *File) applyPendingMetadata() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.d.vfs.cache != nil && f.d.vfs.cache.DirtyItem(f._cachePath()) != nil {
+		return nil
+	}
+	return f._applyPendingMetadata()
+}
+
 // Apply a pending mod time
 // Call with the mutex held
 func (f *File) _applyPendingModTime() error {
@@ -536,6 +595,7 @@ func (f *File) setObject(o fs.Object) {
 	f.o = o
 	f._setIsLink()
 	_ = f._applyPendingModTime()
+	_ = f._applyPendingMetadata()
 	d := f.d
 	f.mu.Unlock()
 
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/rclone/rclone/fs"
@@ -163,6 +164,52 @@ func TestFileSetModTime(t *testing.T) {
 	}
 }
 
+// Test setting metadata on a file open for writing which is applied
+// once the file has been uploaded, or when it is closed if it wasn't
+// written.
+func TestFileSetMetadata(t *testing.T) {
+	for _, cacheMode := range []vfscommon.CacheMode{vfscommon.CacheModeOff, vfscommon.CacheModeFull} {
+		for _, write := range []bool{false, true} {
+			t.Run(fmt.Sprintf("cache=%v,write=%v", cacheMode, write), func(t *testing.T) {
+				testFileSetMetadata(t, cacheMode, write)
+			})
+		}
+	}
+}
+
+func testFileSetMetadata(t *testing.T, cacheMode vfscommon.CacheMode, write bool) {
+	r, vfs, file, file1 := fileCreate(t, cacheMode)
+	if !r.Fremote.Features().WriteMetadata {
+		t.Skip("can't set metadata")
+	}
+
+	contents := "file1 contents"
+	flags := os.O_WRONLY
+	if write {
+		contents = "hello"
+		flags |= os.O_TRUNC
+	}
+	fd, err := file.Open(flags)
+	require.NoError(t, err)
+	if write {
+		_, err = fd.WriteString(contents)
+		require.NoError(t, err)
+	}
+
+	err = file.SetMetadata(fs.Metadata{"mtime": t2.Format(time.RFC3339Nano)})
+	require.NoError(t, err)
+
+	require.NoError(t, fd.Close())
+	vfs.WaitForWriters(waitForWritersDelay)
+
+	file1 = fstest.NewItem(file1.Path, contents, t2)
+	r.CheckRemoteItems(t, file1)
+
+	vfs.Opt.ReadOnly = true
+	err = file.SetMetadata(fs.Metadata{"mtime": t1.Format(time.RFC3339Nano)})
+	assert.Equal(t, EROFS, err)
+}
+
 func fileCheckContents(t *testing.T, file *File) {
 	fd, err := file.Open(os.O_RDONLY)
 	require.NoError(t, err)
@@ -168,8 +168,9 @@ func (fh *RWFileHandle) close() (err error) {
 		err = fh.item.Close(fh.file.setObject)
 		fh.opened = false
 	} else {
-		// apply any pending mod times if any
+		// apply any pending mod times and metadata if any
 		_ = fh.file.applyPendingModTime()
+		_ = fh.file.applyPendingMetadata()
 	}
 
 	if !fh.readOnly() {
@@ -40,6 +40,7 @@ import (
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/log"
+	"github.com/rclone/rclone/fs/pollnotify"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/vfs/vfscache"
@@ -190,7 +191,8 @@ type VFS struct {
 	usage       *fs.Usage
 	pollMu      sync.Mutex
 	pollChan    chan time.Duration
-	inUse       atomic.Int32 // count of number of opens
+	poller      *pollnotify.Poller // polls directory listings if the remote can't notify changes
+	inUse       atomic.Int32       // count of number of opens
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -253,6 +255,16 @@ func New(ctx context.Context, f fs.Fs, opt *vfscommon.Options) *VFS {
 		vfs.pollChan = make(chan time.Duration)
 		do(vfs.ctx, vfs.root.changeNotify, vfs.pollChan)
 		vfs.pollChan <- time.Duration(vfs.Opt.PollInterval)
+	} else if vfs.Opt.PollInterval > 0 && vfs.Opt.PollListings > 0 {
+		fs.Infof(f, "poll-interval is not supported by this remote so polling directory listings instead")
+		vfs.poller = pollnotify.New(f, pollnotify.Options{
+			MaxInterval:    time.Duration(vfs.Opt.DirCacheTime),
+			HotTime:        time.Duration(vfs.Opt.DirCacheTime),
+			ListsPerMinute: vfs.Opt.PollListings,
+		})
+		vfs.pollChan = make(chan time.Duration)
+		vfs.poller.ChangeNotify(vfs.ctx, vfs.root.changeNotify, vfs.pollChan)
+		vfs.pollChan <- time.Duration(vfs.Opt.PollInterval)
 	} else if vfs.Opt.PollInterval > 0 {
 		fs.Infof(f, "poll-interval is not supported by this remote")
 	}
@@ -488,6 +500,30 @@ func newInode() (inode uint64) {
 	return inodeCount.Add(1)
 }
 
+// isWriting returns true if the node at path is being written by the
+// VFS, either a file through an open handle or by uploading it from
+// the cache, or a directory it has created which hasn't been listed
+// yet.
+func (vfs *VFS) isWriting(path string) bool {
+	switch node := vfs.root.cachedNode(path).(type) {
+	case *File:
+		if node.activeWriters() > 0 {
+			return true
+		}
+	case *Dir:
+		node.mu.RLock()
+		parent, leaf := node.parent, name(node.path)
+		node.mu.RUnlock()
+		if parent == nil {
+			return false
+		}
+		parent.mu.RLock()
+		defer parent.mu.RUnlock()
+		return parent.virtual[leaf] == vAddDir
+	}
+	return vfs.cache != nil && vfs.cache.DirtyItem(path) != nil
+}
+
 // Stat finds the Node by path starting from the root
 //
 // It is the equivalent of os.Stat - Node contains the os.FileInfo
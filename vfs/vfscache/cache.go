@@ -62,6 +62,7 @@ type Cache struct {
 	cleanerKicked bool             // some thread kicked the cleaner upon out of space
 	kickerMu      sync.Mutex       // mutex for cleanerKicked
 	kick          chan struct{}    // channel for kicking clear to start
+	locker        ItemLocker       // if set, used to skip items in use by other processes
 
 }
 
@@ -73,6 +74,19 @@ type Cache struct {
 // go into the directory tree.
 type AddVirtualFn func(remote string, size int64, isDir bool) error
 
+// ItemLocker can be set with SetItemLocker to share the cache
+// directory with other processes.
+//
+// The cache cleaner calls TryLock before removing an item which isn't
+// open in this process and skips the item if it returns false.
+type ItemLocker interface {
+	// TryLock attempts to lock the item called name without blocking
+	// and returns false if it is in use elsewhere.
+	TryLock(name string) bool
+	// Unlock releases a lock obtained with TryLock.
+	Unlock(name string)
+}
+
 // New creates a new cache hierarchy for fremote
 //
 // This starts background goroutines which can be cancelled with the
@@ -150,6 +164,14 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 	return c, nil
 }
 
+// SetItemLocker sets the locker used to coordinate removing items with
+// other processes sharing the cache directory.
+func (c *Cache) SetItemLocker(locker ItemLocker) {
+	c.mu.Lock()
+	c.locker = locker
+	c.mu.Unlock()
+}
+
 // Stats returns info about the Cache
 func (c *Cache) Stats() (out rc.Params) {
 	out = make(rc.Params)
@@ -592,6 +614,13 @@ func (c *Cache) KickCleaner() {
 // removeNotInUse removes items not in use with a possible maxAge cutoff
 // called with cache mutex locked and up-to-date c.used (as we update it directly here)
 func (c *Cache) removeNotInUse(item *Item, maxAge time.Duration, emptyOnly bool) {
+	if c.locker != nil {
+		if !c.locker.TryLock(item.name) {
+			fs.Debugf(c.fremote, "vfs cache RemoveNotInUse: item %s in use by another process", item.GetName())
+			return
+		}
+		defer c.locker.Unlock(item.name)
+	}
 	removed, spaceFreed := item.RemoveNotInUse(maxAge, emptyOnly)
 	// The item space might be freed even if we get an error after the cache file is removed
 	// The item will not be removed or reset the cache data is dirty (DataDirty)
@@ -654,7 +683,19 @@ func (c *Cache) purgeClean() {
 		if c.quotasOK() {
 			break
 		}
+		// Items open here can be reset, others may be open elsewhere
+		locked := false
+		if c.locker != nil && !item.inUse() {
+			if !c.locker.TryLock(item.name) {
+				fs.Debugf(c.fremote, "vfs cache purgeClean: item %s in use by another process", item.GetName())
+				continue
+			}
+			locked = true
+		}
 		resetResult, spaceFreed, err := item.Reset()
+		if locked {
+			c.locker.Unlock(item.name)
+		}
 		// The item space might be freed even if we get an error after the cache file is removed
 		// The item will not be removed or reset if the cache data is dirty (DataDirty)
 		c.used -= spaceFreed
@@ -535,6 +535,59 @@ func TestCacheInUse(t *testing.T) {
 	assert.False(t, c.InUse("potato"))
 }
 
+// testLocker is an ItemLocker which refuses to lock the items in busy
+type testLocker struct {
+	busy   map[string]bool
+	locked []string
+}
+
+func (l *testLocker) TryLock(name string) bool {
+	if l.busy[name] {
+		return false
+	}
+	l.locked = append(l.locked, name)
+	return true
+}
+
+func (l *testLocker) Unlock(name string) {
+	for i, locked := range l.locked {
+		if locked == name {
+			l.locked = append(l.locked[:i], l.locked[i+1:]...)
+			return
+		}
+	}
+	panic("unlock of item not locked: " + name)
+}
+
+func TestCacheItemLocker(t *testing.T) {
+	_, c := newTestCache(t)
+	locker := &testLocker{busy: map[string]bool{"potato": true}}
+	c.SetItemLocker(locker)
+
+	for _, name := range []string{"potato", "potato2"} {
+		item := c.Item(name)
+		require.NoError(t, item.Open(nil))
+		require.NoError(t, item.Close(nil))
+	}
+	assert.Equal(t, []string{
+		`name="potato" opens=0 size=0`,
+		`name="potato2" opens=0 size=0`,
+	}, itemAsString(c))
+
+	// Items locked elsewhere are kept
+	c.purgeOld(-10 * time.Second)
+	assert.Equal(t, []string{
+		`name="potato" opens=0 size=0`,
+	}, itemAsString(c))
+	assert.Empty(t, locker.locked)
+
+	// and removed once released
+	locker.busy = nil
+	c.purgeOld(-10 * time.Second)
+	assert.Equal(t, []string(nil), itemAsString(c))
+	assert.Empty(t, locker.locked)
+}
+
 func TestCacheDirtyItem(t *testing.T) {
 	_, c := newTestCache(t)
 
@@ -190,6 +190,13 @@ func (item *Item) getDiskSize() int64 {
 func (item *Item) load() (exists bool, err error) {
 	item.mu.Lock()
 	defer item.mu.Unlock()
+	return item._load()
+}
+
+// _load reads an item from the disk or returns nil if not found
+//
+// call with the lock held
+func (item *Item) _load() (exists bool, err error) {
 	osPathMeta := item.c.toOSPathMeta(item.name) // No locking in Cache
 	in, err := os.Open(osPathMeta)
 	if err != nil {
@@ -207,6 +214,25 @@ func (item *Item) load() (exists bool, err error) {
 	return true, nil
 }
 
+// Reload re-reads the item metadata from the disk if the item isn't
+// open or dirty.
+//
+// This is used when the cache directory is shared with other
+// processes to pick up changes they have made to the item.
+func (item *Item) Reload() error {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if item.opens != 0 || item.info.Dirty || item.graceTimer != nil {
+		return nil
+	}
+	exists, err := item._load()
+	if !exists {
+		item.info.clean()
+		return nil
+	}
+	return err
+}
+
 // save writes an item to the disk
 //
 // call with the lock held
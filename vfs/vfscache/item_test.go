@@ -342,6 +342,41 @@ func TestItemReload(t *testing.T) {
 	}, avInfos)
 }
 
+func TestItemReloadShared(t *testing.T) {
+	r, c := newItemTestCache(t)
+	_, obj, item := newFile(t, r, c, "existing")
+	all := ranges.Range{Pos: 0, Size: 100}
+
+	// Read the whole object into the cache
+	require.NoError(t, item.Open(obj))
+	buf := make([]byte, 100)
+	_, err := item.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.NoError(t, item.Close(nil))
+	assert.True(t, item.HasRange(all))
+
+	// Reload picks up the metadata from the disk
+	item.mu.Lock()
+	item.info.Rs = nil
+	item.mu.Unlock()
+	assert.False(t, item.HasRange(all))
+	require.NoError(t, item.Reload())
+	assert.True(t, item.HasRange(all))
+
+	// Reload leaves open items alone
+	osPathMeta := c.toOSPathMeta(item.name)
+	require.NoError(t, item.Open(obj))
+	require.NoError(t, os.Remove(osPathMeta))
+	require.NoError(t, item.Reload())
+	assert.True(t, item.HasRange(all))
+	require.NoError(t, item.Close(nil))
+
+	// Reload forgets the data if the metadata was removed
+	require.NoError(t, os.Remove(osPathMeta))
+	require.NoError(t, item.Reload())
+	assert.False(t, item.HasRange(all))
+}
+
 func TestItemReloadRemoteGone(t *testing.T) {
 	r, c := newItemTestCache(t)
 
@@ -40,6 +40,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(time.Minute),
 	Help:    "Time to wait between polling for changes, must be smaller than dir-cache-time and only on supported remotes (set 0 to disable)",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_poll_listings",
+	Default: 0,
+	Help:    "Max directory listings per minute to poll for changes on remotes which don't support it (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "read_only",
 	Default: false,
@@ -191,6 +196,7 @@ type Options struct {
 	DirCacheTime       fs.Duration   `config:"dir_cache_time"` // how long to consider directory listing cache valid
 	Refresh            bool          `config:"vfs_refresh"`    // refreshes the directory listing recursively on start
 	PollInterval       fs.Duration   `config:"poll_interval"`
+	PollListings       int           `config:"vfs_poll_listings"` // max listings per minute when polling directories for changes
 	Umask              FileMode      `config:"umask"`
 	UID                uint32        `config:"uid"`
 	GID                uint32        `config:"gid"`
@@ -224,6 +224,8 @@ func (fh *WriteFileHandle) closeWithError(reason error) (err error) {
 	}()
 	// If file not opened and not safe to truncate then leave file intact
 	if !fh.opened && !fh.safeToTruncate() {
+		// nothing is uploaded so apply any pending metadata now
+		_ = fh.file.applyPendingMetadata()
 		return nil
 	}
 	if err = fh.openPending(); err != nil {